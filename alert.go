@@ -0,0 +1,103 @@
+// alert.go
+package memsize
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultWatchInterval is how often the roots of a registry with thresholds are measured
+var DefaultWatchInterval = 10 * time.Second
+
+// threshold is a callback waiting for a root to grow past a limit
+type threshold struct {
+	name  string
+	limit uint64
+	fn    func(*Report)
+	opts  []Option
+	// over is set while the root exceeds the limit, so fn is called once per crossing
+	over bool
+}
+
+// OnThreshold calls fn with a report of the root registered under name in DefaultRegistry
+// once its size exceeds limit, see Registry.OnThreshold
+func OnThreshold(name string, limit uint64, fn func(*Report), opts ...Option) (cancel func()) {
+	return DefaultRegistry.OnThreshold(name, limit, fn, opts...)
+}
+
+// OnThreshold calls fn with a report of the root registered under name the first time its
+// size exceeds limit, e.g. to log the breakdown and evict entries of a cache. fn is called
+// again only after the size dropped back to the limit and crossed it once more.
+//
+// Roots with thresholds are measured every DefaultWatchInterval by a goroutine that runs until
+// all thresholds of the registry are cancelled. A root registered later is watched as well.
+func (r *Registry) OnThreshold(name string, limit uint64, fn func(*Report), opts ...Option) (cancel func()) {
+	t := &threshold{name: name, limit: limit, fn: fn, opts: opts}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.thresholds = append(r.thresholds, t)
+	if r.stopWatch == nil {
+		r.stopWatch = make(chan struct{})
+		go r.watch(DefaultWatchInterval, r.stopWatch)
+	}
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, other := range r.thresholds {
+			if other == t {
+				r.thresholds = append(r.thresholds[:i], r.thresholds[i+1:]...)
+				break
+			}
+		}
+		if len(r.thresholds) == 0 && r.stopWatch != nil {
+			close(r.stopWatch)
+			r.stopWatch = nil
+		}
+	}
+}
+
+func (r *Registry) watch(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.CheckThresholds()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// CheckThresholds measures the roots with thresholds right away and calls the callbacks of
+// those that crossed their limit, instead of waiting for the watcher
+func (r *Registry) CheckThresholds() {
+	r.mu.RLock()
+	thresholds := append([]*threshold(nil), r.thresholds...)
+	r.mu.RUnlock()
+
+	for _, t := range thresholds {
+		r.mu.RLock()
+		e, ok := r.roots[t.name]
+		r.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		root := resolveRoot(e.value)
+
+		// Roots below their limit have to be measured fully, those above only up to it
+		opts := append(t.opts[:len(t.opts):len(t.opts)], WithMaxBytes(t.limit))
+		size, err := GetTotalSizeE(root, opts...)
+		over := errors.Is(err, ErrLimitExceeded) || size > t.limit
+
+		r.mu.Lock()
+		crossed := over && !t.over
+		t.over = over
+		r.mu.Unlock()
+		if crossed {
+			t.fn(GetReport(root, t.opts...))
+		}
+	}
+}
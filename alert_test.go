@@ -0,0 +1,69 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOnThreshold(t *testing.T) {
+	Debug = false
+
+	cache := map[string][]byte{"a": make([]byte, 100)}
+	r := NewRegistry()
+	r.Register("cache", &cache)
+
+	var reports []*Report
+	cancel := r.OnThreshold("cache", 1000, func(report *Report) {
+		reports = append(reports, report)
+	})
+	defer cancel()
+
+	t.Run("Below", func(t *testing.T) {
+		r.CheckThresholds()
+		if len(reports) != 0 {
+			t.Errorf("Expected no alert below the limit, got %d", len(reports))
+		}
+	})
+
+	t.Run("Crossed", func(t *testing.T) {
+		cache["b"] = make([]byte, 2000)
+		r.CheckThresholds()
+		r.CheckThresholds()
+		if len(reports) != 1 {
+			t.Fatalf("Expected a single alert per crossing, got %d", len(reports))
+		}
+		fmt.Print(reports[0])
+		if reports[0].Total() <= 1000 || len(reports[0].Root.Children) == 0 {
+			t.Errorf("Expected a detailed report above the limit, got %d bytes", reports[0].Total())
+		}
+	})
+
+	t.Run("Crossed Again", func(t *testing.T) {
+		delete(cache, "b")
+		r.CheckThresholds()
+		cache["c"] = make([]byte, 2000)
+		r.CheckThresholds()
+		if len(reports) != 2 {
+			t.Errorf("Expected a second alert after dropping below the limit, got %d", len(reports))
+		}
+	})
+
+	t.Run("Watcher", func(t *testing.T) {
+		defer func(interval time.Duration) { DefaultWatchInterval = interval }(DefaultWatchInterval)
+		DefaultWatchInterval = time.Millisecond
+
+		big := make([]byte, 5000)
+		Register("memsize_test_alert", &big)
+		defer Unregister("memsize_test_alert")
+
+		alerts := make(chan *Report, 1)
+		cancel := OnThreshold("memsize_test_alert", 1000, func(report *Report) { alerts <- report })
+		defer cancel()
+		select {
+		case <-alerts:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the watcher to report the root above its limit")
+		}
+	})
+}
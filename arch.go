@@ -0,0 +1,171 @@
+// arch.go
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// archInfo describes how the gc compiler lays out types on a GOARCH
+type archInfo struct {
+	name     string
+	word     uint64
+	maxAlign uint64
+
+	// sizes caches a typeLayout per reflect.Type
+	sizes sync.Map
+}
+
+type typeLayout struct {
+	size, align uint64
+}
+
+// arches lists the architectures supported by WithArch
+var arches = map[string]*archInfo{}
+
+func init() {
+	for _, name := range []string{"386", "arm", "mips", "mipsle"} {
+		arches[name] = &archInfo{name: name, word: 4, maxAlign: 4}
+	}
+	for _, name := range []string{"amd64", "arm64", "loong64", "mips64", "mips64le", "ppc64",
+		"ppc64le", "riscv64", "s390x", "wasm"} {
+		arches[name] = &archInfo{name: name, word: 8, maxAlign: 8}
+	}
+}
+
+// layout is how a measurement lays out values: its size model and target architecture
+type layout struct {
+	model SizeModel
+	// arch is nil for the host architecture
+	arch *archInfo
+	// handlers are the tables of WithHandlers and DefaultHandlers the measurement started
+	// with, which change how types are sized
+	handlers handlerTables
+	// overheads is the model given with WithModel, nil for DefaultModel
+	overheads *Model
+}
+
+func (c *config) layout() layout {
+	return layout{model: c.model, arch: c.arch, handlers: c.tables, overheads: c.overheads}
+}
+
+// sizeof returns the size of values of type t on the target architecture
+func (l layout) sizeof(t reflect.Type) uint64 {
+	if l.arch == nil {
+		return uint64(t.Size())
+	}
+	return l.arch.layoutOf(t).size
+}
+
+// layoutOf returns the size and alignment of values of type t on the target architecture
+func (l layout) layoutOf(t reflect.Type) typeLayout {
+	if l.arch == nil {
+		return typeLayout{uint64(t.Size()), uint64(t.Align())}
+	}
+	return l.arch.layoutOf(t)
+}
+
+// word returns the pointer size of the target architecture
+func (l layout) word() uint64 {
+	if l.arch == nil {
+		return uint64(unsafe.Sizeof(uintptr(0)))
+	}
+	return l.arch.word
+}
+
+// hmapSize is the size of the runtime's map header: by default a count, four flag bytes, a
+// 32-bit hash seed and four words
+func (l layout) hmapSize() uint64 {
+	m := l.overhead()
+	return m.MapHeaderWords*l.word() + m.MapHeaderBytes
+}
+
+// hchanSize approximates the size of the runtime's channel header
+func (l layout) hchanSize() uint64 {
+	return l.overhead().ChanHeaderWords * l.word()
+}
+
+// WithArch sizes values as laid out on the given GOARCH, e.g. "386", "arm" or "wasm", instead
+// of the host, using its pointer width and alignment rules. It selects ExactSizes, since legacy
+// sizes don't depend on the architecture. Unknown architectures make GetTotalSizeE fail.
+func WithArch(goarch string) Option {
+	return func(c *config) {
+		c.model = ExactSizes
+		c.arch = arches[goarch]
+		if c.arch == nil {
+			c.err = fmt.Errorf("memsize: unsupported GOARCH %q", goarch)
+		}
+	}
+}
+
+// layoutOf computes the size and alignment of t following the gc compiler's rules
+func (a *archInfo) layoutOf(t reflect.Type) typeLayout {
+	if l, ok := a.sizes.Load(t); ok {
+		return l.(typeLayout)
+	}
+
+	var l typeLayout
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint, reflect.Uintptr, reflect.Ptr, reflect.Map, reflect.Chan,
+		reflect.Func, reflect.UnsafePointer:
+		l = typeLayout{a.word, a.word}
+	case reflect.String, reflect.Interface:
+		l = typeLayout{2 * a.word, a.word}
+	case reflect.Slice:
+		l = typeLayout{3 * a.word, a.word}
+	case reflect.Complex64:
+		l = typeLayout{8, 4}
+	case reflect.Complex128:
+		l = typeLayout{16, min64(8, a.maxAlign)}
+	case reflect.Array:
+		elem := a.layoutOf(t.Elem())
+		l = typeLayout{uint64(t.Len()) * elem.size, elem.align}
+	case reflect.Struct:
+		l = a.structLayout(t)
+	default:
+		size := uint64(t.Size())
+		l = typeLayout{size, min64(size, a.maxAlign)}
+	}
+
+	a.sizes.Store(t, l)
+	return l
+}
+
+func (a *archInfo) structLayout(t reflect.Type) typeLayout {
+	fields := make([]typeLayout, t.NumField())
+	for i := range fields {
+		fields[i] = a.layoutOf(t.Field(i).Type)
+	}
+	return packFields(fields)
+}
+
+// packFields lays out struct fields in the given order
+func packFields(fields []typeLayout) typeLayout {
+	var offset, last uint64
+	align := uint64(1)
+	for _, field := range fields {
+		if field.align > align {
+			align = field.align
+		}
+		offset = alignUp(offset, field.align) + field.size
+		last = field.size
+	}
+	// A trailing zero-size field gets a byte, so pointers to it don't point past the struct
+	if len(fields) > 0 && last == 0 && offset > 0 {
+		offset++
+	}
+	return typeLayout{alignUp(offset, align), align}
+}
+
+func alignUp(n, align uint64) uint64 {
+	return (n + align - 1) / align * align
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
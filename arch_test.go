@@ -0,0 +1,97 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestArch(t *testing.T) {
+	Debug = false
+
+	type padded struct {
+		A int8
+		B int64
+	}
+	type trailing struct {
+		A int64
+		B struct{}
+	}
+	type complexField struct {
+		A int8
+		C complex128
+	}
+
+	t.Run("Layout", func(t *testing.T) {
+		tests := []struct {
+			value      interface{}
+			i386, wasm uint64
+		}{
+			{int(0), 4, 8},
+			{"", 8, 16},
+			{[]int(nil), 12, 24},
+			{map[int]int(nil), 4, 8},
+			{padded{}, 12, 16},
+			{trailing{}, 12, 16},
+			{complexField{}, 20, 24},
+			{[3]padded{}, 36, 48},
+		}
+		for _, tt := range tests {
+			typ := reflect.TypeOf(tt.value)
+			if size := arches["386"].layoutOf(typ).size; size != tt.i386 {
+				t.Errorf("%s on 386: expected %d bytes, got %d", typ, tt.i386, size)
+			}
+			if size := arches["wasm"].layoutOf(typ).size; size != tt.wasm {
+				t.Errorf("%s on wasm: expected %d bytes, got %d", typ, tt.wasm, size)
+			}
+		}
+	})
+
+	t.Run("Totals", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			value    interface{}
+			expected uint64
+		}{
+			{"String", "hello", 8 + 5},
+			{"String Slice", []string{"a", "bb"}, 12 + 2*8 + 3},
+			{"Pointer", &padded{}, 4 + 12},
+			// Header, 28 byte hmap and a bucket of 8 tophash bytes, keys, values and an overflow pointer
+			{"Map", map[int32]int32{1: 1}, 4 + 28 + 8*(1+4+4) + 4},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				size := GetTotalSize(tt.value, WithArch("arm"))
+				if size != tt.expected {
+					t.Errorf("Expected %d bytes, got %d", tt.expected, size)
+				}
+				if report := GetReport(tt.value, WithArch("arm")); report.Total() != size {
+					t.Errorf("Expected the report total %d to match %d", report.Total(), size)
+				}
+			})
+		}
+	})
+
+	t.Run("Host", func(t *testing.T) {
+		if _, ok := arches[runtime.GOARCH]; !ok {
+			t.Skipf("%s is not supported", runtime.GOARCH)
+		}
+		v := &Person{
+			Name:    "host",
+			Friends: []*Person{{Name: "friend"}},
+			Data:    map[string]interface{}{"pad": padded{}, "tail": trailing{}, "c": complexField{}},
+		}
+		host := GetTotalSize(v, WithSizeModel(ExactSizes))
+		if size := GetTotalSize(v, WithArch(runtime.GOARCH)); size != host {
+			t.Errorf("Expected %s to match the host's %d bytes, got %d", runtime.GOARCH, host, size)
+		}
+		fmt.Printf("Person: %d bytes on %s, %d on 386\n", host, runtime.GOARCH, GetTotalSize(v, WithArch("386")))
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		if _, err := GetTotalSizeE(1, WithArch("z80")); err == nil {
+			t.Error("Expected an error for an unknown GOARCH")
+		}
+	})
+}
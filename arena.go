@@ -0,0 +1,87 @@
+// arena.go
+package memsize
+
+import (
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// arenaRegion is a block of memory objects are allocated from, see WithArena
+type arenaRegion struct {
+	memRegion
+	name string
+}
+
+// arenaBlock is the type arenas are marked as visited with, so every measurement sharing the
+// visited objects counts an arena once
+type arenaBlock struct{}
+
+var arenaBlockType = reflect.TypeOf(arenaBlock{})
+
+// WithArena declares size bytes at start as an arena: a block, such as a large slice or a
+// chunk of an experimental arena, that objects are allocated from. The first pointer or slice
+// referring into the arena counts its whole capacity and the others count nothing, instead of
+// summing the objects they refer to and counting the block holding them on top. Objects in the
+// arena are not traversed, so memory they reference elsewhere is only counted if reached
+// otherwise. Reports name the arena in Node.Arena, see Report.Arenas.
+func WithArena(name string, start unsafe.Pointer, size uintptr) Option {
+	return func(c *config) {
+		if size == 0 {
+			return
+		}
+		a := arenaRegion{memRegion{uintptr(start), uintptr(start) + size}, name}
+		c.arenas = append(c.arenas, a)
+		sort.Slice(c.arenas, func(i, j int) bool { return c.arenas[i].start < c.arenas[j].start })
+	}
+}
+
+// arenaOf returns the arena addr lies in, or nil
+func (w *walker) arenaOf(addr uintptr) *arenaRegion {
+	arenas := w.cfg.arenas
+	if len(arenas) == 0 {
+		return nil
+	}
+	i := sort.Search(len(arenas), func(i int) bool { return arenas[i].end > addr })
+	if i < len(arenas) && arenas[i].start <= addr {
+		return &arenas[i]
+	}
+	return nil
+}
+
+// enterArena accounts for a reference into the arena a, returning the capacity of the arena
+// the first time it is reached. The memory referenced is not traversed.
+func (w *walker) enterArena(f *frame, a *arenaRegion) uint64 {
+	seen := w.visitAddr(a.start, arenaBlockType)
+	f.arena = a
+	f.addr, f.shared = a.start, seen
+	if f.node != nil {
+		f.node.Arena = a.name
+		f.node.Addr = a.start
+		f.node.Shared = seen
+	}
+	if seen {
+		return 0
+	}
+	size := uint64(a.end - a.start)
+	if w.cfg.debug {
+		w.debugPrint(f, "Arena %s of %d bytes", a.name, size)
+	}
+	return size
+}
+
+// Arenas returns the capacity of every arena the report reached by name, see WithArena. It is
+// part of the sizes of the nodes referring to them first.
+func (r *Report) Arenas() map[string]uint64 {
+	arenas := make(map[string]uint64)
+	if r == nil || r.Root == nil {
+		return arenas
+	}
+	r.Walk(func(n *Node) bool {
+		if n.Arena != "" && !n.Shared {
+			arenas[n.Arena] += n.Alloc
+		}
+		return true
+	})
+	return arenas
+}
@@ -0,0 +1,63 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+)
+
+func TestWithArena(t *testing.T) {
+	Debug = false
+
+	type item struct {
+		id    int
+		value float64
+	}
+	slab := make([]item, 100)
+	ptrs := []*item{&slab[0], &slab[10], &slab[99]}
+	arena := WithArena("items", unsafe.Pointer(&slab[0]), unsafe.Sizeof(item{})*100)
+	opts := []Option{WithSizeModel(ExactSizes), arena}
+
+	t.Run("Pointers", func(t *testing.T) {
+		expected := uint64(24 + 3*8 + 1600)
+		if got := GetTotalSize(ptrs, opts...); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+
+	t.Run("Slices", func(t *testing.T) {
+		// Sub-slices of the arena and pointers into it share its capacity
+		v := struct {
+			all  []item
+			some []item
+			one  *item
+		}{slab, slab[50:60], &slab[5]}
+		expected := uint64(24 + 24 + 8 + 1600)
+		if got := GetTotalSize(&v, opts...); got != 8+expected {
+			t.Errorf("Expected %d, got %d", 8+expected, got)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		report := GetReport(ptrs, opts...)
+		fmt.Printf("Report:\n%s", report)
+		arenas := report.Arenas()
+		if len(arenas) != 1 || arenas["items"] != 1600 {
+			t.Errorf("Expected the arena items of 1600 bytes, got %v", arenas)
+		}
+		first, second := report.Root.Children[0], report.Root.Children[1]
+		if first.Arena != "items" || first.Shared || first.Size != 8+1600 {
+			t.Errorf("Expected the first pointer to count the arena, got %+v", first)
+		}
+		if second.Arena != "items" || !second.Shared || second.Size != 8 {
+			t.Errorf("Expected the second pointer to share the arena, got %+v", second)
+		}
+	})
+
+	t.Run("Outside", func(t *testing.T) {
+		other := &item{}
+		if got := GetTotalSize(other, opts...); got != 8+16 {
+			t.Errorf("Expected %d, got %d", 8+16, got)
+		}
+	})
+}
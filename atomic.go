@@ -0,0 +1,41 @@
+// atomic.go
+package memsize
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+func init() {
+	typeMatchers = append(typeMatchers, atomicPointerHandler)
+}
+
+// atomicPointerHandler sizes instantiations of atomic.Pointer[T], whose target is held in an
+// unsafe.Pointer field, by loading it as a *T. atomic.Value needs no special case: it
+// holds an interface, which is traversed like any other.
+func atomicPointerHandler(t reflect.Type) typeHandler {
+	if t.Kind() != reflect.Struct || t.PkgPath() != "sync/atomic" || !strings.HasPrefix(t.Name(), "Pointer[") {
+		return nil
+	}
+	field, ok := t.FieldByName("v")
+	if !ok || field.Type.Kind() != reflect.UnsafePointer {
+		return nil
+	}
+	// The first field is a [0]*T tying the type parameter to the struct
+	ptrType := t.Field(0).Type.Elem()
+
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		p, ok := pointerTo(v)
+		if !ok {
+			w.strict.reject(path, v.Type(),
+				"atomic.Pointer values of unexported fields reached by value cannot be loaded")
+			return headerSize(ptrType, w.cfg.layout()), nil
+		}
+		target := atomic.LoadPointer((*unsafe.Pointer)(unsafe.Add(p, field.Offset)))
+
+		// The loaded pointer accounts for the field holding it
+		return 0, []handledChild{{v: reflect.NewAt(ptrType.Elem(), target), suffix: ".load"}}
+	}
+}
@@ -0,0 +1,55 @@
+package memsize
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAtomic(t *testing.T) {
+	Debug = false
+
+	t.Run("Pointer", func(t *testing.T) {
+		s := strings.Repeat("x", 1000)
+		var p atomic.Pointer[string]
+		p.Store(&s)
+
+		if size := GetTotalSize(&p); size < 1000 {
+			t.Errorf("Expected the stored string to be counted, got %d", size)
+		}
+		// The atomic pointer is sized like the pointer it holds
+		size := GetTotalSize(&p, WithSizeModel(ExactSizes))
+		if plain := GetTotalSize(&struct{ P *string }{&s}, WithSizeModel(ExactSizes)); size != plain {
+			t.Errorf("Expected %d bytes like a plain pointer, got %d", plain, size)
+		}
+	})
+
+	t.Run("Nil Pointer", func(t *testing.T) {
+		var p atomic.Pointer[string]
+		// A pointer to the atomic pointer holding a nil pointer
+		if size := GetTotalSize(&p, WithSizeModel(ExactSizes)); size != 16 {
+			t.Errorf("Expected two pointers of 8 bytes, got %d", size)
+		}
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		b := &blob{Data: make([]byte, 1000)}
+		var p atomic.Pointer[blob]
+		p.Store(b)
+		v := &struct {
+			Current *atomic.Pointer[blob]
+			Direct  *blob
+		}{&p, b}
+		if size := GetTotalSize(v, WithSizeModel(ExactSizes)); size > 1100 {
+			t.Errorf("Expected the blob to be counted once, got %d", size)
+		}
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		var v atomic.Value
+		v.Store(strings.Repeat("x", 1000))
+		if size := GetTotalSize(&v); size < 1000 {
+			t.Errorf("Expected the stored string to be counted, got %d", size)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+// attribution.go
+package memsize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HeapAttribution splits the live heap of the process between the roots of a registry, e.g.
+// "of our 4 GiB heap, cacheA holds 1.2 GiB, router 300 MiB, and 2.5 GiB is unattributed"
+type HeapAttribution struct {
+	// Heap is the live heap according to runtime/metrics, see HeapMetrics
+	Heap uint64
+	// Roots lists the registered roots ordered by name, and Shared is the part of Attributed
+	// reachable from more than one of them
+	Roots  []RootSize
+	Shared uint64
+	// Attributed counts every object reachable from any root once; Unattributed is the rest
+	// of the heap, held by roots nobody registered or by garbage not collected yet
+	Attributed   uint64
+	Unattributed uint64
+	// Truncated is set when a limit stopped the traversal early
+	Truncated bool
+}
+
+// String summarizes the attribution, largest roots first
+func (a HeapAttribution) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "heap %s:", Format(a.Heap))
+	roots := append([]RootSize(nil), a.Roots...)
+	sort.SliceStable(roots, func(i, j int) bool { return roots[i].Size > roots[j].Size })
+	for _, r := range roots {
+		fmt.Fprintf(&b, " %s=%s,", r.Name, Format(r.Size))
+	}
+	if a.Shared > 0 {
+		fmt.Fprintf(&b, " shared=%s,", Format(a.Shared))
+	}
+	fmt.Fprintf(&b, " unattributed=%s", Format(a.Unattributed))
+	return b.String()
+}
+
+// AttributeHeap attributes the heap to the roots of DefaultRegistry, see Registry.AttributeHeap
+func AttributeHeap(opts ...Option) HeapAttribution {
+	return DefaultRegistry.AttributeHeap(opts...)
+}
+
+// AttributeHeap measures the registered roots together and compares them with the live heap
+// read from runtime/metrics once they are measured. The heap figure dates from the last
+// collection; WithGC collects first, so it is current at the cost of pausing the program.
+// Sizes compare best with the heap under WithSizeClasses, which counts whole allocations.
+func (r *Registry) AttributeHeap(opts ...Option) HeapAttribution {
+	m := r.MeasureAll(opts...)
+	a := HeapAttribution{Heap: ReadHeapMetrics().heap(), Roots: m.Roots, Shared: m.Shared,
+		Attributed: m.Total, Truncated: m.Truncated}
+	if a.Heap > a.Attributed {
+		a.Unattributed = a.Heap - a.Attributed
+	}
+	return a
+}
@@ -0,0 +1,33 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAttributeHeap(t *testing.T) {
+	Debug = false
+
+	cache := make([][]byte, 64)
+	for i := range cache {
+		cache[i] = make([]byte, 16<<10)
+	}
+	router := map[string][]byte{"/": make([]byte, 256<<10)}
+
+	r := NewRegistry()
+	r.Register("cache", &cache)
+	r.Register("router", &router)
+
+	a := r.AttributeHeap(WithGC(), WithSizeClasses())
+	fmt.Println(a)
+	if len(a.Roots) != 2 || a.Roots[0].Name != "cache" || a.Roots[0].Size < 1<<20 {
+		t.Errorf("Expected both roots with the cache above 1 MiB, got %+v", a.Roots)
+	}
+	if a.Heap < a.Attributed || a.Attributed+a.Unattributed != a.Heap {
+		t.Errorf("Expected the heap split into attributed and unattributed bytes, got %+v", a)
+	}
+	if s := a.String(); !strings.HasPrefix(s, "heap ") || strings.Index(s, "cache=") > strings.Index(s, "router=") {
+		t.Errorf("Expected the largest root first, got %q", s)
+	}
+}
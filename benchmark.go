@@ -0,0 +1,71 @@
+// benchmark.go
+package memsize
+
+import (
+	"runtime"
+	"time"
+)
+
+// selfBenchmarkTime is about how long SelfBenchmark spends measuring, and selfBenchmarkRuns
+// bounds the number of measurements it averages
+const (
+	selfBenchmarkTime = 100 * time.Millisecond
+	selfBenchmarkRuns = 1000
+)
+
+// SelfCost is the cost of measuring a value, averaged over several measurements
+type SelfCost struct {
+	// Runs is the number of measurements averaged
+	Runs int
+	// Duration is the wall time of one measurement
+	Duration time.Duration
+	// Allocs and AllocBytes are the heap allocations of one measurement
+	Allocs     uint64
+	AllocBytes uint64
+	// Nodes is the number of values one measurement visits
+	Nodes uint64
+}
+
+// PerNode returns the time spent per value visited
+func (c SelfCost) PerNode() time.Duration {
+	if c.Nodes == 0 {
+		return 0
+	}
+	return c.Duration / time.Duration(c.Nodes)
+}
+
+// SelfBenchmark measures v with opts repeatedly and reports how long and how many allocations
+// one measurement takes, to decide whether measuring it on a hot path is affordable or should
+// be sampled or cached instead. It takes about 100ms, runs a first measurement to warm up the
+// pooled scratch memory and cached plans, and reads runtime.MemStats, which briefly stops the
+// world. Allocations of other goroutines in the meantime are counted as well.
+func SelfBenchmark(v interface{}, opts ...Option) SelfCost {
+	_, stats, _ := GetTotalSizeStats(v, opts...)
+	runs := selfBenchmarkRuns
+	if stats.Duration > 0 {
+		if n := int(selfBenchmarkTime / stats.Duration); n < runs {
+			runs = n
+		}
+	}
+	if runs < 1 {
+		runs = 1
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		GetTotalSizeE(v, opts...)
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	n := uint64(runs)
+	return SelfCost{
+		Runs:       runs,
+		Duration:   elapsed / time.Duration(runs),
+		Allocs:     (after.Mallocs - before.Mallocs) / n,
+		AllocBytes: (after.TotalAlloc - before.TotalAlloc) / n,
+		Nodes:      stats.Nodes,
+	}
+}
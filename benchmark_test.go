@@ -0,0 +1,21 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSelfBenchmark(t *testing.T) {
+	Debug = false
+
+	nodes := buildGraph(1000)
+	cost := SelfBenchmark(nodes)
+	fmt.Printf("Self cost: %+v, %v per node\n", cost, cost.PerNode())
+	if cost.Runs < 1 || cost.Duration <= 0 || cost.Nodes == 0 || cost.PerNode() <= 0 {
+		t.Errorf("Expected the cost of at least one run, got %+v", cost)
+	}
+	// The traversal reuses pooled memory, so allocations don't grow with the value
+	if cost.Allocs >= cost.Nodes {
+		t.Errorf("Expected fewer allocations than the %d nodes, got %d", cost.Nodes, cost.Allocs)
+	}
+}
@@ -0,0 +1,163 @@
+// binary.go
+package memsize
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReportBinaryVersion is the version of the stream written by Report.WriteBinary
+const ReportBinaryVersion = 1
+
+type binaryHeader struct {
+	Version              int
+	Truncated            bool
+	OffHeapBytes         uint64
+	MappedBytes          uint64
+	StringBytes          uint64
+	DuplicateStringBytes uint64
+	SharedBytes          uint64
+	SharedObjects        []SharedObject
+	Groups               map[string]uint64
+	Unstable             []string
+	Errors               []pathErrorRecord
+	Model                *Model
+	// Nodes is false for reports without a root
+	Nodes bool
+}
+
+// binaryNode is a node without its children, which follow it in the stream
+type binaryNode struct {
+	Node     Node
+	Children int
+	// Relative is set when Node.Path only holds the suffix following the parent's path
+	Relative bool
+}
+
+// WriteBinary writes the report to w as a gob stream, which is much smaller and faster to
+// write and read than JSON for large reports. Nodes are written one at a time in depth-first
+// order with paths relative to their parent's, so the encoding holds no second copy of the
+// tree; ReadBinaryReport reads it back.
+func (r *Report) WriteBinary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := gob.NewEncoder(bw)
+	header := binaryHeader{
+		Version:              ReportBinaryVersion,
+		Truncated:            r.Truncated,
+		OffHeapBytes:         r.OffHeapBytes,
+		MappedBytes:          r.MappedBytes,
+		StringBytes:          r.StringBytes,
+		DuplicateStringBytes: r.DuplicateStringBytes,
+		SharedBytes:          r.SharedBytes,
+		SharedObjects:        r.SharedObjects,
+		Groups:               r.Groups,
+		Unstable:             r.Unstable,
+		Errors:               encodePathErrors(r.Errors),
+		Model:                r.Model,
+		Nodes:                r.Root != nil,
+	}
+	if err := enc.Encode(&header); err != nil {
+		return err
+	}
+
+	if r.Root != nil {
+		type queued struct{ node, parent *Node }
+		stack := []queued{{node: r.Root}}
+		for len(stack) > 0 {
+			q := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			n := q.node
+			b := binaryNode{Node: *n, Children: len(n.Children)}
+			b.Node.Children = nil
+			// Paths mostly extend their parent's, which would otherwise dominate the stream
+			if q.parent != nil && strings.HasPrefix(n.Path, q.parent.Path) {
+				b.Node.Path, b.Relative = n.Path[len(q.parent.Path):], true
+			}
+			if err := enc.Encode(&b); err != nil {
+				return err
+			}
+			for i := len(n.Children) - 1; i >= 0; i-- {
+				stack = append(stack, queued{node: n.Children[i], parent: n})
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadBinaryReport reads a report written by Report.WriteBinary. It may read past the end of
+// the report.
+func ReadBinaryReport(r io.Reader) (*Report, error) {
+	dec := gob.NewDecoder(bufio.NewReader(r))
+	var header binaryHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, err
+	}
+	switch {
+	case header.Version < 1:
+		return nil, fmt.Errorf("memsize: report stream has no version")
+	case header.Version > ReportBinaryVersion:
+		return nil, fmt.Errorf("memsize: report stream version %d is newer than supported version %d",
+			header.Version, ReportBinaryVersion)
+	}
+
+	report := &Report{
+		Truncated:            header.Truncated,
+		OffHeapBytes:         header.OffHeapBytes,
+		MappedBytes:          header.MappedBytes,
+		StringBytes:          header.StringBytes,
+		DuplicateStringBytes: header.DuplicateStringBytes,
+		SharedBytes:          header.SharedBytes,
+		SharedObjects:        header.SharedObjects,
+		Groups:               header.Groups,
+		Unstable:             header.Unstable,
+		Errors:               decodePathErrors(header.Errors),
+		Model:                header.Model,
+	}
+	if !header.Nodes {
+		return report, nil
+	}
+
+	// pending holds the nodes still missing children, with the number they miss
+	type pending struct {
+		node    *Node
+		missing int
+	}
+	var stack []pending
+	for {
+		var b binaryNode
+		if err := dec.Decode(&b); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("memsize: reading report node: %w", err)
+		}
+		n := new(Node)
+		*n = b.Node
+		if b.Children > 0 {
+			n.Children = make([]*Node, 0, b.Children)
+		}
+
+		if len(stack) == 0 {
+			report.Root = n
+		} else {
+			top := &stack[len(stack)-1]
+			if b.Relative {
+				n.Path = top.node.Path + n.Path
+			}
+			top.node.Children = append(top.node.Children, n)
+			top.missing--
+		}
+		if b.Children > 0 {
+			stack = append(stack, pending{node: n, missing: b.Children})
+		}
+		for len(stack) > 0 && stack[len(stack)-1].missing == 0 {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			return report, nil
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package memsize
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryReport(t *testing.T) {
+	Debug = false
+
+	type entry struct {
+		Name string
+		Tags []string
+		Next *entry
+	}
+	entries := make([]*entry, 1000)
+	for i := range entries {
+		entries[i] = &entry{Name: strings.Repeat("e", i%50), Tags: []string{"a", "b"}}
+		if i > 0 {
+			entries[i-1].Next = entries[i]
+		}
+	}
+	r := GetReport(entries)
+	r.ComputeRetained()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.WriteBinary(&buf); err != nil {
+			t.Fatal(err)
+		}
+		jsonData, _ := json.Marshal(r)
+		fmt.Printf("Binary: %d bytes, JSON: %d bytes\n", buf.Len(), len(jsonData))
+		if buf.Len() >= len(jsonData) {
+			t.Errorf("Expected the binary stream to be smaller than %d bytes, got %d", len(jsonData), buf.Len())
+		}
+
+		decoded, err := ReadBinaryReport(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, r) {
+			t.Error("Expected the decoded report to equal the original")
+		}
+		if diff := r.Diff(decoded); diff.Delta() != 0 || len(diff.Entries) != 0 {
+			t.Errorf("Expected no differences, got %+v", diff)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		r := &Report{Root: &Node{Path: "root", Size: 8}, Truncated: true, Unstable: []string{"root.x"},
+			Errors: []PathError{{Path: "root.x", Type: "[]int", Err: errors.New("index out of range")}},
+			SharedObjects: []SharedObject{{Type: "*int", Path: "root.a", Size: 8, References: 2,
+				Referrers: map[string]int{"root.a": 1, "root.b": 1}}},
+			Groups: map[string]uint64{"cache": 64, "other": 8}}
+		var buf bytes.Buffer
+		if err := r.WriteBinary(&buf); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := ReadBinaryReport(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, r) {
+			t.Errorf("Expected %+v after round trip, got %+v", r, decoded)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := (&Report{Truncated: true}).WriteBinary(&buf); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := ReadBinaryReport(&buf)
+		if err != nil || decoded.Root != nil || !decoded.Truncated {
+			t.Errorf("Expected an empty truncated report, got %+v, %v", decoded, err)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.WriteBinary(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadBinaryReport(bytes.NewReader(buf.Bytes()[:buf.Len()/2])); err == nil {
+			t.Error("Expected an error reading a truncated stream")
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// bloom.go
+package memsize
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// WithApproximateDedup replaces the exact set of visited objects with a Bloom filter sized for
+// expectedObjects distinct objects at the given false positive rate, e.g. 0.01. At 1% it
+// takes about 10 bits per object instead of 128, for graphs too large to dedupe exactly.
+//
+// Objects visited before are always recognized, so nothing is counted twice and cycles end.
+// A false positive takes an object seen for the first time as visited, dropping it and the
+// objects only reachable through it, so sizes are lower bounds: each object reached through a
+// pointer, interface, map or channel is dropped with probability at most errorRate as long as
+// no more than expectedObjects are visited, and the rate grows beyond that. Safe mode retries
+// can't forget the objects of a failed attempt and count them as visited.
+func WithApproximateDedup(expectedObjects uint64, errorRate float64) Option {
+	return func(c *config) {
+		if expectedObjects == 0 || errorRate <= 0 || errorRate >= 1 {
+			c.err = errors.New("memsize: WithApproximateDedup needs expected objects and an error rate between 0 and 1")
+			return
+		}
+		c.approxObjects, c.approxRate = expectedObjects, errorRate
+	}
+}
+
+// bloomSet is an addrSet answering from a Bloom filter, safe for concurrent use
+type bloomSet struct {
+	words  []uint64
+	bits   uint64
+	hashes int
+}
+
+func newBloomSet(n uint64, p float64) *bloomSet {
+	// The optimal filter for n keys at rate p has -n ln p / ln² 2 bits and ln 2 bits/n hashes
+	bits := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if bits < 1024 {
+		bits = 1024
+	}
+	words := (bits + 63) / 64
+	hashes := int(math.Round(float64(words*64) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomSet{words: make([]uint64, words), bits: words * 64, hashes: hashes}
+}
+
+func (s *bloomSet) visit(k visitKey) bool {
+	// Double hashing derives all probes from two hashes of the entry
+	h1 := entryOf(k).hash()
+	h2 := (h1>>29|h1<<35)*0xBF58476D1CE4E5B9 | 1
+	seen := true
+	for i := 0; i < s.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % s.bits
+		word, mask := &s.words[bit/64], uint64(1)<<(bit%64)
+		for {
+			old := atomic.LoadUint64(word)
+			if old&mask != 0 {
+				break
+			}
+			if atomic.CompareAndSwapUint64(word, old, old|mask) {
+				seen = false
+				break
+			}
+		}
+	}
+	return seen
+}
+
+func (s *bloomSet) bytes() uint64 {
+	return uint64(len(s.words)) * 8
+}
@@ -0,0 +1,61 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestApproximateDedup(t *testing.T) {
+	Debug = false
+
+	items := make([]*[4]int, 100000)
+	for i := range items {
+		items[i] = new([4]int)
+	}
+	// Every other element repeats the previous pointer
+	for i := 1; i < len(items); i += 2 {
+		items[i] = items[i-1]
+	}
+	exact, exactStats, _ := GetTotalSizeStats(items)
+
+	t.Run("Bounded", func(t *testing.T) {
+		size, stats, err := GetTotalSizeStats(items, WithApproximateDedup(uint64(len(items)), 0.01))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("Approximate: %d of %d bytes, visited set %d instead of %d bytes\n",
+			size, exact, stats.VisitedBytes, exactStats.VisitedBytes)
+		// Dropped objects are at most 1% of the 50000 distinct ones, 32 bytes each
+		if size > exact || exact-size > 500*32 {
+			t.Errorf("Expected at most %d bytes missing from %d, got %d", 500*32, exact, size)
+		}
+		if stats.VisitedBytes*8 > exactStats.VisitedBytes {
+			t.Errorf("Expected a visited set much smaller than %d bytes, got %d", exactStats.VisitedBytes, stats.VisitedBytes)
+		}
+	})
+
+	t.Run("Cycles", func(t *testing.T) {
+		type node struct{ next *node }
+		a := &node{}
+		a.next = &node{next: a}
+		if got := GetTotalSize(a, WithApproximateDedup(10, 0.01)); got != GetTotalSize(a) {
+			t.Errorf("Expected %d, got %d", GetTotalSize(a), got)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		size := GetTotalSize(items, WithApproximateDedup(uint64(len(items)), 0.01), WithParallelism(4))
+		if size > exact || exact-size > 500*32 {
+			t.Errorf("Expected at most %d bytes missing from %d, got %d", 500*32, exact, size)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := GetTotalSizeE(items, WithApproximateDedup(0, 0.01)); err == nil {
+			t.Error("Expected an error without expected objects")
+		}
+		if _, err := GetTotalSizeE(items, WithApproximateDedup(10, 1)); err == nil {
+			t.Error("Expected an error for a rate of 1")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+// bounded.go
+package memsize
+
+import "sync"
+
+// BoundedContainer keeps a running size estimate of the items of a container, such as a
+// cache, and evicts items through a callback whenever the estimate exceeds a budget. Each item
+// is measured once when added, with the traversal plans cached per type, so a size-bounded LRU
+// cache never measures the whole container again.
+//
+// Items are measured on their own: objects shared between items count once per item, and items
+// mutated after they were added keep the size they had then unless added again.
+type BoundedContainer[K comparable] struct {
+	max   uint64
+	evict func() (K, bool)
+	opts  []Option
+
+	mu    sync.Mutex
+	sizes map[K]uint64
+	total uint64
+}
+
+// NewBoundedContainer creates a BoundedContainer holding at most maxBytes. When an item pushes
+// the total over the budget, evict is called until it fits again: it removes an item from the
+// container, e.g. the least recently used one, and returns its key, or returns false if there is
+// nothing left to evict. Items are measured with the given options.
+func NewBoundedContainer[K comparable](maxBytes uint64, evict func() (K, bool), opts ...Option) *BoundedContainer[K] {
+	return &BoundedContainer[K]{max: maxBytes, evict: evict, opts: opts, sizes: make(map[K]uint64)}
+}
+
+// Add measures value and records it under key, replacing the size of an item added under the
+// same key before, then evicts items while the total exceeds the budget. It returns the size of
+// value. The item just added may be evicted too if evict returns its key.
+func (c *BoundedContainer[K]) Add(key K, value interface{}) uint64 {
+	size := GetTotalSize(value, c.opts...)
+	c.mu.Lock()
+	c.total += size - c.sizes[key]
+	c.sizes[key] = size
+	c.mu.Unlock()
+
+	// evict runs unlocked, so it may call Remove or Size
+	for c.Size() > c.max {
+		victim, ok := c.evict()
+		if !ok {
+			break
+		}
+		c.Remove(victim)
+	}
+	return size
+}
+
+// Remove forgets the item recorded under key, for items the container dropped on its own
+func (c *BoundedContainer[K]) Remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size, ok := c.sizes[key]; ok {
+		c.total -= size
+		delete(c.sizes, key)
+	}
+}
+
+// ItemSize returns the recorded size of the item under key
+func (c *BoundedContainer[K]) ItemSize(key K) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size, ok := c.sizes[key]
+	return size, ok
+}
+
+// Size returns the total size of the recorded items
+func (c *BoundedContainer[K]) Size() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Len returns the number of recorded items
+func (c *BoundedContainer[K]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sizes)
+}
@@ -0,0 +1,97 @@
+package memsize
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBoundedContainer(t *testing.T) {
+	Debug = false
+
+	// A minimal LRU cache bounded through the container
+	order := list.New()
+	items := make(map[int]*list.Element)
+	var c *BoundedContainer[int]
+	c = NewBoundedContainer(4096, func() (int, bool) {
+		oldest := order.Back()
+		if oldest == nil {
+			return 0, false
+		}
+		order.Remove(oldest)
+		key := oldest.Value.(int)
+		delete(items, key)
+		return key, true
+	})
+	put := func(key int, value string) {
+		if e, ok := items[key]; ok {
+			order.MoveToFront(e)
+		} else {
+			items[key] = order.PushFront(key)
+		}
+		c.Add(key, value)
+	}
+
+	t.Run("Evicts", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			put(i, strings.Repeat("x", 200))
+		}
+		fmt.Printf("Bounded: %d items, %d bytes\n", c.Len(), c.Size())
+		if c.Size() > 4096 || c.Len() != len(items) || c.Len() == 0 {
+			t.Errorf("Expected at most 4096 bytes in %d items, got %d in %d", len(items), c.Size(), c.Len())
+		}
+		if _, ok := items[99]; !ok {
+			t.Error("Expected the most recent item to be kept")
+		}
+		if _, ok := c.ItemSize(0); ok {
+			t.Error("Expected the oldest item to be evicted")
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		before := c.Size()
+		old, _ := c.ItemSize(99)
+		put(99, "short")
+		size, _ := c.ItemSize(99)
+		if c.Size() != before-old+size {
+			t.Errorf("Expected %d bytes after replacing the item, got %d", before-old+size, c.Size())
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		size, _ := c.ItemSize(99)
+		before := c.Size()
+		c.Remove(99)
+		c.Remove(99)
+		if c.Size() != before-size {
+			t.Errorf("Expected %d bytes after removing the item, got %d", before-size, c.Size())
+		}
+	})
+
+	t.Run("Nothing To Evict", func(t *testing.T) {
+		c := NewBoundedContainer(10, func() (string, bool) { return "", false })
+		c.Add("big", strings.Repeat("x", 100))
+		if c.Len() != 1 || c.Size() <= 10 {
+			t.Errorf("Expected the item to stay when nothing can be evicted, got %d items", c.Len())
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		c := NewBoundedContainer(1<<20, func() (int, bool) { return 0, false })
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				c.Add(i, []int{i})
+				c.Remove(i)
+			}(i)
+		}
+		wg.Wait()
+		if c.Size() != 0 || c.Len() != 0 {
+			t.Errorf("Expected an empty container, got %d bytes in %d items", c.Size(), c.Len())
+		}
+	})
+}
@@ -0,0 +1,74 @@
+// boxing.go
+package memsize
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// boxRules lists the values the runtime boxes into interfaces without allocating, pointing the
+// data word at static memory instead
+type boxRules struct {
+	// byteValues are values of a single byte, which point into the runtime's staticuint64s
+	byteValues bool
+	// smallValues are values of 2, 4 or 8 bytes without pointers below 256, which point into
+	// staticuint64s too
+	smallValues bool
+	// emptyValues are empty strings and slices without array, which point at zeroVal
+	emptyValues bool
+}
+
+// boxing holds the rules of every Go release the module supports: small integers have been
+// boxed without allocating since Go 1.15, as single bytes and empty values always were
+var boxing = boxRules{byteValues: true, smallValues: true, emptyValues: true}
+
+// staticLo and staticHi delimit the runtime's staticuint64s, and zeroBox is the address of
+// its zeroVal. They are found by boxing values whose boxes are known to be static, so they
+// stay zero if the runtime allocates them after all.
+var staticLo, staticHi, zeroBox uintptr
+
+func init() {
+	lo, hi := dataWord(boxByte(0)), dataWord(boxByte(255))
+	if hi-lo == 255*8 {
+		staticLo, staticHi = lo&^7, hi&^7+8
+	}
+	if a, b := dataWord(boxString("")), dataWord(boxSlice(nil)); a == b {
+		zeroBox = a
+	}
+}
+
+// staticBox reports whether the value v of an interface is boxed at addr in static memory
+// rather than on the heap
+func staticBox(v reflect.Value, addr uintptr) bool {
+	t := v.Type()
+	switch {
+	case addr >= staticLo && addr < staticHi:
+		size := t.Size()
+		return boxing.byteValues && size == 1 ||
+			boxing.smallValues && (size == 2 || size == 4 || size == 8) && !hasPointers(t)
+	case addr == zeroBox && addr != 0:
+		kind := t.Kind()
+		return boxing.emptyValues && (kind == reflect.String || kind == reflect.Slice) && v.Len() == 0
+	}
+	return false
+}
+
+// dataWord returns the data word of an interface
+func dataWord(i interface{}) uintptr {
+	return uintptr((*[2]unsafe.Pointer)(unsafe.Pointer(&i))[1])
+}
+
+//go:noinline
+func boxByte(b uint8) interface{} {
+	return b
+}
+
+//go:noinline
+func boxString(s string) interface{} {
+	return s
+}
+
+//go:noinline
+func boxSlice(s []byte) interface{} {
+	return s
+}
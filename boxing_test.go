@@ -0,0 +1,95 @@
+package memsize
+
+import "testing"
+
+//go:noinline
+func boxInt(n int) interface{} {
+	return n
+}
+
+//go:noinline
+func boxUint16(n uint16) interface{} {
+	return n
+}
+
+//go:noinline
+func boxFloat(f float64) interface{} {
+	return f
+}
+
+var (
+	boxSink   interface{}
+	boxedText = "boxed"
+)
+
+func TestBoxing(t *testing.T) {
+	Debug = false
+
+	if staticLo == 0 || zeroBox == 0 {
+		t.Fatalf("Expected the static boxes of the runtime to be found, got %x and %x", staticLo, zeroBox)
+	}
+
+	// The rules must match the runtime the tests run with, so a release boxing values
+	// differently fails here and needs rules of its own
+	t.Run("Rules", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			box    func()
+			static bool
+		}{
+			{"Byte", func() { boxSink = boxByte(200) }, boxing.byteValues},
+			{"Small Int", func() { boxSink = boxInt(42) }, boxing.smallValues},
+			{"Small Uint16", func() { boxSink = boxUint16(7) }, boxing.smallValues},
+			{"Zero Float", func() { boxSink = boxFloat(0) }, boxing.smallValues},
+			{"Large Int", func() { boxSink = boxInt(1000) }, false},
+			{"Empty String", func() { boxSink = boxString("") }, boxing.emptyValues},
+			{"Nil Slice", func() { boxSink = boxSlice(nil) }, boxing.emptyValues},
+			{"String", func() { boxSink = boxString(boxedText) }, false},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				allocs := testing.AllocsPerRun(100, test.box)
+				if static := allocs == 0; static != test.static {
+					t.Errorf("Expected static %v, got %v allocations", test.static, allocs)
+				}
+			})
+		}
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		exact := WithSizeModel(ExactSizes)
+		tests := []struct {
+			name     string
+			v        interface{}
+			expected uint64
+		}{
+			{"Small Int", boxInt(42), 16},
+			{"Large Int", boxInt(1000), 16 + 8},
+			{"Byte", boxByte(1), 16},
+			{"Zero Float", boxFloat(0), 16},
+			{"Float", boxFloat(1.5), 16 + 8},
+			{"Empty String", boxString(""), 16},
+			{"Nil Slice", boxSlice(nil), 16},
+			{"String", boxString(boxedText), 16 + 16 + 5},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				if got := SizeOf(test.v, exact); got != test.expected {
+					t.Errorf("Expected %d, got %d", test.expected, got)
+				}
+			})
+		}
+	})
+
+	t.Run("Config Map", func(t *testing.T) {
+		exact := WithSizeModel(ExactSizes)
+		small := map[string]interface{}{"age": boxInt(30)}
+		large := map[string]interface{}{"age": boxInt(3000)}
+		if diff := GetTotalSize(large, exact) - GetTotalSize(small, exact); diff != 8 {
+			t.Errorf("Expected the heap box of the large value to add 8 bytes, got %d", diff)
+		}
+		if legacy := GetTotalSize(small); legacy != GetTotalSize(large) {
+			t.Errorf("Expected legacy sizes to ignore boxing, got %d and %d", legacy, GetTotalSize(large))
+		}
+	})
+}
@@ -0,0 +1,104 @@
+// budget.go
+package memsize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrLimitExceeded is returned when WithMaxNodes or WithMaxBytes stopped a traversal early
+var ErrLimitExceeded = errors.New("memsize: traversal limit exceeded")
+
+// GetTotalSizeE is like GetTotalSize but reports why the traversal stopped early, in which
+// case the returned size is a lower bound of the actual size. With WithStrict it also
+// returns a *SizeError for values that could only be sized approximately.
+func GetTotalSizeE(v interface{}, opts ...Option) (uint64, error) {
+	return newWalker(opts...).measure(reflect.ValueOf(v))
+}
+
+// GetTotalSizeContext is like GetTotalSizeE but stops once ctx is done, returning the
+// size accounted so far together with ctx.Err()
+func GetTotalSizeContext(ctx context.Context, v interface{}, opts ...Option) (uint64, error) {
+	opts = append(opts[:len(opts):len(opts)], func(c *config) { c.ctx = ctx })
+	return newWalker(opts...).measure(reflect.ValueOf(v))
+}
+
+// contextCheckInterval is the number of visited values between checks of the context
+const contextCheckInterval = 1024
+
+// budget enforces the limits and context of a measurement, shared by all of its walkers
+type budget struct {
+	maxNodes uint64
+	maxBytes uint64
+	ctx      context.Context
+
+	nodes uint64 // atomic
+	bytes uint64 // atomic
+	stop  uint32 // atomic
+
+	mu     sync.Mutex
+	ctxErr error
+}
+
+// newBudget returns nil when nothing can stop the traversal, which makes all budget methods no-ops
+func newBudget(cfg *config) *budget {
+	if cfg.maxNodes == 0 && cfg.maxBytes == 0 && cfg.ctx == nil {
+		return nil
+	}
+	return &budget{maxNodes: cfg.maxNodes, maxBytes: cfg.maxBytes, ctx: cfg.ctx}
+}
+
+// charge accounts a visited value of the given shallow size
+func (b *budget) charge(size uint64) {
+	if b == nil {
+		return
+	}
+	nodes := atomic.AddUint64(&b.nodes, 1)
+	if bytes := atomic.AddUint64(&b.bytes, size); b.maxBytes > 0 && bytes > b.maxBytes {
+		atomic.StoreUint32(&b.stop, 1)
+	}
+
+	// The first value is checked too, so an already cancelled context stops right away
+	if b.ctx != nil && (nodes-1)%contextCheckInterval == 0 {
+		if err := b.ctx.Err(); err != nil {
+			b.mu.Lock()
+			b.ctxErr = err
+			b.mu.Unlock()
+			atomic.StoreUint32(&b.stop, 1)
+		}
+	}
+}
+
+// admit reports whether one more value may be visited, stopping the traversal otherwise
+func (b *budget) admit() bool {
+	if b == nil {
+		return true
+	}
+	if b.maxNodes > 0 && atomic.LoadUint64(&b.nodes) >= b.maxNodes {
+		atomic.StoreUint32(&b.stop, 1)
+	}
+	return !b.exceeded()
+}
+
+// exceeded reports whether the traversal has to stop
+func (b *budget) exceeded() bool {
+	return b != nil && atomic.LoadUint32(&b.stop) != 0
+}
+
+func (b *budget) err() error {
+	if !b.exceeded() {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctxErr != nil {
+		return b.ctxErr
+	}
+	return fmt.Errorf("%w after %d nodes and %d bytes",
+		ErrLimitExceeded, atomic.LoadUint64(&b.nodes), atomic.LoadUint64(&b.bytes))
+}
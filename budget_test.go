@@ -0,0 +1,68 @@
+package memsize
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBudget(t *testing.T) {
+	Debug = false
+
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = "a reasonably long string value"
+	}
+	full := GetTotalSize(values)
+
+	t.Run("Within Limits", func(t *testing.T) {
+		size, err := GetTotalSizeE(values, WithMaxNodes(1001), WithMaxBytes(full))
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if size != full {
+			t.Errorf("Expected %d, got %d", full, size)
+		}
+	})
+
+	t.Run("Max Nodes", func(t *testing.T) {
+		size, err := GetTotalSizeE(values, WithMaxNodes(10))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("Expected ErrLimitExceeded, got %v", err)
+		}
+		if size == 0 || size >= full {
+			t.Errorf("Expected a partial size below %d, got %d", full, size)
+		}
+	})
+
+	t.Run("Max Bytes", func(t *testing.T) {
+		limit := full / 4
+		size, err := GetTotalSizeE(values, WithMaxBytes(limit))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("Expected ErrLimitExceeded, got %v", err)
+		}
+		if size <= limit || size >= full {
+			t.Errorf("Expected a partial size above the limit %d and below %d, got %d", limit, full, size)
+		}
+	})
+
+	t.Run("Truncated Report", func(t *testing.T) {
+		report := GetReport(values, WithMaxNodes(5))
+		if !report.Truncated {
+			t.Error("Expected the report to be marked truncated")
+		}
+		if len(report.Root.Children) != 4 {
+			t.Errorf("Expected 4 visited elements, got %d", len(report.Root.Children))
+		}
+		if GetReport(values).Truncated {
+			t.Error("Expected a complete report without limits")
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		nodes := buildGraph(10000)
+		_, err := GetTotalSizeE(nodes, WithParallelism(4), WithMaxNodes(100))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded, got %v", err)
+		}
+	})
+}
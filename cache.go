@@ -0,0 +1,133 @@
+// cache.go
+package memsize
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CachedSizer memoizes the sizes of the roots of a Registry, so HTTP handlers and metric
+// scrapes reuse a recent measurement instead of traversing a large graph on every request. A
+// size is recomputed once its TTL expired or after the root was invalidated or registered
+// again, and concurrent readers of an expired root wait for a single traversal.
+type CachedSizer struct {
+	registry *Registry
+	opts     []Option
+
+	mu    sync.Mutex
+	ttl   time.Duration
+	cache map[string]*cachedRoot
+}
+
+type cachedRoot struct {
+	root *registeredRoot
+
+	// mu is held while measuring
+	mu       sync.Mutex
+	size     uint64
+	measured uint64
+	computed time.Time
+}
+
+// NewCachedSizer creates a CachedSizer of a new Registry measuring roots with the given
+// options. Sizes are reused for ttl; a zero TTL keeps them until the root is invalidated and
+// a negative one measures roots on every read.
+func NewCachedSizer(ttl time.Duration, opts ...Option) *CachedSizer {
+	return NewRegistrySizer(NewRegistry(), ttl, opts...)
+}
+
+// NewRegistrySizer creates a CachedSizer of the roots of a Registry shared with other
+// exporters, such as DefaultRegistry, see NewCachedSizer
+func NewRegistrySizer(r *Registry, ttl time.Duration, opts ...Option) *CachedSizer {
+	return &CachedSizer{registry: r, ttl: ttl, opts: opts, cache: make(map[string]*cachedRoot)}
+}
+
+// Register adds or replaces the root measured under name and returns a function invalidating
+// its cached size, for the code mutating the root to call
+func (c *CachedSizer) Register(name string, root interface{}) (invalidate func()) {
+	return c.registry.register(name, root).invalidate
+}
+
+// Unregister removes a previously registered root
+func (c *CachedSizer) Unregister(name string) {
+	c.registry.Unregister(name)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cache, name)
+}
+
+// Invalidate drops the cached size of a root, which is measured again on the next read
+func (c *CachedSizer) Invalidate(name string) {
+	if e := c.registry.root(name); e != nil {
+		e.invalidate()
+	}
+}
+
+// InvalidateAll drops the cached sizes of every root
+func (c *CachedSizer) InvalidateAll() {
+	c.registry.mu.RLock()
+	defer c.registry.mu.RUnlock()
+	for _, e := range c.registry.roots {
+		e.invalidate()
+	}
+}
+
+// Size returns the size of the root registered under name, measuring it if its cached size is
+// stale. It reports false if no such root is registered.
+func (c *CachedSizer) Size(name string) (uint64, bool) {
+	e := c.registry.root(name)
+	c.mu.Lock()
+	r, ttl := c.cache[name], c.ttl
+	switch {
+	case e == nil:
+		delete(c.cache, name)
+	case r == nil || r.root != e:
+		// The root was registered again since it was cached
+		r = &cachedRoot{root: e}
+		c.cache[name] = r
+	}
+	c.mu.Unlock()
+	if e == nil {
+		return 0, false
+	}
+	return r.get(ttl, c.opts), true
+}
+
+// Sizes returns the sizes of all registered roots, measuring those whose cached size is stale
+func (c *CachedSizer) Sizes() map[string]uint64 {
+	names := c.Names()
+	sizes := make(map[string]uint64, len(names))
+	for _, name := range names {
+		if size, ok := c.Size(name); ok {
+			sizes[name] = size
+		}
+	}
+	return sizes
+}
+
+// Names returns the names of the registered roots in order
+func (c *CachedSizer) Names() []string {
+	return c.registry.names()
+}
+
+func (c *CachedSizer) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+func (r *cachedRoot) get(ttl time.Duration, opts []Option) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	generation := atomic.LoadUint64(&r.root.generation)
+	if r.measured == generation && (ttl == 0 || time.Since(r.computed) < ttl) {
+		return r.size
+	}
+	// An invalidation during the traversal leaves the size stale for the next read
+	r.size = GetTotalSize(resolveRoot(r.root.value), opts...)
+	r.measured = generation
+	r.computed = time.Now()
+	return r.size
+}
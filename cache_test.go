@@ -0,0 +1,105 @@
+package memsize
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachedSizer(t *testing.T) {
+	Debug = false
+
+	data := []string{"a", "b"}
+	other := map[string]int{"a": 1}
+
+	c := NewCachedSizer(time.Hour)
+	invalidate := c.Register("data", &data)
+	c.Register("other", &other)
+
+	t.Run("Sizes", func(t *testing.T) {
+		sizes := c.Sizes()
+		fmt.Printf("Sizes: %v\n", sizes)
+		if sizes["data"] != GetTotalSize(&data) || sizes["other"] != GetTotalSize(&other) {
+			t.Errorf("Expected the sizes of both roots, got %v", sizes)
+		}
+		if _, ok := c.Size("missing"); ok {
+			t.Error("Expected no size for an unregistered root")
+		}
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		before, _ := c.Size("data")
+		data = append(data, "a much longer string that changes the size")
+		if after, _ := c.Size("data"); after != before {
+			t.Errorf("Expected cached value %d, got %d", before, after)
+		}
+	})
+
+	t.Run("Invalidated", func(t *testing.T) {
+		invalidate()
+		if got, _ := c.Size("data"); got != GetTotalSize(&data) {
+			t.Errorf("Expected recomputed value %d, got %d", GetTotalSize(&data), got)
+		}
+
+		other["b"] = 2
+		c.InvalidateAll()
+		if got, _ := c.Size("other"); got != GetTotalSize(&other) {
+			t.Errorf("Expected recomputed value %d, got %d", GetTotalSize(&other), got)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		c := NewCachedSizer(time.Nanosecond)
+		c.Register("data", &data)
+		c.Size("data")
+		data = append(data, "another string")
+		time.Sleep(time.Millisecond)
+		if got, _ := c.Size("data"); got != GetTotalSize(&data) {
+			t.Errorf("Expected recomputed value %d, got %d", GetTotalSize(&data), got)
+		}
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("data", &data)
+		c := NewRegistrySizer(r, time.Hour)
+		if got, _ := c.Size("data"); got != GetTotalSize(&data) {
+			t.Errorf("Expected the size %d of the registry's root, got %d", GetTotalSize(&data), got)
+		}
+
+		// Registering a root again replaces its cached size
+		r.Register("data", &other)
+		if got, _ := c.Size("data"); got != GetTotalSize(&other) {
+			t.Errorf("Expected the size %d of the replaced root, got %d", GetTotalSize(&other), got)
+		}
+
+		c = NewRegistrySizer(r, -1)
+		c.Size("data")
+		other["c"] = 3
+		if got, _ := c.Size("data"); got != GetTotalSize(&other) {
+			t.Errorf("Expected a negative TTL to measure on every read, got %d", got)
+		}
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		c.Unregister("other")
+		if names := c.Names(); len(names) != 1 || names[0] != "data" {
+			t.Errorf("Expected only data to remain, got %v", names)
+		}
+		c.Invalidate("other")
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Invalidate("data")
+				c.Size("data")
+			}()
+		}
+		wg.Wait()
+	})
+}
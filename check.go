@@ -0,0 +1,237 @@
+// check.go
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WarningKind classifies the values Check can't size confidently
+type WarningKind int
+
+const (
+	// WarnApproximated is a value sized with an estimate, such as a func or a channel buffer,
+	// which WithStrict would reject
+	WarnApproximated WarningKind = iota
+	// WarnUnsafePointer is an unsafe.Pointer, whose target is not followed
+	WarnUnsafePointer
+	// WarnCgo is a C type or a runtime/cgo.Handle, whose memory is not visible to memsize
+	WarnCgo
+	// WarnOpaque is a struct keeping its state in unexported uintptr or unsafe.Pointer fields,
+	// such as handles into memory managed elsewhere
+	WarnOpaque
+	// WarnNotInHeap is a runtime type marked as never allocated on the Go heap
+	WarnNotInHeap
+	// WarnUnstable is a value that panicked while being sized and was given up
+	WarnUnstable
+	// WarnIncomplete is a traversal stopped early by a limit, leaving a lower bound
+	WarnIncomplete
+)
+
+var warningKinds = [...]string{"approximated", "unsafe pointer", "cgo", "opaque", "not in heap",
+	"unstable", "incomplete"}
+
+func (k WarningKind) String() string {
+	if k < 0 || int(k) >= len(warningKinds) {
+		return fmt.Sprintf("WarningKind(%d)", int(k))
+	}
+	return warningKinds[k]
+}
+
+// Warning is a construct Check couldn't size confidently. Values of the same type found for
+// the same reason are reported once, at the first path they were found at.
+type Warning struct {
+	Kind WarningKind
+	// Path is the location of the first value, as in Node.Path
+	Path string
+	// Type is empty when the value has no type, i.e. for a nil root
+	Type   string
+	Reason string
+	// Count is the number of values found with the same type and reason
+	Count int
+}
+
+func (w Warning) String() string {
+	s := fmt.Sprintf("%s: %s", w.Kind, w.Path)
+	if w.Type != "" {
+		s += " (" + w.Type + ")"
+	}
+	s += ": " + w.Reason
+	if w.Count > 1 {
+		s += fmt.Sprintf(" (%d values)", w.Count)
+	}
+	return s
+}
+
+// Check traverses v in a hardened mode and lists the constructs whose size it can't vouch for,
+// instead of silently returning a number: values sized with estimates, unsafe pointers, cgo
+// handles and C types, opaque structs of other packages, runtime types outside the heap, values
+// that panicked while being sized and limits given with opts. No warnings mean the total of the
+// same measurement is trustworthy, which makes Check suitable for tests run in code review.
+//
+// The traversal recovers from panics as in WithSafeMode(0) and tracks paths like WithStrict.
+func Check(v interface{}, opts ...Option) []Warning {
+	w := newWalker(append(opts[:len(opts):len(opts)], WithSafeMode(0))...)
+	c := &checker{layout: w.cfg.layout(), index: make(map[warningKey]int)}
+	w.strict = &strictLog{each: c.reject}
+	w.visitor = c.visit
+	w.measure(reflect.ValueOf(v))
+
+	for _, p := range w.panics {
+		c.add(WarnUnstable, p.Path, p.Type, p.Err.Error())
+	}
+	if w.cfg.sampling > 0 && w.cfg.sampling < 1 {
+		c.add(WarnApproximated, "root", "", "sizes are extrapolated from sampled elements")
+	}
+	switch {
+	case w.cfg.err != nil:
+		c.add(WarnIncomplete, "root", "", w.cfg.err.Error())
+	case w.budget.err() != nil:
+		c.add(WarnIncomplete, "root", "", w.budget.err().Error())
+	case w.truncated:
+		c.add(WarnIncomplete, "root", "", "values below the maximum depth were not visited")
+	}
+
+	sort.SliceStable(c.warnings, func(i, j int) bool { return c.warnings[i].Kind < c.warnings[j].Kind })
+	return c.warnings
+}
+
+type warningKey struct {
+	kind   WarningKind
+	typ    string
+	reason string
+}
+
+// checker collects the warnings of a measurement by Check
+type checker struct {
+	layout layout
+
+	mu       sync.Mutex
+	index    map[warningKey]int
+	warnings []Warning
+}
+
+func (c *checker) add(kind WarningKind, path, typ, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := warningKey{kind, typ, reason}
+	if i, ok := c.index[key]; ok {
+		c.warnings[i].Count++
+		return
+	}
+	c.index[key] = len(c.warnings)
+	c.warnings = append(c.warnings, Warning{Kind: kind, Path: path, Type: typ, Reason: reason, Count: 1})
+}
+
+// reject records a value the strict log couldn't size. cgo handles are left to inspect, which
+// explains what they hide better than the strict log.
+func (c *checker) reject(path string, t reflect.Type, reason string) {
+	kind, typ := WarnApproximated, ""
+	if t != nil {
+		if cgoHandle(t) {
+			return
+		}
+		typ = t.String()
+		if t.Kind() == reflect.UnsafePointer {
+			kind = WarnUnsafePointer
+		}
+	}
+	c.add(kind, path, typ, reason)
+}
+
+// visit inspects the type of every value reached, and the types of elements folded into it
+func (c *checker) visit(path string, v reflect.Value, shallow uint64) WalkAction {
+	if !v.IsValid() {
+		return WalkContinue
+	}
+	t := v.Type()
+	c.inspect(path, t)
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if planFor(t.Elem(), c.layout).constant {
+			c.inspectFolded(path+"[0]", t.Elem())
+		}
+	case reflect.Map:
+		if planFor(t.Key(), c.layout).constant {
+			c.inspectFolded(path+".key[*]", t.Key())
+		}
+		if planFor(t.Elem(), c.layout).constant {
+			c.inspectFolded(path+"[*]", t.Elem())
+		}
+	}
+	return WalkContinue
+}
+
+// inspect warns about a value of type t at path
+func (c *checker) inspect(path string, t reflect.Type) {
+	switch {
+	case cgoHandle(t):
+		c.add(WarnCgo, path, t.String(), "the value a cgo.Handle refers to is held by the runtime and not followed")
+	case strings.HasPrefix(t.Name(), "_Ctype_"):
+		c.add(WarnCgo, path, t.String(), "C types may refer to memory allocated by C, which is not counted")
+	case t.Kind() != reflect.Struct || handlerFor(t, c.layout) != nil:
+	case notInHeap(t):
+		c.add(WarnNotInHeap, path, t.String(), "values of the type are allocated outside the Go heap")
+	case opaque(t):
+		c.add(WarnOpaque, path, t.String(), "its state is kept in unexported uintptr or unsafe.Pointer fields")
+	}
+}
+
+// inspectFolded warns about the values of type t folded into a container, and the fields and
+// elements folded into them
+func (c *checker) inspectFolded(path string, t reflect.Type) {
+	c.inspect(path, t)
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			c.inspectFolded(path+fieldSegment(t.Field(i)), t.Field(i).Type)
+		}
+	case reflect.Array:
+		if t.Len() > 0 {
+			c.inspectFolded(path+"[0]", t.Elem())
+		}
+	}
+}
+
+// cgoHandle reports whether t is runtime/cgo.Handle
+func cgoHandle(t reflect.Type) bool {
+	return t.PkgPath() == "runtime/cgo" && t.Name() == "Handle"
+}
+
+// notInHeap reports whether struct type t embeds the runtime's marker of types never allocated
+// on the Go heap
+func notInHeap(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		switch ft.PkgPath() {
+		case "runtime/internal/sys", "internal/runtime/sys":
+			if ft.Name() == "NotInHeap" || ft.Name() == "nih" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// opaque reports whether the named struct type t only has unexported fields, some of which are
+// uintptrs or unsafe pointers
+func opaque(t reflect.Type) bool {
+	if t.Name() == "" {
+		return false
+	}
+	hidden := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() {
+			return false
+		}
+		switch f.Type.Kind() {
+		case reflect.Uintptr, reflect.UnsafePointer:
+			hidden = true
+		}
+	}
+	return hidden
+}
@@ -0,0 +1,114 @@
+package memsize
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"testing"
+	"unsafe"
+)
+
+// handle mimics a type of another package keeping its state out of sight of reflection
+type handle struct {
+	ptr  unsafe.Pointer
+	size uintptr
+}
+
+func TestCheck(t *testing.T) {
+	Debug = false
+
+	type Record struct {
+		Name   string
+		Values []int
+		Index  map[string]int
+	}
+	type Job struct {
+		Name string
+		Run  func()
+	}
+	type Buffer struct {
+		Data unsafe.Pointer
+	}
+	type Session struct {
+		Name   string
+		Handle cgo.Handle
+	}
+
+	t.Run("Trustworthy", func(t *testing.T) {
+		v := &Record{Name: "a", Values: []int{1, 2, 3}, Index: map[string]int{"a": 1}}
+		if warnings := Check(v); len(warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", warnings)
+		}
+	})
+
+	buf := make([]byte, 16)
+	h := cgo.NewHandle("value")
+	defer h.Delete()
+
+	tests := []struct {
+		name  string
+		value interface{}
+		kind  WarningKind
+		path  string
+		typ   string
+	}{
+		{"Func", &Job{Name: "cleanup", Run: func() {}}, WarnApproximated, "root.ptr.Run", "func()"},
+		{"Unsafe Pointer", Buffer{Data: unsafe.Pointer(&buf[0])}, WarnUnsafePointer, "root.Data", "unsafe.Pointer"},
+		{"Cgo Handle", &Session{Name: "a", Handle: h}, WarnCgo, "root.ptr.Handle", "cgo.Handle"},
+		{"Folded Cgo Handle", []cgo.Handle{h, h}, WarnCgo, "root[0]", "cgo.Handle"},
+		{"Opaque", []handle{{ptr: unsafe.Pointer(&buf[0]), size: 16}}, WarnOpaque, "root[0]", "memsize.handle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := Check(tt.value)
+			for _, w := range warnings {
+				fmt.Printf("%s: %v\n", tt.name, w)
+			}
+			for _, w := range warnings {
+				if w.Kind == tt.kind && w.Path == tt.path && w.Type == tt.typ {
+					return
+				}
+			}
+			t.Errorf("Expected a %s warning for %s at %s, got %v", tt.kind, tt.typ, tt.path, warnings)
+		})
+	}
+
+	t.Run("Cgo Handle Only", func(t *testing.T) {
+		if warnings := Check([]cgo.Handle{h}); len(warnings) != 1 {
+			t.Errorf("Expected a single warning for the handles, got %v", warnings)
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		jobs := []*Job{{Run: func() {}}, {Run: func() {}}, {Run: func() {}}}
+		warnings := Check(jobs)
+		if len(warnings) != 1 || warnings[0].Count != 3 || warnings[0].Path != "root[0].ptr.Run" {
+			t.Errorf("Expected a single warning for 3 values at root[0].ptr.Run, got %v", warnings)
+		}
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		v := []*Record{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+		warnings := Check(v, WithMaxNodes(2))
+		fmt.Printf("Incomplete: %v\n", warnings)
+		if len(warnings) != 1 || warnings[0].Kind != WarnIncomplete {
+			t.Errorf("Expected an incomplete warning, got %v", warnings)
+		}
+		warnings = Check(v, WithMaxDepth(1))
+		if len(warnings) != 1 || warnings[0].Kind != WarnIncomplete {
+			t.Errorf("Expected an incomplete warning with WithMaxDepth, got %v", warnings)
+		}
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		v := struct {
+			Run  func()
+			Data unsafe.Pointer
+		}{func() {}, nil}
+		warnings := Check(v, WithMaxNodes(100))
+		for i := 1; i < len(warnings); i++ {
+			if warnings[i].Kind < warnings[i-1].Kind {
+				t.Errorf("Expected warnings sorted by kind, got %v", warnings)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+// Command memsize prints the memory size breakdown of a JSON or gob encoded value.
+//
+// Usage:
+//
+//	memsize [flags] [file]
+//
+// The value is read from file, or from standard input when no file is given. The input
+// format is taken from the file extension unless --format is set. Gob input must have been
+// encoded as an interface value holding basic types or one of the generic maps and slices
+// registered below.
+//
+// Flags:
+//
+//	--format string  input format: json or gob
+//	--top int        number of heaviest paths to print (default 10)
+//	--json           print the full report as JSON
+//	--dot            print the object graph in Graphviz DOT format
+package main
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+func init() {
+	// Generic containers that gob-encoded fixtures are commonly made of
+	gob.Register(map[string]interface{}{})
+	gob.Register(map[string]string{})
+	gob.Register(map[string]int{})
+	gob.Register(map[string]float64{})
+	gob.Register([]interface{}{})
+	gob.Register([]string{})
+	gob.Register([]int{})
+	gob.Register([]float64{})
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "memsize:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("memsize", flag.ContinueOnError)
+	format := flags.String("format", "", "input format: json or gob")
+	top := flags.Int("top", 10, "number of heaviest paths to print")
+	asJSON := flags.Bool("json", false, "print the full report as JSON")
+	asDOT := flags.Bool("dot", false, "print the object graph in Graphviz DOT format")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	in := stdin
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(f.Name()), ".")
+		}
+	}
+
+	value, err := decode(in, *format)
+	if err != nil {
+		return err
+	}
+	report := memsize.GetReport(value)
+
+	switch {
+	case *asJSON:
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case *asDOT:
+		return report.WriteDOT(stdout)
+	}
+
+	fmt.Fprintf(stdout, "Total: %d bytes\n", report.Total())
+	for _, c := range report.TopContributors(*top) {
+		fmt.Fprintf(stdout, "%12d  %s (%s)\n", c.Size, c.Path, c.Type)
+	}
+	return nil
+}
+
+func decode(r io.Reader, format string) (interface{}, error) {
+	var value interface{}
+	switch format {
+	case "json", "":
+		if err := json.NewDecoder(r).Decode(&value); err != nil {
+			return nil, fmt.Errorf("decoding JSON: %w", err)
+		}
+	case "gob":
+		if err := gob.NewDecoder(r).Decode(&value); err != nil {
+			return nil, fmt.Errorf("decoding gob: %w", err)
+		}
+	default:
+		return nil, errors.New("unsupported format " + format)
+	}
+	return value, nil
+}
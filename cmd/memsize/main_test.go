@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	input := `{"name": "fixture", "items": [1, 2, 3], "tags": {"a": "b"}}`
+
+	t.Run("Top", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := run([]string{"--top", "2"}, strings.NewReader(input), &out); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected total and 2 paths, got %q", out.String())
+		}
+		if !strings.HasPrefix(lines[0], "Total: ") {
+			t.Errorf("Expected total first, got %q", lines[0])
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := run([]string{"--json"}, strings.NewReader(input), &out); err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("Expected JSON output: %v", err)
+		}
+		if doc["version"] == nil {
+			t.Error("Expected a versioned report")
+		}
+	})
+
+	t.Run("DOT", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := run([]string{"--dot"}, strings.NewReader(input), &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(out.String(), "digraph memsize {") {
+			t.Errorf("Expected DOT output, got %q", out.String())
+		}
+	})
+
+	t.Run("Gob File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "fixture.gob")
+		var buf bytes.Buffer
+		var value interface{} = map[string]string{"a": "b"}
+		if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		if err := run([]string{path}, nil, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), `root["a"] (string)`) {
+			t.Errorf("Expected the decoded map values in the output, got %q", out.String())
+		}
+	})
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		if err := run([]string{"--format", "xml"}, strings.NewReader(input), &bytes.Buffer{}); err == nil {
+			t.Error("Expected an error for an unsupported format")
+		}
+	})
+}
@@ -0,0 +1,44 @@
+// compare.go
+package memsize
+
+import "reflect"
+
+// CompareReport is the result of Compare: the per-path differences between two values,
+// Before being a and After b
+type CompareReport struct {
+	*DiffReport
+	// A and B are the reports of the two values
+	A, B *Report
+	// Shared is the size of the objects b shares with a, which are counted in A only
+	Shared uint64
+}
+
+// Compare sizes a and then b with a single visited set and compares them path by path, e.g. to
+// check that replacing a map[string]string with a slice of pairs actually saves memory. Paths
+// of both values start at root, so fields and elements in the same place are compared with each
+// other. Objects b shares with a, such as strings carried over, count in a only and are
+// reported as Shared. A negative Delta means b is smaller.
+func Compare(a, b interface{}, opts ...Option) *CompareReport {
+	w := newWalker(opts...)
+	defer w.release()
+	if w.cfg.err != nil {
+		return &CompareReport{DiffReport: &DiffReport{}, A: &Report{}, B: &Report{}}
+	}
+	w.tree = true
+
+	c := &CompareReport{A: w.partReport(reflect.ValueOf(a)), B: w.partReport(reflect.ValueOf(b))}
+	c.DiffReport = c.A.Diff(c.B)
+	if alone := GetTotalSize(b, opts...); alone > c.B.Total() {
+		c.Shared = alone - c.B.Total()
+	}
+	return c
+}
+
+// partReport measures v with the walker's visited set as it stands and reports on it, leaving
+// the set for further values
+func (w *walker) partReport(v reflect.Value) *Report {
+	w.strings = newStringStats()
+	w.offHeapBytes, w.mappedBytes = 0, 0
+	w.getTotalSize(v, "root")
+	return w.buildReport(w.budget.err())
+}
@@ -0,0 +1,65 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	Debug = false
+
+	type pair struct{ Key, Value string }
+	type before struct {
+		Headers map[string]string
+		Config  *[1024]byte
+	}
+	type after struct {
+		Headers []pair
+		Config  *[1024]byte
+	}
+
+	config := new([1024]byte)
+	a := before{Headers: make(map[string]string), Config: config}
+	b := after{Config: config}
+	for i := 0; i < 10; i++ {
+		k, v := fmt.Sprint("header-", i), fmt.Sprint("value-", i)
+		a.Headers[k] = v
+		b.Headers = append(b.Headers, pair{k, v})
+	}
+
+	t.Run("Refactor", func(t *testing.T) {
+		c := Compare(a, b, WithSizeModel(ExactSizes))
+		fmt.Printf("Compare: %d -> %d bytes, %d shared\n", c.Before, c.After, c.Shared)
+		for _, e := range c.Entries[:3] {
+			fmt.Printf("  %s %s: %d -> %d\n", e.Path, e.Type, e.Before, e.After)
+		}
+		exact := WithSizeModel(ExactSizes)
+		if c.Before != GetTotalSize(a, exact) || c.Delta() >= 0 {
+			t.Errorf("Expected a smaller b than %d bytes, got %d", GetTotalSize(a, exact), c.After)
+		}
+		// The config is carried over, so b counts only its pointer
+		if c.Shared != 1024 || c.After+c.Shared != GetTotalSize(b, exact) {
+			t.Errorf("Expected %d bytes of b with 1024 shared, got %d plus %d", GetTotalSize(b, exact), c.After, c.Shared)
+		}
+		found := false
+		for _, e := range c.Entries {
+			found = found || e.Path == "root.Headers" && e.Before > 0 && e.After > 0
+		}
+		if !found {
+			t.Errorf("Expected root.Headers in both values, got %+v", c.Entries)
+		}
+	})
+
+	t.Run("Identical", func(t *testing.T) {
+		c := Compare(a, before{Headers: map[string]string{}})
+		if c.Shared != 0 || c.A.Total() != GetTotalSize(a) {
+			t.Errorf("Expected nothing shared, got %d", c.Shared)
+		}
+	})
+
+	t.Run("Invalid Option", func(t *testing.T) {
+		if c := Compare(a, b, WithArch("nope")); c.Before != 0 || c.A == nil {
+			t.Errorf("Expected an empty comparison, got %+v", c)
+		}
+	})
+}
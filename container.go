@@ -0,0 +1,114 @@
+// container.go
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+func init() {
+	typeMatchers = append(typeMatchers, matchList, matchListElement, matchRing)
+}
+
+// containerType reports whether t is the struct type name of package pkg. Containers are
+// matched by name, so sizing them doesn't link their packages into every program.
+func containerType(t reflect.Type, pkg, name string) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == pkg && t.Name() == name
+}
+
+// nodeSize is the size of a container node of type t without its Value interface, which
+// accounts for itself, including the slack of its allocation in exact sizes
+func nodeSize(w *walker, t reflect.Type) uint64 {
+	l := w.cfg.layout()
+	size := inlineSize(t, l) - inlineSize(interfaceType, l)
+	if l.model == ExactSizes {
+		size += w.cfg.slack(l.sizeof(t), true)
+	}
+	return size
+}
+
+// matchList handles container/list.List, whose elements are linked through unexported
+// pointers. Following them nests every element below the previous one, so the elements are
+// counted by the list instead and their values are visited at the list's path, indexed by
+// position. Elements already counted through a *list.Element are skipped.
+func matchList(t reflect.Type) typeHandler {
+	if !containerType(t, "container/list", "List") {
+		return nil
+	}
+	root, ok := t.FieldByName("root")
+	if !ok || !containerType(root.Type, "container/list", "Element") {
+		return nil
+	}
+	elemType := root.Type
+	next, _ := elemType.FieldByName("next")
+	value, _ := elemType.FieldByName("Value")
+
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		// The root element is held by the list and ends the ring of its elements
+		size := inlineSize(t, w.cfg.layout())
+		n := int(v.FieldByName("len").Int())
+		e := unsafe.Pointer(v.FieldByIndex(append(root.Index, next.Index...)).Pointer())
+
+		var children []handledChild
+		for i := 0; i < n && e != nil; i++ {
+			if !w.visitAddr(uintptr(e), elemType) {
+				size += nodeSize(w, elemType)
+				children = append(children, handledChild{
+					v:      reflect.NewAt(interfaceType, unsafe.Add(e, value.Offset)).Elem(),
+					suffix: fmt.Sprintf("[%d]", i),
+				})
+			}
+			e = *(*unsafe.Pointer)(unsafe.Add(e, next.Offset))
+		}
+		return size, children
+	}
+}
+
+// matchListElement handles container/list.Element, which is counted with its value but not
+// the other elements and the list it links to: elements held outside their list, e.g. in the
+// index of an LRU cache, are not charged for the whole list.
+func matchListElement(t reflect.Type) typeHandler {
+	if !containerType(t, "container/list", "Element") {
+		return nil
+	}
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		size := inlineSize(t, w.cfg.layout()) - inlineSize(interfaceType, w.cfg.layout())
+		return size, []handledChild{{v: v.FieldByName("Value"), suffix: ".Value"}}
+	}
+}
+
+// matchRing handles container/ring.Ring, whose members are linked in a circle of unexported
+// pointers. A ring is counted as a whole by the first of its members reached, with the
+// values of the members visited at its path, indexed by position from that member.
+func matchRing(t reflect.Type) typeHandler {
+	if !containerType(t, "container/ring", "Ring") {
+		return nil
+	}
+	next, ok := t.FieldByName("next")
+	if !ok || next.Type != reflect.PtrTo(t) {
+		return nil
+	}
+	value, _ := t.FieldByName("Value")
+
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		size := inlineSize(t, w.cfg.layout()) - inlineSize(interfaceType, w.cfg.layout())
+		children := []handledChild{{v: v.FieldByName("Value"), suffix: "[0]"}}
+
+		// Members reached again end the circle, which also ends it when v is a copy of one
+		var self unsafe.Pointer
+		if v.CanAddr() {
+			self = unsafe.Pointer(v.UnsafeAddr())
+		}
+		r := unsafe.Pointer(v.FieldByIndex(next.Index).Pointer())
+		for i := 1; r != nil && r != self && !w.visitAddr(uintptr(r), t); i++ {
+			size += nodeSize(w, t)
+			children = append(children, handledChild{
+				v:      reflect.NewAt(interfaceType, unsafe.Add(r, value.Offset)).Elem(),
+				suffix: fmt.Sprintf("[%d]", i),
+			})
+			r = *(*unsafe.Pointer)(unsafe.Add(r, next.Offset))
+		}
+		return size, children
+	}
+}
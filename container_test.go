@@ -0,0 +1,104 @@
+package memsize
+
+import (
+	"container/heap"
+	"container/list"
+	"container/ring"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// intHeap is a container/heap of ints, whose elements are held in an ordinary slice
+type intHeap []int
+
+func (h intHeap) Len() int            { return len(h) }
+func (h intHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func TestContainers(t *testing.T) {
+	Debug = false
+	exact := WithSizeModel(ExactSizes)
+	value := strings.Repeat("x", 100)
+
+	t.Run("List", func(t *testing.T) {
+		l := list.New()
+		for i := 0; i < 100; i++ {
+			l.PushBack(value)
+		}
+		report := GetReport(l, exact)
+		fmt.Printf("list.List with 100 elements: %d bytes\n", report.Total())
+		// Every element is allocated with its boxed string
+		expected := GetTotalSize(list.New(), exact) + 100*(40+16+100)
+		if report.Total() != expected {
+			t.Errorf("Expected %d, got %d", expected, report.Total())
+		}
+		paths := make(map[string]bool)
+		report.Walk(func(n *Node) bool {
+			paths[n.Path] = true
+			return true
+		})
+		if !paths["root.ptr[99]"] || paths["root.ptr.root.next"] {
+			t.Errorf("Expected the values at root.ptr[i] without nested elements, got %d paths", len(paths))
+		}
+	})
+
+	t.Run("LRU Index", func(t *testing.T) {
+		type lru struct {
+			order *list.List
+			index map[int]*list.Element
+		}
+		c := lru{order: list.New(), index: make(map[int]*list.Element)}
+		for i := 0; i < 100; i++ {
+			c.index[i] = c.order.PushFront(i)
+		}
+		withIndex := GetTotalSize(c, exact)
+		withoutIndex := GetTotalSize(c.order, exact)
+		index := GetTotalSize(c.index, exact)
+		fmt.Printf("LRU: %d bytes, list %d, index %d\n", withIndex, withoutIndex, index)
+		if withIndex >= withoutIndex+index {
+			t.Errorf("Expected elements to be counted once, got %d for %d and %d", withIndex, withoutIndex, index)
+		}
+		// An element alone doesn't drag in the whole list
+		if e := GetTotalSize(c.index[0], exact); e > 100 {
+			t.Errorf("Expected a single element, got %d bytes", e)
+		}
+	})
+
+	t.Run("Ring", func(t *testing.T) {
+		r := ring.New(10)
+		for i := 0; i < r.Len(); i++ {
+			r.Value = value
+			r = r.Next()
+		}
+		size := GetTotalSize(r, exact)
+		fmt.Printf("ring.Ring with 10 members: %d bytes\n", size)
+		// The pointer and 10 members with their boxed strings
+		if expected := uint64(8 + 10*(32+16+100)); size != expected {
+			t.Errorf("Expected %d, got %d", expected, size)
+		}
+		// Every member reaches the same ring
+		if members := GetTotalSize([]*ring.Ring{r, r.Next(), r.Move(5)}, exact); members != size+24+16 {
+			t.Errorf("Expected the ring to be counted once, got %d", members)
+		}
+		if single := GetTotalSize(&ring.Ring{}, exact); single != 8+32 {
+			t.Errorf("Expected an unlinked ring of 40 bytes, got %d", single)
+		}
+	})
+
+	t.Run("Heap", func(t *testing.T) {
+		h := &intHeap{5, 2, 8}
+		heap.Init(h)
+		heap.Push(h, 3)
+		if size := GetTotalSize(h, exact); size != 8+24+uint64(cap(*h))*8 {
+			t.Errorf("Expected the heap's slice to be counted, got %d", size)
+		}
+	})
+}
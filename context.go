@@ -0,0 +1,120 @@
+// context.go
+package memsize
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	valueCtxType = reflect.TypeOf(context.WithValue(context.Background(), contextKey{}, nil)).Elem()
+)
+
+type contextKey struct{}
+
+// ContextReport is the memory retained by the values of a context.Context chain
+type ContextReport struct {
+	// Total counts every object reachable from any value of the chain once
+	Total uint64
+	// Values lists the values from the innermost context outwards
+	Values []ContextValue
+	// Truncated is set when a limit stopped the traversal early; sizes are then lower bounds
+	Truncated bool
+}
+
+// ContextValue is a value stored in a context by context.WithValue
+type ContextValue struct {
+	// Key is the key of the value formatted with %#v, e.g. "main.userKey{}"
+	Key string
+	// Size is the size of the value on its own, and Exclusive the part of it not reachable
+	// from any other value of the chain
+	Size      uint64
+	Exclusive uint64
+	// Shadowed is set when a context closer to the innermost one holds a value with the same
+	// key, so Context.Value no longer returns this one although it is still retained
+	Shadowed bool
+}
+
+// MeasureContext measures the values stored in ctx and its parents by context.WithValue, which
+// stay alive as long as any context derived from them does; request-scoped contexts kept by
+// long-lived goroutines are a classic leak. The chain is followed through the unexported parent
+// of the standard library's contexts and through the context.Context fields of other types.
+// Objects shared between values are counted once in the total.
+func MeasureContext(ctx context.Context, opts ...Option) ContextReport {
+	var keys []interface{}
+	var names []string
+	var values []reflect.Value
+	var shadowed []bool
+
+	seen := make(map[uintptr]bool)
+	for v := reflect.ValueOf(ctx); v.IsValid(); v = parentContext(v) {
+		if v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				break
+			}
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() || seen[v.Pointer()] {
+				break
+			}
+			seen[v.Pointer()] = true
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			break
+		}
+		if v.Type() != valueCtxType || !v.CanAddr() {
+			continue
+		}
+
+		key, val := fieldInterface(v.FieldByName("key")), fieldInterface(v.FieldByName("val"))
+		dup := false
+		for _, k := range keys {
+			if k == key {
+				dup = true
+				break
+			}
+		}
+		keys = append(keys, key)
+		names = append(names, fmt.Sprintf("%#v", key))
+		values = append(values, reflect.ValueOf(val))
+		shadowed = append(shadowed, dup)
+	}
+
+	m := measureRoots(names, values, opts)
+	r := ContextReport{Total: m.Total, Truncated: m.Truncated}
+	for i, root := range m.Roots {
+		r.Values = append(r.Values, ContextValue{Key: root.Name, Size: root.Size,
+			Exclusive: root.Exclusive, Shadowed: shadowed[i]})
+	}
+	return r
+}
+
+// parentContext returns the first context.Context field of the struct v, searching embedded
+// structs such as the cancelCtx of a timerCtx, or an invalid value if there is none
+func parentContext(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Type() == contextType:
+			return field
+		case field.Kind() == reflect.Struct && v.Type().Field(i).Anonymous:
+			if parent := parentContext(field); parent.IsValid() {
+				return parent
+			}
+		}
+	}
+	return reflect.Value{}
+}
+
+// fieldInterface returns the value of an addressable field, exported or not
+func fieldInterface(field reflect.Value) interface{} {
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface()
+}
@@ -0,0 +1,108 @@
+package memsize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetTotalSizeContext(t *testing.T) {
+	Debug = false
+
+	nodes := buildGraph(5000)
+
+	t.Run("Completes", func(t *testing.T) {
+		size, err := GetTotalSizeContext(context.Background(), nodes)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if size != GetTotalSize(nodes) {
+			t.Errorf("Expected %d, got %d", GetTotalSize(nodes), size)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		size, err := GetTotalSizeContext(ctx, nodes)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if size >= GetTotalSize(nodes) {
+			t.Errorf("Expected a partial size, got %d", size)
+		}
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		if _, err := GetTotalSizeContext(ctx, nodes, WithParallelism(4)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("Limits Still Apply", func(t *testing.T) {
+		_, err := GetTotalSizeContext(context.Background(), nodes, WithMaxNodes(10))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded, got %v", err)
+		}
+	})
+}
+
+// tracedContext is a context type of another package, holding its parent in a field
+type tracedContext struct {
+	parent context.Context
+	Span   string
+}
+
+func (c tracedContext) Deadline() (time.Time, bool)       { return c.parent.Deadline() }
+func (c tracedContext) Done() <-chan struct{}             { return c.parent.Done() }
+func (c tracedContext) Err() error                        { return c.parent.Err() }
+func (c tracedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+func TestMeasureContext(t *testing.T) {
+	Debug = false
+
+	type userKey struct{}
+	type bodyKey struct{}
+	body := make([]byte, 1000)
+	user := &struct{ Name string }{"ann"}
+
+	ctx := context.WithValue(context.Background(), bodyKey{}, body)
+	ctx = context.WithValue(ctx, userKey{}, user)
+	ctx, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+	ctx = &tracedContext{parent: ctx, Span: "handler"}
+	ctx = context.WithValue(ctx, userKey{}, user)
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	r := MeasureContext(ctx)
+	fmt.Printf("Context: %+v\n", r)
+	if len(r.Values) != 3 {
+		t.Fatalf("Expected 3 values, got %d", len(r.Values))
+	}
+	if r.Values[0].Key != "memsize.userKey{}" || r.Values[0].Shadowed || !r.Values[1].Shadowed {
+		t.Errorf("Expected the outer user to be shadowed by the inner one, got %+v", r.Values[:2])
+	}
+	if last := r.Values[2]; last.Key != "memsize.bodyKey{}" || last.Size != GetTotalSize(body) || last.Exclusive != last.Size {
+		t.Errorf("Expected the body to take %d bytes on its own, got %+v", GetTotalSize(body), last)
+	}
+	// Both user values refer to the same object, which is counted once
+	pointer := GetTotalSize(&struct{ Name string }{})
+	pointer -= GetTotalSize(struct{ Name string }{})
+	if expected := GetTotalSize(body) + GetTotalSize(user) + pointer; r.Total != expected {
+		t.Errorf("Expected total %d, got %d", expected, r.Total)
+	}
+	if r.Values[0].Exclusive != pointer {
+		t.Errorf("Expected only the pointer to the shared user to be exclusive, got %d", r.Values[0].Exclusive)
+	}
+
+	if r := MeasureContext(context.Background()); r.Total != 0 || len(r.Values) != 0 {
+		t.Errorf("Expected no values, got %+v", r)
+	}
+}
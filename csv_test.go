@@ -0,0 +1,58 @@
+package memsize
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	Debug = false
+
+	v := &struct {
+		Name  string
+		Items []string
+	}{"name", []string{"a", "b,c"}}
+	r := GetReport(v)
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.WriteCSV(&buf); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Print(buf.String())
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(rows[0], ",") != "path,type,size,shallow,retained,count" {
+			t.Errorf("Expected the header row, got %v", rows[0])
+		}
+		if len(rows) != 7 {
+			t.Fatalf("Expected 6 nodes, got %d rows", len(rows)-1)
+		}
+		root := rows[1]
+		if root[0] != "root" || root[2] != fmt.Sprint(r.Total()) || root[4] != fmt.Sprint(r.Total()) || root[5] != "6" {
+			t.Errorf("Expected the root with its total and 6 nodes, got %v", root)
+		}
+		if last := rows[6]; last[0] != "root.ptr.Items[1]" || last[5] != "1" {
+			t.Errorf("Expected the last item as a leaf, got %v", last)
+		}
+		if r.Root.Retained != 0 {
+			t.Errorf("Expected the report to be left without retained sizes, got %d", r.Root.Retained)
+		}
+	})
+
+	t.Run("TSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.WriteTSV(&buf); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 7 || strings.Count(lines[0], "\t") != 5 {
+			t.Errorf("Expected 7 tab-separated lines, got %q", lines)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+// diff.go
+package memsize
+
+import "sort"
+
+// DiffReport describes how the size of a value changed between two reports
+type DiffReport struct {
+	// Before and After are the totals of the two reports
+	Before uint64
+	After  uint64
+	// Entries lists the paths whose size changed, largest change first
+	Entries []DiffEntry
+}
+
+// DiffEntry is the change in size of a single path
+type DiffEntry struct {
+	Path   string
+	Type   string
+	Before uint64
+	After  uint64
+	// Delta is After minus Before; positive values indicate growth
+	Delta int64
+}
+
+// Delta returns the change of the total size
+func (d *DiffReport) Delta() int64 {
+	return int64(d.After) - int64(d.Before)
+}
+
+// Diff compares r, the earlier measurement, with other, a later measurement of the same value.
+// Sizes of nodes sharing a path (such as map entries) are summed before comparing.
+func (r *Report) Diff(other *Report) *DiffReport {
+	return diffPaths(r.Total(), other.Total(), r.pathSizes(), other.pathSizes())
+}
+
+// MeasureDelta measures root, runs op and measures root again, returning the paths whose size op
+// changed, e.g. how much inserting items into a structure really adds. op has to change what
+// root refers to, so root is usually a pointer. Only the sizes per path of the first
+// measurement are kept while op runs.
+func MeasureDelta(root interface{}, op func(), opts ...Option) *DiffReport {
+	report := GetReport(root, opts...)
+	total, paths := report.Total(), report.pathSizes()
+	op()
+	after := GetReport(root, opts...)
+	return diffPaths(total, after.Total(), paths, after.pathSizes())
+}
+
+// diffPaths compares the sizes per path of two measurements with the given totals
+func diffPaths(beforeTotal, afterTotal uint64, before, after map[string]pathSize) *DiffReport {
+	d := &DiffReport{Before: beforeTotal, After: afterTotal}
+	for path, b := range before {
+		a := after[path]
+		if a.size != b.size {
+			d.Entries = append(d.Entries, newDiffEntry(path, b, a))
+		}
+	}
+	for path, a := range after {
+		if _, ok := before[path]; !ok && a.size != 0 {
+			d.Entries = append(d.Entries, newDiffEntry(path, pathSize{}, a))
+		}
+	}
+
+	sort.Slice(d.Entries, func(i, j int) bool {
+		ai, aj := abs(d.Entries[i].Delta), abs(d.Entries[j].Delta)
+		if ai != aj {
+			return ai > aj
+		}
+		return d.Entries[i].Path < d.Entries[j].Path
+	})
+	return d
+}
+
+type pathSize struct {
+	typ  string
+	size uint64
+}
+
+func (r *Report) pathSizes() map[string]pathSize {
+	sizes := make(map[string]pathSize)
+	r.Walk(func(n *Node) bool {
+		ps := sizes[n.Path]
+		ps.typ = n.Type
+		ps.size += n.Size
+		sizes[n.Path] = ps
+		return true
+	})
+	return sizes
+}
+
+func newDiffEntry(path string, before, after pathSize) DiffEntry {
+	typ := after.typ
+	if typ == "" {
+		typ = before.typ
+	}
+	return DiffEntry{
+		Path:   path,
+		Type:   typ,
+		Before: before.size,
+		After:  after.size,
+		Delta:  int64(after.size) - int64(before.size),
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
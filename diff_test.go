@@ -0,0 +1,100 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+type cache struct {
+	Entries []string
+	Hits    int
+}
+
+func TestReportDiff(t *testing.T) {
+	Debug = false
+
+	c := &cache{Entries: []string{"a"}}
+	before := GetReport(c)
+
+	c.Entries = append(c.Entries, "bbbbbbbbbb", "cccccccccc")
+	after := GetReport(c)
+
+	diff := before.Diff(after)
+	for _, e := range diff.Entries {
+		fmt.Printf("%s: %d -> %d (%+d)\n", e.Path, e.Before, e.After, e.Delta)
+	}
+
+	t.Run("Total Delta", func(t *testing.T) {
+		expected := int64(after.Total()) - int64(before.Total())
+		if diff.Delta() != expected {
+			t.Errorf("Expected total delta %d, got %d", expected, diff.Delta())
+		}
+		if diff.Delta() <= 0 {
+			t.Error("Expected the cache to have grown")
+		}
+	})
+
+	t.Run("Growing Path", func(t *testing.T) {
+		var entries *DiffEntry
+		for i := range diff.Entries {
+			if diff.Entries[i].Path == "root.ptr.Entries" {
+				entries = &diff.Entries[i]
+			}
+		}
+		if entries == nil {
+			t.Fatal("Expected root.ptr.Entries in the diff")
+		}
+		if entries.Delta <= 0 {
+			t.Errorf("Expected root.ptr.Entries to grow, got %+d", entries.Delta)
+		}
+	})
+
+	t.Run("Unchanged Paths Omitted", func(t *testing.T) {
+		for _, e := range diff.Entries {
+			if e.Path == "root.ptr.Hits" {
+				t.Error("Unchanged path root.ptr.Hits should not be listed")
+			}
+		}
+	})
+
+	t.Run("Shrinking", func(t *testing.T) {
+		reverse := after.Diff(before)
+		if reverse.Delta() != -diff.Delta() {
+			t.Errorf("Expected reverse delta %d, got %d", -diff.Delta(), reverse.Delta())
+		}
+	})
+}
+
+func TestMeasureDelta(t *testing.T) {
+	Debug = false
+
+	type index struct {
+		Items map[int]string
+		Name  string
+	}
+	x := &index{Items: make(map[int]string), Name: "index"}
+	before := GetTotalSize(x)
+
+	diff := MeasureDelta(x, func() {
+		for i := 0; i < 10000; i++ {
+			x.Items[i] = fmt.Sprint("item ", i)
+		}
+	})
+	fmt.Printf("Inserting 10k items added %d bytes\n", diff.Delta())
+
+	if diff.Before != before || diff.After != GetTotalSize(x) {
+		t.Errorf("Expected totals %d and %d, got %d and %d", before, GetTotalSize(x), diff.Before, diff.After)
+	}
+	if diff.Delta() <= 0 || len(diff.Entries) == 0 || diff.Entries[0].Path != "root" {
+		t.Fatalf("Expected the root to grow the most, got %+v", diff.Entries)
+	}
+	for _, e := range diff.Entries {
+		if e.Path == "root.ptr.Name" {
+			t.Error("Unchanged path root.ptr.Name should not be listed")
+		}
+	}
+
+	if diff := MeasureDelta(x, func() {}); len(diff.Entries) != 0 || diff.Delta() != 0 {
+		t.Errorf("Expected no change from a no-op, got %+v", diff.Entries)
+	}
+}
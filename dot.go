@@ -0,0 +1,86 @@
+// dot.go
+package memsize
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// WriteDOT writes the traversed object graph in Graphviz DOT format. Graph nodes are the root
+// and every pointed-to object, labeled with their type and retained bytes; edges are labeled
+// with the field path leading to the pointer. Pointers to objects already counted through
+// another path are drawn as dashed edges, which makes unexpected back references visible.
+func (r *Report) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph memsize {")
+	fmt.Fprintln(bw, "\tnode [shape=box];")
+
+	if r != nil && r.Root != nil {
+		g := &dotGraph{w: bw, total: r.Total(), ids: make(map[uintptr]int)}
+		root := g.node(r.Root)
+		if r.Root.Addr != 0 {
+			g.ids[r.Root.Addr] = root
+		}
+		objPath := r.Root.Path + derefStep(r.Root)
+		for _, child := range r.Root.Children {
+			g.walk(child, root, r.Root.Path, objPath)
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+type dotGraph struct {
+	w     *bufio.Writer
+	total uint64
+	ids   map[uintptr]int
+	next  int
+}
+
+func (g *dotGraph) node(n *Node) int {
+	id := g.next
+	g.next++
+
+	// Scale the font with the share of the total so heavy objects stand out
+	fontSize := 10.0
+	if g.total > 0 && n.Size > 0 {
+		fontSize += 20 * math.Log1p(float64(n.Size)) / math.Log1p(float64(g.total))
+	}
+	fmt.Fprintf(g.w, "\tn%d [label=%s, fontsize=%.1f];\n",
+		id, dotQuote(fmt.Sprintf("%s\n%d bytes", n.Type, n.Size)), fontSize)
+	return id
+}
+
+// walk emits the graph below n, where from is the object containing n, fromPath the path of
+// its node and objPath the path of its contents
+func (g *dotGraph) walk(n *Node, from int, fromPath, objPath string) {
+	if n.Addr != 0 {
+		label := strings.TrimPrefix(strings.TrimPrefix(n.Path, objPath), ".")
+		if label == "" {
+			// n is the contents itself, such as the box of an interface a pointer refers to
+			label = strings.TrimPrefix(strings.TrimPrefix(n.Path, fromPath), ".")
+		}
+		if id, ok := g.ids[n.Addr]; ok && n.Shared {
+			fmt.Fprintf(g.w, "\tn%d -> n%d [label=%s, style=dashed];\n", from, id, dotQuote(label))
+			return
+		}
+
+		id := g.node(n)
+		g.ids[n.Addr] = id
+		fmt.Fprintf(g.w, "\tn%d -> n%d [label=%s];\n", from, id, dotQuote(label))
+		from, fromPath, objPath = id, n.Path, n.Path+derefStep(n)
+	}
+	for _, child := range n.Children {
+		g.walk(child, from, fromPath, objPath)
+	}
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
@@ -0,0 +1,76 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type server struct {
+	Name  string
+	Cache map[string]*entry
+}
+
+type entry struct {
+	Value  string
+	Server *server
+}
+
+func TestWriteDOT(t *testing.T) {
+	Debug = false
+
+	srv := &server{Name: "api", Cache: make(map[string]*entry)}
+	srv.Cache["a"] = &entry{Value: "cached", Server: srv}
+
+	var buf bytes.Buffer
+	if err := GetReport(srv).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	fmt.Print(dot)
+
+	if !strings.HasPrefix(dot, "digraph memsize {") || !strings.HasSuffix(dot, "}\n") {
+		t.Error("Expected a complete digraph")
+	}
+	if !strings.Contains(dot, `n0 -> n1 [label="Cache[\"a\"]"]`) {
+		t.Error("Expected an edge from the server to the cache entry")
+	}
+	if !strings.Contains(dot, `n1 -> n0 [label="Server", style=dashed]`) {
+		t.Error("Expected a dashed back edge from the cache entry to the server")
+	}
+	if !strings.Contains(dot, `*memsize.entry`) {
+		t.Error("Expected the entry type in a node label")
+	}
+
+	t.Run("Interface", func(t *testing.T) {
+		var v interface{} = entry{Value: "boxed", Server: srv}
+
+		var buf bytes.Buffer
+		if err := GetReport(&v).WriteDOT(&buf); err != nil {
+			t.Fatal(err)
+		}
+		dot := buf.String()
+		fmt.Print(dot)
+
+		if !strings.Contains(dot, `n0 -> n1 [label="ptr"]`) {
+			t.Error("Expected an edge from the pointer to the interface box")
+		}
+		if !strings.Contains(dot, `n1 -> n2 [label="Server"]`) {
+			t.Error("Expected the edge from the box to the server labeled with the field")
+		}
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := GetReport(srv, WithSizeModel(ExactSizes)).WriteDOT(&buf); err != nil {
+			t.Fatal(err)
+		}
+		dot := buf.String()
+		fmt.Print(dot)
+
+		if !strings.Contains(dot, `n1 -> n2 [label="[\"a\"]"]`) {
+			t.Error("Expected the edge from the map to the cache entry labeled with the key")
+		}
+	})
+}
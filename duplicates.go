@@ -0,0 +1,133 @@
+// duplicates.go
+package memsize
+
+import (
+	"hash/maphash"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// maxDuplicatePaths is the number of example paths kept per duplicated value
+const maxDuplicatePaths = 3
+
+// Duplicate is a value stored in several byte-identical but distinct allocations
+type Duplicate struct {
+	// Type is the type of the value: a string, the target of a pointer or a slice
+	Type string
+	// Size is the size of one copy in bytes
+	Size uint64
+	// Count is the number of distinct allocations holding the value
+	Count int
+	// Savings is the memory freed by keeping a single copy, (Count-1)*Size
+	Savings uint64
+	// Paths are the first paths the value was found at
+	Paths []string
+}
+
+// Duplicates lists duplicated values, largest savings first
+type Duplicates []Duplicate
+
+// Savings returns the memory deduplicating or interning all values would save
+func (d Duplicates) Savings() uint64 {
+	var total uint64
+	for _, dup := range d {
+		total += dup.Savings
+	}
+	return total
+}
+
+// FindDuplicates traverses v and reports the values stored more than once in separate
+// allocations: strings with equal contents but different data, pointers to equal values
+// without indirections, and slices of such values with equal elements. Copies sharing an
+// allocation, such as strings sliced from the same data, are already deduplicated and not
+// reported. Contents are compared by a 64-bit hash.
+func FindDuplicates(v interface{}, opts ...Option) Duplicates {
+	d := &duplicateFinder{seed: maphash.MakeSeed(), groups: make(map[duplicateKey]*duplicateGroup)}
+	_ = Walk(v, func(path string, val reflect.Value, _ uint64) WalkAction {
+		d.add(path, val)
+		return WalkContinue
+	}, opts...)
+	return d.duplicates()
+}
+
+type duplicateKey struct {
+	typ  reflect.Type
+	size uint64
+	hash uint64
+}
+
+type duplicateGroup struct {
+	Duplicate
+	addrs map[uintptr]bool
+}
+
+type duplicateFinder struct {
+	seed   maphash.Seed
+	groups map[duplicateKey]*duplicateGroup
+	// order keeps groups in the order they were found, for stable results
+	order []*duplicateGroup
+}
+
+// add records the allocation a value refers to, if it holds no indirections
+func (d *duplicateFinder) add(path string, v reflect.Value) {
+	var (
+		t    reflect.Type
+		addr unsafe.Pointer
+		size uint64
+	)
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		t, addr, size = v.Type(), *(*unsafe.Pointer)(unsafe.Pointer(&s)), uint64(len(s))
+	case reflect.Ptr:
+		if v.IsNil() || hasPointers(v.Type().Elem()) {
+			return
+		}
+		t, addr, size = v.Type().Elem(), v.UnsafePointer(), uint64(v.Type().Elem().Size())
+	case reflect.Slice:
+		if v.IsNil() || hasPointers(v.Type().Elem()) {
+			return
+		}
+		t, addr, size = v.Type(), v.UnsafePointer(), uint64(v.Len())*uint64(v.Type().Elem().Size())
+	default:
+		return
+	}
+	if size == 0 {
+		return
+	}
+
+	var h maphash.Hash
+	h.SetSeed(d.seed)
+	h.Write(unsafe.Slice((*byte)(addr), size))
+	key := duplicateKey{typ: t, size: size, hash: h.Sum64()}
+	g := d.groups[key]
+	if g == nil {
+		g = &duplicateGroup{Duplicate: Duplicate{Type: t.String(), Size: size},
+			addrs: make(map[uintptr]bool)}
+		d.groups[key] = g
+		d.order = append(d.order, g)
+	}
+	if g.addrs[uintptr(addr)] {
+		return
+	}
+	g.addrs[uintptr(addr)] = true
+	g.Count++
+	if len(g.Paths) < maxDuplicatePaths {
+		g.Paths = append(g.Paths, path)
+	}
+}
+
+func (d *duplicateFinder) duplicates() Duplicates {
+	var dups Duplicates
+	for _, g := range d.order {
+		if g.Count > 1 {
+			g.Savings = uint64(g.Count-1) * g.Size
+			dups = append(dups, g.Duplicate)
+		}
+	}
+	sort.SliceStable(dups, func(i, j int) bool {
+		return dups[i].Savings > dups[j].Savings
+	})
+	return dups
+}
@@ -0,0 +1,60 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	Debug = false
+
+	type point struct{ X, Y int64 }
+	shared := strings.Repeat("s", 50)
+	p := &point{1, 2}
+	v := struct {
+		Names  []string
+		Points []*point
+		Blobs  [][]byte
+	}{
+		Names:  []string{strings.Repeat("n", 100), strings.Repeat("n", 100), strings.Repeat("n", 100), shared, shared},
+		Points: []*point{p, p, {1, 2}, {3, 4}},
+		Blobs:  [][]byte{[]byte("blob"), []byte("blob"), []byte("other")},
+	}
+
+	dups := FindDuplicates(v)
+	for _, d := range dups {
+		fmt.Printf("%s x%d: %d bytes saved at %v\n", d.Type, d.Count, d.Savings, d.Paths)
+	}
+	if len(dups) != 3 {
+		t.Fatalf("Expected 3 duplicated values, got %d", len(dups))
+	}
+
+	t.Run("Strings", func(t *testing.T) {
+		d := dups[0]
+		if d.Type != "string" || d.Count != 3 || d.Savings != 200 {
+			t.Errorf("Expected 3 copies of a string saving 200 bytes, got %+v", d)
+		}
+		if len(d.Paths) != 3 || d.Paths[0] != "root.Names[0]" {
+			t.Errorf("Expected the paths of the copies, got %v", d.Paths)
+		}
+	})
+
+	t.Run("Pointers", func(t *testing.T) {
+		d := dups[1]
+		if d.Type != "memsize.point" || d.Count != 2 || d.Savings != 16 {
+			t.Errorf("Expected 2 copies of a point saving 16 bytes, got %+v", d)
+		}
+	})
+
+	t.Run("Slices", func(t *testing.T) {
+		d := dups[2]
+		if d.Type != "[]uint8" || d.Count != 2 || d.Savings != 4 {
+			t.Errorf("Expected 2 copies of a byte slice saving 4 bytes, got %+v", d)
+		}
+	})
+
+	if total := dups.Savings(); total != 200+16+4 {
+		t.Errorf("Expected %d bytes of savings, got %d", 200+16+4, total)
+	}
+}
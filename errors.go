@@ -0,0 +1,87 @@
+// errors.go
+package memsize
+
+import (
+	"reflect"
+	"strconv"
+)
+
+var (
+	unwrapType     = reflect.TypeOf((*interface{ Unwrap() error })(nil)).Elem()
+	unwrapJoinType = reflect.TypeOf((*interface{ Unwrap() []error })(nil)).Elem()
+)
+
+// WithErrorChains follows the errors returned by the Unwrap methods of wrapped errors, in
+// addition to their fields, so errors kept for long, e.g. in a cache of results, are counted
+// with their whole chain even where reflection can't see it, such as a cause captured by a
+// closure. Causes already reached through a field are not counted again; only causes that are
+// pointers are followed, with paths such as "root.ptr.Unwrap()[1]".
+func WithErrorChains() Option {
+	return func(c *config) {
+		c.errorChains = true
+	}
+}
+
+// nextCause returns the next error the frame's value wraps that was not counted yet, once its
+// other children were visited
+func (w *walker) nextCause(f *frame) (reflect.Value, pathStep, bool) {
+	if !w.cfg.errorChains || f.custom || f.pruned {
+		return reflect.Value{}, pathStep{}, false
+	}
+	if !f.unwrapped {
+		f.unwrapped = true
+		f.causes = causes(f.v)
+	}
+	for f.cause < len(f.causes) {
+		i := f.cause
+		f.cause++
+		cause := f.causes[i]
+		if w.visitAddr(cause.Pointer(), cause.Type().Elem()) {
+			continue
+		}
+		suffix := ".Unwrap()"
+		if len(f.causes) > 1 || f.v.Type().Implements(unwrapJoinType) {
+			suffix += "[" + strconv.Itoa(i) + "]"
+		}
+		// The pointer returned by Unwrap is not stored anywhere, only its target is counted
+		return cause.Elem(), suffixStep(suffix), true
+	}
+	return reflect.Value{}, pathStep{}, false
+}
+
+// causes returns the non-nil pointers among the errors v wraps, recovering from Unwrap methods
+// that panic, e.g. on nil receivers
+func causes(v reflect.Value) (ptrs []reflect.Value) {
+	if !v.IsValid() || v.Kind() == reflect.Interface {
+		return nil
+	}
+	t := v.Type()
+	if !t.Implements(unwrapType) && !t.Implements(unwrapJoinType) {
+		return nil
+	}
+	if !v.CanInterface() {
+		if !v.CanAddr() {
+			return nil
+		}
+		v = reflect.NewAt(t, v.Addr().UnsafePointer()).Elem()
+	}
+
+	defer func() {
+		if recover() != nil {
+			ptrs = nil
+		}
+	}()
+	var errs []error
+	switch u := v.Interface().(type) {
+	case interface{ Unwrap() error }:
+		errs = []error{u.Unwrap()}
+	case interface{ Unwrap() []error }:
+		errs = u.Unwrap()
+	}
+	for _, err := range errs {
+		if p := reflect.ValueOf(err); p.Kind() == reflect.Ptr && !p.IsNil() {
+			ptrs = append(ptrs, p)
+		}
+	}
+	return ptrs
+}
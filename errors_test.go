@@ -0,0 +1,77 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lazyError keeps its cause in a closure, which reflection can't look into
+type lazyError struct {
+	msg   string
+	cause func() error
+}
+
+func (e *lazyError) Error() string { return e.msg }
+func (e *lazyError) Unwrap() error { return e.cause() }
+
+// stackError records a stack trace like the errors of pkg/errors
+type stackError struct {
+	error
+	stack []uintptr
+}
+
+func (e *stackError) Unwrap() error { return e.error }
+
+// joinedError wraps several errors like those of errors.Join, which needs Go 1.20
+type joinedError struct {
+	errs []error
+}
+
+func (e *joinedError) Error() string   { return fmt.Sprint(e.errs) }
+func (e *joinedError) Unwrap() []error { return e.errs }
+
+func TestWithErrorChains(t *testing.T) {
+	Debug = false
+
+	t.Run("Fields", func(t *testing.T) {
+		// Causes held in fields are counted either way, and not twice with the option
+		base := &stackError{error: errors.New("not found"), stack: make([]uintptr, 32)}
+		err := fmt.Errorf("loading: %w", &joinedError{errs: []error{base, errors.New("other")}})
+		with, without := GetTotalSize(err, WithErrorChains()), GetTotalSize(err)
+		fmt.Printf("Fields: %d with chains, %d without\n", with, without)
+		if with != without {
+			t.Errorf("Expected %d, got %d", without, with)
+		}
+	})
+
+	t.Run("Closure", func(t *testing.T) {
+		cause := &stackError{error: errors.New(strings.Repeat("x", 500)), stack: make([]uintptr, 32)}
+		err := error(&lazyError{msg: "lazy", cause: func() error { return cause }})
+		with, without := GetTotalSize(err, WithErrorChains()), GetTotalSize(err)
+		fmt.Printf("Closure: %d with chains, %d without\n", with, without)
+		// The cause's pointer is only returned by Unwrap, so its target is counted without it
+		expected := without + GetTotalSize(cause) - GetTotalSize((*stackError)(nil))
+		if with != expected {
+			t.Errorf("Expected %d, got %d", expected, with)
+		}
+
+		r := GetReport(err, WithErrorChains())
+		found := false
+		r.Walk(func(n *Node) bool {
+			found = found || strings.HasSuffix(n.Path, ".Unwrap()")
+			return true
+		})
+		if !found || r.Total() != with {
+			t.Errorf("Expected a report of %d bytes with the cause, got %d", with, r.Total())
+		}
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		err := &lazyError{msg: "nil cause"}
+		if got, expected := GetTotalSize(err, WithErrorChains()), GetTotalSize(err); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+// escape.go
+package memsize
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultEscapeFactor is the factor EscapePoints uses when given none
+const DefaultEscapeFactor = 10.0
+
+// escapeMinBytes is the size below which a subtree is never reported as an escape point
+const escapeMinBytes = 4 << 10
+
+// globals maps the addresses of well-known singletons to their names, see RegisterGlobal
+var globals sync.Map
+
+func init() {
+	RegisterGlobal("http.DefaultTransport", http.DefaultTransport)
+	RegisterGlobal("http.DefaultClient", http.DefaultClient)
+	RegisterGlobal("http.DefaultServeMux", http.DefaultServeMux)
+	RegisterGlobal("os.Stdin", os.Stdin)
+	RegisterGlobal("os.Stdout", os.Stdout)
+	RegisterGlobal("os.Stderr", os.Stderr)
+	RegisterGlobal("log.Default", log.Default())
+}
+
+// RegisterGlobal names a singleton of the program, such as the application's logger or
+// configuration, given as a pointer, map or channel. Values referring to it are marked in
+// reports with Node.Global and listed by Report.EscapePoints, since a field reaching it
+// usually makes a small struct account for much of the program. The singletons of the
+// standard library, such as http.DefaultTransport and os.Stdout, are registered already.
+func RegisterGlobal(name string, ref interface{}) {
+	v := reflect.ValueOf(ref)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		if !v.IsNil() {
+			globals.Store(v.Pointer(), name)
+		}
+	default:
+		panic("memsize: RegisterGlobal of " + v.Kind().String() + ", not a reference")
+	}
+}
+
+// markGlobal sets Node.Global on frames referring to a registered singleton
+func (w *walker) markGlobal(f *frame) {
+	if f.node == nil && !w.cfg.debug {
+		return
+	}
+	addr := f.addr
+	if k := f.v.Kind(); addr == 0 && (k == reflect.Map || k == reflect.Chan) && !f.v.IsNil() {
+		// Maps and channels are only deduplicated with ExactSizes
+		addr = f.v.Pointer()
+	}
+	if addr == 0 {
+		return
+	}
+	name, ok := globals.Load(addr)
+	if !ok {
+		return
+	}
+	if f.node != nil {
+		f.node.Global = name.(string)
+	}
+	if w.cfg.debug && !f.shared {
+		w.debugPrint(f, "Escapes into global %s", name)
+	}
+}
+
+// EscapePoint is a reference through which a value reaches a large subtree it is unlikely to
+// own, such as a field holding a client that leads to http.DefaultTransport
+type EscapePoint struct {
+	// Node is the reference and Holder the value holding it
+	Node   *Node
+	Holder *Node
+	// Global names the registered singleton Node refers to, if it does
+	Global string
+}
+
+// EscapePoints returns the references of the report leading to a registered singleton, see
+// RegisterGlobal, or to a subtree of at least 4 KiB whose type comes from another package than
+// its holder and that is factor times larger than the rest of the holder; DefaultEscapeFactor
+// applies to a factor of zero. Sizes behind escape points are often not what the measured
+// value owns. The largest come first, and nothing below an escape point is reported.
+func (r *Report) EscapePoints(factor float64) []EscapePoint {
+	if r == nil || r.Root == nil {
+		return nil
+	}
+	if factor <= 0 {
+		factor = DefaultEscapeFactor
+	}
+
+	var points []EscapePoint
+	type entry struct{ n, parent *Node }
+	stack := []entry{{n: r.Root}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e.parent != nil && (e.n.Addr != 0 || e.n.Global != "") && !e.n.Shared && escapes(e.n, e.parent, factor) {
+			points = append(points, EscapePoint{Node: e.n, Holder: e.parent, Global: e.n.Global})
+			continue
+		}
+		for i := len(e.n.Children) - 1; i >= 0; i-- {
+			stack = append(stack, entry{n: e.n.Children[i], parent: e.n})
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Node.Size > points[j].Node.Size
+	})
+	return points
+}
+
+// escapes reports whether the reference n held by holder is an escape point
+func escapes(n, holder *Node, factor float64) bool {
+	if n.Global != "" {
+		return true
+	}
+	var rest uint64
+	if holder.Size > n.Size {
+		rest = holder.Size - n.Size
+	}
+	if n.Size < escapeMinBytes || float64(n.Size) < factor*float64(rest) {
+		return false
+	}
+	pkg := typePackage(n.Type)
+	return pkg != "" && pkg != typePackage(holder.Type)
+}
+
+// typePackage returns the package name of the named type a type string refers to through
+// pointers, slices and arrays, or "" for other types
+func typePackage(typ string) string {
+	for {
+		switch {
+		case strings.HasPrefix(typ, "*"):
+			typ = typ[1:]
+		case strings.HasPrefix(typ, "["):
+			end := strings.IndexByte(typ, ']')
+			if end < 0 {
+				return ""
+			}
+			typ = typ[end+1:]
+		default:
+			dot := strings.IndexByte(typ, '.')
+			if dot <= 0 || strings.ContainsAny(typ[:dot], " []{}()*") {
+				return ""
+			}
+			return typ[:dot]
+		}
+	}
+}
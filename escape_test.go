@@ -0,0 +1,87 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEscapePoints(t *testing.T) {
+	Debug = false
+
+	type service struct {
+		name      string
+		transport http.RoundTripper
+		buf       *bytes.Buffer
+		small     *bytes.Buffer
+	}
+	s := &service{
+		name:      "api",
+		transport: http.DefaultTransport,
+		buf:       bytes.NewBuffer(make([]byte, 0, 16<<10)),
+		small:     bytes.NewBufferString("small"),
+	}
+	report := GetReport(s)
+	points := report.EscapePoints(0)
+	for _, p := range points {
+		fmt.Printf("Escape point: %s (%s) %d bytes, global %q\n", p.Node.Path, p.Node.Type, p.Node.Size, p.Global)
+	}
+
+	t.Run("Global", func(t *testing.T) {
+		var found bool
+		for _, p := range points {
+			if p.Global == "http.DefaultTransport" {
+				found = true
+				if !strings.HasPrefix(p.Node.Path, "root.ptr.transport") || p.Holder == nil {
+					t.Errorf("Expected the transport field to escape, got %s", p.Node.Path)
+				}
+			}
+		}
+		if !found {
+			t.Error("Expected an escape point into http.DefaultTransport")
+		}
+	})
+
+	t.Run("Large", func(t *testing.T) {
+		var buf, small bool
+		for _, p := range points {
+			buf = buf || p.Node.Path == "root.ptr.buf"
+			small = small || p.Node.Path == "root.ptr.small"
+		}
+		if !buf || small {
+			t.Errorf("Expected only the large buffer to escape, got buf %v, small %v", buf, small)
+		}
+		if len(points) > 0 && points[0].Node.Path != "root.ptr.buf" {
+			t.Errorf("Expected the largest escape point first, got %s", points[0].Node.Path)
+		}
+	})
+
+	t.Run("Factor", func(t *testing.T) {
+		for _, p := range report.EscapePoints(1e6) {
+			if p.Global == "" {
+				t.Errorf("Expected only globals with a large factor, got %s", p.Node.Path)
+			}
+		}
+	})
+
+	t.Run("Registered", func(t *testing.T) {
+		config := map[string]string{"key": "value"}
+		RegisterGlobal("test.config", config)
+		defer globals.Delete(reflect.ValueOf(config).Pointer())
+		v := struct{ cfg map[string]string }{config}
+		points := GetReport(&v).EscapePoints(0)
+		if len(points) != 1 || points[0].Global != "test.config" {
+			t.Errorf("Expected an escape point into test.config, got %+v", points)
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		text := report.String()
+		if !strings.Contains(text, "escape points:") || !strings.Contains(text, "(global http.DefaultTransport)") {
+			t.Errorf("Expected escape points in the text report, got\n%s", text)
+		}
+	})
+}
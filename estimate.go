@@ -0,0 +1,201 @@
+// estimate.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// EstimateItemSize returns SizeOf(item) with the default options, taking fast paths for the
+// shapes cache entries usually have. Types of constant size, strings, slices of values of
+// constant size, structs whose other fields are such strings and slices, and pointers to any of
+// these are sized from a formula cached per type without a traversal; other types reuse the
+// cached traversal plans. It never collects garbage or reads MemStats, so it fits the insert
+// path of a cache. Fields sharing a backing array are counted once per field by the formulas.
+// With Debug set every item is traversed.
+func EstimateItemSize[T any](item T) uint64 {
+	if Debug {
+		return SizeOf(item)
+	}
+	if e := estimateFor(reflect.TypeOf((*T)(nil)).Elem()); e != nil {
+		return e.at(unsafe.Pointer(&item))
+	}
+	return SizeOf(item)
+}
+
+// itemShape is a kind of type EstimateItemSize sizes from a formula
+type itemShape int
+
+const (
+	shapeConstant itemShape = iota
+	shapeString
+	shapeSlice
+	shapeStruct
+	shapePointer
+)
+
+// itemEstimate is the cached size formula of a type: base plus perCap and perLen bytes per
+// element of capacity and length, plus the estimates of the fields of structs or the target of
+// pointers, or nilSize for nil slices and pointers
+type itemEstimate struct {
+	shape          itemShape
+	base           uint64
+	perCap, perLen uint64
+	nilSize        uint64
+	fields         []fieldEstimate
+	elem           *itemEstimate
+}
+
+// fieldEstimate is the formula of a struct field at an offset
+type fieldEstimate struct {
+	offset uintptr
+	*itemEstimate
+}
+
+// sliceHeader mirrors the runtime representation of a slice
+type sliceHeader struct {
+	data     unsafe.Pointer
+	len, cap int
+}
+
+// at evaluates the formula for the value at p
+func (e *itemEstimate) at(p unsafe.Pointer) uint64 {
+	switch e.shape {
+	case shapeString:
+		return e.base + e.perLen*uint64(len(*(*string)(p)))
+	case shapeSlice:
+		s := (*sliceHeader)(p)
+		if s.data == nil {
+			return e.nilSize
+		}
+		return e.base + e.perCap*uint64(s.cap) + e.perLen*uint64(s.len)
+	case shapeStruct:
+		size := e.base
+		for _, f := range e.fields {
+			size += f.at(unsafe.Add(p, f.offset))
+		}
+		return size
+	case shapePointer:
+		target := *(*unsafe.Pointer)(p)
+		if target == nil {
+			return e.nilSize
+		}
+		return e.base + e.elem.at(target)
+	}
+	return e.base
+}
+
+// estimates caches an *itemEstimate per type, nil for types without a formula
+var estimates sync.Map
+
+func estimateFor(t reflect.Type) *itemEstimate {
+	if e, ok := estimates.Load(t); ok {
+		return e.(*itemEstimate)
+	}
+	e, _ := estimates.LoadOrStore(t, computeEstimate(t))
+	return e.(*itemEstimate)
+}
+
+// computeEstimate derives the formula of t by measuring a few probe values, so it follows the
+// size model exactly, and checks it on one more probe; types whose size isn't linear in the
+// lengths of their strings and slices get none
+func computeEstimate(t reflect.Type) *itemEstimate {
+	l := newConfig(nil).layout()
+	if p := planFor(t, l); p.constant {
+		if p.issue != nil {
+			return nil
+		}
+		return &itemEstimate{shape: shapeConstant, base: p.size}
+	}
+	if handlerFor(t, l) != nil {
+		return nil
+	}
+
+	var e *itemEstimate
+	switch t.Kind() {
+	case reflect.String:
+		e = &itemEstimate{shape: shapeString, base: estimateProbe(reflect.Zero(t))}
+		one := reflect.New(t).Elem()
+		one.SetString("x")
+		e.perLen = estimateProbe(one) - e.base
+
+	case reflect.Slice:
+		if p := planFor(t.Elem(), l); !p.constant || p.issue != nil {
+			return nil
+		}
+		// Two points of capacity and one of length determine the linear formula
+		e = &itemEstimate{shape: shapeSlice, nilSize: estimateProbe(reflect.Zero(t))}
+		cap1 := estimateProbe(reflect.MakeSlice(t, 0, 1))
+		cap2 := estimateProbe(reflect.MakeSlice(t, 0, 2))
+		e.perCap = cap2 - cap1
+		e.base = cap1 - e.perCap
+		e.perLen = (estimateProbe(reflect.MakeSlice(t, 2, 2)) - cap2) / 2
+
+	case reflect.Struct:
+		e = &itemEstimate{shape: shapeStruct}
+		zero := reflect.New(t)
+		var fields uint64
+		for _, i := range planFor(t, l).fields {
+			// Checking the kind first keeps recursive types from recursing
+			k := t.Field(i).Type.Kind()
+			if k != reflect.String && k != reflect.Slice {
+				return nil
+			}
+			f := estimateFor(t.Field(i).Type)
+			if f == nil {
+				return nil
+			}
+			offset := t.Field(i).Offset
+			e.fields = append(e.fields, fieldEstimate{offset, f})
+			fields += f.at(unsafe.Add(zero.UnsafePointer(), offset))
+		}
+		e.base = estimateProbe(zero.Elem()) - fields
+
+	case reflect.Ptr:
+		elem := estimateFor(t.Elem())
+		if elem == nil || elem.shape == shapePointer {
+			return nil
+		}
+		target := reflect.New(t.Elem())
+		e = &itemEstimate{shape: shapePointer, elem: elem, nilSize: estimateProbe(reflect.Zero(t))}
+		e.base = estimateProbe(target) - elem.at(target.UnsafePointer())
+
+	default:
+		return nil
+	}
+
+	probe := reflect.New(t)
+	probe.Elem().Set(probeValue(t))
+	if e.at(probe.UnsafePointer()) != estimateProbe(probe.Elem()) {
+		return nil
+	}
+	return e
+}
+
+// estimateProbe measures a probe value with the default options
+func estimateProbe(v reflect.Value) uint64 {
+	return GetTotalSizeValue(v)
+}
+
+// probeValue returns a value of t with non-empty strings and slices, to check formulas on
+func probeValue(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString("sample")
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(t, 3, 5))
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if k := t.Field(i).Type.Kind(); k == reflect.String || k == reflect.Slice {
+				f := reflect.NewAt(t.Field(i).Type, unsafe.Add(v.Addr().UnsafePointer(), t.Field(i).Offset))
+				f.Elem().Set(probeValue(t.Field(i).Type))
+			}
+		}
+	case reflect.Ptr:
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(probeValue(t.Elem()))
+	}
+	return v
+}
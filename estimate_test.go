@@ -0,0 +1,80 @@
+package memsize
+
+import (
+	"reflect"
+	"testing"
+)
+
+type estimatePoint struct{ X, Y float64 }
+
+type estimateEntry struct {
+	Key   string
+	Value []byte
+	Hits  int
+}
+
+func TestEstimateItemSize(t *testing.T) {
+	Debug = false
+
+	check := func(name string, got, want uint64) {
+		t.Helper()
+		if got != want {
+			t.Errorf("%s: expected %d, got %d", name, want, got)
+		}
+	}
+
+	t.Run("Fast Paths", func(t *testing.T) {
+		check("int", EstimateItemSize(42), SizeOf(42))
+		check("struct", EstimateItemSize(estimatePoint{1, 2}), SizeOf(estimatePoint{1, 2}))
+		for _, s := range []string{"", "x", "a longer string value"} {
+			check("string", EstimateItemSize(s), SizeOf(s))
+		}
+		for _, b := range [][]byte{nil, {}, make([]byte, 10, 64), make([]byte, 1000)} {
+			check("[]byte", EstimateItemSize(b), SizeOf(b))
+		}
+		check("[]int", EstimateItemSize([]int{1, 2, 3}), SizeOf([]int{1, 2, 3}))
+		check("*point", EstimateItemSize(&estimatePoint{}), SizeOf(&estimatePoint{}))
+		check("nil *point", EstimateItemSize((*estimatePoint)(nil)), SizeOf((*estimatePoint)(nil)))
+
+		e := &estimateEntry{Key: "key", Value: make([]byte, 100, 128), Hits: 3}
+		check("*entry", EstimateItemSize(e), SizeOf(e))
+		check("entry", EstimateItemSize(*e), SizeOf(*e))
+		check("empty entry", EstimateItemSize(estimateEntry{}), SizeOf(estimateEntry{}))
+		if estimateFor(reflect.TypeOf(e)) == nil {
+			t.Error("Expected a formula for pointers to structs of strings and slices")
+		}
+	})
+
+	t.Run("Traversed", func(t *testing.T) {
+		type node struct {
+			name string
+			next *node
+		}
+		n := &node{name: "a", next: &node{name: "b"}}
+		check("*node", EstimateItemSize(n), SizeOf(n))
+		var i interface{} = "boxed"
+		check("interface", EstimateItemSize(i), SizeOf(i))
+		m := map[string]int{"a": 1}
+		check("map", EstimateItemSize(m), SizeOf(m))
+	})
+}
+
+func BenchmarkEstimateItemSize(b *testing.B) {
+	value := make([]byte, 512)
+	entry := &estimateEntry{Key: "key", Value: value}
+	b.Run("Bytes", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EstimateItemSize(value)
+		}
+	})
+	b.Run("String", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EstimateItemSize("session:1234")
+		}
+	})
+	b.Run("Entry", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EstimateItemSize(entry)
+		}
+	})
+}
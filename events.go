@@ -0,0 +1,78 @@
+// events.go
+package memsize
+
+import "reflect"
+
+// EventPhase tells which part of the traversal an Event reports
+type EventPhase int
+
+const (
+	// EventEnter is sent when a value is reached, with its shallow size and the dedup
+	// decision for the memory it references
+	EventEnter EventPhase = iota
+	// EventExit is sent once the children of a value were sized, with its total size
+	EventExit
+	// EventDone is sent once at the end of a measurement, with the total size in Size
+	EventDone
+)
+
+func (p EventPhase) String() string {
+	switch p {
+	case EventEnter:
+		return "enter"
+	case EventExit:
+		return "exit"
+	case EventDone:
+		return "done"
+	}
+	return "unknown"
+}
+
+// Event is a step of a traversal sent to the sink set with WithEventSink
+type Event struct {
+	Phase EventPhase
+	// Path is the location of the value relative to the root, as in Node.Path; empty for
+	// EventDone
+	Path string
+	Kind reflect.Kind
+	// Type is empty for nil values and EventDone
+	Type string
+	// Depth is the number of levels between the root and the value
+	Depth int
+	// Shallow is the size of the value itself; Size is the cumulative size of the value and
+	// its children, known on EventExit, or of the whole measurement on EventDone
+	Shallow uint64
+	Size    uint64
+	// Addr is the address of the memory a pointer, interface, map or channel references,
+	// and Shared is set when it was already counted through another path
+	Addr   uintptr
+	Shared bool
+}
+
+// EventSink receives the events of a traversal, from the goroutine measuring
+type EventSink func(Event)
+
+// WithEventSink sends every value of a single measurement to sink as it is entered and
+// exited, and the total once done, for tools rendering traversals live without parsing debug
+// output. Like debug output, a sink keeps the measurement from being parallelized.
+func WithEventSink(sink EventSink) Option {
+	return func(c *config) {
+		c.events = sink
+	}
+}
+
+// emit sends an event about the frame's value to the event sink, if there is one
+func (w *walker) emit(phase EventPhase, f *frame) {
+	if w.cfg.events == nil {
+		return
+	}
+	e := Event{Phase: phase, Path: w.path(f), Kind: f.v.Kind(), Depth: len(w.stack) - 1,
+		Shallow: f.shallow, Addr: f.addr, Shared: f.shared}
+	if f.v.IsValid() {
+		e.Type = f.v.Type().String()
+	}
+	if phase == EventExit {
+		e.Size = f.size
+	}
+	w.cfg.events(e)
+}
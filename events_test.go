@@ -0,0 +1,75 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWithEventSink(t *testing.T) {
+	Debug = false
+
+	shared := &Person{Name: "Shared"}
+	data := []*Person{shared, shared}
+
+	var events []Event
+	size := GetTotalSize(&data, WithEventSink(func(e Event) {
+		events = append(events, e)
+	}))
+
+	t.Run("Order", func(t *testing.T) {
+		if len(events) < 3 {
+			t.Fatalf("Expected events to be sent, got %d", len(events))
+		}
+		first, last := events[0], events[len(events)-1]
+		if first.Phase != EventEnter || first.Path != "root" || first.Kind != reflect.Ptr {
+			t.Errorf("Expected the root to be entered first, got %+v", first)
+		}
+		if last.Phase != EventDone || last.Size != size {
+			t.Errorf("Expected done with size %d last, got %+v", size, last)
+		}
+		root := events[len(events)-2]
+		if root.Phase != EventExit || root.Path != "root" || root.Size != size {
+			t.Errorf("Expected the root to be exited with size %d, got %+v", size, root)
+		}
+
+		depth := 0
+		for _, e := range events[:len(events)-1] {
+			if e.Phase == EventEnter {
+				if e.Depth != depth {
+					t.Errorf("Expected depth %d at %s, got %d", depth, e.Path, e.Depth)
+				}
+				depth++
+			} else if depth--; e.Depth != depth {
+				t.Errorf("Expected depth %d at %s, got %d", depth, e.Path, e.Depth)
+			}
+		}
+	})
+
+	t.Run("Dedup", func(t *testing.T) {
+		var pointers []Event
+		for _, e := range events {
+			if e.Phase == EventEnter && e.Type == "*memsize.Person" {
+				pointers = append(pointers, e)
+			}
+		}
+		fmt.Printf("Pointers: %+v\n", pointers)
+		if len(pointers) != 2 {
+			t.Fatalf("Expected 2 pointers, got %d", len(pointers))
+		}
+		addr := uintptr(reflect.ValueOf(shared).Pointer())
+		if pointers[0].Addr != addr || pointers[1].Addr != addr {
+			t.Errorf("Expected address %x, got %x and %x", addr, pointers[0].Addr, pointers[1].Addr)
+		}
+		if pointers[0].Shared || !pointers[1].Shared {
+			t.Errorf("Expected only the second pointer to be shared, got %v and %v",
+				pointers[0].Shared, pointers[1].Shared)
+		}
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		if got := GetTotalSize(&data); got != size {
+			t.Errorf("Expected the same size without a sink %d, got %d", got, size)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/afshin-deriv/go-memsize/leakcheck"
+)
+
+type Server struct {
+	// Sessions are added for every request but never removed
+	Sessions map[int][]byte
+	Scratch  []byte
+}
+
+func main() {
+	server := &Server{Sessions: make(map[int][]byte)}
+
+	d := leakcheck.New(leakcheck.Config{})
+	d.Register("server", server)
+	stop := d.Start(50 * time.Millisecond)
+	defer stop()
+
+	// Simulate a second of traffic
+	for i := 0; i < 100; i++ {
+		server.Sessions[i] = make([]byte, 1024)
+		server.Scratch = make([]byte, 4096*(1+i%3))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, s := range d.Suspects() {
+		fmt.Println(s)
+	}
+}
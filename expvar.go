@@ -0,0 +1,59 @@
+// expvar.go
+package memsize
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// DefaultExpvarTTL is how long a published size is cached before it is recomputed
+var DefaultExpvarTTL = 10 * time.Second
+
+// sizeVarRoot is the name a SizeVar registers its root under
+const sizeVarRoot = "root"
+
+// SizeVar is an expvar.Var reporting the size of a root, recomputed lazily when read
+type SizeVar struct {
+	sizer *CachedSizer
+}
+
+// PublishExpvar registers an expvar variable with the given name reporting the size of root.
+// Like expvar.Publish, it panics if the name is already in use.
+func PublishExpvar(name string, root interface{}) *SizeVar {
+	v := NewSizeVar(root)
+	expvar.Publish(name, v)
+	return v
+}
+
+// NewSizeVar creates an unpublished SizeVar for root using DefaultExpvarTTL
+func NewSizeVar(root interface{}) *SizeVar {
+	v := &SizeVar{sizer: NewCachedSizer(DefaultExpvarTTL)}
+	v.sizer.Register(sizeVarRoot, root)
+	return v
+}
+
+// SetTTL changes how long a computed size is reused; zero recomputes on every read
+func (v *SizeVar) SetTTL(ttl time.Duration) {
+	v.sizer.setTTL(expvarTTL(ttl))
+}
+
+// Value returns the size of the root, recomputing it if the cached value expired
+func (v *SizeVar) Value() uint64 {
+	size, _ := v.sizer.Size(sizeVarRoot)
+	return size
+}
+
+// String implements expvar.Var
+func (v *SizeVar) String() string {
+	return strconv.FormatUint(v.Value(), 10)
+}
+
+// expvarTTL converts the TTL of an expvar variable, which recomputes sizes on every read when
+// zero, to the TTL of its CachedSizer
+func expvarTTL(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return -1
+	}
+	return ttl
+}
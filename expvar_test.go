@@ -0,0 +1,46 @@
+package memsize
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	Debug = false
+
+	data := []string{"a", "b"}
+	v := PublishExpvar("memsize_test_data", &data)
+
+	t.Run("Published", func(t *testing.T) {
+		published := expvar.Get("memsize_test_data")
+		if published == nil {
+			t.Fatal("Expected variable to be published")
+		}
+
+		var size uint64
+		if err := json.Unmarshal([]byte(published.String()), &size); err != nil {
+			t.Fatalf("Published value is not a JSON number: %v", err)
+		}
+		if size != GetTotalSize(&data) {
+			t.Errorf("Expected %d, got %d", GetTotalSize(&data), size)
+		}
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		v.SetTTL(time.Hour)
+		before := v.Value()
+		data = append(data, "a much longer string that changes the size")
+		if after := v.Value(); after != before {
+			t.Errorf("Expected cached value %d, got %d", before, after)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		v.SetTTL(0)
+		if got, expected := v.Value(), GetTotalSize(&data); got != expected {
+			t.Errorf("Expected recomputed value %d, got %d", expected, got)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+// fields.go
+package memsize
+
+import "reflect"
+
+// FieldSizes returns the retained size of each exported field of a struct, or of the struct
+// a pointer refers to: the bytes that clearing the field would free. Objects shared between
+// fields are attributed to none of them. It returns nil if v is neither a struct nor a
+// non-nil pointer to one.
+func FieldSizes(v interface{}, opts ...Option) map[string]uint64 {
+	rv := reflect.ValueOf(v)
+	ptr := rv.Kind() == reflect.Ptr
+	if ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	// Values of types with a handler have children other than their fields
+	if rv.Kind() != reflect.Struct || handlerFor(rv.Type(), newConfig(opts).layout()) != nil {
+		return nil
+	}
+	t := rv.Type()
+
+	report := GetReport(v, opts...)
+	report.ComputeRetained()
+	node := report.Root
+	if ptr {
+		// The traversal of the struct may have been stopped by a limit
+		if len(node.Children) != 1 {
+			return nil
+		}
+		node = node.Children[0]
+	}
+
+	sizes := make(map[string]uint64)
+	for i, child := range node.Children {
+		if field := t.Field(i); field.IsExported() {
+			sizes[field.Name] = child.Retained
+		}
+	}
+	return sizes
+}
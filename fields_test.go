@@ -0,0 +1,65 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+type appServer struct {
+	Name    string
+	Buffer  []byte
+	Primary *blob
+	Replica *blob
+	Own     *blob
+	private []byte
+}
+
+func TestFieldSizes(t *testing.T) {
+	Debug = false
+
+	shared := &blob{Data: make([]byte, 1000)}
+	s := &appServer{
+		Name:    "srv",
+		Buffer:  make([]byte, 500),
+		Primary: shared,
+		Replica: shared,
+		Own:     &blob{Data: make([]byte, 200)},
+		private: make([]byte, 300),
+	}
+
+	sizes := FieldSizes(s, WithSizeModel(ExactSizes))
+	fmt.Printf("Field sizes: %v\n", sizes)
+
+	if len(sizes) != 5 {
+		t.Errorf("Expected the 5 exported fields, got %v", sizes)
+	}
+	if _, ok := sizes["private"]; ok {
+		t.Errorf("Expected unexported fields to be left out")
+	}
+	// The blob shared by Primary and Replica is freed by clearing neither of them alone
+	if sizes["Primary"] != 8 || sizes["Replica"] != 8 {
+		t.Errorf("Expected the shared blob to be attributed to no field, got %d and %d",
+			sizes["Primary"], sizes["Replica"])
+	}
+	if own := GetTotalSize(s.Own, WithSizeModel(ExactSizes)); sizes["Own"] != own {
+		t.Errorf("Expected Own to retain its size %d, got %d", own, sizes["Own"])
+	}
+	if sizes["Buffer"] < 500 {
+		t.Errorf("Expected Buffer to retain its backing array, got %d", sizes["Buffer"])
+	}
+
+	t.Run("Value", func(t *testing.T) {
+		if byValue := FieldSizes(*s, WithSizeModel(ExactSizes)); byValue["Own"] != sizes["Own"] {
+			t.Errorf("Expected the same sizes for a struct value, got %v", byValue)
+		}
+	})
+
+	t.Run("Not A Struct", func(t *testing.T) {
+		var nilServer *appServer
+		for _, v := range []interface{}{nil, 42, []int{1}, nilServer} {
+			if sizes := FieldSizes(v); sizes != nil {
+				t.Errorf("Expected nil for %T, got %v", v, sizes)
+			}
+		}
+	})
+}
@@ -0,0 +1,51 @@
+// folded.go
+package memsize
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteFolded writes the report in the folded stack format used by flamegraph.pl and
+// speedscope: one "root;field;subfield bytes" line per path, valued at its shallow size
+func (r *Report) WriteFolded(w io.Writer) error {
+	var order []string
+	sizes := make(map[string]uint64)
+
+	var fold func(n *Node, stack string)
+	fold = func(n *Node, stack string) {
+		if n.Shallow > 0 {
+			if _, ok := sizes[stack]; !ok {
+				order = append(order, stack)
+			}
+			sizes[stack] += n.Shallow
+		}
+		for _, child := range n.Children {
+			fold(child, stack+";"+foldedFrame(pathSegment(n.Path, child.Path)))
+		}
+	}
+	if r != nil && r.Root != nil {
+		fold(r.Root, foldedFrame(r.Root.Path))
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, stack := range order {
+		if _, err := fmt.Fprintf(bw, "%s %d\n", stack, sizes[stack]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// pathSegment returns the part of a child's path that is added to its parent's path
+func pathSegment(parent, child string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(child, parent), ".")
+}
+
+var foldedReplacer = strings.NewReplacer(";", "_", "\n", " ")
+
+func foldedFrame(segment string) string {
+	return foldedReplacer.Replace(segment)
+}
@@ -0,0 +1,53 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteFolded(t *testing.T) {
+	Debug = false
+
+	person := &Person{
+		Name:    "John Doe",
+		Friends: []*Person{{Name: "Jane"}},
+		Data:    map[string]interface{}{"a": 1, "b": 2},
+	}
+	report := GetReport(person)
+
+	var buf bytes.Buffer
+	if err := report.WriteFolded(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(buf.String())
+
+	var total uint64
+	stacks := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			t.Fatalf("Malformed line %q", line)
+		}
+		size, err := strconv.ParseUint(line[i+1:], 10, 64)
+		if err != nil {
+			t.Fatalf("Malformed size in line %q: %v", line, err)
+		}
+		if stacks[line[:i]] {
+			t.Errorf("Stack %q written more than once", line[:i])
+		}
+		stacks[line[:i]] = true
+		total += size
+	}
+
+	if total != report.Total() {
+		t.Errorf("Folded sizes add up to %d, expected %d", total, report.Total())
+	}
+	for _, want := range []string{"root;ptr;Name", "root;ptr;Friends;[0];ptr;Name", `root;ptr;Data;key["a"]`} {
+		if !stacks[want] {
+			t.Errorf("Expected stack %q", want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// gcreach.go
+package memsize
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ErrProfileDisabled is returned by CheckReachability when runtime.MemProfileRate is 0
+var ErrProfileDisabled = errors.New("memsize: heap profiling is disabled")
+
+// Reachability compares the reflection-based walk of a value with the heap objects the garbage
+// collector keeps alive for it, see CheckReachability
+type Reachability struct {
+	// Computed is the size found by the walk
+	Computed uint64
+	// Profiled is the size of the objects allocated while building the value and still alive
+	// after a collection, according to the heap profile
+	Profiled uint64
+	// Kinds compares both methods by kind of allocation: chan, map, slice, string and object.
+	// The heap profile records where objects were allocated but not their types, so sizes
+	// can't be compared per type.
+	Kinds []KindReachability
+	// Sites lists the allocation sites of the profiled objects, largest first
+	Sites []AllocSite
+}
+
+// KindReachability is the memory of one kind of allocation found by each method
+type KindReachability struct {
+	Kind             string
+	Walked, Profiled uint64
+}
+
+// AllocSite is a place where objects still alive were allocated
+type AllocSite struct {
+	Kind string
+	// Function is the innermost function outside the runtime on the allocating stack
+	Function string
+	Objects  uint64
+	Bytes    uint64
+}
+
+// Mismatches returns the kinds of allocation only one of the methods found, which point at
+// memory the walk skips, such as closures held in funcs, or at memory it counts though the
+// heap doesn't hold it, such as string constants in static data
+func (r Reachability) Mismatches() []KindReachability {
+	var kinds []KindReachability
+	for _, k := range r.Kinds {
+		if (k.Walked == 0) != (k.Profiled == 0) {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+// reachabilityKinds lists the kinds of allocation in the order of Reachability.Kinds
+var reachabilityKinds = []string{"chan", "map", "slice", "string", "object"}
+
+// CheckReachability cross-checks the walk against the garbage collector. It looks up the
+// allocations build made in the heap profile, pins the value across a collection, and compares
+// the objects still alive by kind with the memory the walk found, so memory the walk silently
+// skips shows up. Allocations build makes that stay alive without being referenced by the
+// value, e.g. through globals, count too.
+//
+// The heap profile samples allocations at runtime.MemProfileRate, which CheckReachability
+// leaves alone since it applies to the whole program. Sampled sizes are scaled up as pprof does,
+// so small values are only measured reliably by programs setting the rate to 1 at startup, as
+// tests can. This is a debugging aid: the forced collections pause the whole program.
+func CheckReachability(build func() interface{}, opts ...Option) (Reachability, error) {
+	rate := runtime.MemProfileRate
+	if rate == 0 {
+		return Reachability{}, ErrProfileDisabled
+	}
+	// Stacks of the objects build allocates pass through this function
+	pc, _, _, _ := runtime.Caller(0)
+	self := runtime.FuncForPC(pc).Name()
+
+	// The profile is published once the cycle after the allocations completed
+	runtime.GC()
+	runtime.GC()
+	before := inUseSites(self, rate)
+	v := build()
+	runtime.GC()
+	runtime.GC()
+	after := inUseSites(self, rate)
+
+	var r Reachability
+	walked := make(map[string]uint64)
+	err := Walk(v, func(path string, val reflect.Value, shallow uint64) WalkAction {
+		kind := "object"
+		switch val.Kind() {
+		case reflect.Chan, reflect.Map, reflect.Slice, reflect.String:
+			// Headers are part of the object holding them
+			kind = val.Kind().String()
+			if header := uint64(val.Type().Size()); path != "root" && shallow >= header {
+				walked["object"] += header
+				shallow -= header
+			}
+		}
+		walked[kind] += shallow
+		r.Computed += shallow
+		return WalkContinue
+	}, opts...)
+	runtime.KeepAlive(v)
+
+	profiled := make(map[string]uint64)
+	for key, site := range after {
+		old := before[key]
+		if site.Bytes <= old.Bytes {
+			continue
+		}
+		site.Objects -= old.Objects
+		site.Bytes -= old.Bytes
+		r.Sites = append(r.Sites, site)
+		profiled[site.Kind] += site.Bytes
+		r.Profiled += site.Bytes
+	}
+	sort.Slice(r.Sites, func(i, j int) bool {
+		if r.Sites[i].Bytes != r.Sites[j].Bytes {
+			return r.Sites[i].Bytes > r.Sites[j].Bytes
+		}
+		return r.Sites[i].Function < r.Sites[j].Function
+	})
+	for _, kind := range reachabilityKinds {
+		r.Kinds = append(r.Kinds, KindReachability{Kind: kind, Walked: walked[kind], Profiled: profiled[kind]})
+	}
+	return r, err
+}
+
+// inUseSites returns the objects alive per allocation stack passing through the function self,
+// scaled from the samples of a profile taken at rate
+func inUseSites(self string, rate int) map[[32]uintptr]AllocSite {
+	var records []runtime.MemProfileRecord
+	n, _ := runtime.MemProfile(nil, false)
+	for {
+		// Some room for records added in between
+		records = make([]runtime.MemProfileRecord, n+64)
+		var ok bool
+		if n, ok = runtime.MemProfile(records, false); ok {
+			break
+		}
+	}
+
+	sites := make(map[[32]uintptr]AllocSite)
+	for _, rec := range records[:n] {
+		if rec.InUseBytes() <= 0 {
+			continue
+		}
+		site, ok := allocSite(rec.Stack(), self)
+		if !ok {
+			continue
+		}
+		site.Objects, site.Bytes = scaleSample(rec.InUseObjects(), rec.InUseBytes(), rate)
+		sites[rec.Stack0] = site
+	}
+	return sites
+}
+
+// scaleSample estimates the objects and bytes sampled ones stand for at a profile rate, from
+// the probability of an object of their average size being sampled
+func scaleSample(objects, bytes int64, rate int) (uint64, uint64) {
+	if objects == 0 || rate <= 1 {
+		return uint64(objects), uint64(bytes)
+	}
+	avg := float64(bytes) / float64(objects)
+	scale := 1 / (1 - math.Exp(-avg/float64(rate)))
+	return uint64(float64(objects) * scale), uint64(float64(bytes) * scale)
+}
+
+// allocSite classifies an allocation stack, reporting false unless it passes through self
+func allocSite(stack []uintptr, self string) (AllocSite, bool) {
+	var site AllocSite
+	var runtimeFrames []string
+	built := false
+	frames := runtime.CallersFrames(stack)
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == self:
+			built = true
+		case site.Function == "" && isRuntimeFrame(f.Function):
+			runtimeFrames = append(runtimeFrames, f.Function)
+		case site.Function == "":
+			site.Function = f.Function
+		}
+		if !more {
+			break
+		}
+	}
+	site.Kind = allocKind(runtimeFrames, site.Function)
+	return site, built
+}
+
+func isRuntimeFrame(fn string) bool {
+	return strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "internal/") ||
+		strings.HasPrefix(fn, "reflect.")
+}
+
+// allocKind tells the kind of allocation from the runtime functions that made it
+func allocKind(runtimeFrames []string, caller string) string {
+	for _, kind := range []struct{ kind, marker string }{
+		{"map", "maps."}, {"map", "runtime.makemap"}, {"map", "runtime.mapassign"},
+		{"map", "runtime.hashGrow"}, {"chan", "makechan"},
+		{"string", "runtime.rawstring"}, {"string", "runtime.concatstring"},
+		{"string", "runtime.slicebytetostring"}, {"string", "runtime.intstring"},
+		{"slice", "runtime.makeslice"}, {"slice", "runtime.growslice"},
+		{"slice", "MakeNoZero"}, {"slice", "reflect.MakeSlice"},
+	} {
+		for _, fn := range runtimeFrames {
+			if strings.Contains(fn, kind.marker) {
+				// Builders grow byte slices that become their strings
+				if kind.kind == "slice" && strings.HasPrefix(caller, "strings.") {
+					return "string"
+				}
+				return kind.kind
+			}
+		}
+	}
+	return "object"
+}
@@ -0,0 +1,84 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckReachability(t *testing.T) {
+	Debug = false
+
+	// Profile every allocation, so the small values built below are all sampled
+	rate := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	defer func() { runtime.MemProfileRate = rate }()
+
+	type holder struct {
+		ch    chan [64]byte
+		m     map[int][]byte
+		s     []int
+		f     func() int
+		label string
+	}
+	build := func() interface{} {
+		n := 5
+		return &holder{
+			ch:    make(chan [64]byte, 100),
+			m:     map[int][]byte{1: make([]byte, 100)},
+			s:     make([]int, 1000),
+			f:     func() int { return n },
+			label: strings.Repeat("x", 500),
+		}
+	}
+
+	r, err := CheckReachability(build, WithSizeModel(ExactSizes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Reachability: walked %d, profiled %d\n", r.Computed, r.Profiled)
+	for _, k := range r.Kinds {
+		fmt.Printf("  %s: walked %d, profiled %d\n", k.Kind, k.Walked, k.Profiled)
+	}
+	for _, s := range r.Sites {
+		fmt.Printf("  %s %s: %d objects, %d bytes\n", s.Kind, s.Function, s.Objects, s.Bytes)
+	}
+
+	kinds := make(map[string]KindReachability)
+	for _, k := range r.Kinds {
+		kinds[k.Kind] = k
+	}
+	for _, kind := range []string{"chan", "map", "slice", "string"} {
+		k := kinds[kind]
+		if k.Walked == 0 || k.Profiled == 0 {
+			t.Errorf("Expected both methods to find the %s, got %+v", kind, k)
+		}
+	}
+	if chans := kinds["chan"]; chans.Profiled < 6400 {
+		t.Errorf("Expected the channel buffer to be profiled, got %d bytes", chans.Profiled)
+	}
+	if len(r.Sites) == 0 || r.Profiled == 0 {
+		t.Errorf("Expected allocation sites, got %+v", r)
+	}
+	for _, m := range r.Mismatches() {
+		fmt.Printf("Mismatch: %+v\n", m)
+	}
+
+	t.Run("Sampled", func(t *testing.T) {
+		if objects, bytes := scaleSample(2, 200, 1); objects != 2 || bytes != 200 {
+			t.Errorf("Expected samples to be exact at rate 1, got %d objects and %d bytes", objects, bytes)
+		}
+		// An object of 512 KiB is sampled with a probability of 1-1/e at the default rate
+		if objects, _ := scaleSample(100, 100*512*1024, 512*1024); objects < 150 || objects > 160 {
+			t.Errorf("Expected about 158 objects, got %d", objects)
+		}
+
+		runtime.MemProfileRate = 0
+		defer func() { runtime.MemProfileRate = 1 }()
+		if _, err := CheckReachability(build); !errors.Is(err, ErrProfileDisabled) {
+			t.Errorf("Expected ErrProfileDisabled, got %v", err)
+		}
+	})
+}
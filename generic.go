@@ -0,0 +1,24 @@
+// generic.go
+package memsize
+
+import "reflect"
+
+// SizeOf returns the total memory size of v including indirect allocations.
+// Unlike GetTotalSize, v is measured as its static type T, so for interface types
+// the interface header is included in the result.
+func SizeOf[T any](v T, opts ...Option) uint64 {
+	size, _ := newWalker(opts...).measure(valueOf(&v))
+	return size
+}
+
+// ReportOf returns a per-path breakdown of the size of v measured as its static type T
+func ReportOf[T any](v T, opts ...Option) *Report {
+	w := newWalker(opts...)
+	w.tree = true
+	return w.report(valueOf(&v))
+}
+
+// valueOf returns the value behind p without boxing it into an interface first
+func valueOf[T any](p *T) reflect.Value {
+	return reflect.ValueOf(p).Elem()
+}
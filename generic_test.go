@@ -0,0 +1,49 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+)
+
+func TestSizeOf(t *testing.T) {
+	Debug = false
+
+	t.Run("Matches GetTotalSize", func(t *testing.T) {
+		person := &Person{Name: "John Doe", Data: map[string]interface{}{"age": 30}}
+		if got, expected := SizeOf(person), GetTotalSize(person); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+		if got, expected := SizeOf([]string{"a", "b"}), GetTotalSize([]string{"a", "b"}); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+
+	t.Run("Static Interface Type", func(t *testing.T) {
+		var v interface{} = "hello"
+		size := SizeOf(v)
+		fmt.Printf("interface{} holding a string: %d bytes\n", size)
+		if expected := GetTotalSize(v) + uint64(unsafe.Sizeof(v)); size != expected {
+			t.Errorf("Expected the interface header to be included: %d, got %d", expected, size)
+		}
+	})
+
+	t.Run("Debug Option", func(t *testing.T) {
+		if size := SizeOf(42, WithDebug(true)); size != uint64(unsafe.Sizeof(0)) {
+			t.Errorf("Expected %d bytes for an int, got %d", unsafe.Sizeof(0), size)
+		}
+	})
+}
+
+func TestReportOf(t *testing.T) {
+	Debug = false
+
+	person := &Person{Name: "John Doe"}
+	report := ReportOf(person)
+	if report.Total() != GetTotalSize(person) {
+		t.Errorf("Expected total %d, got %d", GetTotalSize(person), report.Total())
+	}
+	if report.Root.Type != "*memsize.Person" {
+		t.Errorf("Expected root type *memsize.Person, got %s", report.Root.Type)
+	}
+}
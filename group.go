@@ -0,0 +1,80 @@
+// group.go
+package memsize
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TagName is the struct tag key read by the library. A field tagged `memsize:"group=index"`
+// puts its bytes, and those of everything reached through it, into the group "index" unless
+// a field below it names another group.
+const TagName = "memsize"
+
+// GetSizeByGroup returns the bytes of v per group named by struct tags, see TagName. Bytes
+// outside any group are not listed, so the sizes add up to at most the total size of v.
+func GetSizeByGroup(v interface{}, opts ...Option) map[string]uint64 {
+	w := newWalker(opts...)
+	w.groups = make(map[string]uint64)
+	w.measure(reflect.ValueOf(v))
+	return w.groups
+}
+
+// fieldGroups caches the groups of the fields of struct types, a nil slice for types
+// without any
+var fieldGroups sync.Map
+
+// groupOf returns the group of the frame's value: the group its field is tagged with, or the
+// group of its parent
+func (w *walker) groupOf(f *frame) string {
+	if len(w.stack) < 2 {
+		return ""
+	}
+	parent := &w.stack[len(w.stack)-2]
+	if f.step.kind == stepField {
+		if groups := structGroups(parent.v.Type()); groups != nil && groups[f.step.index] != "" {
+			return groups[f.step.index]
+		}
+	}
+	return parent.group
+}
+
+// recordGroup adds the shallow size of a frame to its group
+func (w *walker) recordGroup(f *frame) {
+	if w.groups == nil {
+		return
+	}
+	f.group = w.groupOf(f)
+	if f.group != "" {
+		w.groups[f.group] += f.shallow
+	}
+}
+
+// structGroups returns the groups of the fields of the struct type t, or nil if no field has one
+func structGroups(t reflect.Type) []string {
+	if groups, ok := fieldGroups.Load(t); ok {
+		return groups.([]string)
+	}
+	var groups []string
+	for i := 0; i < t.NumField(); i++ {
+		if g := tagGroup(t.Field(i).Tag.Get(TagName)); g != "" {
+			if groups == nil {
+				groups = make([]string, t.NumField())
+			}
+			groups[i] = g
+		}
+	}
+	fieldGroups.Store(t, groups)
+	return groups
+}
+
+// tagGroup returns the group named by a tag of comma-separated options
+func tagGroup(tag string) string {
+	for _, opt := range strings.Split(tag, ",") {
+		if opt = strings.TrimSpace(opt); strings.HasPrefix(opt, "group=") {
+			return opt[len("group="):]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	Debug = false
+
+	type metadata struct {
+		Labels map[string]string
+		// Notes are budgeted with the index although they sit in the metadata
+		Notes []byte `memsize:"group=index"`
+	}
+	type entry struct {
+		Key   [16]byte  `memsize:"group=index"`
+		Terms []uint32  `memsize:"group=index"`
+		Meta  *metadata `memsize:"group=metadata"`
+		Count int
+	}
+	entries := []entry{
+		{Terms: make([]uint32, 100), Meta: &metadata{Labels: map[string]string{"a": "b"}, Notes: make([]byte, 50)}},
+		{Terms: make([]uint32, 20), Meta: &metadata{}},
+	}
+
+	groups := GetSizeByGroup(entries)
+	fmt.Printf("Groups: %v\n", groups)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", groups)
+	}
+	// The index holds the tagged fields with everything below them
+	var index uint64
+	GetReport(entries).Walk(func(n *Node) bool {
+		if strings.HasSuffix(n.Path, ".Key") || strings.HasSuffix(n.Path, ".Terms") || strings.HasSuffix(n.Path, ".Notes") {
+			index += n.Size
+			return false
+		}
+		return true
+	})
+	if groups["index"] != index {
+		t.Errorf("Expected index bytes %d, got %d", index, groups["index"])
+	}
+	if groups["index"]+groups["metadata"] >= GetTotalSize(entries) {
+		t.Errorf("Expected the slice and counts outside any group, got %v of %d", groups, GetTotalSize(entries))
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		r := GetReport(entries)
+		for name, size := range groups {
+			if r.Groups[name] != size {
+				t.Errorf("Expected %s bytes %d, got %d", name, size, r.Groups[name])
+			}
+		}
+		if r := GetReport([]int{1, 2}); r.Groups != nil {
+			t.Errorf("Expected no groups, got %v", r.Groups)
+		}
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		for tag, expected := range map[string]string{
+			"group=index":          "index",
+			"omit, group=metadata": "metadata",
+			"":                     "",
+			"groups=other":         "",
+		} {
+			if got := tagGroup(tag); got != expected {
+				t.Errorf("Expected group %q for tag %q, got %q", expected, tag, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// handler.go
+package memsize
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// Root is a named value exposed by Handler
+type Root struct {
+	Name  string
+	Value interface{}
+}
+
+// Handler returns an http.Handler rendering the size breakdown of the given roots,
+// similar to /debug/pprof. It serves HTML by default and JSON when the request has
+// ?format=json or accepts application/json. A single root can be selected with ?root=name.
+// Without roots it renders the roots registered in DefaultRegistry at the time of the request.
+func Handler(roots ...Root) http.Handler {
+	if len(roots) == 0 {
+		return DefaultRegistry.Handler()
+	}
+	return rootsHandler(func() []Root { return roots })
+}
+
+// rootsHandler returns a Handler rendering the roots returned by roots for every request
+func rootsHandler(roots func() []Root) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selected := r.URL.Query().Get("root")
+
+		var results []rootReport
+		for _, root := range roots() {
+			if selected != "" && root.Name != selected {
+				continue
+			}
+			report := GetReport(root.Value)
+			sortBySize(report.Root)
+			results = append(results, rootReport{Name: root.Name, Total: report.Total(), Root: report.Root})
+		}
+		if selected != "" && len(results) == 0 {
+			http.Error(w, "unknown root "+selected, http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "json" || r.Header.Get("Accept") == "application/json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(results)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		handlerTemplate.Execute(w, results)
+	})
+}
+
+type rootReport struct {
+	Name  string `json:"name"`
+	Total uint64 `json:"total"`
+	Root  *Node  `json:"root"`
+}
+
+// sortBySize orders children so the largest ones are rendered first
+func sortBySize(n *Node) {
+	if n == nil {
+		return
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Size > n.Children[j].Size
+	})
+	for _, child := range n.Children {
+		sortBySize(child)
+	}
+}
+
+var handlerTemplate = template.Must(template.New("memsize").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>/debug/memsize</title>
+<style>
+body { font-family: monospace; }
+details { margin-left: 1.5em; }
+.leaf { margin-left: 2.7em; }
+.type { color: #888; }
+</style>
+</head>
+<body>
+<h1>/debug/memsize</h1>
+{{range .}}
+<h2>{{.Name}}: {{.Total}} bytes</h2>
+{{template "node" .Root}}
+{{end}}
+</body>
+</html>
+{{define "node"}}{{if .Children}}<details>
+<summary>{{.Path}} <span class="type">{{.Type}}</span> {{.Size}} bytes</summary>
+{{range .Children}}{{template "node" .}}{{end}}
+</details>
+{{else}}<div class="leaf">{{.Path}} <span class="type">{{.Type}}</span> {{.Size}} bytes</div>
+{{end}}{{end}}`))
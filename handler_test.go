@@ -0,0 +1,71 @@
+package memsize
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	Debug = false
+
+	sessions := map[string]string{"alice": "token"}
+	numbers := []string{"one", "two", "three"}
+
+	handler := Handler(
+		Root{Name: "sessions", Value: &sessions},
+		Root{Name: "numbers", Value: numbers},
+	)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	t.Run("HTML", func(t *testing.T) {
+		rec := get("/debug/memsize")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"sessions", "numbers", "<details>", "root.ptr"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected HTML to contain %q", want)
+			}
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := get("/debug/memsize?format=json")
+		var results []struct {
+			Name  string `json:"name"`
+			Total uint64 `json:"total"`
+			Root  *Node  `json:"root"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 roots, got %d", len(results))
+		}
+		if results[1].Total != GetTotalSize(numbers) {
+			t.Errorf("Expected total %d for numbers, got %d", GetTotalSize(numbers), results[1].Total)
+		}
+		if len(results[1].Root.Children) != 3 {
+			t.Errorf("Expected 3 children for numbers, got %d", len(results[1].Root.Children))
+		}
+	})
+
+	t.Run("Select Root", func(t *testing.T) {
+		body := get("/debug/memsize?root=numbers").Body.String()
+		if strings.Contains(body, "sessions") {
+			t.Error("Expected only the selected root to be rendered")
+		}
+		if rec := get("/debug/memsize?root=missing"); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for unknown root, got %d", rec.Code)
+		}
+	})
+}
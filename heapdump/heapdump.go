@@ -0,0 +1,329 @@
+// heapdump.go
+package heapdump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// header starts every dump written by runtime/debug.WriteHeapDump since Go 1.7
+const header = "go1.7 heap dump\n"
+
+// Record tags and field kinds of the dump format, as written by runtime/heapdump.go
+const (
+	tagEOF             = 0
+	tagObject          = 1
+	tagOtherRoot       = 2
+	tagType            = 3
+	tagGoroutine       = 4
+	tagStackFrame      = 5
+	tagParams          = 6
+	tagFinalizer       = 7
+	tagItab            = 8
+	tagOSThread        = 9
+	tagMemStats        = 10
+	tagQueuedFinalizer = 11
+	tagData            = 12
+	tagBSS             = 13
+	tagDefer           = 14
+	tagPanic           = 15
+	tagMemProf         = 16
+	tagAllocSample     = 17
+
+	fieldKindEol   = 0
+	fieldKindPtr   = 1
+	fieldKindIface = 2
+	fieldKindEface = 3
+)
+
+// ErrFormat is wrapped by the errors returned for input that isn't a valid heap dump
+var ErrFormat = errors.New("heapdump: invalid heap dump")
+
+// Dump is the object graph of a heap dump written by runtime/debug.WriteHeapDump. Heap dumps
+// don't record the types of objects, only their size and where they hold pointers, so objects
+// are described by the function allocating them when the memory profiler sampled them.
+type Dump struct {
+	// GOARCH and GoVersion describe the program that wrote the dump
+	GOARCH    string
+	GoVersion string
+	PtrSize   int
+	BigEndian bool
+	// HeapAlloc is runtime.MemStats.HeapAlloc at the time of the dump
+	HeapAlloc uint64
+
+	// Objects are the heap objects allocated at the time of the dump, by address
+	Objects []*Object
+	// Roots are the locations outside the heap holding pointers into it
+	Roots []*Root
+}
+
+// Object is an allocated heap object
+type Object struct {
+	Addr uint64
+	// Size is the size of the object's allocation, which is a whole size class
+	Size uint64
+	// Pointers are the non-nil pointers stored in the object, with their offsets
+	Pointers []Pointer
+	// Site is the innermost function outside the runtime that allocated the object, if the
+	// memory profiler sampled it
+	Site string
+}
+
+// Pointer is a pointer stored at an offset of an object or root
+type Pointer struct {
+	Offset uint64
+	Target uint64
+}
+
+// Root is a location outside the heap holding pointers into it
+type Root struct {
+	// Kind is "data", "bss", "goroutine", "finalizer" or "other"
+	Kind string
+	// Name tells the root apart from others of its kind, e.g. the goroutine ID and function
+	// of a stack frame
+	Name     string
+	Pointers []Pointer
+}
+
+// ReadFile reads the heap dump at path
+func ReadFile(path string) (*Dump, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read parses a heap dump written by runtime/debug.WriteHeapDump. Core files are not supported.
+func Read(r io.Reader) (*Dump, error) {
+	p := &parser{r: bufio.NewReader(r), d: &Dump{PtrSize: 8}, samples: make(map[uint64]uint64),
+		sites: make(map[uint64]string)}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.d, nil
+}
+
+// parser reads the records of a dump, remembering what later records refer to
+type parser struct {
+	r   *bufio.Reader
+	d   *Dump
+	err error
+
+	// samples maps sampled objects to their memory profile bucket, and sites the buckets to
+	// their allocating function
+	samples map[uint64]uint64
+	sites   map[uint64]string
+	// goroutine is the ID of the goroutine the following stack frames belong to
+	goroutine uint64
+}
+
+func (p *parser) parse() error {
+	hdr := make([]byte, len(header))
+	if _, err := io.ReadFull(p.r, hdr); err != nil || string(hdr) != header {
+		return fmt.Errorf("%w: missing %q header", ErrFormat, strings.TrimSpace(header))
+	}
+
+	for {
+		tag := p.uint()
+		if p.err != nil {
+			return p.err
+		}
+		switch tag {
+		case tagEOF:
+			p.finish()
+			return nil
+		case tagObject:
+			o := &Object{Addr: p.uint()}
+			contents := p.bytes()
+			o.Size = uint64(len(contents))
+			o.Pointers = p.fields(contents)
+			p.d.Objects = append(p.d.Objects, o)
+		case tagOtherRoot:
+			name := p.string()
+			if target := p.uint(); target != 0 {
+				p.root("other", name, Pointer{Target: target})
+			}
+		case tagType:
+			p.skip(2)
+			p.string()
+			p.uint()
+		case tagGoroutine:
+			p.skip(2)
+			p.goroutine = p.uint()
+			p.skip(5)
+			p.string()
+			p.skip(4)
+		case tagStackFrame:
+			p.skip(3)
+			contents := p.bytes()
+			p.skip(3)
+			name := p.string()
+			p.root("goroutine", fmt.Sprintf("%d %s", p.goroutine, name), p.fields(contents)...)
+		case tagParams:
+			p.d.BigEndian = p.uint() != 0
+			p.d.PtrSize = int(p.uint())
+			p.skip(2)
+			p.d.GOARCH = p.string()
+			p.d.GoVersion = p.string()
+			p.uint()
+		case tagFinalizer:
+			p.uint()
+			fn := p.uint()
+			p.skip(3)
+			p.root("finalizer", "func", Pointer{Target: fn})
+		case tagQueuedFinalizer:
+			obj, fn := p.uint(), p.uint()
+			p.skip(3)
+			p.root("finalizer", "queued", Pointer{Target: obj}, Pointer{Target: fn})
+		case tagItab:
+			p.skip(2)
+		case tagOSThread:
+			p.skip(3)
+		case tagMemStats:
+			p.skip(6)
+			p.d.HeapAlloc = p.uint()
+			p.skip(17 + 256 + 1)
+		case tagData, tagBSS:
+			p.uint()
+			contents := p.bytes()
+			kind := "data"
+			if tag == tagBSS {
+				kind = "bss"
+			}
+			p.root(kind, kind, p.fields(contents)...)
+		case tagDefer:
+			p.skip(7)
+		case tagPanic:
+			p.skip(6)
+		case tagMemProf:
+			bucket := p.uint()
+			p.uint()
+			frames := p.uint()
+			site := ""
+			for i := uint64(0); i < frames && p.err == nil; i++ {
+				fn := p.string()
+				p.string()
+				p.uint()
+				if site == "" && !runtimeFunc(fn) {
+					site = fn
+				}
+			}
+			p.skip(2)
+			p.sites[bucket] = site
+		case tagAllocSample:
+			addr := p.uint()
+			p.samples[addr] = p.uint()
+		default:
+			return fmt.Errorf("%w: unknown record tag %d", ErrFormat, tag)
+		}
+	}
+}
+
+// finish sorts the objects and names those the memory profiler sampled
+func (p *parser) finish() {
+	sort.Slice(p.d.Objects, func(i, j int) bool { return p.d.Objects[i].Addr < p.d.Objects[j].Addr })
+	for _, o := range p.d.Objects {
+		if bucket, ok := p.samples[o.Addr]; ok {
+			o.Site = p.sites[bucket]
+		}
+	}
+}
+
+func (p *parser) root(kind, name string, ptrs ...Pointer) {
+	if len(ptrs) > 0 {
+		p.d.Roots = append(p.d.Roots, &Root{Kind: kind, Name: name, Pointers: ptrs})
+	}
+}
+
+// fields reads a field list and returns the non-nil pointers it locates in contents
+func (p *parser) fields(contents []byte) []Pointer {
+	var ptrs []Pointer
+	for p.err == nil {
+		kind := p.uint()
+		if kind == fieldKindEol {
+			break
+		}
+		offset := p.uint()
+		switch kind {
+		case fieldKindIface, fieldKindEface:
+			// The data word follows the type word
+			offset += uint64(p.d.PtrSize)
+		case fieldKindPtr:
+		default:
+			p.fail("unknown field kind %d", kind)
+			return nil
+		}
+		if target := p.word(contents, offset); target != 0 {
+			ptrs = append(ptrs, Pointer{Offset: offset, Target: target})
+		}
+	}
+	return ptrs
+}
+
+// word reads the pointer-sized word at offset of contents
+func (p *parser) word(contents []byte, offset uint64) uint64 {
+	size := uint64(p.d.PtrSize)
+	if offset+size > uint64(len(contents)) {
+		return 0
+	}
+	b := contents[offset : offset+size]
+	var order binary.ByteOrder = binary.LittleEndian
+	if p.d.BigEndian {
+		order = binary.BigEndian
+	}
+	if size == 4 {
+		return uint64(order.Uint32(b))
+	}
+	return order.Uint64(b)
+}
+
+func (p *parser) uint() uint64 {
+	if p.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		p.fail("%v", err)
+	}
+	return v
+}
+
+func (p *parser) skip(n int) {
+	for i := 0; i < n; i++ {
+		p.uint()
+	}
+}
+
+func (p *parser) bytes() []byte {
+	n := p.uint()
+	if p.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		p.fail("%v", err)
+	}
+	return b
+}
+
+func (p *parser) string() string {
+	return string(p.bytes())
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%w: %s", ErrFormat, fmt.Sprintf(format, args...))
+	}
+}
+
+func runtimeFunc(fn string) bool {
+	return strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "internal/")
+}
@@ -0,0 +1,99 @@
+package heapdump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+type chain struct {
+	next    *chain
+	payload []byte
+}
+
+// retained is reachable from the data or bss segment when the dump is written
+var retained *chain
+
+func writeDump(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "heap.dump")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	debug.WriteHeapDump(f.Fd())
+	return path
+}
+
+func TestRead(t *testing.T) {
+	memsize.Debug = false
+
+	rate := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	retained = &chain{payload: make([]byte, 1<<20)}
+	retained.next = &chain{next: retained, payload: make([]byte, 1<<19)}
+	runtime.MemProfileRate = rate
+	path := writeDump(t)
+
+	d, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("Dump of %s %s: %d objects, %d roots\n", d.GoVersion, d.GOARCH, len(d.Objects), len(d.Roots))
+
+	t.Run("Params", func(t *testing.T) {
+		if d.GOARCH != runtime.GOARCH || d.GoVersion != runtime.Version() || d.HeapAlloc == 0 {
+			t.Errorf("Expected the parameters of this program, got %s %s and %d heap bytes", d.GOARCH, d.GoVersion, d.HeapAlloc)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		r := d.Report()
+		var payload *memsize.Node
+		r.Walk(func(n *memsize.Node) bool {
+			if payload == nil && n.Size == 1<<20 && !n.Shared {
+				payload = n
+			}
+			return true
+		})
+		if payload == nil {
+			t.Fatalf("Expected the 1 MiB payload in the report")
+		}
+		fmt.Printf("Payload at %s: %s\n", payload.Path, payload.Type)
+		if !strings.HasPrefix(payload.Path, "heap.data") && !strings.HasPrefix(payload.Path, "heap.bss") {
+			t.Errorf("Expected the payload below a data segment, got %s", payload.Path)
+		}
+		if !strings.Contains(payload.Type, "TestRead") {
+			t.Errorf("Expected the sampled payload to name its allocating function, got %q", payload.Type)
+		}
+		if r.Total() < 3<<19 || r.Total() > 4*d.HeapAlloc {
+			t.Errorf("Expected the heap in the total, got %d for a heap of %d", r.Total(), d.HeapAlloc)
+		}
+
+		var buf bytes.Buffer
+		if err := r.Prune(1<<18, 0, 0).WriteText(&buf, memsize.TextOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Print(buf.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := Read(strings.NewReader("not a dump")); !errors.Is(err, ErrFormat) {
+			t.Errorf("Expected ErrFormat, got %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if _, err := Read(bytes.NewReader(data[:len(data)/2])); !errors.Is(err, ErrFormat) {
+			t.Errorf("Expected ErrFormat for a truncated dump, got %v", err)
+		}
+	})
+	runtime.KeepAlive(retained)
+}
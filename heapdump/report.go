@@ -0,0 +1,139 @@
+// report.go
+package heapdump
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+// UnreachableSegment names the node holding the objects no root reaches, which are garbage
+// the collector had not freed yet when the dump was written
+const UnreachableSegment = "(unreachable)"
+
+// Report breaks the heap down by root in the form of a memsize report, so the usual output
+// formats and analyses such as ComputeRetained and Prune apply to dumps as well. Below the
+// "heap" root are the data and bss segments, goroutine stack frames, finalizers and other
+// roots, each holding the objects first reached through it depth-first. Paths locate objects
+// by the offset of the pointer in the object holding it, e.g. heap.data[+16][+8], and their
+// Type names the allocating function if the memory profiler sampled them.
+func (d *Dump) Report() *memsize.Report {
+	b := &reportBuilder{d: d, visited: make([]bool, len(d.Objects))}
+	top := &memsize.Node{Path: "heap", Type: "heap dump"}
+	b.nodes = append(b.nodes, top)
+	b.parents = append(b.parents, -1)
+
+	kinds := make(map[string]int)
+	for _, r := range d.Roots {
+		parent := 0
+		if r.Name == r.Kind {
+			parent = b.add(0, &memsize.Node{Path: "heap." + r.Kind, Type: r.Kind})
+		} else {
+			k, ok := kinds[r.Kind]
+			if !ok {
+				k = b.add(0, &memsize.Node{Path: "heap." + r.Kind, Type: r.Kind})
+				kinds[r.Kind] = k
+			}
+			parent = b.add(k, &memsize.Node{Path: b.nodes[k].Path + "[" + strconv.Quote(r.Name) + "]",
+				Type: r.Kind})
+		}
+		b.walk(parent, edges(r.Pointers))
+	}
+
+	unreachable := -1
+	for i, o := range d.Objects {
+		if b.visited[i] {
+			continue
+		}
+		if unreachable < 0 {
+			unreachable = b.add(0, &memsize.Node{Path: "heap." + UnreachableSegment, Type: "garbage"})
+		}
+		b.walk(unreachable, []edge{{segment: fmt.Sprintf("[%#x]", o.Addr), target: o.Addr}})
+	}
+
+	// Nodes are in preorder, so every node is summed before its parent
+	for i := len(b.nodes) - 1; i > 0; i-- {
+		b.nodes[b.parents[i]].Size += b.nodes[i].Size
+	}
+	return &memsize.Report{Root: top}
+}
+
+// reportBuilder collects the nodes of a report in preorder with the index of their parent
+type reportBuilder struct {
+	d       *Dump
+	visited []bool
+	nodes   []*memsize.Node
+	parents []int
+}
+
+func (b *reportBuilder) add(parent int, n *memsize.Node) int {
+	b.nodes[parent].Children = append(b.nodes[parent].Children, n)
+	b.nodes = append(b.nodes, n)
+	b.parents = append(b.parents, parent)
+	return len(b.nodes) - 1
+}
+
+// edge is a pointer to follow with the path segment leading to its target
+type edge struct {
+	segment string
+	target  uint64
+}
+
+// edges names pointers by their offset in the object or root holding them
+func edges(ptrs []Pointer) []edge {
+	e := make([]edge, len(ptrs))
+	for i, p := range ptrs {
+		e[i] = edge{segment: fmt.Sprintf("[+%d]", p.Offset), target: p.Target}
+	}
+	return e
+}
+
+// walk adds the objects reached through the edges below the node parent, depth-first
+func (b *reportBuilder) walk(parent int, out []edge) {
+	type pending struct {
+		parent int
+		edge
+	}
+	var stack []pending
+	for i := len(out) - 1; i >= 0; i-- {
+		stack = append(stack, pending{parent, out[i]})
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		i, ok := b.object(p.target)
+		if !ok {
+			continue
+		}
+		o := b.d.Objects[i]
+		n := &memsize.Node{Path: b.nodes[p.parent].Path + p.segment, Type: "object",
+			Addr: uintptr(o.Addr)}
+		if o.Site != "" {
+			n.Type = "object allocated by " + o.Site
+		}
+		if b.visited[i] {
+			n.Shared = true
+			b.add(p.parent, n)
+			continue
+		}
+		b.visited[i] = true
+		n.Size, n.Shallow, n.Alloc = o.Size, o.Size, o.Size
+		id := b.add(p.parent, n)
+		children := edges(o.Pointers)
+		for j := len(children) - 1; j >= 0; j-- {
+			stack = append(stack, pending{id, children[j]})
+		}
+	}
+}
+
+// object returns the index of the object containing addr
+func (b *reportBuilder) object(addr uint64) (int, bool) {
+	objects := b.d.Objects
+	i := sort.Search(len(objects), func(i int) bool { return objects[i].Addr > addr }) - 1
+	if i < 0 || addr >= objects[i].Addr+objects[i].Size {
+		return 0, false
+	}
+	return i, true
+}
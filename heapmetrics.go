@@ -0,0 +1,124 @@
+// heapmetrics.go
+package memsize
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"runtime/metrics"
+)
+
+// HeapMetrics is a snapshot of the Go heap read from runtime/metrics
+type HeapMetrics struct {
+	// Objects and Bytes count the objects on the heap, including unswept dead ones
+	Objects uint64
+	Bytes   uint64
+	// Live is the heap marked live by the last collection, zero before the first one or Go 1.21
+	Live uint64
+	// SizeClasses lists the objects on the heap by size class, in increasing size
+	SizeClasses []SizeClassUsage
+}
+
+// SizeClassUsage counts the heap objects of one size class
+type SizeClassUsage struct {
+	// Size is the size of the class, zero for objects larger than every class
+	Size    uint64
+	Objects uint64
+}
+
+// HeapShare puts a measurement in the context of the whole heap
+type HeapShare struct {
+	// Size is the measured size of the value
+	Size uint64
+	// Before and After are the heap before and after the measurement; their difference is
+	// mostly the memory taken by the traversal itself
+	Before, After HeapMetrics
+	// Share is Size as a fraction of the heap before the measurement
+	Share float64
+}
+
+// String summarizes the share, e.g. "12.30 MiB, 4.5% of 273.00 MiB heap"
+func (s HeapShare) String() string {
+	return fmt.Sprintf("%s, %.1f%% of %s heap", Format(s.Size), s.Share*100, Format(s.Before.heap()))
+}
+
+// heap is the best estimate of the live heap available
+func (m HeapMetrics) heap() uint64 {
+	if m.Live > 0 {
+		return m.Live
+	}
+	return m.Bytes
+}
+
+const (
+	metricHeapObjects  = "/gc/heap/objects:objects"
+	metricHeapBytes    = "/memory/classes/heap/objects:bytes"
+	metricHeapLive     = "/gc/heap/live:bytes"
+	metricAllocsBySize = "/gc/heap/allocs-by-size:bytes"
+	metricFreesBySize  = "/gc/heap/frees-by-size:bytes"
+)
+
+// ReadHeapMetrics reads the current state of the heap from runtime/metrics. Metrics the
+// running Go version doesn't support are left zero.
+func ReadHeapMetrics() HeapMetrics {
+	samples := []metrics.Sample{
+		{Name: metricHeapObjects}, {Name: metricHeapBytes}, {Name: metricHeapLive},
+		{Name: metricAllocsBySize}, {Name: metricFreesBySize},
+	}
+	metrics.Read(samples)
+
+	var m HeapMetrics
+	var allocs, frees *metrics.Float64Histogram
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			switch s.Name {
+			case metricHeapObjects:
+				m.Objects = s.Value.Uint64()
+			case metricHeapBytes:
+				m.Bytes = s.Value.Uint64()
+			case metricHeapLive:
+				m.Live = s.Value.Uint64()
+			}
+		case metrics.KindFloat64Histogram:
+			if s.Name == metricAllocsBySize {
+				allocs = s.Value.Float64Histogram()
+			} else {
+				frees = s.Value.Float64Histogram()
+			}
+		}
+	}
+	if allocs == nil || frees == nil || len(allocs.Counts) != len(frees.Counts) {
+		return m
+	}
+
+	// Bucket i holds the objects of sizes from Buckets[i] up to but excluding Buckets[i+1],
+	// whose boundaries lie one byte above the size classes
+	for i, n := range allocs.Counts {
+		if n <= frees.Counts[i] {
+			continue
+		}
+		var size uint64
+		if upper := allocs.Buckets[i+1]; !math.IsInf(upper, 1) {
+			size = uint64(upper) - 1
+		}
+		m.SizeClasses = append(m.SizeClasses, SizeClassUsage{Size: size, Objects: n - frees.Counts[i]})
+	}
+	return m
+}
+
+// MeasureHeapShare measures v and relates its size to the heap read from runtime/metrics,
+// answering which share of the heap the value accounts for. The heap is collected first, so
+// it holds live objects only, which pauses the whole program.
+func MeasureHeapShare(v interface{}, opts ...Option) (HeapShare, error) {
+	runtime.GC()
+	s := HeapShare{Before: ReadHeapMetrics()}
+	var err error
+	s.Size, err = newWalker(opts...).measure(reflect.ValueOf(v))
+	s.After = ReadHeapMetrics()
+	if heap := s.Before.heap(); heap > 0 {
+		s.Share = float64(s.Size) / float64(heap)
+	}
+	return s, err
+}
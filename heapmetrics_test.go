@@ -0,0 +1,49 @@
+package memsize
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHeapShare(t *testing.T) {
+	Debug = false
+
+	data := make([][]byte, 1000)
+	for i := range data {
+		data[i] = make([]byte, 1000)
+	}
+
+	t.Run("Metrics", func(t *testing.T) {
+		m := ReadHeapMetrics()
+		fmt.Printf("Heap: %d objects, %d bytes, %d live, %d size classes\n", m.Objects, m.Bytes, m.Live, len(m.SizeClasses))
+		if m.Objects == 0 || m.Bytes == 0 || len(m.SizeClasses) == 0 {
+			t.Errorf("Expected heap metrics, got %+v", m)
+		}
+		var class *SizeClassUsage
+		for i := range m.SizeClasses {
+			if c := &m.SizeClasses[i]; c.Size >= 1000 && class == nil {
+				class = c
+			}
+		}
+		if class == nil || class.Objects < uint64(len(data)) {
+			t.Errorf("Expected %d objects of the 1000 byte class, got %+v", len(data), class)
+		}
+	})
+
+	t.Run("Share", func(t *testing.T) {
+		s, err := MeasureHeapShare(data, WithSizeModel(ExactSizes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println(s)
+		if s.Size != GetTotalSize(data, WithSizeModel(ExactSizes)) || s.Share <= 0 || s.Share > 1 {
+			t.Errorf("Expected a share of the heap, got %d bytes and %f", s.Size, s.Share)
+		}
+		if !strings.Contains(s.String(), "% of ") {
+			t.Errorf("Expected a summary with the share, got %q", s)
+		}
+	})
+	runtime.KeepAlive(data)
+}
@@ -0,0 +1,102 @@
+// histogram.go
+package memsize
+
+import (
+	"math"
+	"reflect"
+	"sort"
+)
+
+// DefaultHistogramBuckets are the bucket bounds used by SizeHistogram when none are given:
+// powers of two from 8 bytes to 1 MiB
+var DefaultHistogramBuckets = func() []uint64 {
+	var buckets []uint64
+	for size := uint64(8); size <= 1<<20; size *= 2 {
+		buckets = append(buckets, size)
+	}
+	return buckets
+}()
+
+// HistogramBucket counts the allocations of a report up to a size
+type HistogramBucket struct {
+	// Max is the inclusive upper bound of the bucket, math.MaxUint64 for the last one
+	Max uint64
+	// Count is the number of allocations larger than the previous bound and at most Max
+	Count uint64
+	// Bytes is the total size of those allocations
+	Bytes uint64
+}
+
+// SizeHistogram counts the allocations of the report, see Node.Alloc, by size. Buckets are
+// inclusive upper bounds and are sorted; a last bucket counts larger allocations. Without
+// buckets, DefaultHistogramBuckets are used.
+func (r *Report) SizeHistogram(buckets []uint64) []HistogramBucket {
+	if len(buckets) == 0 {
+		buckets = DefaultHistogramBuckets
+	}
+	bounds := append([]uint64(nil), buckets...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	hist := make([]HistogramBucket, len(bounds)+1)
+	for i, max := range bounds {
+		hist[i].Max = max
+	}
+	hist[len(bounds)].Max = math.MaxUint64
+
+	r.Walk(func(n *Node) bool {
+		if n.Alloc == 0 {
+			return true
+		}
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= n.Alloc })
+		hist[i].Count++
+		hist[i].Bytes += n.Alloc
+		return true
+	})
+	return hist
+}
+
+// alloc returns the size of the heap allocation a frame's value refers to and accounts for:
+// the target of a pointer or interface, the backing array of a slice, the bytes of a string,
+// the buckets of a map or the buffer of a channel. Allocations are rounded up to their size
+// class with WithSizeClasses.
+func (w *walker) alloc(f *frame) uint64 {
+	if f.arena != nil {
+		// The first reference accounts for the whole arena
+		if f.shared {
+			return 0
+		}
+		return uint64(f.arena.end - f.arena.start)
+	}
+	v := f.v
+	l := w.cfg.layout()
+	var size uint64
+	switch v.Kind() {
+	case reflect.Ptr:
+		if f.follow {
+			size = l.sizeof(v.Type().Elem())
+		}
+	case reflect.Interface:
+		if f.follow && !directIface(v.Elem().Type()) {
+			size = l.sizeof(v.Elem().Type())
+		}
+	case reflect.Slice:
+		if !v.IsNil() && f.mapped == 0 {
+			size = uint64(v.Cap()) * l.sizeof(v.Type().Elem())
+		}
+	case reflect.String:
+		size = uint64(v.Len())
+	case reflect.Map:
+		if !v.IsNil() && (w.cfg.model == LegacySizes || f.follow) {
+			_, buckets, inline := mapSizes(v, w.cfg)
+			size = buckets + inline
+		}
+	case reflect.Chan:
+		if f.follow {
+			size = l.hchanSize() + uint64(v.Cap())*l.sizeof(v.Type().Elem())
+		}
+	}
+	if w.cfg.sizeClasses {
+		size = l.overhead().roundAlloc(size)
+	}
+	return size
+}
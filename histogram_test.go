@@ -0,0 +1,62 @@
+package memsize
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	Debug = false
+
+	type small struct {
+		A, B, C, D, E int64
+	}
+	v := make([]*small, 100)
+	for i := range v {
+		v[i] = &small{}
+	}
+	v[1] = v[0] // shared pointers are a single allocation
+
+	report := GetReport(v, WithSizeModel(ExactSizes))
+	hist := report.SizeHistogram([]uint64{1024, 32})
+	fmt.Printf("Histogram: %+v\n", hist)
+
+	if len(hist) != 3 {
+		t.Fatalf("Expected 3 buckets, got %d", len(hist))
+	}
+	if hist[0].Max != 32 || hist[1].Max != 1024 || hist[2].Max != math.MaxUint64 {
+		t.Errorf("Expected sorted bounds and an overflow bucket, got %+v", hist)
+	}
+	// 99 structs of 40 bytes and the backing array of 800 bytes
+	if hist[1].Count != 100 || hist[1].Bytes != 99*40+800 {
+		t.Errorf("Expected 100 allocations of %d bytes up to 1024, got %+v", 99*40+800, hist[1])
+	}
+	if hist[0].Count != 0 || hist[2].Count != 0 {
+		t.Errorf("Expected no other allocations, got %+v", hist)
+	}
+
+	t.Run("Size Classes", func(t *testing.T) {
+		report := GetReport(v, WithSizeClasses())
+		hist := report.SizeHistogram([]uint64{40, 48})
+		if hist[0].Count != 0 || hist[1].Count != 99 {
+			t.Errorf("Expected the structs rounded up to 48 bytes, got %+v", hist)
+		}
+	})
+
+	t.Run("Default Buckets", func(t *testing.T) {
+		report := GetReport(map[string]string{"key": "value"})
+		hist := report.SizeHistogram(nil)
+		if len(hist) != len(DefaultHistogramBuckets)+1 {
+			t.Errorf("Expected %d buckets, got %d", len(DefaultHistogramBuckets)+1, len(hist))
+		}
+		var count uint64
+		for _, b := range hist {
+			count += b.Count
+		}
+		// The map and the bytes of its key and value
+		if count != 3 {
+			t.Errorf("Expected 3 allocations, got %d", count)
+		}
+	})
+}
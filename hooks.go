@@ -0,0 +1,275 @@
+// hooks.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// HandlerFunc sizes values of a type registered with RegisterHandler, for types whose memory
+// reflection can't see or would miscount. It returns the size of v, including memory v owns
+// that isn't traversed, and the values v references that are traversed as usual.
+type HandlerFunc func(c *HandlerContext, v reflect.Value) (uint64, []Child)
+
+// Child is a value referenced by a handled value
+type Child struct {
+	Value reflect.Value
+	// Path is appended to the path of the handled value, e.g. ".buf" or "[0]"
+	Path string
+}
+
+// HandlerContext gives a HandlerFunc access to the measurement sizing the value
+type HandlerContext struct {
+	w    *walker
+	t    reflect.Type
+	path string
+}
+
+// Path returns the path of the handled value, as in Node.Path. Paths are only built for
+// measurements needing them, such as reports; it is empty otherwise.
+func (c *HandlerContext) Path() string {
+	return c.path
+}
+
+// Model returns the size model of the measurement
+func (c *HandlerContext) Model() SizeModel {
+	return c.w.cfg.model
+}
+
+// InlineSize returns the size of a value of type t without the memory it references, as the
+// measurement counts it
+func (c *HandlerContext) InlineSize(t reflect.Type) uint64 {
+	return inlineSize(t, c.w.cfg.layout())
+}
+
+// Size returns the total size of v with the options of the measurement. Memory already counted
+// is skipped and memory counted here is skipped by the rest of the measurement, but the
+// values are not part of reports; return them as children instead to break them down. The
+// handled value itself can be passed to size it by its kind.
+func (c *HandlerContext) Size(v reflect.Value) uint64 {
+	return c.measure(v, c.w.cfg.model)
+}
+
+// ExactSize returns the total size of v like Size but with ExactSizes whatever the model of the
+// measurement, for private state whose layout is known exactly
+func (c *HandlerContext) ExactSize(v reflect.Value) uint64 {
+	return c.measure(v, ExactSizes)
+}
+
+func (c *HandlerContext) measure(v reflect.Value, model SizeModel) uint64 {
+	// Values sized by the handler are logged and reported as part of the handled value
+	cfg := *c.w.cfg
+	cfg.model = model
+	cfg.debug = false
+	cfg.debugRecord = nil
+	cfg.events = nil
+	inner := c.w.child(&cfg)
+	inner.unhandled = c.t
+	return inner.getTotalSize(v, "")
+}
+
+// Handlers is a set of handlers for types, interfaces and kinds. DefaultHandlers applies to
+// every measurement; a set given with WithHandlers applies to the measurements using it and
+// takes precedence, so libraries can ship their handlers without conflicting with those of
+// the application. Sets take precedence over the built-in handlers of the package.
+//
+// Within a set a handler for the type of a value comes first, then handlers for interfaces
+// the type implements in the order they were registered, then the handler for its kind.
+// Handlers can be registered and unregistered at any time; measurements in progress keep
+// using the handlers they started with.
+type Handlers struct {
+	mu sync.Mutex
+	// table holds the current *handlerTable, replaced on every change
+	table atomic.Value
+}
+
+// handlerTable is an immutable snapshot of the handlers of a set
+type handlerTable struct {
+	types  map[reflect.Type]HandlerFunc
+	ifaces []interfaceHandler
+	kinds  map[reflect.Kind]HandlerFunc
+	// matched caches the typeHandler found per reflect.Type, nil included
+	matched sync.Map
+}
+
+// handlerTables are the tables of the sets of handlers applying to a measurement
+type handlerTables struct {
+	set      *handlerTable
+	defaults *handlerTable
+}
+
+type interfaceHandler struct {
+	iface reflect.Type
+	fn    HandlerFunc
+}
+
+// NewHandlers creates an empty set of handlers
+func NewHandlers() *Handlers {
+	h := &Handlers{}
+	h.table.Store(&handlerTable{})
+	return h
+}
+
+// DefaultHandlers holds the handlers registered with RegisterHandler
+var DefaultHandlers = NewHandlers()
+
+// RegisterHandler sizes values of type t with fn instead of traversing them by their kind, in
+// every measurement. It lets packages supporting popular types, such as those under
+// stdlibhooks, make sizes accurate with an import.
+func RegisterHandler(t reflect.Type, fn HandlerFunc) {
+	DefaultHandlers.Register(t, fn)
+}
+
+// UnregisterHandler removes the handler registered for t with RegisterHandler
+func UnregisterHandler(t reflect.Type) {
+	DefaultHandlers.Unregister(t)
+}
+
+// Register sizes values of type t with fn, replacing the handler registered for t before
+func (h *Handlers) Register(t reflect.Type, fn HandlerFunc) {
+	h.update(func(table *handlerTable) {
+		if table.types == nil {
+			table.types = make(map[reflect.Type]HandlerFunc)
+		}
+		table.types[t] = fn
+	})
+}
+
+// Unregister removes the handler registered for t
+func (h *Handlers) Unregister(t reflect.Type) {
+	h.update(func(table *handlerTable) {
+		delete(table.types, t)
+	})
+}
+
+// RegisterInterface sizes values whose type implements the interface type iface with fn,
+// replacing the handler registered for iface before. Values of interface types are not
+// handled, the values they hold are. Handlers for interfaces registered earlier come first.
+func (h *Handlers) RegisterInterface(iface reflect.Type, fn HandlerFunc) {
+	if iface.Kind() != reflect.Interface {
+		panic("memsize: RegisterInterface of non-interface type " + iface.String())
+	}
+	h.update(func(table *handlerTable) {
+		for i := range table.ifaces {
+			if table.ifaces[i].iface == iface {
+				table.ifaces[i].fn = fn
+				return
+			}
+		}
+		table.ifaces = append(table.ifaces, interfaceHandler{iface: iface, fn: fn})
+	})
+}
+
+// UnregisterInterface removes the handler registered for iface
+func (h *Handlers) UnregisterInterface(iface reflect.Type) {
+	h.update(func(table *handlerTable) {
+		for i := range table.ifaces {
+			if table.ifaces[i].iface == iface {
+				table.ifaces = append(table.ifaces[:i], table.ifaces[i+1:]...)
+				return
+			}
+		}
+	})
+}
+
+// RegisterKind sizes values of kind k with fn, replacing the handler registered for k before
+func (h *Handlers) RegisterKind(k reflect.Kind, fn HandlerFunc) {
+	h.update(func(table *handlerTable) {
+		if table.kinds == nil {
+			table.kinds = make(map[reflect.Kind]HandlerFunc)
+		}
+		table.kinds[k] = fn
+	})
+}
+
+// UnregisterKind removes the handler registered for k
+func (h *Handlers) UnregisterKind(k reflect.Kind) {
+	h.update(func(table *handlerTable) {
+		delete(table.kinds, k)
+	})
+}
+
+// update replaces the table of the set with a copy changed by fn
+func (h *Handlers) update(fn func(table *handlerTable)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.table.Load().(*handlerTable)
+	table := &handlerTable{
+		types:  make(map[reflect.Type]HandlerFunc, len(old.types)),
+		ifaces: append([]interfaceHandler(nil), old.ifaces...),
+		kinds:  make(map[reflect.Kind]HandlerFunc, len(old.kinds)),
+	}
+	for t, fn := range old.types {
+		table.types[t] = fn
+	}
+	for k, fn := range old.kinds {
+		table.kinds[k] = fn
+	}
+	fn(table)
+	h.table.Store(table)
+	// Plans computed before may have folded types as constants
+	resetPlans()
+}
+
+// snapshot returns the current table of the set, nil if the set is nil or empty
+func (h *Handlers) snapshot() *handlerTable {
+	if h == nil {
+		return nil
+	}
+	table := h.table.Load().(*handlerTable)
+	if len(table.types) == 0 && len(table.ifaces) == 0 && len(table.kinds) == 0 {
+		return nil
+	}
+	return table
+}
+
+// lookup returns the handler the table has for t, or nil
+func (table *handlerTable) lookup(t reflect.Type) typeHandler {
+	if table == nil {
+		return nil
+	}
+	if th, ok := table.matched.Load(t); ok {
+		return th.(typeHandler)
+	}
+	var th typeHandler
+	if fn := table.find(t); fn != nil {
+		th = fn.handler()
+	}
+	table.matched.Store(t, th)
+	return th
+}
+
+func (table *handlerTable) find(t reflect.Type) HandlerFunc {
+	if fn := table.types[t]; fn != nil {
+		return fn
+	}
+	if t.Kind() != reflect.Interface {
+		for _, ih := range table.ifaces {
+			if t.Implements(ih.iface) {
+				return ih.fn
+			}
+		}
+	}
+	return table.kinds[t.Kind()]
+}
+
+// handler adapts fn to the handlers of the walker
+func (fn HandlerFunc) handler() typeHandler {
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		size, children := fn(&HandlerContext{w: w, t: v.Type(), path: path}, v)
+		handled := make([]handledChild, len(children))
+		for i, c := range children {
+			handled[i] = handledChild{v: c.Value, suffix: c.Path}
+		}
+		return size, handled
+	}
+}
+
+// WithHandlers sizes values with the handlers of h before those of DefaultHandlers, for this
+// measurement only
+func WithHandlers(h *Handlers) Option {
+	return func(c *config) {
+		c.handlers = h
+	}
+}
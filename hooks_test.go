@@ -0,0 +1,174 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// hooked owns hidden bytes besides its fields
+type hooked struct {
+	hidden int
+	data   []byte
+	name   string
+}
+
+func TestRegisterHandler(t *testing.T) {
+	Debug = false
+
+	defer UnregisterHandler(reflect.TypeOf(hooked{}))
+	RegisterHandler(reflect.TypeOf(hooked{}), func(c *HandlerContext, v reflect.Value) (uint64, []Child) {
+		data := v.FieldByName("data")
+		size := c.InlineSize(v.Type()) - c.InlineSize(data.Type()) + uint64(v.FieldByName("hidden").Int())
+		return size, []Child{{Value: data, Path: ".data"}}
+	})
+
+	h := &hooked{hidden: 1000, data: make([]byte, 100), name: "ignored"}
+	opts := []Option{WithSizeModel(ExactSizes)}
+	inline := uint64(reflect.TypeOf(hooked{}).Size())
+
+	t.Run("Size", func(t *testing.T) {
+		expected := 8 + inline + 1000 + 100
+		if got := GetTotalSize(h, opts...); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		report := GetReport(h, opts...)
+		fmt.Printf("Report:\n%s", report)
+		value := report.Root.Children[0]
+		if len(value.Children) != 1 || value.Children[0].Path != "root.ptr.data" || value.Children[0].Size != 124 {
+			t.Errorf("Expected the data child only, got %+v", value.Children)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		var path string
+		var own, exact uint64
+		RegisterHandler(reflect.TypeOf(hooked{}), func(c *HandlerContext, v reflect.Value) (uint64, []Child) {
+			path = c.Path()
+			own = c.Size(v)
+			exact = c.ExactSize(v)
+			return own, nil
+		})
+		GetReport(h)
+		if path != "root.ptr" {
+			t.Errorf("Expected path root.ptr, got %s", path)
+		}
+		// The handled value is sized by its kind
+		if expected := inline + 100 + 7; exact != expected {
+			t.Errorf("Expected %d, got %d", expected, exact)
+		}
+		if own <= exact {
+			t.Errorf("Expected the legacy size to be larger than %d, got %d", exact, own)
+		}
+	})
+}
+
+type stringerPoint struct{ x, y int }
+
+func (p stringerPoint) String() string { return fmt.Sprint(p.x, p.y) }
+
+type plainPoint struct{ x, y int }
+
+type typedPoint struct{ x, y int }
+
+func (p typedPoint) String() string { return fmt.Sprint(p.x, p.y) }
+
+func TestHandlers(t *testing.T) {
+	Debug = false
+
+	constant := func(size uint64) HandlerFunc {
+		return func(c *HandlerContext, v reflect.Value) (uint64, []Child) {
+			return size, nil
+		}
+	}
+	stringer := reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	opts := []Option{WithSizeModel(ExactSizes)}
+
+	t.Run("Precedence", func(t *testing.T) {
+		h := NewHandlers()
+		h.RegisterKind(reflect.Struct, constant(1))
+		h.RegisterInterface(stringer, constant(2))
+		h.Register(reflect.TypeOf(typedPoint{}), constant(3))
+		opts := append(opts, WithHandlers(h))
+
+		for _, c := range []struct {
+			v        interface{}
+			expected uint64
+		}{
+			{plainPoint{}, 1},
+			{stringerPoint{}, 2},
+			{typedPoint{}, 3},
+			// Handlers apply to the values held by interfaces, not to the interfaces
+			{[]fmt.Stringer{stringerPoint{}}, 24 + 16 + 2},
+		} {
+			if got := GetTotalSize(c.v, opts...); got != c.expected {
+				t.Errorf("Expected %d for %T, got %d", c.expected, c.v, got)
+			}
+		}
+	})
+
+	t.Run("Scoped", func(t *testing.T) {
+		typ := reflect.TypeOf(plainPoint{})
+		defer UnregisterHandler(typ)
+		RegisterHandler(typ, constant(100))
+		h := NewHandlers()
+		h.Register(typ, constant(200))
+
+		if got := GetTotalSize(plainPoint{}, opts...); got != 100 {
+			t.Errorf("Expected the default handler's size 100, got %d", got)
+		}
+		if got := GetTotalSize(plainPoint{}, append(opts, WithHandlers(h))...); got != 200 {
+			t.Errorf("Expected the scoped handler's size 200, got %d", got)
+		}
+		// Sets of other measurements don't leak through cached plans
+		if got := GetTotalSize([]plainPoint{{}}, opts...); got != 24+100 {
+			t.Errorf("Expected %d, got %d", 24+100, got)
+		}
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		h := NewHandlers()
+		h.RegisterKind(reflect.Struct, constant(1))
+		h.RegisterInterface(stringer, constant(2))
+		opts := append(opts, WithHandlers(h))
+
+		h.UnregisterInterface(stringer)
+		if got := GetTotalSize(stringerPoint{}, opts...); got != 1 {
+			t.Errorf("Expected the kind handler's size 1, got %d", got)
+		}
+		h.UnregisterKind(reflect.Struct)
+		if got := GetTotalSize(stringerPoint{}, opts...); got != 16 {
+			t.Errorf("Expected the size by kind 16, got %d", got)
+		}
+	})
+
+	t.Run("In Progress", func(t *testing.T) {
+		child := reflect.ValueOf(typedPoint{})
+		byKind := GetTotalSize(typedPoint{}, opts...)
+		h := NewHandlers()
+		h.Register(reflect.TypeOf(plainPoint{}), func(c *HandlerContext, v reflect.Value) (uint64, []Child) {
+			h.Register(child.Type(), constant(50))
+			return 1, []Child{{Value: child, Path: ".child"}}
+		})
+		opts := append(opts, WithHandlers(h))
+
+		if got := GetTotalSize(plainPoint{}, opts...); got != 1+byKind {
+			t.Errorf("Expected a handler registered during the measurement to be ignored, got %d", got)
+		}
+		if got := GetTotalSize(plainPoint{}, opts...); got != 1+50 {
+			t.Errorf("Expected the handler to apply to the next measurement, got %d", got)
+		}
+	})
+
+	t.Run("Builtin", func(t *testing.T) {
+		h := NewHandlers()
+		h.Register(reflect.TypeOf(sync.Mutex{}), constant(1000))
+		if got := GetTotalSize([]sync.Mutex{{}, {}}, append(opts, WithHandlers(h))...); got != 24+2000 {
+			t.Errorf("Expected the registered handler to replace the built-in one, got %d", got)
+		}
+	})
+}
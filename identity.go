@@ -0,0 +1,62 @@
+// identity.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+)
+
+// IdentityFunc returns the logical identity of the object a pointer, map or channel refers to,
+// or false if it has none. Objects of the same type with the same identity are counted once,
+// as if they were stored at the same address.
+type IdentityFunc func(v reflect.Value) (id uint64, ok bool)
+
+// WithIdentityFunc deduplicates objects by the identity fn returns for the pointers, maps and
+// channels referring to them, in addition to their address. It answers how much interning
+// logically identical objects, such as decoded copies of the same configuration, would save:
+// every copy but the first is counted like a pointer to an object already seen.
+func WithIdentityFunc(fn IdentityFunc) Option {
+	return func(c *config) {
+		c.identity = fn
+	}
+}
+
+type identityKey struct {
+	id  uint64
+	typ reflect.Type
+}
+
+// identitySet keeps track of the identities already counted
+type identitySet struct {
+	fn IdentityFunc
+
+	mu  sync.Mutex
+	ids map[identityKey]bool
+}
+
+func newIdentitySet(cfg *config) *identitySet {
+	if cfg.identity == nil {
+		return nil
+	}
+	return &identitySet{fn: cfg.identity, ids: make(map[identityKey]bool)}
+}
+
+// visit marks the identity of the object v refers to as counted and reports whether it already
+// was
+func (s *identitySet) visit(v reflect.Value) bool {
+	if s == nil {
+		return false
+	}
+	id, ok := s.fn(v)
+	if !ok {
+		return false
+	}
+	k := identityKey{id: id, typ: v.Type()}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids[k] {
+		return true
+	}
+	s.ids[k] = true
+	return false
+}
@@ -0,0 +1,71 @@
+package memsize
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"testing"
+)
+
+func TestWithIdentityFunc(t *testing.T) {
+	Debug = false
+
+	type settings struct {
+		Name  string
+		Rules [64]int
+	}
+	byName := func(v reflect.Value) (uint64, bool) {
+		s, ok := v.Interface().(*settings)
+		if !ok {
+			return 0, false
+		}
+		h := fnv.New64a()
+		h.Write([]byte(s.Name))
+		return h.Sum64(), true
+	}
+
+	// Decoded copies of the same settings, stored at different addresses
+	copies := []*settings{{Name: "prod"}, {Name: "prod"}, {Name: "prod"}, {Name: "dev"}}
+	interned := []*settings{copies[0], copies[0], copies[0], copies[3]}
+
+	t.Run("Pointers", func(t *testing.T) {
+		got := GetTotalSize(copies, WithIdentityFunc(byName))
+		expected := GetTotalSize(interned)
+		fmt.Printf("Identity: %d, interned %d, copies %d\n", got, expected, GetTotalSize(copies))
+		if got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		r := GetReport(copies, WithIdentityFunc(byName))
+		shared := 0
+		for _, child := range r.Root.Children {
+			if child.Shared {
+				shared++
+			}
+		}
+		if shared != 2 {
+			t.Errorf("Expected 2 copies marked shared, got %d", shared)
+		}
+	})
+
+	t.Run("Maps", func(t *testing.T) {
+		first := func(v reflect.Value) (uint64, bool) {
+			m, ok := v.Interface().(map[string]int)
+			return uint64(m["version"]), ok
+		}
+		a, b := map[string]int{"version": 1, "x": 2}, map[string]int{"version": 1, "x": 2}
+		got := GetTotalSize([]map[string]int{a, b}, WithIdentityFunc(first))
+		if expected := GetTotalSize([]map[string]int{a, a}); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+
+	t.Run("NoIdentity", func(t *testing.T) {
+		none := func(v reflect.Value) (uint64, bool) { return 0, false }
+		if got, expected := GetTotalSize(copies, WithIdentityFunc(none)), GetTotalSize(copies); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+}
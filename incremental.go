@@ -0,0 +1,344 @@
+// incremental.go
+package memsize
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// IncrementalSizer keeps the size of a long-lived root up to date without re-walking it. The
+// first measurement records the size of every object reached through a pointer or map; after
+// the caller invalidates the objects it changed, only those are traversed again and the
+// difference is applied to the totals of the objects holding them.
+//
+// Objects reached again through a pointer or map are not counted twice, but objects only
+// counted through an invalidated object and still referenced elsewhere are dropped with it;
+// Reset forces an exact measurement.
+type IncrementalSizer struct {
+	root reflect.Value
+	opts []Option
+
+	mu      sync.Mutex
+	regions *regionSet
+	total   uint64
+	// full is set when the next measurement has to walk the whole root
+	full  bool
+	dirty map[*region]bool
+}
+
+// NewIncrementalSizer creates an IncrementalSizer for root, which is measured on the first
+// call to Size. Options apply to every traversal; parallelism is ignored.
+func NewIncrementalSizer(root interface{}, opts ...Option) *IncrementalSizer {
+	return &IncrementalSizer{root: reflect.ValueOf(root), opts: opts, full: true,
+		dirty: make(map[*region]bool)}
+}
+
+// Size returns the size of the root, traversing the objects invalidated since the last call
+func (s *IncrementalSizer) Size() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.full {
+		s.regions = newRegionSet()
+		s.total = s.measure(s.root, nil)
+		s.full = false
+		s.dirty = make(map[*region]bool)
+		return s.total
+	}
+
+	// Objects held by another invalidated object are traversed along with it. All are
+	// removed first, so the others still count as accounted elsewhere while traversing one.
+	var dirty []*region
+	for r := range s.dirty {
+		if !r.within(s.dirty) {
+			dirty = append(dirty, r)
+			s.regions.remove(r)
+		}
+	}
+	for _, r := range dirty {
+		size := s.measure(r.v, r.parent)
+		delta := size - r.size
+		for p := r.parent; p != nil; p = p.parent {
+			p.size += delta
+		}
+		s.total += delta
+	}
+	s.dirty = make(map[*region]bool)
+	return s.total
+}
+
+// Invalidate marks the object a pointer or map refers to as changed. Objects added to the
+// graph are found through the object holding them, which has to be invalidated instead. If
+// the last measurement didn't reach ptr, the next one walks the whole root.
+func (s *IncrementalSizer) Invalidate(ptr interface{}) {
+	v := reflect.ValueOf(ptr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.UnsafePointer:
+		s.invalidate(v.Pointer())
+	default:
+		s.full = true
+	}
+}
+
+// InvalidatePath marks the value at a path, as in Node.Path, as changed. The innermost object
+// reached through a pointer or map on the path is traversed again; paths through map entries
+// invalidate the map.
+func (s *IncrementalSizer) InvalidatePath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if addr, ok := resolveRegion(s.root, path); ok {
+		s.invalidate(addr)
+	} else {
+		s.full = true
+	}
+}
+
+// Reset makes the next call to Size walk the whole root
+func (s *IncrementalSizer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.full = true
+}
+
+func (s *IncrementalSizer) invalidate(addr uintptr) {
+	if s.full {
+		return
+	}
+	regions := s.regions.byAddr[addr]
+	if len(regions) == 0 {
+		s.full = true
+		return
+	}
+	for _, r := range regions {
+		s.dirty[r] = true
+	}
+}
+
+// measure walks v, recording the objects it reaches below parent
+func (s *IncrementalSizer) measure(v reflect.Value, parent *region) uint64 {
+	w := newWalker(s.opts...)
+	// Only the root may leave its header out, other regions are counted with their pointer
+	w.cfg.omitRootHeader = w.cfg.omitRootHeader && s.isRoot(v, parent)
+	w.regions = s.regions
+	s.regions.base = parent
+	size, _ := w.measure(v)
+	return size
+}
+
+// isRoot reports whether v, traversed below parent, is the root
+func (s *IncrementalSizer) isRoot(v reflect.Value, parent *region) bool {
+	if parent != nil || !v.IsValid() || !s.root.IsValid() || v.Type() != s.root.Type() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		return v.Pointer() == s.root.Pointer()
+	}
+	return true
+}
+
+// region is an object reached through a pointer or map by a recorded measurement
+type region struct {
+	// v is the pointer or map, detached from the memory it was read from
+	v    reflect.Value
+	size uint64
+
+	parent   *region
+	children []*region
+}
+
+// within reports whether one of the regions holding r is in set
+func (r *region) within(set map[*region]bool) bool {
+	for p := r.parent; p != nil; p = p.parent {
+		if set[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// regionSet indexes the regions of a root by the address of their object
+type regionSet struct {
+	byAddr map[uintptr][]*region
+	// base holds the regions recorded at the top of the traversal
+	base *region
+}
+
+func newRegionSet() *regionSet {
+	return &regionSet{byAddr: make(map[uintptr][]*region)}
+}
+
+// counted reports whether the object of type t at addr is accounted to a recorded region
+func (s *regionSet) counted(addr uintptr, t reflect.Type) bool {
+	if s == nil {
+		return false
+	}
+	for _, r := range s.byAddr[addr] {
+		if r.v.Type() == t || r.v.Kind() == reflect.Ptr && r.v.Type().Elem() == t {
+			return true
+		}
+	}
+	return false
+}
+
+// enter starts a region on frames of pointers and maps whose object is accounted to them
+func (s *regionSet) enter(w *walker, f *frame) {
+	if s == nil {
+		return
+	}
+	if len(w.stack) > 1 {
+		f.region = w.stack[len(w.stack)-2].region
+	} else {
+		f.region = s.base
+	}
+
+	v := f.v
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !f.follow {
+			return
+		}
+	case reflect.Map:
+		if v.IsNil() || w.cfg.model == ExactSizes && !f.follow {
+			return
+		}
+	default:
+		return
+	}
+
+	r := &region{v: detach(v), parent: f.region}
+	if r.parent != nil {
+		r.parent.children = append(r.parent.children, r)
+	}
+	addr := v.Pointer()
+	s.byAddr[addr] = append(s.byAddr[addr], r)
+	f.region = r
+}
+
+// finish records the size of the region started by a frame
+func (s *regionSet) finish(w *walker, f *frame) {
+	if s == nil || f.region == nil {
+		return
+	}
+	parent := s.base
+	if len(w.stack) > 1 {
+		parent = w.stack[len(w.stack)-2].region
+	}
+	if f.region != parent {
+		f.region.size = f.size
+	}
+}
+
+// remove drops a region and the regions it holds from the index
+func (s *regionSet) remove(r *region) {
+	stack := []*region{r}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		addr := r.v.Pointer()
+		regions := s.byAddr[addr]
+		for i, other := range regions {
+			if other == r {
+				regions = append(regions[:i], regions[i+1:]...)
+				break
+			}
+		}
+		if len(regions) == 0 {
+			delete(s.byAddr, addr)
+		} else {
+			s.byAddr[addr] = regions
+		}
+		stack = append(stack, r.children...)
+		r.children = nil
+	}
+	if p := r.parent; p != nil {
+		for i, child := range p.children {
+			if child == r {
+				p.children = append(p.children[:i], p.children[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// detach returns a pointer or map equal to v that doesn't refer to the memory v was read
+// from, which may be scratch memory reused for other map entries
+func detach(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		return reflect.NewAt(v.Type().Elem(), v.UnsafePointer())
+	}
+	m := reflect.New(v.Type())
+	*(*unsafe.Pointer)(m.UnsafePointer()) = v.UnsafePointer()
+	return m.Elem()
+}
+
+// resolveRegion follows a path from root and returns the address of the innermost pointer or
+// map on it, reporting false if there is none
+func resolveRegion(root reflect.Value, path string) (uintptr, bool) {
+	rest := strings.TrimPrefix(path, "root")
+	if rest == path {
+		return 0, false
+	}
+	v := root
+	var addr uintptr
+	found := false
+	for {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map:
+			if !v.IsNil() {
+				addr, found = v.Pointer(), true
+			}
+		}
+		if rest == "" {
+			return addr, found
+		}
+
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() || !strings.HasPrefix(rest, ".ptr") {
+				return addr, found
+			}
+			v, rest = v.Elem(), rest[len(".ptr"):]
+		case reflect.Interface:
+			if v.IsNil() || !strings.HasPrefix(rest, ".elem") {
+				return addr, found
+			}
+			v, rest = v.Elem(), rest[len(".elem"):]
+		case reflect.Slice, reflect.Array:
+			end := strings.IndexByte(rest, ']')
+			if !strings.HasPrefix(rest, "[") || end < 0 {
+				return addr, found
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 || i >= v.Len() {
+				return addr, found
+			}
+			v, rest = v.Index(i), rest[end+1:]
+		case reflect.Struct:
+			if !strings.HasPrefix(rest, ".") {
+				return addr, found
+			}
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if strings.HasPrefix(name, "(") && strings.HasSuffix(name, ")") {
+				name = name[1 : len(name)-1]
+			}
+			field := v.FieldByName(name)
+			if !field.IsValid() {
+				return addr, found
+			}
+			v, rest = field, rest[end+1:]
+		default:
+			// Map entries have no key in their path, so the map is the innermost object known
+			return addr, found
+		}
+	}
+}
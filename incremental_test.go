@@ -0,0 +1,101 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+type route struct {
+	Prefix  string
+	Targets []string
+}
+
+type routingTable struct {
+	Routes  []*route
+	ByName  map[string]*route
+	Default *route
+}
+
+func newRoutingTable(n int) *routingTable {
+	t := &routingTable{ByName: make(map[string]*route)}
+	for i := 0; i < n; i++ {
+		r := &route{Prefix: fmt.Sprintf("/api/v%d", i), Targets: []string{"a", "b"}}
+		t.Routes = append(t.Routes, r)
+		t.ByName[r.Prefix] = r
+	}
+	t.Default = &route{Prefix: "/"}
+	return t
+}
+
+func TestIncrementalSizer(t *testing.T) {
+	Debug = false
+
+	models := []struct {
+		name  string
+		model SizeModel
+	}{{"Legacy", LegacySizes}, {"Exact", ExactSizes}}
+	for _, tt := range models {
+		model := tt.model
+		t.Run(tt.name, func(t *testing.T) {
+			table := newRoutingTable(20)
+			s := NewIncrementalSizer(table, WithSizeModel(model))
+			check := func(step string) {
+				t.Helper()
+				got, expected := s.Size(), GetTotalSize(table, WithSizeModel(model))
+				fmt.Printf("%s: %d\n", step, got)
+				if got != expected {
+					t.Errorf("%s: expected %d, got %d", step, expected, got)
+				}
+			}
+			check("Initial")
+
+			r := table.Routes[3]
+			r.Targets = append(r.Targets, "c", "d", "e")
+			s.Invalidate(r)
+			check("Pointer")
+
+			table.Default.Prefix = "/fallback"
+			s.InvalidatePath("root.ptr.Default.ptr.Prefix")
+			check("Path")
+
+			// New objects are found through the invalidated object holding them
+			extra := &route{Prefix: "/extra", Targets: []string{"x"}}
+			table.ByName["extra"] = extra
+			s.Invalidate(table.ByName)
+			table.Routes[5].Prefix = "/changed"
+			s.Invalidate(table.Routes[5])
+			check("Map")
+
+			// A pointer that wasn't reached makes the next measurement walk everything
+			table.Default = &route{Prefix: "/new"}
+			s.Invalidate(table.Default)
+			check("Unknown")
+
+			s.Reset()
+			check("Reset")
+		})
+	}
+
+	t.Run("Resolve", func(t *testing.T) {
+		table := newRoutingTable(2)
+		tests := []struct {
+			path string
+			addr uintptr
+		}{
+			{"root", uintptr(unsafe.Pointer(table))},
+			{"root.ptr.Routes[1].ptr.Targets[0]", uintptr(unsafe.Pointer(table.Routes[1]))},
+			{`root.ptr.ByName["a"].ptr`, reflect.ValueOf(table.ByName).Pointer()},
+			{"root.ptr.Missing", uintptr(unsafe.Pointer(table))},
+		}
+		for _, tt := range tests {
+			if addr, ok := resolveRegion(reflect.ValueOf(table), tt.path); !ok || addr != tt.addr {
+				t.Errorf("Expected %s to resolve to %x, got %x", tt.path, tt.addr, addr)
+			}
+		}
+		if _, ok := resolveRegion(reflect.ValueOf(table), "other"); ok {
+			t.Error("Expected paths not starting at the root not to resolve")
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// indirection.go
+package memsize
+
+import "sort"
+
+// Hotspot is a value whose traversal chases many pointers
+type Hotspot struct {
+	Path string
+	Type string
+	// FanOut and Depth are the value's Node.FanOut and Node.Depth
+	FanOut uint64
+	Depth  int
+	Size   uint64
+}
+
+// ComputeIndirections sets the FanOut and Depth of every node of the report, which measure
+// how much pointer chasing reading the value takes: the number of indirections it holds, in
+// its own memory or in the allocation it refers to, and the longest chain of indirections
+// below it. Pointers, interfaces
+// holding boxed values, slices, strings, maps and channels are indirections.
+func (r *Report) ComputeIndirections() {
+	if r == nil || r.Root == nil {
+		return
+	}
+
+	// Nodes in preorder come before their children, so walking the order backwards completes
+	// the children of every node before the node itself
+	var order []*Node
+	r.Walk(func(n *Node) bool {
+		order = append(order, n)
+		return true
+	})
+	for i := len(order) - 1; i >= 0; i-- {
+		n := order[i]
+		n.FanOut, n.Depth = 0, 0
+		for _, child := range n.Children {
+			depth := child.Depth
+			if indirection(child) {
+				n.FanOut++
+				depth++
+			} else {
+				// Values stored inline, such as fields, hold their indirections in the parent's memory
+				n.FanOut += child.FanOut
+			}
+			if depth > n.Depth {
+				n.Depth = depth
+			}
+		}
+	}
+}
+
+// indirection reports whether reading a node's children dereferences a pointer
+func indirection(n *Node) bool {
+	return n.Addr != 0 || n.Alloc > 0 || n.Mapped > 0
+}
+
+// PointerHotspots returns the n values with the largest fan-out, ties broken by depth, as
+// candidates for flattening into contiguous memory. It computes indirections if needed.
+func (r *Report) PointerHotspots(n int) []Hotspot {
+	if n <= 0 || r == nil || r.Root == nil {
+		return nil
+	}
+	r.ComputeIndirections()
+
+	var all []Hotspot
+	r.Walk(func(node *Node) bool {
+		if node.FanOut > 0 {
+			all = append(all, Hotspot{Path: node.Path, Type: node.Type, FanOut: node.FanOut,
+				Depth: node.Depth, Size: node.Size})
+		}
+		return true
+	})
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].FanOut != all[j].FanOut {
+			return all[i].FanOut > all[j].FanOut
+		}
+		if all[i].Depth != all[j].Depth {
+			return all[i].Depth > all[j].Depth
+		}
+		return all[i].Path < all[j].Path
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
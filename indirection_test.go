@@ -0,0 +1,78 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestComputeIndirections(t *testing.T) {
+	Debug = false
+
+	type leaf struct {
+		Name string
+	}
+	type inline struct {
+		A, B *leaf
+	}
+	type root struct {
+		Inline inline
+		Leaves []*leaf
+		Count  int
+	}
+	v := &root{
+		Inline: inline{A: &leaf{Name: "a"}, B: &leaf{Name: "b"}},
+		Leaves: []*leaf{{Name: "c"}, {Name: "d"}, {Name: "e"}, nil},
+	}
+
+	report := GetReport(v)
+	report.ComputeIndirections()
+	nodes := make(map[string]*Node)
+	report.Walk(func(n *Node) bool {
+		fmt.Printf("%s: fan-out %d, depth %d\n", n.Path, n.FanOut, n.Depth)
+		nodes[n.Path] = n
+		return true
+	})
+
+	// The pointers of the inline struct and the slice header are stored in the root struct
+	if n := nodes["root.ptr"]; n.FanOut != 3 {
+		t.Errorf("Expected a fan-out of 3 for the struct, got %d", n.FanOut)
+	}
+	// The nil element is not chased
+	if n := nodes["root.ptr.Leaves"]; n.FanOut != 3 {
+		t.Errorf("Expected a fan-out of 3 for the slice, got %d", n.FanOut)
+	}
+	// Slice, element pointer, then the string data
+	if n := nodes["root.ptr"]; n.Depth != 3 {
+		t.Errorf("Expected a depth of 3, got %d", n.Depth)
+	}
+	if n := nodes["root.ptr.Count"]; n.FanOut != 0 || n.Depth != 0 {
+		t.Errorf("Expected no indirections for an int, got %d and %d", n.FanOut, n.Depth)
+	}
+
+	t.Run("Hotspots", func(t *testing.T) {
+		wide := struct {
+			Few  []*leaf
+			Many []*leaf
+		}{Few: []*leaf{{}}, Many: make([]*leaf, 10)}
+		for i := range wide.Many {
+			wide.Many[i] = &leaf{}
+		}
+
+		hotspots := GetReport(wide).PointerHotspots(2)
+		fmt.Printf("Hotspots: %+v\n", hotspots)
+		if len(hotspots) != 2 {
+			t.Fatalf("Expected 2 hotspots, got %+v", hotspots)
+		}
+		if hotspots[0].Path != "root.Many" || hotspots[0].FanOut != 10 {
+			t.Errorf("Expected the wide slice first, got %+v", hotspots[0])
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var r *Report
+		r.ComputeIndirections()
+		if hotspots := r.PointerHotspots(3); hotspots != nil {
+			t.Errorf("Expected no hotspots, got %+v", hotspots)
+		}
+	})
+}
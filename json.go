@@ -0,0 +1,155 @@
+// json.go
+package memsize
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ReportSchemaVersion is the version of the JSON document written by Report.MarshalJSON.
+// It is increased whenever the layout changes; older documents remain readable.
+//
+// Version 2 lists the nodes in depth-first order instead of nesting them, since JSON nested
+// as deeply as a long linked list exceeds the depth encoding/json supports.
+const ReportSchemaVersion = 2
+
+type reportJSON struct {
+	Version   int               `json:"version"`
+	Total     uint64            `json:"total"`
+	Truncated bool              `json:"truncated,omitempty"`
+	OffHeap   uint64            `json:"offHeapBytes,omitempty"`
+	Mapped    uint64            `json:"mappedBytes,omitempty"`
+	Strings   uint64            `json:"stringBytes,omitempty"`
+	Duplicate uint64            `json:"duplicateStringBytes,omitempty"`
+	Shared    uint64            `json:"sharedBytes,omitempty"`
+	Objects   []SharedObject    `json:"sharedObjects,omitempty"`
+	Groups    map[string]uint64 `json:"groups,omitempty"`
+	Unstable  []string          `json:"unstable,omitempty"`
+	Errors    []pathErrorRecord `json:"errors,omitempty"`
+	Model     *Model            `json:"model,omitempty"`
+	Nodes     []nodeJSON        `json:"nodes,omitempty"`
+	// Root holds the nested nodes of version 1 documents
+	Root *Node `json:"root,omitempty"`
+}
+
+// nodeJSON is a node whose children follow it in the document instead of being nested
+type nodeJSON struct {
+	*Node
+	Children int `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes the report together with its schema version
+func (r *Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reportJSON{
+		Version:   ReportSchemaVersion,
+		Total:     r.Total(),
+		Truncated: r.Truncated,
+		OffHeap:   r.OffHeapBytes,
+		Mapped:    r.MappedBytes,
+		Strings:   r.StringBytes,
+		Duplicate: r.DuplicateStringBytes,
+		Shared:    r.SharedBytes,
+		Objects:   r.SharedObjects,
+		Groups:    r.Groups,
+		Unstable:  r.Unstable,
+		Errors:    encodePathErrors(r.Errors),
+		Model:     r.Model,
+		Nodes:     flattenNodes(r.Root),
+	})
+}
+
+// flattenNodes lists the nodes below root in depth-first order
+func flattenNodes(root *Node) []nodeJSON {
+	if root == nil {
+		return nil
+	}
+	var nodes []nodeJSON
+	stack := []*Node{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		nodes = append(nodes, nodeJSON{Node: n, Children: len(n.Children)})
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			stack = append(stack, n.Children[i])
+		}
+	}
+	return nodes
+}
+
+// nestNodes rebuilds the tree of nodes listed by flattenNodes and returns its root
+func nestNodes(nodes []nodeJSON) (*Node, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	// pending holds the nodes still missing children, with the number they miss
+	type pending struct {
+		node    *Node
+		missing int
+	}
+	var root *Node
+	var stack []pending
+	for i, nj := range nodes {
+		n := nj.Node
+		if n == nil {
+			n = new(Node)
+		}
+		switch {
+		case i == 0:
+			root = n
+		case len(stack) == 0:
+			return nil, fmt.Errorf("memsize: report has %d nodes after the root's last descendant", len(nodes)-i)
+		default:
+			top := &stack[len(stack)-1]
+			top.node.Children = append(top.node.Children, n)
+			top.missing--
+		}
+		if nj.Children > 0 {
+			n.Children = make([]*Node, 0, nj.Children)
+			stack = append(stack, pending{node: n, missing: nj.Children})
+		}
+		for len(stack) > 0 && stack[len(stack)-1].missing == 0 {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("memsize: report is missing the children of %s", stack[len(stack)-1].node.Path)
+	}
+	return root, nil
+}
+
+// UnmarshalJSON decodes a report written by MarshalJSON with any supported schema version
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var doc reportJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	switch {
+	case doc.Version < 1:
+		return fmt.Errorf("memsize: report has no schema version")
+	case doc.Version > ReportSchemaVersion:
+		return fmt.Errorf("memsize: report schema version %d is newer than supported version %d",
+			doc.Version, ReportSchemaVersion)
+	}
+
+	r.Root = doc.Root
+	if doc.Version >= 2 {
+		root, err := nestNodes(doc.Nodes)
+		if err != nil {
+			return err
+		}
+		r.Root = root
+	}
+	r.Truncated = doc.Truncated
+	r.OffHeapBytes = doc.OffHeap
+	r.MappedBytes = doc.Mapped
+	r.StringBytes = doc.Strings
+	r.DuplicateStringBytes = doc.Duplicate
+	r.SharedBytes = doc.Shared
+	r.SharedObjects = doc.Objects
+	r.Groups = doc.Groups
+	r.Unstable = doc.Unstable
+	r.Errors = decodePathErrors(doc.Errors)
+	r.Model = doc.Model
+	return nil
+}
@@ -0,0 +1,106 @@
+package memsize
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportJSON(t *testing.T) {
+	Debug = false
+
+	person := &Person{
+		Name:    "John Doe",
+		Friends: []*Person{{Name: "Jane"}},
+		Data:    map[string]interface{}{"age": 30},
+	}
+	report := GetReport(person)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Versioned", func(t *testing.T) {
+		if !strings.HasPrefix(string(data), `{"version":2,`) {
+			t.Errorf("Expected a schema version, got %s", data)
+		}
+	})
+
+	t.Run("Round Trip", func(t *testing.T) {
+		var decoded Report
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Total() != report.Total() {
+			t.Errorf("Expected total %d, got %d", report.Total(), decoded.Total())
+		}
+		if diff := report.Diff(&decoded); len(diff.Entries) != 0 {
+			t.Errorf("Expected no differences after round trip, got %v", diff.Entries)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		r := &Report{Root: &Node{Path: "root", Size: 8}, Truncated: true, Unstable: []string{"root.x"},
+			Errors: []PathError{{Path: "root.x", Type: "[]int", Err: errors.New("index out of range")}}}
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Report
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&decoded, r) {
+			t.Errorf("Expected %+v after round trip, got %+v", r, &decoded)
+		}
+	})
+
+	t.Run("Deep", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("The paths of deep reports take too long to encode under the race detector")
+		}
+		// Nesting the nodes of a list of 3000 elements, 2 nodes each, would exceed the depth of
+		// 10000 encoding/json supports
+		var head *listNode
+		for i := 0; i < 3000; i++ {
+			head = &listNode{Value: i, Next: head}
+		}
+		report := GetReport(head)
+		data, err := json.Marshal(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Report
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded.Root, report.Root) {
+			t.Error("Expected the nodes to survive the round trip")
+		}
+	})
+
+	t.Run("Version 1", func(t *testing.T) {
+		var decoded Report
+		doc := `{"version":1,"total":16,"root":{"path":"root","type":"*int","size":16,"shallow":8,` +
+			`"children":[{"path":"root.ptr","type":"int","size":8,"shallow":8}]}}`
+		if err := json.Unmarshal([]byte(doc), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Total() != 16 || len(decoded.Root.Children) != 1 || decoded.Root.Children[0].Path != "root.ptr" {
+			t.Errorf("Expected the nested nodes of a version 1 document, got %+v", decoded.Root)
+		}
+	})
+
+	t.Run("Unsupported Versions", func(t *testing.T) {
+		var decoded Report
+		if err := json.Unmarshal([]byte(`{"version":99,"root":null}`), &decoded); err == nil {
+			t.Error("Expected an error for a newer schema version")
+		}
+		if err := json.Unmarshal([]byte(`{"root":null}`), &decoded); err == nil {
+			t.Error("Expected an error for a missing schema version")
+		}
+	})
+}
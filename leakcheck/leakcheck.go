@@ -0,0 +1,313 @@
+// leakcheck.go
+package leakcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+// Defaults for the zero fields of a Config
+const (
+	DefaultWindow     = 30
+	DefaultMinSamples = 5
+	DefaultMinFit     = 0.8
+	DefaultMaxDepth   = 6
+	DefaultMinBytes   = 1 << 10
+)
+
+// leafShare is the part of a suspect's growth one of its descendants has to account for to
+// replace it in Suspects
+const leafShare = 0.9
+
+// Config tunes the sampling and the growth detection of a Detector
+type Config struct {
+	// Window is the number of samples kept per root
+	Window int
+	// MinSamples is the number of samples a path needs before it can be suspected
+	MinSamples int
+	// MinGrowth is the growth in bytes per second a path has to exceed to be suspected
+	MinGrowth float64
+	// MinFit is the coefficient of determination (R²) of the linear fit a path needs, between 0
+	// and 1; values close to 1 only accept steady growth, lower ones also noisy growth
+	MinFit float64
+	// MaxDepth limits the paths followed below each root, counted from 0 at the root
+	MaxDepth int
+	// MinBytes is the size below which nodes are collapsed instead of followed
+	MinBytes uint64
+	// Options are passed to every measurement
+	Options []memsize.Option
+}
+
+// Suspect is a path whose size grew steadily over the recorded samples
+type Suspect struct {
+	// Root is the name the root was registered under
+	Root string
+	// Path and Type identify the node, as in memsize.Node
+	Path string
+	Type string
+	// Size is the size of the path in the latest sample
+	Size uint64
+	// Growth is the slope of a least-squares fit of the sizes, in bytes per second
+	Growth float64
+	// Fit is the coefficient of determination of the fit: 1 for perfectly linear growth
+	Fit float64
+}
+
+func (s Suspect) String() string {
+	return fmt.Sprintf("%s %s (%s): %s, growing %s/s (R² %.2f)", s.Root, s.Path, s.Type,
+		memsize.Format(s.Size), memsize.Format(uint64(s.Growth)), s.Fit)
+}
+
+// Detector samples registered roots and points at the paths that keep growing, the usual
+// symptom of a leak in a long-running service: a cache without eviction, a map of sessions
+// never cleaned up, a slice only ever appended to.
+type Detector struct {
+	cfg Config
+
+	mu    sync.Mutex
+	roots map[string]*root
+	stop  chan struct{}
+}
+
+// root is a registered root with its recent samples, oldest first
+type root struct {
+	v       interface{}
+	samples []sample
+}
+
+// sample holds the sizes of the paths of a root at one point in time
+type sample struct {
+	time  time.Time
+	nodes map[string]node
+}
+
+type node struct {
+	typ  string
+	size uint64
+}
+
+// New creates a Detector with cfg, whose zero fields take their defaults
+func New(cfg Config) *Detector {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = DefaultMinSamples
+	}
+	if cfg.MinSamples < 2 {
+		cfg.MinSamples = 2
+	}
+	if cfg.MinFit <= 0 {
+		cfg.MinFit = DefaultMinFit
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = DefaultMaxDepth
+	}
+	if cfg.MinBytes == 0 {
+		cfg.MinBytes = DefaultMinBytes
+	}
+	return &Detector{cfg: cfg, roots: make(map[string]*root)}
+}
+
+// Register adds or replaces the root sampled under name, discarding the samples of a root
+// previously registered under it
+func (d *Detector) Register(name string, v interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.roots[name] = &root{v: v}
+}
+
+// Unregister removes a previously registered root along with its samples
+func (d *Detector) Unregister(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.roots, name)
+}
+
+// Start samples the roots every interval on a new goroutine until stop is called. Calling
+// Start again stops the previous sampling.
+func (d *Detector) Start(interval time.Duration) (stop func()) {
+	ch := make(chan struct{})
+	d.mu.Lock()
+	if d.stop != nil {
+		close(d.stop)
+	}
+	d.stop = ch
+	d.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.Sample()
+			case <-ch:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.stop == ch {
+				d.stop = nil
+			}
+			close(ch)
+		})
+	}
+}
+
+// Sample measures every root right away and records the result, dropping the oldest sample
+// of a root once its window is full
+func (d *Detector) Sample() {
+	d.sampleAt(time.Now())
+}
+
+func (d *Detector) sampleAt(t time.Time) {
+	d.mu.Lock()
+	roots := make(map[string]*root, len(d.roots))
+	for name, r := range d.roots {
+		roots[name] = r
+	}
+	d.mu.Unlock()
+
+	// Roots are measured without the lock, so Suspects isn't blocked by a long traversal
+	for name, r := range roots {
+		s := d.measure(r.v, t)
+		d.mu.Lock()
+		if d.roots[name] == r {
+			if len(r.samples) == d.cfg.Window {
+				r.samples = append(r.samples[:0], r.samples[1:]...)
+			}
+			r.samples = append(r.samples, s)
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *Detector) measure(v interface{}, t time.Time) sample {
+	report := memsize.GetReport(v, d.cfg.Options...).Prune(d.cfg.MinBytes, 0, d.cfg.MaxDepth)
+	s := sample{time: t, nodes: make(map[string]node)}
+	report.Walk(func(n *memsize.Node) bool {
+		if !strings.HasSuffix(n.Path, memsize.OtherSegment) {
+			s.nodes[n.Path] = node{typ: n.Type, size: n.Size}
+		}
+		return true
+	})
+	return s
+}
+
+// Suspects returns the paths whose size grew linearly over the samples of their root, fastest
+// growth first. A path is only suspected once it was seen in MinSamples samples, and replaced
+// by a path below it that accounts for nearly all of its growth, so the list points at the
+// innermost value that leaks rather than at every value holding it.
+func (d *Detector) Suspects() []Suspect {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var suspects []Suspect
+	for name, r := range d.roots {
+		suspects = append(suspects, d.suspects(name, r.samples)...)
+	}
+	sort.Slice(suspects, func(i, j int) bool {
+		if suspects[i].Growth != suspects[j].Growth {
+			return suspects[i].Growth > suspects[j].Growth
+		}
+		if suspects[i].Root != suspects[j].Root {
+			return suspects[i].Root < suspects[j].Root
+		}
+		return suspects[i].Path < suspects[j].Path
+	})
+	return suspects
+}
+
+// suspects returns the suspects among the paths of the latest of samples
+func (d *Detector) suspects(name string, samples []sample) []Suspect {
+	if len(samples) < d.cfg.MinSamples {
+		return nil
+	}
+	latest := samples[len(samples)-1]
+
+	var found []Suspect
+	for path, n := range latest.nodes {
+		// Samples taken before the path appeared don't count
+		first := len(samples) - 1
+		for first > 0 {
+			if _, ok := samples[first-1].nodes[path]; !ok {
+				break
+			}
+			first--
+		}
+		series := samples[first:]
+		if len(series) < d.cfg.MinSamples {
+			continue
+		}
+		growth, fit := regression(series, path)
+		if growth > d.cfg.MinGrowth && growth > 0 && fit >= d.cfg.MinFit {
+			found = append(found, Suspect{Root: name, Path: path, Type: n.typ, Size: n.size,
+				Growth: growth, Fit: fit})
+		}
+	}
+
+	// Longer paths go first, so a suspect is compared with those below it
+	sort.Slice(found, func(i, j int) bool { return len(found[i].Path) > len(found[j].Path) })
+	var kept []Suspect
+	for _, s := range found {
+		explained := false
+		for _, below := range kept {
+			if within(below.Path, s.Path) && below.Growth >= leafShare*s.Growth {
+				explained = true
+				break
+			}
+		}
+		if !explained {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// regression fits the sizes of path over samples with a line and returns its slope in bytes
+// per second and its coefficient of determination, which is zero if the size never changed
+func regression(samples []sample, path string) (slope, fit float64) {
+	// Times are relative to the first sample so that the sums stay precise
+	var sumX, sumY, sumXY, sumXX, sumYY float64
+	for _, s := range samples {
+		x := s.time.Sub(samples[0].time).Seconds()
+		y := float64(s.nodes[path].size)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+		sumYY += y * y
+	}
+	n := float64(len(samples))
+	varX := n*sumXX - sumX*sumX
+	varY := n*sumYY - sumY*sumY
+	if varX == 0 || varY == 0 {
+		return 0, 0
+	}
+	cov := n*sumXY - sumX*sumY
+	return cov / varX, cov * cov / (varX * varY)
+}
+
+// within reports whether path lies below parent
+func within(path, parent string) bool {
+	if len(path) <= len(parent) || !strings.HasPrefix(path, parent) {
+		return false
+	}
+	switch path[len(parent)] {
+	case '.', '[':
+		return true
+	}
+	return false
+}
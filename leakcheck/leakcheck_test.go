@@ -0,0 +1,86 @@
+package leakcheck
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+type service struct {
+	Sessions map[int][]byte
+	Buffer   []byte
+	Config   []byte
+}
+
+func TestSuspects(t *testing.T) {
+	memsize.Debug = false
+
+	s := &service{Sessions: make(map[int][]byte), Config: make([]byte, 4096)}
+	d := New(Config{})
+	d.Register("service", s)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		s.Sessions[i] = make([]byte, 512)
+		// The buffer is resized back and forth, which is no leak
+		s.Buffer = make([]byte, 2048*(1+i%2))
+		d.sampleAt(start.Add(time.Duration(i) * time.Second))
+	}
+
+	suspects := d.Suspects()
+	fmt.Printf("Suspects: %v\n", suspects)
+	if len(suspects) != 1 {
+		t.Fatalf("Expected one suspect, got %d", len(suspects))
+	}
+	got := suspects[0]
+	if got.Root != "service" || got.Path != "root.ptr.Sessions" {
+		t.Errorf("Expected the sessions to be suspected, got %s %s", got.Root, got.Path)
+	}
+	if got.Growth < 512 || got.Fit < DefaultMinFit {
+		t.Errorf("Expected a steady growth of at least 512 B/s, got %.0f (R² %.2f)", got.Growth, got.Fit)
+	}
+
+	t.Run("MinSamples", func(t *testing.T) {
+		d := New(Config{})
+		d.Register("service", s)
+		for i := 0; i < DefaultMinSamples-1; i++ {
+			s.Sessions[100+i] = make([]byte, 512)
+			d.sampleAt(start.Add(time.Duration(i) * time.Second))
+		}
+		if suspects := d.Suspects(); len(suspects) != 0 {
+			t.Errorf("Expected no suspects before %d samples, got %v", DefaultMinSamples, suspects)
+		}
+	})
+
+	t.Run("Window", func(t *testing.T) {
+		d := New(Config{Window: 3, MinSamples: 2})
+		d.Register("service", s)
+		for i := 0; i < 5; i++ {
+			d.Sample()
+		}
+		if n := len(d.roots["service"].samples); n != 3 {
+			t.Errorf("Expected 3 samples, got %d", n)
+		}
+		d.Unregister("service")
+		if suspects := d.Suspects(); len(suspects) != 0 {
+			t.Errorf("Expected no suspects after unregistering, got %v", suspects)
+		}
+	})
+
+	t.Run("Start", func(t *testing.T) {
+		d := New(Config{})
+		d.Register("service", s)
+		stop := d.Start(time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		stop()
+		stop()
+		d.mu.Lock()
+		n := len(d.roots["service"].samples)
+		d.mu.Unlock()
+		if n == 0 {
+			t.Error("Expected samples taken in the background")
+		}
+	})
+}
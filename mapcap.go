@@ -0,0 +1,62 @@
+// mapcap.go
+package memsize
+
+import "reflect"
+
+// mapBuckets returns the number of buckets a non-nil map has allocated and the number a map
+// built with its current length would have. Maps don't shrink when entries are deleted, so a
+// map that once held many more entries keeps their buckets. The allocated count is read from
+// the runtime's map header for the host architecture and estimated from the length otherwise.
+func mapBuckets(v reflect.Value, l layout) (allocated, needed uint64) {
+	needed = neededBuckets(uint64(v.Len()), l)
+	if l.arch != nil {
+		return needed, needed
+	}
+	// Both map implementations are counted in buckets of MapBucketEntries slots
+	allocated = mapSlots(v.UnsafePointer()) / l.overhead().MapBucketEntries
+	if allocated == 0 {
+		allocated = 1
+	}
+	return allocated, needed
+}
+
+// neededBuckets returns the number of buckets a map built with n entries has
+func neededBuckets(n uint64, l layout) uint64 {
+	buckets := uint64(1)
+	for float64(n) > l.overhead().MapLoadFactor*float64(buckets) {
+		buckets *= 2
+	}
+	return buckets
+}
+
+// mapBucketSize is the size of a map bucket holding keys and values in slots of the given sizes
+func mapBucketSize(keySlot, valSlot uint64, l layout) uint64 {
+	return l.overhead().MapBucketEntries*(1+keySlot+valSlot) + l.word()
+}
+
+// spare returns the bytes a followed map spends on buckets it doesn't need for its entries
+func (w *walker) spare(f *frame) uint64 {
+	v := f.v
+	l := w.cfg.layout()
+	if v.Kind() != reflect.Map || v.IsNil() || l.model != ExactSizes || !f.follow {
+		return 0
+	}
+	allocated, needed := mapBuckets(v, l)
+	if allocated <= needed {
+		return 0
+	}
+	keySlot, _ := mapSlot(v.Type().Key(), l)
+	valSlot, _ := mapSlot(v.Type().Elem(), l)
+	return (allocated - needed) * mapBucketSize(keySlot, valSlot, l)
+}
+
+// WastedMapCapacity returns the total number of bytes maps in the report spend on buckets
+// left over from entries since deleted, see Node.Spare
+func (r *Report) WastedMapCapacity() uint64 {
+	var total uint64
+	r.Walk(func(n *Node) bool {
+		total += n.Spare
+		return true
+	})
+	return total
+}
@@ -0,0 +1,27 @@
+//go:build !go1.26 && !goexperiment.swissmap
+
+// mapcap_classic.go
+package memsize
+
+import "unsafe"
+
+// hmap mirrors the beginning of the runtime's bucketed map header
+type hmap struct {
+	count     int
+	flags     uint8
+	B         uint8
+	noverflow uint16
+	hash0     uint32
+	buckets   unsafe.Pointer
+}
+
+// mapSlots returns the number of slots in the buckets allocated by the map m points to,
+// excluding overflow buckets
+func mapSlots(m unsafe.Pointer) uint64 {
+	h := (*hmap)(m)
+	if h.buckets == nil {
+		return 0
+	}
+	// Buckets of the runtime hold 8 entries
+	return 8 << h.B
+}
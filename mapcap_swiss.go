@@ -0,0 +1,44 @@
+//go:build go1.26 || goexperiment.swissmap
+
+// mapcap_swiss.go
+package memsize
+
+import "unsafe"
+
+// swissMap mirrors the beginning of the runtime's Swiss table map header
+type swissMap struct {
+	used uint64
+	seed uintptr
+	// dirPtr points at the single group of a small map, or at the directory of tables
+	dirPtr unsafe.Pointer
+	dirLen int
+}
+
+// swissTable mirrors the beginning of a table of a map with more than one group
+type swissTable struct {
+	used     uint16
+	capacity uint16
+}
+
+// swissGroupSlots is the number of slots in a group
+const swissGroupSlots = 8
+
+// mapSlots returns the number of slots allocated by the map m points to. Tables referenced
+// by several consecutive directory entries are counted once.
+func mapSlots(m unsafe.Pointer) uint64 {
+	h := (*swissMap)(m)
+	if h.dirLen == 0 {
+		if h.dirPtr == nil {
+			return 0
+		}
+		return swissGroupSlots
+	}
+	dir := unsafe.Slice((**swissTable)(h.dirPtr), h.dirLen)
+	var slots uint64
+	for i, t := range dir {
+		if i == 0 || t != dir[i-1] {
+			slots += uint64(t.capacity)
+		}
+	}
+	return slots
+}
@@ -0,0 +1,58 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMapCapacity(t *testing.T) {
+	Debug = false
+
+	shrunk := make(map[int]int)
+	for i := 0; i < 10000; i++ {
+		shrunk[i] = i
+	}
+	for i := 10; i < 10000; i++ {
+		delete(shrunk, i)
+	}
+	fresh := make(map[int]int)
+	for i := 0; i < 10; i++ {
+		fresh[i] = i
+	}
+
+	t.Run("Size", func(t *testing.T) {
+		shrunkSize := GetTotalSize(shrunk, WithSizeModel(ExactSizes))
+		freshSize := GetTotalSize(fresh, WithSizeModel(ExactSizes))
+		fmt.Printf("Shrunk map: %d bytes, fresh map: %d bytes\n", shrunkSize, freshSize)
+		if shrunkSize < 10*freshSize {
+			t.Errorf("Expected the shrunk map to keep its buckets, got %d bytes vs %d", shrunkSize, freshSize)
+		}
+	})
+
+	t.Run("Wasted", func(t *testing.T) {
+		r := GetReport(&shrunk, WithSizeModel(ExactSizes))
+		m := r.Root.Children[0]
+		if m.Spare == 0 || m.Spare >= m.Shallow {
+			t.Errorf("Expected spare capacity below the shallow size %d, got %d", m.Shallow, m.Spare)
+		}
+		if wasted := r.WastedMapCapacity(); wasted != m.Spare {
+			t.Errorf("Expected %d wasted bytes, got %d", m.Spare, wasted)
+		}
+
+		if wasted := GetReport(fresh, WithSizeModel(ExactSizes)).WastedMapCapacity(); wasted != 0 {
+			t.Errorf("Expected no wasted capacity in a fresh map, got %d", wasted)
+		}
+		if wasted := GetReport(shrunk).WastedMapCapacity(); wasted != 0 {
+			t.Errorf("Expected no wasted capacity with legacy sizes, got %d", wasted)
+		}
+	})
+
+	t.Run("Arch", func(t *testing.T) {
+		// Other architectures are estimated from the length
+		shrunkSize := GetTotalSize(shrunk, WithArch("386"))
+		freshSize := GetTotalSize(fresh, WithArch("386"))
+		if shrunkSize != freshSize {
+			t.Errorf("Expected %d, got %d", freshSize, shrunkSize)
+		}
+	})
+}
@@ -3,44 +3,541 @@ package memsize
 
 import (
 	"fmt"
+	"math/rand"
+	"os"
 	"reflect"
 	"runtime"
 	"unsafe"
 )
 
-// visited keeps track of addresses we've already counted
-type visited map[uintptr]bool
+// visitKey identifies an object that was counted by its address and the type it was counted
+// as, so a struct and its first field, or zero-sized values sharing an address, are not merged
+type visitKey struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// addrSet keeps track of objects we've already counted
+type addrSet interface {
+	// visit marks k and reports whether it had been marked before
+	visit(k visitKey) bool
+	// bytes returns the memory taken by the set itself
+	bytes() uint64
+}
 
 // Debug enables detailed size calculation logging
 var Debug bool = false
 
-func debugPrint(format string, args ...interface{}) {
-	if Debug {
-		fmt.Printf(format+"\n", args...)
+// valueHeaderSize is the Header of DefaultModel, counted by LegacySizes for every header and
+// for kinds sized as a whole. It is the size of an interface value, computed from the type
+// alone: calling Interface() on values reached through unexported fields panics, so sizing
+// never converts values back to interfaces.
+const valueHeaderSize = uint64(unsafe.Sizeof(interface{}(nil)))
+
+// walker carries the state of a single traversal
+type walker struct {
+	cfg      *config
+	seen     addrSet
+	par      *parallel
+	budget   *budget
+	progress *progress
+	sampler  *sampler
+	strict   *strictLog
+	stats    *traversalStats
+	descs    *descriptorSet
+	ids      *identitySet
+	regions  *regionSet
+	rng      *rand.Rand
+	// scratch is the pooled memory of seen and stack, returned by release
+	scratch *scratch
+
+	// stack holds the values being traversed; each frame is the parent of the one above it
+	stack []frame
+	total uint64
+
+	// byType aggregates shallow bytes per concrete type when non-nil
+	byType map[string]*TypeStats
+	// groups aggregates shallow bytes per group of struct tags when non-nil, see TagName
+	groups map[string]uint64
+
+	// tree enables building a report rooted at root
+	tree bool
+	root *Node
+	// offHeapBytes and mappedBytes sum the OffHeap and Mapped bytes of all nodes
+	offHeapBytes uint64
+	mappedBytes  uint64
+	// strings totals the string data of the nodes of a report
+	strings *stringStats
+
+	// debugLines counts the lines logged about values
+	debugLines int
+	// steps counts the steps since the last yield, see WithYield
+	steps int
+
+	// rootPackage is the package of the measured value's type, see FollowSamePackage
+	rootPackage string
+
+	// visitor is called for every value by Walk; stopped is set once it returned WalkStop
+	visitor WalkFunc
+	stopped bool
+
+	// unhandled is a type whose handler doesn't size the root, for handlers measuring the
+	// fields of their own value
+	unhandled reflect.Type
+
+	// detach is set for walkers sizing the private state of a wrapper: values of the types it
+	// matches are not visited but collected in detached, for the wrapper to return as children
+	detach   func(t reflect.Type) bool
+	detached []handledChild
+
+	// truncated is set once WithMaxDepth kept the children of a value from being visited
+	truncated bool
+
+	// visitLog lists the objects marked as visited in safe mode, so a retried value can
+	// visit them again, and unstable the paths of values given up with their panics
+	visitLog []visitKey
+	unstable []string
+	panics   []PathError
+	// retry is the value to push again after a panic, with attempts set on its frame
+	retry    *retryFrame
+	attempts int
+}
+
+// frame is a value whose children are being traversed
+type frame struct {
+	v    reflect.Value
+	node *Node
+
+	// step leads from the parent to the value; path is built from it by walker.path
+	step     pathStep
+	path     string
+	pathDone bool
+
+	// shallow is the size of the value itself; size accumulates the totals of finished children
+	shallow uint64
+	size    uint64
+
+	// next is the index of the next slice element or struct field to visit
+	next int
+	// plan lists the struct fields to visit, or all of them when nil
+	plan *typePlan
+
+	// iter walks map entries; keys and values with a constant size are folded into shallow.
+	// key and val are the values entries are copied into, see mapEntry.
+	iter     *reflect.MapIter
+	key, val reflect.Value
+	// keys holds the keys of a map visited in order, of which next is the number visited
+	keys []reflect.Value
+
+	// sample is set on large slices and maps of which only a random subset is visited
+	sample *sampleState
+
+	// follow is set on pointers and interfaces whose element has to be visited, and with
+	// ExactSizes on maps and channels accounting for the memory they reference
+	follow     bool
+	skipKeys   bool
+	skipValues bool
+	pendingVal bool
+
+	// depth is the number of pointers and interfaces followed to reach the value
+	depth int
+
+	// addr is the memory a pointer, interface, map or channel references, and shared is set
+	// when it was already counted
+	addr   uintptr
+	shared bool
+
+	// pruned is set when a WalkFunc skipped the children of the value
+	pruned bool
+
+	// group is the group of struct tags the value belongs to, see TagName
+	group string
+
+	// custom is set on values sized by a typeHandler, whose children are handled
+	custom  bool
+	handled []handledChild
+
+	// causes lists the errors wrapped by the value once unwrapped is set, and cause is the
+	// index of the next one to visit, see WithErrorChains
+	unwrapped bool
+	causes    []reflect.Value
+	cause     int
+
+	// mapped is the capacity of a slice backed by mapped memory
+	mapped uint64
+	// arena is the arena a pointer or slice refers into, see WithArena
+	arena *arenaRegion
+
+	// region is the innermost object recorded for an IncrementalSizer holding the value
+	region *region
+
+	// logMark is the length of the walker's visitLog when the frame was pushed, attempts
+	// counts how often it was retried in safe mode and unstable is set once it was given up
+	logMark  int
+	attempts int
+	unstable bool
+}
+
+func newWalker(opts ...Option) *walker {
+	cfg := newConfig(opts)
+	s := scratchPool.Get().(*scratch)
+	w := &walker{cfg: cfg, seen: s.seen, stack: s.stack, scratch: s, budget: newBudget(cfg),
+		progress: newProgress(cfg), sampler: newSampler(cfg), strict: newStrictLog(cfg),
+		descs: newDescriptorSet(cfg), ids: newIdentitySet(cfg)}
+	if cfg.approxObjects > 0 {
+		w.seen = newBloomSet(cfg.approxObjects, cfg.approxRate)
+	} else if cfg.visitedArena {
+		w.seen = newVisitedSet(true)
 	}
+	w.rng = w.sampler.newRand()
+	return w
 }
 
-// GetTotalSize returns the total memory size including indirect allocations
-func GetTotalSize(v interface{}) uint64 {
-	runtime.GC()
+// child returns a walker measuring values on behalf of w with cfg, such as the private state of
+// a handled value. It shares the visited objects, identities, budget and progress of w, so
+// memory is counted once across both.
+func (w *walker) child(cfg *config) *walker {
+	return &walker{cfg: cfg, seen: w.seen, ids: w.ids, budget: w.budget, progress: w.progress}
+}
+
+// visitAddr marks the object of type t at addr as counted and reports whether it already was.
+// Addresses excluded by WithExcludePointers count as visited whatever their type, and so
+// do the objects an IncrementalSizer already accounted.
+func (w *walker) visitAddr(addr uintptr, t reflect.Type) bool {
+	if w.cfg.exclude[addr] || w.regions.counted(addr, t) {
+		return true
+	}
+	k := visitKey{addr: addr, typ: t}
+	if w.seen.visit(k) {
+		return true
+	}
+	if w.cfg.safe {
+		w.visitLog = append(w.visitLog, k)
+	}
+	return false
+}
+
+// record attributes the shallow size of a node (bytes not accounted to any child) to its type
+func (w *walker) record(v reflect.Value, shallow uint64) {
+	if w.byType == nil {
+		return
+	}
+	name := v.Type().String()
+	stats := w.byType[name]
+	if stats == nil {
+		stats = &TypeStats{}
+		w.byType[name] = stats
+	}
+	stats.Count++
+	stats.Bytes += shallow
+}
+
+// fast reports whether only the total is needed, so nodes may be sized without being visited
+func (w *walker) fast() bool {
+	return !w.tree && w.byType == nil && w.groups == nil && !w.cfg.tracing() && w.visitor == nil
+}
 
-	var stats runtime.MemStats
-	runtime.ReadMemStats(&stats)
-	initialHeap := stats.HeapAlloc
+// collapse reports whether frames of pointers and interfaces may be dropped before their
+// child is visited, which is not the case when frames are needed for output, statistics,
+// counting depths, sizing the objects of an IncrementalSizer, grouping them by struct tags,
+// unwrapping errors or retrying them in safe mode
+func (w *walker) collapse() bool {
+	return !w.paths() && w.stats == nil && w.cfg.maxPointerDepth == 0 && w.cfg.maxDepth == 0 &&
+		w.regions == nil && w.groups == nil && !w.cfg.errorChains && !w.cfg.safe && w.detach == nil
+}
 
-	val := reflect.ValueOf(v)
-	size := getTotalSize(val, make(visited), "root")
+// paths reports whether paths are needed; they grow with depth, so deep graphs would take
+// quadratic memory if paths were always built
+func (w *walker) paths() bool {
+	return w.tree || w.cfg.tracing() || w.cfg.strict || w.visitor != nil
+}
 
-	if Debug {
-		fmt.Printf("Initial heap: %d, Final size: %d\n", initialHeap, size)
+// debugPrint logs a line about the value of a frame, or about the whole measurement if f is nil.
+// Arguments are boxed even when debugging is off, so hot paths check w.cfg.debug first.
+func (w *walker) debugPrint(f *frame, format string, args ...interface{}) {
+	if !w.cfg.debug {
+		return
+	}
+	out := w.cfg.debugOut
+	if out == nil {
+		out = os.Stdout
+	}
+	if f == nil {
+		fmt.Fprintf(out, format+"\n", args...)
+		return
+	}
+	path := w.path(f)
+	if !w.cfg.debugPath(path) {
+		return
 	}
 
+	w.debugLines++
+	if limit := w.cfg.debugMaxLines; limit > 0 && w.debugLines > limit {
+		if w.debugLines == limit+1 {
+			fmt.Fprintf(out, "... debug output truncated after %d lines\n", limit)
+		}
+		return
+	}
+	fmt.Fprintf(out, "%s: "+format+"\n", append([]interface{}{path}, args...)...)
+}
+
+// GetTotalSize returns the total memory size including indirect allocations
+func GetTotalSize(v interface{}, opts ...Option) uint64 {
+	size, _ := newWalker(opts...).measure(reflect.ValueOf(v))
 	return size
 }
 
-func getTotalSize(v reflect.Value, seen visited, path string) uint64 {
+// GetTotalSizeValue is like GetTotalSize for a value that is already a reflect.Value. Values
+// obtained through unexported fields can be measured, since they are never converted back to
+// interfaces, and the value is measured as its static type, including interface headers.
+func GetTotalSizeValue(v reflect.Value, opts ...Option) uint64 {
+	size, _ := newWalker(opts...).measure(v)
+	return size
+}
+
+// measure computes the total size of v honoring all options. The error reports why
+// the traversal stopped early, in which case the size is only a lower bound.
+func (w *walker) measure(v reflect.Value) (uint64, error) {
+	defer w.release()
+	if w.cfg.err != nil {
+		return 0, w.cfg.err
+	}
+	if w.cfg.gc {
+		runtime.GC()
+	}
+
+	var size uint64
+	if w.cfg.parallelism > 1 && w.fast() && w.cfg.maxPointerDepth == 0 && w.cfg.maxDepth == 0 &&
+		w.regions == nil && !w.cfg.safe && w.cfg.order == TraversalOrder {
+		size = parallelTotalSize(w, v)
+	} else {
+		size = w.getTotalSize(v, "root")
+	}
+
+	return w.result(size)
+}
+
+// result finishes a measurement of the given size, reporting why it stopped early if it did
+func (w *walker) result(size uint64) (uint64, error) {
+	w.progress.done()
+	if w.cfg.events != nil {
+		w.cfg.events(Event{Phase: EventDone, Size: size})
+	}
+	if w.cfg.debug {
+		w.debugPrint(nil, "Final size: %d", size)
+	}
+
+	if err := w.budget.err(); err != nil {
+		return size, err
+	}
+	if err := w.strict.err(); err != nil {
+		return size, err
+	}
+	return size, w.unstableErr()
+}
+
+// getTotalSize traverses v depth-first using an explicit stack, so the depth of the
+// object graph is bounded only by the heap
+func (w *walker) getTotalSize(v reflect.Value, path string) uint64 {
+	fast := w.fast()
+	w.stack = w.stack[:0]
+	w.total = 0
+	if w.cfg.safe {
+		w.safeTotalSize(v, path, fast)
+		return w.total
+	}
+
+	w.push(v, rootStep(path))
+	for len(w.stack) > 0 {
+		w.step(fast)
+	}
+	return w.total
+}
+
+// step visits the next child of the frame on top of the stack, or finishes the frame
+func (w *walker) step(fast bool) {
+	if w.cfg.yieldEvery > 0 {
+		if w.steps++; w.steps >= w.cfg.yieldEvery {
+			w.steps = 0
+			runtime.Gosched()
+		}
+	}
+	f := &w.stack[len(w.stack)-1]
+
+	// Once a limit is hit, frames are finished without visiting their remaining children
+	child, step, ok := reflect.Value{}, pathStep{}, false
+	if !w.budget.exceeded() && !w.stopped {
+		child, step, ok = w.nextChild(f)
+		if !ok {
+			child, step, ok = w.nextCause(f)
+		}
+		ok = ok && !w.depthLimited(f) && w.budget.admit()
+	}
+	if !ok {
+		size := w.finish(f)
+		w.stack = w.stack[:len(w.stack)-1]
+		w.add(size)
+		return
+	}
+
+	// Pointers and interfaces are done once their only child is, so unless the frame is
+	// needed the child replaces it, halving the depth of pointer chains
+	if w.collapse() && (f.v.Kind() == reflect.Ptr || f.v.Kind() == reflect.Interface) {
+		size := f.size
+		w.stack = w.stack[:len(w.stack)-1]
+		w.add(size)
+	}
+
+	// Without per-node output, cached plans let us skip reflection entirely for constant types
+	if fast {
+		if p := planFor(child.Type(), w.cfg.layout()); p.constant {
+			w.rejectPlan(f, step, p)
+			w.add(p.size)
+			w.budget.charge(p.size)
+			w.progress.charge(p.size)
+			w.stats.node(len(w.stack) + 1)
+			return
+		}
+	}
+	w.push(child, step)
+}
+
+// add accounts a finished size to the frame on top of the stack, or to the total once empty
+func (w *walker) add(size uint64) {
+	if len(w.stack) > 0 {
+		w.stack[len(w.stack)-1].size += size
+	} else {
+		w.total += size
+	}
+}
+
+// push starts visiting a value: its shallow size is accounted immediately and its
+// children are produced one at a time by nextChild
+func (w *walker) push(v reflect.Value, step pathStep) {
+	var node *Node
+	if w.tree {
+		node = &Node{}
+		if v.IsValid() {
+			node.Type = v.Type().String()
+		}
+		if len(w.stack) == 0 {
+			w.root = node
+		} else {
+			parent := w.stack[len(w.stack)-1].node
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	if len(w.stack) == 0 && v.IsValid() {
+		w.rootPackage = basePackage(v.Type())
+	}
+	w.stack = append(w.stack, frame{v: v, step: step, node: node, depth: w.pointerDepth(),
+		logMark: len(w.visitLog), attempts: w.attempts})
+	w.attempts = 0
+	f := &w.stack[len(w.stack)-1]
+	if node != nil {
+		node.Path = w.path(f)
+	}
+	f.shallow = w.enter(f)
+	if w.omitsHeader(f) {
+		f.shallow -= w.rootHeader(f)
+	}
+	f.size = f.shallow
+	w.emit(EventEnter, f)
+	w.markGlobal(f)
+	w.regions.enter(w, f)
+	if node != nil {
+		node.Shallow = f.shallow
+		if v.IsValid() {
+			node.Padding, node.Reorderable = w.padding(f)
+			node.Alloc = w.alloc(f)
+			node.OffHeap = w.offHeap(f)
+			node.Mapped = f.mapped
+			node.Header = w.header(v)
+			node.Unused = w.unused(f)
+			w.strings.add(v)
+			node.Spare = w.spare(f)
+			w.offHeapBytes += node.OffHeap
+			w.mappedBytes += f.mapped
+		}
+	}
+	if v.IsValid() {
+		w.record(v, f.shallow)
+	}
+	w.recordGroup(f)
+	w.budget.charge(f.shallow)
+	w.progress.charge(f.shallow)
+	w.stats.node(len(w.stack))
+	w.visit(f)
+}
+
+// omitsHeader reports whether the header of a measured root is left out, see WithRootHeader.
+// Walkers sizing values for an enclosing measurement start from unnamed roots, whose headers
+// belong to the enclosing value.
+func (w *walker) omitsHeader(f *frame) bool {
+	return w.cfg.omitRootHeader && f.step.kind == stepRoot && f.step.name != ""
+}
+
+// rootHeader returns the part of the shallow size of a root frame taken by its header
+func (w *walker) rootHeader(f *frame) uint64 {
+	if !f.v.IsValid() {
+		return 0
+	}
+	l := w.cfg.layout()
+	var header uint64
+	switch f.v.Kind() {
+	case reflect.Ptr:
+		header = headerSize(f.v.Type(), l)
+	case reflect.Interface:
+		header = headerSize(f.v.Type(), l)
+		if l.model == ExactSizes && !f.v.IsNil() && directIface(f.v.Elem().Type()) {
+			// The data word is accounted to the pointer-shaped value it holds
+			header -= l.word()
+		}
+	}
+	if header > f.shallow {
+		return f.shallow
+	}
+	return header
+}
+
+// enter returns the shallow size of the frame's value and prepares the traversal of its children
+func (w *walker) enter(f *frame) uint64 {
+	v := f.v
 	if !v.IsValid() {
-		debugPrint("%s: Invalid value", path)
+		w.debugPrint(f, "Invalid value")
+		w.reject(f, nil, "the value is nil")
+		return 0
+	}
+
+	if w.cfg.includeTypes.excludes(v.Type()) {
+		// Like a handled value without children
+		f.custom = true
+		size := inlineSize(v.Type(), w.cfg.layout())
+		if w.cfg.debug {
+			w.debugPrint(f, "%s not included, size %d", v.Type(), size)
+		}
+		return size
+	}
+
+	if w.detach != nil && w.detach(v.Type()) {
+		// Visited by the enclosing measurement as a child of the value that detached it,
+		// including the header counted here otherwise
+		f.custom = true
+		w.detached = append(w.detached, handledChild{v: v, suffix: w.fullPath(f)})
+		return 0
+	}
+
+	if h := handlerFor(v.Type(), w.cfg.layout()); h != nil && (len(w.stack) > 1 || v.Type() != w.unhandled) {
+		return w.enterHandled(f, h)
+	}
+
+	if zeroSized(v.Type()) {
+		// Like a handled value without children, nothing below takes any memory
+		f.custom = true
+		if w.cfg.debug {
+			w.debugPrint(f, "Zero-sized %s, size 0", v.Type())
+		}
 		return 0
 	}
 
@@ -50,146 +547,517 @@ func getTotalSize(v reflect.Value, seen visited, path string) uint64 {
 	switch v.Kind() {
 	case reflect.Bool:
 		size := uint64(1) // 1 byte
-		debugPrint("%s: Bool size %d", path, size)
+		w.debugPrint(f, "Bool size %d", size)
 		return size
 
 	case reflect.Int8, reflect.Uint8:
 		size := uint64(1) // 1 byte
-		debugPrint("%s: Int8/Uint8 size %d", path, size)
+		w.debugPrint(f, "Int8/Uint8 size %d", size)
 		return size
 
 	case reflect.Int16, reflect.Uint16:
 		size := uint64(2) // 2 bytes
-		debugPrint("%s: Int16/Uint16 size %d", path, size)
+		w.debugPrint(f, "Int16/Uint16 size %d", size)
 		return size
 
 	case reflect.Int32, reflect.Uint32, reflect.Float32:
 		size := uint64(4) // 4 bytes
-		debugPrint("%s: Int32/Uint32/Float32 size %d", path, size)
+		w.debugPrint(f, "Int32/Uint32/Float32 size %d", size)
 		return size
 
 	case reflect.Int64, reflect.Uint64, reflect.Float64:
 		size := uint64(8) // 8 bytes
-		debugPrint("%s: Int64/Uint64/Float64 size %d", path, size)
+		w.debugPrint(f, "Int64/Uint64/Float64 size %d", size)
 		return size
 
 	case reflect.Int, reflect.Uint:
 		// Size depends on platform (usually 8 bytes on 64-bit systems)
-		size := uint64(v.Type().Size())
-		debugPrint("%s: Int/Uint size %d", path, size)
+		size := w.cfg.layout().sizeof(v.Type())
+		w.debugPrint(f, "Int/Uint size %d", size)
 		return size
 	}
 
+	// Raw pointers registered with WithUnsafePointerType are sized as ordinary pointers
+	if k := v.Kind(); k == reflect.UnsafePointer || k == reflect.Uintptr {
+		if target, ok := w.unsafeTarget(v); ok {
+			f.v = target
+			return w.enter(f)
+		}
+	}
+
 	// Handle special cases first
+	l := w.cfg.layout()
+	m := l.model
 	switch v.Kind() {
 	case reflect.Interface:
+		size = headerSize(v.Type(), l)
 		if v.IsNil() {
-			size = uint64(unsafe.Sizeof(v.Interface()))
-			debugPrint("%s: Nil interface, size %d", path, size)
+			w.debugPrint(f, "Nil interface, size %d", size)
 			return size
 		}
-		elemSize := getTotalSize(v.Elem(), seen, path+".elem")
-		debugPrint("%s: Interface elem size %d", path, elemSize)
-		return elemSize + uint64(unsafe.Sizeof(v.Interface()))
+		if !w.follows(f, v.Elem().Type()) {
+			return size
+		}
+		elem := v.Elem().Type()
+		if descs := w.descs.size(v.Type(), elem, l); descs > 0 {
+			if w.cfg.debug {
+				w.debugPrint(f, "Type descriptors of %s, size %d", elem, descs)
+			}
+			size += descs
+		}
+		// Copies of an interface share the allocation boxing its value, which is counted once
+		// like the target of a pointer. Pointer-shaped values are visited as pointers instead.
+		if !directIface(elem) && l.sizeof(elem) > 0 {
+			addr := boxAddr(v)
+			if m == ExactSizes && staticBox(v.Elem(), addr) {
+				// Small and empty values boxed by the runtime in static memory hold no heap bytes
+				if w.cfg.debug {
+					w.debugPrint(f, "Statically boxed %s, size %d", elem, size)
+				}
+				return size
+			}
+			seen := w.visitAddr(addr, elem)
+			f.addr, f.shared = addr, seen
+			if f.node != nil {
+				f.node.Addr = addr
+				f.node.Shared = seen
+			}
+			if seen {
+				if w.cfg.debug {
+					w.debugPrint(f, "Already seen boxed %s %x, size %d", elem, addr, size)
+				}
+				return size
+			}
+		}
+		f.follow = true
+		if m == ExactSizes {
+			if directIface(elem) {
+				// The data word holds the value itself, which accounts for it
+				size -= l.word()
+			} else {
+				size += w.cfg.slack(l.sizeof(elem), hasPointers(elem))
+			}
+		}
+		return size
 
 	case reflect.Ptr:
+		ptrSize := headerSize(v.Type(), l)
 		if v.IsNil() {
-			size = uint64(unsafe.Sizeof(v.Interface()))
-			debugPrint("%s: Nil pointer, size %d", path, size)
-			return size
+			w.debugPrint(f, "Nil pointer, size %d", ptrSize)
+			return ptrSize
+		}
+
+		if !w.follows(f, v.Type().Elem()) {
+			return ptrSize
+		}
+		if zeroSized(v.Type().Elem()) {
+			// Pointers to zero-sized values usually all point to the runtime's zerobase, which
+			// holds nothing, so they are not followed or counted as sharing it
+			if w.cfg.debug {
+				w.debugPrint(f, "Pointer to zero-sized %s, size %d", v.Type().Elem(), ptrSize)
+			}
+			return ptrSize
 		}
 
 		// Get pointer address
 		addr := uintptr(v.UnsafePointer())
-		ptrSize := uint64(unsafe.Sizeof(v.Interface()))
+
+		if a := w.arenaOf(addr); a != nil {
+			return ptrSize + w.enterArena(f, a)
+		}
+
+		seen := w.visitAddr(addr, v.Type().Elem()) || w.ids.visit(v)
+		f.addr, f.shared = addr, seen
+		if f.node != nil {
+			f.node.Addr = addr
+			f.node.Shared = seen
+		}
 
 		// Even if we've seen this pointer, we still count the pointer itself
-		if seen[addr] {
-			debugPrint("%s: Already seen pointer %x, size %d", path, addr, ptrSize)
+		if seen {
+			if w.cfg.debug {
+				w.debugPrint(f, "Already seen pointer %x, size %d", addr, ptrSize)
+			}
+			w.stats.seenPointer(w, addr)
 			return ptrSize
 		}
 
-		// Mark as seen
-		seen[addr] = true
-
-		// Get the element size
-		elemSize := getTotalSize(v.Elem(), seen, path+".ptr")
-		totalSize := ptrSize + elemSize
-		debugPrint("%s: Pointer to new address %x (size: %d) + elem (size: %d) = %d",
-			path, addr, ptrSize, elemSize, totalSize)
-		return totalSize
+		// Follow the element of pointers seen for the first time
+		f.follow = true
+		w.stats.pointer()
+		if m == ExactSizes {
+			ptrSize += w.cfg.slack(l.sizeof(v.Type().Elem()), hasPointers(v.Type().Elem()))
+		}
+		return ptrSize
 
 	case reflect.Slice:
 		if v.IsNil() {
-			debugPrint("%s: Nil slice", path)
-			return 0
+			// The header takes the same space whether the slice is nil or not
+			size = headerSize(v.Type(), l)
+			if w.cfg.debug {
+				w.debugPrint(f, "Nil slice, size %d", size)
+			}
+			return size
 		}
 
-		headerSize := uint64(unsafe.Sizeof(v.Interface()))
-		arraySize := uint64(0)
-		if v.Cap() > 0 {
-			arraySize = uint64(v.Cap()) * uint64(v.Type().Elem().Size())
+		if w.isMapped(v) {
+			// Elements of mapped memory are neither heap memory nor traversed
+			f.mapped = uint64(v.Cap()) * uint64(v.Type().Elem().Size())
+			f.next = v.Len()
+			if w.cfg.debug {
+				w.debugPrint(f, "Mapped slice of %d bytes", f.mapped)
+			}
+			return headerSize(v.Type(), l)
 		}
 
-		elementsSize := uint64(0)
-		for i := 0; i < v.Len(); i++ {
-			elemSize := getTotalSize(v.Index(i), seen, fmt.Sprintf("%s[%d]", path, i))
-			elementsSize += elemSize
+		if a := w.arenaOf(uintptr(v.UnsafePointer())); a != nil && v.Cap() > 0 {
+			// Elements allocated from an arena are accounted to it and not traversed
+			f.next = v.Len()
+			return headerSize(v.Type(), l) + w.enterArena(f, a)
 		}
 
-		size = headerSize + arraySize + elementsSize
-		debugPrint("%s: Slice header(%d) + array(%d) + elements(%d) = %d",
-			path, headerSize, arraySize, elementsSize, size)
-		return size
+		headerSize, arraySize, inlineSize := sliceSizes(v, w.cfg)
+		if elemPlan := planFor(v.Type().Elem(), l); elemPlan.constant {
+			if v.Len() > 0 {
+				w.rejectPlan(f, pathStep{kind: stepIndex}, elemPlan)
+			}
+			f.next = v.Len()
+		} else if f.sample = w.sampler.start(v.Len()); f.sample != nil {
+			f.next = w.skip(f.sample)
+		}
+		return headerSize + arraySize + inlineSize
 
 	case reflect.String:
-		headerSize := uint64(unsafe.Sizeof(v.Interface()))
+		headerSize := headerSize(v.Type(), l)
 		dataSize := uint64(v.Len())
+		if m == ExactSizes {
+			dataSize += w.cfg.slack(dataSize, false)
+		}
 		size = headerSize + dataSize
-		debugPrint("%s: String header(%d) + data(%d) = %d", path, headerSize, dataSize, size)
+		if w.cfg.debug {
+			w.debugPrint(f, "String header(%d) + data(%d) = %d", headerSize, dataSize, size)
+		}
 		return size
 
 	case reflect.Map:
 		if v.IsNil() {
-			debugPrint("%s: Nil map", path)
-			return 0
+			size = headerSize(v.Type(), l)
+			if w.cfg.debug {
+				w.debugPrint(f, "Nil map, size %d", size)
+			}
+			return size
+		}
+		if m == ExactSizes && !w.visitRef(f) {
+			return l.sizeof(v.Type())
 		}
 
-		headerSize := uint64(unsafe.Sizeof(v.Interface()))
-		bucketSize := uint64(48) // approximate bucket overhead
-		bucketsSize := (uint64(v.Len())/8 + 1) * bucketSize
+		headerSize, bucketsSize, inlineSize := mapSizes(v, w.cfg)
+		keyPlan, valPlan := planFor(v.Type().Key(), l), planFor(v.Type().Elem(), l)
+		if !keyPlan.constant || !valPlan.constant {
+			f.iter = w.mapRange(v)
+			if w.cfg.order != TraversalOrder {
+				f.keys = sortedKeys(v)
+			}
+			f.skipKeys, f.skipValues = keyPlan.constant, valPlan.constant
+			f.sample = w.sampler.start(v.Len())
+		}
+		if v.Len() > 0 {
+			if keyPlan.constant {
+				w.rejectPlan(f, suffixStep(".key"), keyPlan)
+			}
+			if valPlan.constant {
+				w.rejectPlan(f, suffixStep(".value"), valPlan)
+			}
+		}
+		return headerSize + bucketsSize + inlineSize
 
-		contentSize := uint64(0)
-		iter := v.MapRange()
-		for iter.Next() {
-			keySize := getTotalSize(iter.Key(), seen, path+".key")
-			valSize := getTotalSize(iter.Value(), seen, path+".value")
-			contentSize += keySize + valSize
+	case reflect.Struct:
+		if w.fast() {
+			f.plan = planFor(v.Type(), l)
+			w.rejectPlan(f, pathStep{}, f.plan)
+			return f.plan.size
+		}
+		if m == ExactSizes {
+			// Fields account for their own bytes, leaving the padding
+			size = l.sizeof(v.Type())
+			for i := 0; i < v.NumField(); i++ {
+				size -= l.sizeof(v.Type().Field(i).Type)
+			}
+			return size
+		}
+		return l.header()
+
+	case reflect.Array:
+		// Elements are visited like those of a slice, only constant ones are folded
+		if elemPlan := planFor(v.Type().Elem(), l); elemPlan.constant {
+			if v.Len() > 0 {
+				w.rejectPlan(f, pathStep{kind: stepIndex}, elemPlan)
+			}
+			f.next = v.Len()
+			if m == LegacySizes {
+				return l.header() + uint64(v.Len())*elemPlan.size
+			}
+			return l.sizeof(v.Type())
+		}
+		// Elements account for their own bytes; legacy sizes count a header like for structs
+		if m == LegacySizes {
+			return l.header()
 		}
+		return 0
 
-		size = headerSize + bucketsSize + contentSize
-		debugPrint("%s: Map header(%d) + buckets(%d) + content(%d) = %d",
-			path, headerSize, bucketsSize, contentSize, size)
+	case reflect.Chan:
+		if m == LegacySizes {
+			break
+		}
+		size = l.sizeof(v.Type())
+		if v.IsNil() || !w.visitRef(f) {
+			return size
+		}
+		hchanSize := l.hchanSize()
+		bufSize := uint64(v.Cap()) * l.sizeof(v.Type().Elem())
+		if v.Cap() > 0 && !planFor(v.Type().Elem(), l).constant {
+			w.reject(f, v.Type(), unsupportedReason(reflect.Chan))
+		}
+		if hasPointers(v.Type().Elem()) {
+			// The runtime allocates buffers holding pointers separately
+			bufSize += w.cfg.slack(hchanSize, true) + w.cfg.slack(bufSize, true)
+		} else {
+			bufSize += w.cfg.slack(hchanSize+bufSize, false)
+		}
+		if w.cfg.debug {
+			w.debugPrint(f, "Channel pointer(%d) + header(%d) + buffer(%d) = %d",
+				size, hchanSize, bufSize, size+hchanSize+bufSize)
+		}
+		size += hchanSize + bufSize
 		return size
+	}
+
+	size = headerSize(v.Type(), l)
+	if w.cfg.debug {
+		w.debugPrint(f, "Basic type size %d", size)
+	}
+	if unsupportedKind(v.Kind(), m) {
+		w.reject(f, v.Type(), unsupportedReason(v.Kind()))
+	}
+	return size
+}
+
+// visitRef marks the memory referenced by a map or channel as visited, reporting whether
+// it was seen for the first time and has to be accounted to the frame
+func (w *walker) visitRef(f *frame) bool {
+	addr := uintptr(f.v.UnsafePointer())
+	seen := w.visitAddr(addr, f.v.Type()) || w.ids.visit(f.v)
+	f.addr, f.shared = addr, seen
+	if f.node != nil {
+		f.node.Addr = addr
+		f.node.Shared = seen
+	}
+	if seen {
+		if w.cfg.debug {
+			w.debugPrint(f, "Already seen %s %x", f.v.Kind(), addr)
+		}
+		return false
+	}
+	f.follow = true
+	return true
+}
+
+// nextChild returns the next child of the frame's value that still has to be visited
+func (w *walker) nextChild(f *frame) (reflect.Value, pathStep, bool) {
+	v := f.v
+	if !v.IsValid() || f.pruned {
+		return reflect.Value{}, pathStep{}, false
+	}
+	if f.custom {
+		if f.next >= len(f.handled) {
+			return reflect.Value{}, pathStep{}, false
+		}
+		c := f.handled[f.next]
+		f.next++
+		return c.v, suffixStep(c.suffix), true
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if !f.follow || f.next > 0 {
+			return reflect.Value{}, pathStep{}, false
+		}
+		f.next++
+		return v.Elem(), suffixStep(".elem"), true
+
+	case reflect.Ptr:
+		if !f.follow || f.next > 0 {
+			return reflect.Value{}, pathStep{}, false
+		}
+		f.next++
+		return v.Elem(), suffixStep(".ptr"), true
+
+	case reflect.Slice:
+		if w.par != nil && f.sample == nil {
+			w.par.offloadSlice(f)
+			v = f.v
+		}
+		f.sample.observe(f.size)
+		if f.next >= v.Len() {
+			return reflect.Value{}, pathStep{}, false
+		}
+		i := f.next
+		f.next++
+		if f.sample != nil {
+			f.sample.begin(f.size)
+			f.next += w.skip(f.sample)
+		}
+		return v.Index(i), pathStep{kind: stepIndex, index: i}, true
+
+	case reflect.Array:
+		if f.next >= v.Len() {
+			return reflect.Value{}, pathStep{}, false
+		}
+		i := f.next
+		f.next++
+		return v.Index(i), pathStep{kind: stepIndex, index: i}, true
+
+	case reflect.Map:
+		if w.par != nil && f.sample == nil {
+			w.par.offloadMap(w, f)
+		}
+		if f.iter == nil {
+			return reflect.Value{}, pathStep{}, false
+		}
+		for {
+			if f.pendingVal {
+				f.pendingVal = false
+				if !f.skipValues {
+					return w.mapEntry(f, false), pathStep{kind: stepMapValue}, true
+				}
+			}
+			f.sample.observe(f.size)
+			if !w.nextEntry(f) {
+				w.endRange(f)
+				return reflect.Value{}, pathStep{}, false
+			}
+			f.sample.begin(f.size)
+			f.pendingVal = true
+			if !f.skipKeys {
+				return w.mapEntry(f, true), pathStep{kind: stepMapKey}, true
+			}
+		}
 
 	case reflect.Struct:
-		structSize := uint64(unsafe.Sizeof(v.Interface()))
-		fieldsSize := uint64(0)
+		var i int
+		if f.plan != nil {
+			if f.next >= len(f.plan.fields) {
+				return reflect.Value{}, pathStep{}, false
+			}
+			i = f.plan.fields[f.next]
+		} else {
+			if f.next >= v.NumField() {
+				return reflect.Value{}, pathStep{}, false
+			}
+			i = f.next
+		}
+		f.next++
+		return v.Field(i), pathStep{kind: stepField, index: i}, true
+	}
+
+	return reflect.Value{}, pathStep{}, false
+}
 
-		for i := 0; i < v.NumField(); i++ {
-			field := v.Field(i)
-			fieldName := v.Type().Field(i).Name
-			fieldSize := getTotalSize(field, seen, fmt.Sprintf("%s.%s", path, fieldName))
-			fieldsSize += fieldSize
+// finish completes a frame once all of its children were visited and returns its total size
+func (w *walker) finish(f *frame) uint64 {
+	if f.sample != nil {
+		f.size += w.sampler.extrapolate(f.sample, f.size)
+	}
+	if w.scratch != nil {
+		w.endRange(f)
+		w.endEntries(f)
+	}
+	if f.node != nil {
+		f.node.Size = f.size
+	}
+	w.regions.finish(w, f)
+	if w.cfg.debugRecord != nil && w.cfg.debugPath(w.path(f)) {
+		w.cfg.debugRecord(w.path(f), f.v, f.shallow, f.size)
+	}
+	w.emit(EventExit, f)
+	if !f.v.IsValid() {
+		return f.size
+	}
+
+	if !w.cfg.debug {
+		return f.size
+	}
+	children := f.size - f.shallow
+	switch f.v.Kind() {
+	case reflect.Interface:
+		if f.follow {
+			w.debugPrint(f, "Interface elem size %d", children)
 		}
 
-		size = structSize + fieldsSize
-		debugPrint("%s: Struct size(%d) + fields(%d) = %d", path, structSize, fieldsSize, size)
-		return size
+	case reflect.Ptr:
+		if f.follow {
+			w.debugPrint(f, "Pointer to new address %x (size: %d) + elem (size: %d) = %d",
+				f.v.Pointer(), f.shallow, children, f.size)
+		}
 
-	default:
-		size = uint64(unsafe.Sizeof(v.Interface()))
-		debugPrint("%s: Basic type size %d", path, size)
-		return size
+	case reflect.Slice:
+		if !f.v.IsNil() && f.mapped == 0 {
+			headerSize, arraySize, inlineSize := sliceSizes(f.v, w.cfg)
+			w.debugPrint(f, "Slice header(%d) + array(%d) + elements(%d) = %d",
+				headerSize, arraySize, inlineSize+children, f.size)
+		}
+
+	case reflect.Map:
+		if !f.v.IsNil() && (w.cfg.model == LegacySizes || f.follow) {
+			headerSize, bucketsSize, inlineSize := mapSizes(f.v, w.cfg)
+			w.debugPrint(f, "Map header(%d) + buckets(%d) + content(%d) = %d",
+				headerSize, bucketsSize, inlineSize+children, f.size)
+		}
+
+	case reflect.Struct:
+		w.debugPrint(f, "Struct size(%d) + fields(%d) = %d", f.shallow, children, f.size)
+	}
+	return f.size
+}
+
+// sliceSizes returns the header, backing array and folded element sizes of a non-nil slice.
+// Elements without indirections all have the same size and are folded into the slice.
+// With ExactSizes the array only holds the unused capacity and size-class rounding, since
+// elements account for their own bytes.
+func sliceSizes(v reflect.Value, cfg *config) (header, array, inline uint64) {
+	l := cfg.layout()
+	elemSize := l.sizeof(v.Type().Elem())
+	header = headerSize(v.Type(), l)
+	capacity := v.Cap()
+	if l.model == ExactSizes {
+		capacity -= v.Len()
+		array = cfg.slack(uint64(v.Cap())*elemSize, hasPointers(v.Type().Elem()))
+	}
+	if capacity > 0 {
+		array += uint64(capacity) * elemSize
+	}
+	if p := planFor(v.Type().Elem(), l); p.constant {
+		inline = uint64(v.Len()) * p.size
+	}
+	return header, array, inline
+}
+
+// mapSizes returns the header, bucket and folded entry sizes of a non-nil map.
+// Keys and values without indirections are folded into the map instead of visited.
+func mapSizes(v reflect.Value, cfg *config) (header, buckets, inline uint64) {
+	l := cfg.layout()
+	if l.model == ExactSizes {
+		return exactMapSizes(v, cfg)
+	}
+	header = l.header()
+	overhead := l.overhead()
+	buckets = (uint64(v.Len())/overhead.MapBucketEntries + 1) * overhead.MapBucket
+
+	if p := planFor(v.Type().Key(), l); p.constant {
+		inline += uint64(v.Len()) * p.size
+	}
+	if p := planFor(v.Type().Elem(), l); p.constant {
+		inline += uint64(v.Len()) * p.size
 	}
+	return header, buckets, inline
 }
@@ -1,7 +1,10 @@
 package memsize
 
 import (
+	"bytes"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"unsafe"
 )
@@ -13,7 +16,7 @@ type Person struct {
 }
 
 // Adding structs with function pointers
-type Handler struct {
+type CallbackHandler struct {
 	OnSuccess func(data string) error
 	OnError   func(err error)
 	Process   func(input int) (output int)
@@ -21,7 +24,7 @@ type Handler struct {
 
 type ServiceWithCallbacks struct {
 	Name           string
-	Handlers       []Handler
+	Handlers       []CallbackHandler
 	DefaultHandler func(string) error
 }
 
@@ -53,7 +56,7 @@ func TestGetTotalSize(t *testing.T) {
 
 		// Test struct with function pointers
 		t.Run("Struct With Functions", func(t *testing.T) {
-			handler := Handler{
+			handler := CallbackHandler{
 				OnSuccess: successFn,
 				OnError:   errorFn,
 				Process:   processFn,
@@ -70,17 +73,17 @@ func TestGetTotalSize(t *testing.T) {
 		t.Run("Slice of Handlers", func(t *testing.T) {
 			service := ServiceWithCallbacks{
 				Name:           "TestService",
-				Handlers:       make([]Handler, 2),
+				Handlers:       make([]CallbackHandler, 2),
 				DefaultHandler: successFn,
 			}
 
-			service.Handlers[0] = Handler{
+			service.Handlers[0] = CallbackHandler{
 				OnSuccess: successFn,
 				OnError:   errorFn,
 				Process:   processFn,
 			}
 
-			service.Handlers[1] = Handler{
+			service.Handlers[1] = CallbackHandler{
 				OnSuccess: func(data string) error { return nil },
 				OnError:   func(err error) {},
 				Process:   func(input int) int { return input },
@@ -239,3 +242,304 @@ func TestComplexStructure(t *testing.T) {
 		t.Errorf("Size %d seems unreasonable for this structure", size)
 	}
 }
+
+type listNode struct {
+	Value int
+	Next  *listNode
+}
+
+func TestDeepLinkedList(t *testing.T) {
+	Debug = false
+
+	// A million nodes would overflow the stack of a recursive traversal; the race detector
+	// makes walking them take long, so it and short runs use fewer, and CI runs the full size
+	// in a separate step without it
+	n := 1000000
+	if testing.Short() || raceEnabled {
+		n = 10000
+	}
+	var head *listNode
+	for i := 0; i < n; i++ {
+		head = &listNode{Value: i, Next: head}
+	}
+
+	// Every element is a pointer (interface-sized) to a struct header plus its int value,
+	// and the list ends with a nil pointer
+	ptrSize := uint64(unsafe.Sizeof(interface{}(nil)))
+	element := ptrSize + ptrSize + uint64(unsafe.Sizeof(int(0)))
+	expected := uint64(n)*element + ptrSize
+
+	if size := GetTotalSize(head); size != expected {
+		t.Errorf("Expected %d bytes for a list of %d elements, got %d", expected, n, size)
+	}
+
+	stats := GetSizeByType(head)
+	if count := stats["memsize.listNode"].Count; count != uint64(n) {
+		t.Errorf("Expected %d list nodes, got %d", n, count)
+	}
+}
+
+type hidden struct {
+	name  string
+	data  []byte
+	next  *hidden
+	attrs map[string]interface{}
+}
+
+func TestUnexportedFields(t *testing.T) {
+	Debug = false
+
+	t.Run("Third Party Types", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Grow(1024)
+		buf.WriteString("hello")
+		var sb strings.Builder
+		sb.WriteString("hello")
+
+		// bytes.Buffer holds an unexported []byte, which has to be followed
+		if size := GetTotalSize(&buf); size < uint64(buf.Cap()) {
+			t.Errorf("Expected at least the %d byte buffer, got %d", buf.Cap(), size)
+		}
+		if size := GetTotalSize(&sb); size < uint64(sb.Cap()) {
+			t.Errorf("Expected at least the %d byte builder, got %d", sb.Cap(), size)
+		}
+	})
+
+	t.Run("All Kinds", func(t *testing.T) {
+		v := &hidden{
+			name:  "outer",
+			data:  make([]byte, 10),
+			next:  &hidden{name: "inner"},
+			attrs: map[string]interface{}{"key": &hidden{name: "attr"}},
+		}
+		size := GetTotalSize(v)
+		fmt.Printf("Unexported fields size: %d bytes\n", size)
+
+		// Every way of sizing the value has to read the unexported fields without panicking
+		if report := GetReport(v); report.Total() != size {
+			t.Errorf("Expected the report total %d to match %d", report.Total(), size)
+		}
+		if stats := GetSizeByType(v); stats["memsize.hidden"].Count != 3 {
+			t.Errorf("Expected 3 hidden structs, got %d", stats["memsize.hidden"].Count)
+		}
+		if parallel := GetTotalSize(v, WithParallelism(4)); parallel != size {
+			t.Errorf("Expected the parallel size to match %d, got %d", size, parallel)
+		}
+		if _, err := GetTotalSizeE(v, WithStrict()); err != nil {
+			t.Errorf("Expected no strict error, got %v", err)
+		}
+		if debug := GetTotalSize(v, WithDebug(true)); debug != size {
+			t.Errorf("Expected the debug size to match %d, got %d", size, debug)
+		}
+	})
+}
+
+func TestGetTotalSizeValue(t *testing.T) {
+	Debug = false
+
+	v := &hidden{name: "outer", next: &hidden{name: "inner"}}
+	if size := GetTotalSizeValue(reflect.ValueOf(v)); size != GetTotalSize(v) {
+		t.Errorf("Expected %d bytes like GetTotalSize, got %d", GetTotalSize(v), size)
+	}
+
+	// The value of an unexported field cannot be converted back to an interface
+	next := reflect.ValueOf(v).Elem().FieldByName("next")
+	if next.CanInterface() {
+		t.Fatal("Expected the unexported field not to be convertible to an interface")
+	}
+	if size := GetTotalSizeValue(next); size != GetTotalSize(v.next) {
+		t.Errorf("Expected %d bytes for the unexported field, got %d", GetTotalSize(v.next), size)
+	}
+
+	// The static type is kept, so interface headers are counted
+	var i interface{} = "abc"
+	if size := GetTotalSizeValue(reflect.ValueOf(&i).Elem()); size != SizeOf(i) {
+		t.Errorf("Expected %d bytes like SizeOf, got %d", SizeOf(i), size)
+	}
+}
+
+func TestVisitedKeys(t *testing.T) {
+	Debug = false
+
+	type outer struct {
+		Inner [64]byte
+		Other [32]byte
+	}
+	o := &outer{}
+	// The pointer to the first field comes first and shares the address of the struct
+	v := struct {
+		Field  *[64]byte
+		Struct *outer
+	}{&o.Inner, o}
+
+	opts := []Option{WithSizeModel(ExactSizes)}
+	size := GetTotalSize(v, opts...)
+	expected := GetTotalSize(v.Field, opts...) + GetTotalSize(v.Struct, opts...)
+	if size != expected {
+		t.Errorf("Expected the struct not to be merged with its first field: %d bytes, got %d", expected, size)
+	}
+	if parallel := GetTotalSize(v, append(opts, WithParallelism(4))...); parallel != size {
+		t.Errorf("Expected a parallel measurement to match %d, got %d", size, parallel)
+	}
+
+	// Pointers of the same type to the same object are still counted once
+	shared := struct{ A, B *outer }{o, o}
+	if size := GetTotalSize(shared, opts...); size != 8+GetTotalSize(o, opts...) {
+		t.Errorf("Expected the shared struct once, got %d", size)
+	}
+}
+
+func TestMapSharedValues(t *testing.T) {
+	Debug = false
+
+	type object struct {
+		Data [128]byte
+	}
+	type holder struct {
+		P *object
+	}
+	type private struct {
+		m map[string]interface{}
+	}
+	o := &object{}
+	var box interface{} = [16]int64{1}
+	opts := []Option{WithSizeModel(ExactSizes)}
+
+	// Every entry after the first refers to an object already counted
+	tests := []struct {
+		name   string
+		v      interface{}
+		shared int
+	}{
+		{"Pointer Values", map[string]*object{"a": o, "b": o, "c": o}, 2},
+		{"Struct Values", map[string]holder{"a": {o}, "b": {o}}, 1},
+		{"Boxed Values", map[string]interface{}{"a": box, "b": box}, 1},
+		{"Distinct Pointer Keys", map[*object]bool{o: true, {}: false}, 0},
+		{"Unexported Map", private{m: map[string]interface{}{"a": box, "b": box}}, 1},
+		{"Unexported Map Pointer", &private{m: map[string]interface{}{"a": box, "b": box}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := GetTotalSize(tt.v, opts...)
+			report := GetReport(tt.v, opts...)
+			var shared int
+			report.Walk(func(n *Node) bool {
+				if n.Shared {
+					shared++
+				}
+				return true
+			})
+			fmt.Printf("%s: %d bytes, %d shared references\n", tt.name, size, shared)
+			if shared != tt.shared {
+				t.Errorf("Expected %d shared references, got %d", tt.shared, shared)
+			}
+			if report.Total() != size {
+				t.Errorf("Expected the report total %d to match %d", report.Total(), size)
+			}
+		})
+	}
+}
+
+func TestMapPointerKeys(t *testing.T) {
+	Debug = false
+
+	type object struct {
+		Data [128]byte
+	}
+	type key struct {
+		P    *object
+		Name string
+	}
+	o := &object{}
+	keys := map[key]int{{o, "a"}: 1, {o, "b"}: 2}
+	values := map[key]*object{{o, "a"}: o}
+	boxed := map[interface{}]int{key{o, "a"}: 1, o: 2}
+
+	for _, opts := range [][]Option{
+		{WithSizeModel(ExactSizes)},
+		{WithSizeModel(ExactSizes), WithOrder(PathOrder)},
+		{WithSizeModel(ExactSizes), WithParallelism(4)},
+		nil,
+	} {
+		model, ptrSize := "exact", uint64(8)
+		if len(opts) == 0 {
+			model, ptrSize = "legacy", 16
+		}
+
+		t.Run("Shared Keys "+model, func(t *testing.T) {
+			for _, m := range []interface{}{keys, values, boxed} {
+				size := GetTotalSize(m, opts...)
+				// The object is counted by the map, the other reference only counts itself
+				before := GetTotalSize(struct {
+					P *object
+					M interface{}
+				}{o, m}, opts...)
+				after := GetTotalSize(struct {
+					M interface{}
+					P *object
+				}{m, o}, opts...)
+				fmt.Printf("%T: %d bytes, %d and %d with another reference\n", m, size, before, after)
+				if before != after {
+					t.Errorf("Expected the same size whichever reference comes first, got %d and %d", before, after)
+				}
+				if alone := GetTotalSize(struct{ M interface{} }{m}, opts...); after != alone+ptrSize {
+					t.Errorf("Expected %d, got %d", alone+ptrSize, after)
+				}
+			}
+		})
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		report := GetReport(values, WithSizeModel(ExactSizes), WithOrder(PathOrder))
+		var owners, shared int
+		report.Walk(func(n *Node) bool {
+			if n.Addr == uintptr(unsafe.Pointer(o)) {
+				if n.Shared {
+					shared++
+				} else {
+					owners++
+				}
+			}
+			return true
+		})
+		if owners != 1 || shared != 1 {
+			t.Errorf("Expected one owner and one shared reference of the object, got %d and %d", owners, shared)
+		}
+	})
+}
+
+func TestArrays(t *testing.T) {
+	Debug = false
+
+	type object struct {
+		Data [100]byte
+	}
+	o := &object{}
+	objectSize := GetTotalSize(*o)
+
+	// Arrays count a header like structs, plus their elements
+	tests := []struct {
+		name     string
+		v        interface{}
+		expected uint64
+	}{
+		{"Constant Elements", [4]int64{1, 2, 3, 4}, 16 + 4*8},
+		{"Pointers", [2]*object{{}, {}}, 16 + 2*(16+objectSize)},
+		{"Shared Pointees", [3]*object{o, o, nil}, 16 + 3*16 + objectSize},
+		{"Strings", [2]string{"a", "bb"}, 16 + 2*16 + 3},
+		{"Nested", [2][2]*object{{o, nil}, {o, nil}}, 16 + 2*(16+2*16) + objectSize},
+		{"Empty", [0]*object{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := GetTotalSize(tt.v)
+			fmt.Printf("%s: %d bytes\n", tt.name, size)
+			if size != tt.expected {
+				t.Errorf("Expected %d bytes, got %d", tt.expected, size)
+			}
+			if report := GetReport(tt.v); report.Total() != size {
+				t.Errorf("Expected the report total %d to match %d", report.Total(), size)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// collector.go
+package memsizeprom
+
+import (
+	"github.com/afshin-deriv/go-memsize"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector exposes the memory size of registered roots as Prometheus gauges
+type Collector struct {
+	desc *prometheus.Desc
+
+	// sizer holds the roots measured on scrapes
+	sizer *memsize.CachedSizer
+}
+
+// NewCollector creates a collector exporting memsize_object_bytes{root="<name>"} for the
+// roots registered with it
+func NewCollector() *Collector {
+	return NewRegistryCollector(memsize.NewRegistry())
+}
+
+// NewRegistryCollector creates a collector exporting the roots of a Registry shared with other
+// exporters, such as memsize.DefaultRegistry, measured on every scrape
+func NewRegistryCollector(r *memsize.Registry) *Collector {
+	return NewCachedCollector(memsize.NewRegistrySizer(r, -1))
+}
+
+// NewCachedCollector creates a collector exporting the roots of a CachedSizer, so scrapes
+// reuse sizes until they expire or are invalidated instead of traversing every root
+func NewCachedCollector(sizer *memsize.CachedSizer) *Collector {
+	return &Collector{
+		desc: prometheus.NewDesc(
+			"memsize_object_bytes",
+			"Total memory size of a registered object including indirect allocations.",
+			[]string{"root"}, nil,
+		),
+		sizer: sizer,
+	}
+}
+
+// Register adds a root that is measured on every scrape under the given name, or when its
+// cached size is stale for a collector created by NewCachedCollector
+func (c *Collector) Register(name string, root interface{}) {
+	c.sizer.Register(name, root)
+}
+
+// Unregister removes a previously registered root
+func (c *Collector) Unregister(name string) {
+	c.sizer.Unregister(name)
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, name := range c.sizer.Names() {
+		if size, ok := c.sizer.Size(name); ok {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(size), name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package memsizeprom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afshin-deriv/go-memsize"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	sessionCache := map[string]string{"a": "session-a", "b": "session-b"}
+
+	c := NewCollector()
+	c.Register("sessionCache", &sessionCache)
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Failed to register collector: %v", err)
+	}
+
+	t.Run("Gauge Value", func(t *testing.T) {
+		expected := memsize.GetTotalSize(&sessionCache)
+		got := testutil.ToFloat64(c)
+		if uint64(got) != expected {
+			t.Errorf("Expected gauge value %d, got %v", expected, got)
+		}
+	})
+
+	t.Run("Lint", func(t *testing.T) {
+		problems, err := testutil.CollectAndLint(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range problems {
+			t.Errorf("Lint problem: %s", p.Text)
+		}
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		c.Register("other", []int{1, 2, 3})
+
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var roots []string
+		for _, mf := range families {
+			if mf.GetName() != "memsize_object_bytes" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "root" {
+						roots = append(roots, l.GetValue())
+					}
+				}
+			}
+		}
+
+		if strings.Join(roots, ",") != "other,sessionCache" {
+			t.Errorf("Expected roots other,sessionCache, got %v", roots)
+		}
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		c.Unregister("sessionCache")
+		c.Unregister("other")
+		if count := testutil.CollectAndCount(c); count != 0 {
+			t.Errorf("Expected no series after unregister, got %d", count)
+		}
+	})
+}
+
+func TestCachedCollector(t *testing.T) {
+	sessionCache := map[string]string{"a": "session-a"}
+
+	sizer := memsize.NewCachedSizer(time.Hour)
+	c := NewCachedCollector(sizer)
+	c.Register("sessionCache", &sessionCache)
+
+	expected := memsize.GetTotalSize(&sessionCache)
+	if got := testutil.ToFloat64(c); uint64(got) != expected {
+		t.Errorf("Expected gauge value %d, got %v", expected, got)
+	}
+
+	sessionCache["b"] = "session-b"
+	if got := testutil.ToFloat64(c); uint64(got) != expected {
+		t.Errorf("Expected the cached value %d, got %v", expected, got)
+	}
+
+	sizer.Invalidate("sessionCache")
+	if got, expected := testutil.ToFloat64(c), memsize.GetTotalSize(&sessionCache); uint64(got) != expected {
+		t.Errorf("Expected the recomputed value %d, got %v", expected, got)
+	}
+
+	c.Unregister("sessionCache")
+	if count := testutil.CollectAndCount(c); count != 0 {
+		t.Errorf("Expected no series after unregister, got %d", count)
+	}
+}
+
+func TestRegistryCollector(t *testing.T) {
+	sessionCache := map[string]string{"a": "session-a"}
+
+	r := memsize.NewRegistry()
+	r.Register("sessionCache", &sessionCache)
+	c := NewRegistryCollector(r)
+
+	if got, expected := testutil.ToFloat64(c), memsize.GetTotalSize(&sessionCache); uint64(got) != expected {
+		t.Errorf("Expected gauge value %d, got %v", expected, got)
+	}
+
+	// Roots registered with the collector are shared with the registry's other exporters
+	c.Unregister("sessionCache")
+	if roots := r.Roots(); len(roots) != 0 {
+		t.Errorf("Expected the registry to be empty, got %v", roots)
+	}
+}
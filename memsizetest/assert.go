@@ -0,0 +1,71 @@
+// assert.go
+package memsizetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+// TopContributors is the number of contributors listed when an assertion fails
+var TopContributors = 10
+
+// AssertMaxSize fails the test unless the total size of v is at most max bytes.
+// It reports whether the assertion held.
+func AssertMaxSize(t testing.TB, v interface{}, max uint64, opts ...memsize.Option) bool {
+	t.Helper()
+	report := memsize.GetReport(v, opts...)
+	if size := report.Total(); size > max {
+		t.Errorf("memory size %s (%d bytes) exceeds the budget of %s (%d bytes)\n%s",
+			memsize.Format(size), size, memsize.Format(max), max, contributors(report))
+		return false
+	}
+	return true
+}
+
+// AssertWithinDelta fails the test unless the total size of v differs from expected by at most
+// the fraction delta of expected, e.g. 0.05 for 5%. It reports whether the assertion held.
+func AssertWithinDelta(t testing.TB, v interface{}, expected uint64, delta float64, opts ...memsize.Option) bool {
+	t.Helper()
+	report := memsize.GetReport(v, opts...)
+	size := report.Total()
+	diff := float64(size) - float64(expected)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > delta*float64(expected) {
+		t.Errorf("memory size %s (%d bytes) is not within %.1f%% of %s (%d bytes)\n%s",
+			memsize.Format(size), size, 100*delta, memsize.Format(expected), expected, contributors(report))
+		return false
+	}
+	return true
+}
+
+// AssertTrustworthy fails the test if memsize.Check finds constructs in v whose size it can't
+// vouch for, listing them. It reports whether the assertion held.
+func AssertTrustworthy(t testing.TB, v interface{}, opts ...memsize.Option) bool {
+	t.Helper()
+	warnings := memsize.Check(v, opts...)
+	if len(warnings) == 0 {
+		return true
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "memory size is not trustworthy, %d warnings:", len(warnings))
+	for _, w := range warnings {
+		fmt.Fprintf(&b, "\n  %s", w)
+	}
+	t.Errorf("%s", b.String())
+	return false
+}
+
+// contributors lists the largest paths of a report
+func contributors(report *memsize.Report) string {
+	var b strings.Builder
+	b.WriteString("top contributors:")
+	for _, c := range report.TopContributors(TopContributors) {
+		fmt.Fprintf(&b, "\n  %s (%s) %s", c.Path, c.Type, memsize.Format(c.Size))
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package memsizetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+// recorder captures the failures of an assertion instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertMaxSize(t *testing.T) {
+	v := struct {
+		Small []byte
+		Large []byte
+	}{Small: make([]byte, 10), Large: make([]byte, 1<<20)}
+
+	if !AssertMaxSize(t, v, 2<<20, memsize.WithSizeModel(memsize.ExactSizes)) {
+		t.Errorf("Expected the value to fit in 2 MiB")
+	}
+
+	r := &recorder{TB: t}
+	if AssertMaxSize(r, v, 1<<20, memsize.WithSizeModel(memsize.ExactSizes)) {
+		t.Errorf("Expected the value to exceed 1 MiB")
+	}
+	if len(r.errors) != 1 {
+		t.Fatalf("Expected 1 failure, got %d", len(r.errors))
+	}
+	fmt.Println(r.errors[0])
+	lines := strings.Split(r.errors[0], "\n")
+	if len(lines) < 3 || !strings.HasPrefix(lines[2], "  root.Large ([]uint8)") {
+		t.Errorf("Expected Large to be the top contributor, got %q", r.errors[0])
+	}
+}
+
+func TestAssertWithinDelta(t *testing.T) {
+	v := make([]byte, 1000)
+	size := memsize.GetTotalSize(v)
+
+	tests := []struct {
+		name     string
+		expected uint64
+		delta    float64
+		ok       bool
+	}{
+		{"Exact", size, 0, true},
+		{"Within", size * 104 / 100, 0.05, true},
+		{"Above", size * 110 / 100, 0.05, false},
+		{"Below", size * 90 / 100, 0.05, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			if ok := AssertWithinDelta(r, v, tt.expected, tt.delta); ok != tt.ok {
+				t.Errorf("Expected %v, got %v: %v", tt.ok, ok, r.errors)
+			}
+			if tt.ok != (len(r.errors) == 0) {
+				t.Errorf("Expected failures to match the result, got %v", r.errors)
+			}
+		})
+	}
+}
+
+func TestAssertTrustworthy(t *testing.T) {
+	type Job struct {
+		Name string
+		Run  func()
+	}
+
+	if !AssertTrustworthy(t, map[string][]int{"a": {1, 2, 3}}) {
+		t.Errorf("Expected plain data to be trustworthy")
+	}
+
+	r := &recorder{TB: t}
+	if AssertTrustworthy(r, []Job{{Name: "a", Run: func() {}}}) {
+		t.Errorf("Expected a func field not to be trustworthy")
+	}
+	if len(r.errors) != 1 {
+		t.Fatalf("Expected 1 failure, got %d", len(r.errors))
+	}
+	fmt.Println(r.errors[0])
+	if !strings.Contains(r.errors[0], "root[0].Run (func())") {
+		t.Errorf("Expected the func field to be listed, got %q", r.errors[0])
+	}
+}
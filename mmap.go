@@ -0,0 +1,56 @@
+// mmap.go
+package memsize
+
+import (
+	"reflect"
+	"sort"
+)
+
+// memRegion is a range of addresses outside the Go heap, [start, end)
+type memRegion struct {
+	start, end uintptr
+}
+
+// WithMappedMemory declares the backing arrays of the given slices, e.g. obtained from
+// syscall.Mmap, as mapped memory. Slices whose data lies within any of them are not counted
+// as heap memory; reports track their capacity in Node.Mapped and Report.MappedBytes.
+func WithMappedMemory(regions ...[]byte) Option {
+	return func(c *config) {
+		for _, r := range regions {
+			if cap(r) > 0 {
+				start := uintptr(reflect.ValueOf(r).UnsafePointer())
+				c.mapped = append(c.mapped, memRegion{start, start + uintptr(cap(r))})
+			}
+		}
+	}
+}
+
+// WithMmapDetection finds the file-backed memory mappings of the process before measuring, so
+// slices of mmap'd files are reported as mapped memory like with WithMappedMemory. Detection
+// reads /proc/self/maps and is only supported on Linux; elsewhere the option has no effect.
+func WithMmapDetection() Option {
+	return func(c *config) {
+		c.detectMmap = true
+	}
+}
+
+// mappedRegions returns the sorted regions of mapped memory of a measurement
+func mappedRegions(cfg *config) []memRegion {
+	regions := cfg.mapped
+	if cfg.detectMmap {
+		regions = append(regions[:len(regions):len(regions)], fileMappings()...)
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].start < regions[j].start })
+	return regions
+}
+
+// isMapped reports whether the backing array of the non-nil slice v lies in mapped memory
+func (w *walker) isMapped(v reflect.Value) bool {
+	regions := w.cfg.mappedRegions
+	if len(regions) == 0 || v.Cap() == 0 {
+		return false
+	}
+	addr := uintptr(v.UnsafePointer())
+	i := sort.Search(len(regions), func(i int) bool { return regions[i].end > addr })
+	return i < len(regions) && regions[i].start <= addr
+}
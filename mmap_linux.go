@@ -0,0 +1,38 @@
+// mmap_linux.go
+package memsize
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// fileMappings returns the file-backed mappings listed in /proc/self/maps. Lines read
+// "start-end perms offset dev inode path"; anonymous mappings, including the Go heap, have
+// inode 0.
+func fileMappings() []memRegion {
+	data, err := os.ReadFile("/proc/self/maps")
+	if err != nil {
+		return nil
+	}
+
+	var regions []memRegion
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := bytes.Fields(sc.Bytes())
+		if len(fields) < 6 || string(fields[4]) == "0" {
+			continue
+		}
+		bounds := bytes.SplitN(fields[0], []byte("-"), 2)
+		if len(bounds) != 2 {
+			continue
+		}
+		start, err1 := strconv.ParseUint(string(bounds[0]), 16, 64)
+		end, err2 := strconv.ParseUint(string(bounds[1]), 16, 64)
+		if err1 == nil && err2 == nil {
+			regions = append(regions, memRegion{uintptr(start), uintptr(end)})
+		}
+	}
+	return regions
+}
@@ -0,0 +1,36 @@
+package memsize
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestMmapDetection(t *testing.T) {
+	Debug = false
+
+	f, err := os.CreateTemp(t.TempDir(), "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, 1<<20, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		t.Skip("mmap is not available:", err)
+	}
+	defer syscall.Munmap(data)
+
+	v := &struct{ Data, Heap []byte }{data, make([]byte, 1000)}
+	if size := GetTotalSize(v, WithSizeModel(ExactSizes)); size < 1<<20 {
+		t.Errorf("Expected mapped memory to be counted without detection, got %d", size)
+	}
+	if size := GetTotalSize(v, WithSizeModel(ExactSizes), WithMmapDetection()); size != 8+2*24+1000 {
+		t.Errorf("Expected the mapped file to be left out, got %d", size)
+	}
+	if report := GetReport(v, WithMmapDetection()); report.MappedBytes != 1<<20 {
+		t.Errorf("Expected %d mapped bytes, got %d", 1<<20, report.MappedBytes)
+	}
+}
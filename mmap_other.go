@@ -0,0 +1,9 @@
+//go:build !linux
+
+// mmap_other.go
+package memsize
+
+// fileMappings is only supported on Linux
+func fileMappings() []memRegion {
+	return nil
+}
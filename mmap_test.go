@@ -0,0 +1,28 @@
+package memsize
+
+import "testing"
+
+func TestMappedMemory(t *testing.T) {
+	Debug = false
+
+	// Any slice can be declared as mapped; a region covers subslices of it too
+	region := make([]byte, 1<<16)
+	v := &struct {
+		Index  []byte
+		Window []byte
+		Heap   []byte
+	}{region, region[1024:2048], make([]byte, 100)}
+
+	opts := []Option{WithSizeModel(ExactSizes), WithMappedMemory(region)}
+	if size := GetTotalSize(v, opts...); size != 8+3*24+100 {
+		t.Errorf("Expected only the headers of mapped slices to be counted, got %d", size)
+	}
+
+	report := GetReport(v, opts...)
+	if report.MappedBytes != 1<<16+(1<<16-1024) {
+		t.Errorf("Expected the capacities of both mapped slices, got %d", report.MappedBytes)
+	}
+	if n := report.Root.Children[0].Children[0]; n.Mapped != 1<<16 || n.Size != 24 {
+		t.Errorf("Expected the index to be mapped, got %d mapped and %d bytes", n.Mapped, n.Size)
+	}
+}
@@ -0,0 +1,139 @@
+// model.go
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Model holds the overheads the size models assume for memory the runtime manages out of sight
+// of reflection. DefaultModel returns those of the runtime memsize follows; WithModel measures
+// with adjusted ones, and reports carry the model they were measured with, so results can be
+// reproduced.
+type Model struct {
+	// Header is what LegacySizes counts for every pointer, slice, string, interface, channel
+	// and func header, and on top of the fields or elements of structs and arrays. ExactSizes
+	// sizes headers from the layout of their types instead.
+	Header uint64 `json:"header"`
+	// MapBucket is what LegacySizes counts for every MapBucketEntries entries of a map, plus
+	// one bucket
+	MapBucket uint64 `json:"mapBucket"`
+	// MapBucketEntries is the number of slots of a map bucket
+	MapBucketEntries uint64 `json:"mapBucketEntries"`
+	// MapLoadFactor is the average number of entries per bucket before a map grows, which
+	// tells the buckets a map needs for its entries
+	MapLoadFactor float64 `json:"mapLoadFactor"`
+	// MapMaxInline is the largest key or value stored in a bucket instead of separately
+	MapMaxInline uint64 `json:"mapMaxInline"`
+	// MapHeaderWords and MapHeaderBytes size the runtime's map header with ExactSizes: words
+	// of the target architecture plus bytes of counters and flags
+	MapHeaderWords uint64 `json:"mapHeaderWords"`
+	MapHeaderBytes uint64 `json:"mapHeaderBytes"`
+	// ChanHeaderWords sizes the runtime's channel header with ExactSizes, in words
+	ChanHeaderWords uint64 `json:"chanHeaderWords"`
+	// SizeClasses are the object sizes of the small-object allocator in ascending order and
+	// SizeClassPages the pages of a span of each, see WithSizeClasses and WithGCOverhead
+	SizeClasses    []uint64 `json:"sizeClasses"`
+	SizeClassPages []uint64 `json:"sizeClassPages"`
+	// PageSize is the granularity of spans and of allocations larger than the largest class
+	PageSize uint64 `json:"pageSize"`
+	// SpanOverhead is the runtime's bookkeeping structure of every span, see WithGCOverhead
+	SpanOverhead uint64 `json:"spanOverhead"`
+}
+
+// defaultModel is used by measurements without WithModel and must not be modified
+var defaultModel = DefaultModel()
+
+// models interns the models given with WithModel by their contents, so layouts, which key the
+// cached plans, stay the same for equal models
+var models sync.Map
+
+// DefaultModel returns a copy of the model measurements use unless WithModel is given
+func DefaultModel() *Model {
+	return &Model{
+		Header:           valueHeaderSize,
+		MapBucket:        48,
+		MapBucketEntries: 8,
+		MapLoadFactor:    6.5,
+		MapMaxInline:     128,
+		MapHeaderWords:   5,
+		MapHeaderBytes:   8,
+		ChanHeaderWords:  12,
+		SizeClasses:      append([]uint64(nil), sizeClasses[:]...),
+		SizeClassPages:   append([]uint64(nil), sizeClassPages[:]...),
+		PageSize:         8192,
+		SpanOverhead:     160,
+	}
+}
+
+// WithModel measures with the overheads of m instead of DefaultModel. m is copied, so changing
+// it later doesn't affect measurements already configured. An invalid model makes
+// GetTotalSizeE fail.
+func WithModel(m *Model) Option {
+	return func(c *config) {
+		if err := m.validate(); err != nil {
+			c.err = fmt.Errorf("memsize: invalid model: %w", err)
+			return
+		}
+		c.overheads = intern(m)
+	}
+}
+
+// intern returns the shared copy of models with the contents of m, nil for DefaultModel
+func intern(m *Model) *Model {
+	key := fmt.Sprint(*m)
+	if key == fmt.Sprint(*defaultModel) {
+		return nil
+	}
+	if shared, ok := models.Load(key); ok {
+		return shared.(*Model)
+	}
+	shared, _ := models.LoadOrStore(key, m.clone())
+	return shared.(*Model)
+}
+
+// clone returns a deep copy of m
+func (m *Model) clone() *Model {
+	c := *m
+	c.SizeClasses = append([]uint64(nil), m.SizeClasses...)
+	c.SizeClassPages = append([]uint64(nil), m.SizeClassPages...)
+	return &c
+}
+
+func (m *Model) validate() error {
+	switch {
+	case m == nil:
+		return errors.New("model is nil")
+	case m.MapBucketEntries == 0:
+		return errors.New("map buckets have no entries")
+	case m.MapLoadFactor <= 0:
+		return errors.New("map load factor is not positive")
+	case m.PageSize == 0:
+		return errors.New("page size is zero")
+	case len(m.SizeClasses) == 0 || len(m.SizeClasses) != len(m.SizeClassPages):
+		return fmt.Errorf("%d size classes with %d page counts", len(m.SizeClasses), len(m.SizeClassPages))
+	}
+	for i, size := range m.SizeClasses {
+		if size == 0 || i > 0 && size <= m.SizeClasses[i-1] {
+			return fmt.Errorf("size class %d of %d bytes is out of order", i, size)
+		}
+		if m.SizeClassPages[i] == 0 {
+			return fmt.Errorf("size class %d has no pages", i)
+		}
+	}
+	return nil
+}
+
+// overhead returns the model of the layout
+func (l layout) overhead() *Model {
+	if l.overheads == nil {
+		return defaultModel
+	}
+	return l.overheads
+}
+
+// header returns what LegacySizes counts for a header
+func (l layout) header() uint64 {
+	return l.overhead().Header
+}
@@ -0,0 +1,118 @@
+package memsize
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestModel(t *testing.T) {
+	Debug = false
+
+	t.Run("Default", func(t *testing.T) {
+		m := DefaultModel()
+		if m.Header != 16 || m.MapBucket != 48 || len(m.SizeClasses) != len(sizeClasses) {
+			t.Errorf("Expected the default overheads, got %+v", m)
+		}
+		m.SizeClasses[0] = 1
+		if defaultModel.SizeClasses[0] != 8 {
+			t.Error("Expected DefaultModel to return a copy")
+		}
+
+		v := map[string][]int{"a": {1, 2}, "b": nil}
+		for _, opts := range [][]Option{nil, {WithSizeModel(ExactSizes)}, {WithGCOverhead()}} {
+			if got, want := GetTotalSize(v, append(opts, WithModel(DefaultModel()))...), GetTotalSize(v, opts...); got != want {
+				t.Errorf("Expected %d with the default model, got %d", want, got)
+			}
+		}
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		n := 42
+		m := DefaultModel()
+		m.Header = 8
+		if got := GetTotalSize(&n, WithModel(m)); got != 8+8 {
+			t.Errorf("Expected a pointer header of 8 bytes, got %d", got-8)
+		}
+
+		items := map[int]bool{1: true, 2: true}
+		m = DefaultModel()
+		m.MapBucket = 100
+		if diff := GetTotalSize(items, WithModel(m)) - GetTotalSize(items); diff != 100-48 {
+			t.Errorf("Expected one bucket of 100 bytes instead of 48, got %d more", diff)
+		}
+
+		m = DefaultModel()
+		m.SizeClasses, m.SizeClassPages = []uint64{64, 128}, []uint64{1, 1}
+		exact := WithSizeModel(ExactSizes)
+		s := "a string of 33 bytes, rounded up"
+		if got := GetTotalSize(s, exact, WithSizeClasses(), WithModel(m)); got != 16+64 {
+			t.Errorf("Expected the string to fill a class of 64 bytes, got %d", got-16)
+		}
+
+		m = DefaultModel()
+		m.ChanHeaderWords = 0
+		ch := make(chan int)
+		if got := GetTotalSize(ch, exact, WithModel(m)); got != GetTotalSize(ch, exact)-12*8 {
+			t.Errorf("Expected no channel header, got %d", got)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		m := DefaultModel()
+		m.SizeClasses[1] = m.SizeClasses[0]
+		for _, m := range []*Model{nil, {}, m} {
+			if _, err := GetTotalSizeE(1, WithModel(m)); err == nil {
+				t.Errorf("Expected an error for %+v", m)
+			}
+		}
+	})
+
+	t.Run("Reports", func(t *testing.T) {
+		m := DefaultModel()
+		m.MapBucket = 64
+		r := GetReport(map[string]int{"a": 1}, WithModel(m))
+		if !reflect.DeepEqual(r.Model, m) {
+			t.Fatalf("Expected the report to carry its model, got %+v", r.Model)
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Report
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded.Model, m) {
+			t.Errorf("Expected the model to survive JSON, got %+v", decoded.Model)
+		}
+		if r := GetReport(1); r.Model == nil || r.Model.MapBucket != 48 {
+			t.Errorf("Expected reports to carry the default model, got %+v", r.Model)
+		}
+
+		items := map[int]bool{1: true}
+		want := GetTotalSize(items)
+		GetReport(items).Model.MapBucket = 1000
+		if got := GetTotalSize(items); got != want {
+			t.Errorf("Expected changes to a report's model not to affect measurements, got %d instead of %d", got, want)
+		}
+	})
+
+	t.Run("Interned", func(t *testing.T) {
+		if l := newConfig([]Option{WithModel(DefaultModel())}).layout(); l.overheads != nil {
+			t.Errorf("Expected the default model to share the default layout, got %+v", l.overheads)
+		}
+		m := DefaultModel()
+		m.Header = 8
+		first := newConfig([]Option{WithModel(m)}).layout()
+		m.Header = 24
+		m = DefaultModel()
+		m.Header = 8
+		if second := newConfig([]Option{WithModel(m)}).layout(); first != second {
+			t.Error("Expected equal models to share a layout and its cached plans")
+		}
+		if first.header() != 8 {
+			t.Errorf("Expected WithModel to copy the model, got a header of %d", first.header())
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// multi.go
+package memsize
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// MultiReport is the size of several roots measured together
+type MultiReport struct {
+	// Total counts every object reachable from any of the roots once
+	Total uint64
+	// Shared is the part of Total reachable from more than one root
+	Shared uint64
+	// Roots lists the roots ordered by name
+	Roots []RootSize
+	// Truncated is set when a limit stopped the traversal early; sizes are then lower bounds
+	Truncated bool
+}
+
+// RootSize is the size of a single root of a MultiReport
+type RootSize struct {
+	Name string
+	// Size is the total size of the root on its own, as GetTotalSize would report it
+	Size uint64
+	// Exclusive is the part of Size not reachable from any other root, which is what
+	// dropping the root would free
+	Exclusive uint64
+}
+
+// GetTotalSizeMulti measures several roots with a single visited set, so objects shared
+// between them are counted once. Summing GetTotalSize over the roots instead counts shared
+// objects once per root referencing them.
+func GetTotalSizeMulti(roots map[string]interface{}, opts ...Option) MultiReport {
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]reflect.Value, len(names))
+	for i, name := range names {
+		values[i] = reflect.ValueOf(roots[name])
+	}
+	return measureRoots(names, values, opts)
+}
+
+// measureRoots measures the values as roots with the given names, listed in the same order
+func measureRoots(names []string, values []reflect.Value, opts []Option) MultiReport {
+	w := newWalker(opts...)
+	defer w.release()
+	if w.cfg.err != nil {
+		return MultiReport{}
+	}
+	w.tree = true
+	if w.cfg.gc {
+		runtime.GC()
+	}
+
+	// The roots become children of a node standing for all of them, so the dominator tree
+	// tells which bytes each root retains on its own
+	top := &Node{}
+	for i, name := range names {
+		top.Size += w.getTotalSize(values[i], name)
+		top.Children = append(top.Children, w.root)
+	}
+	w.progress.done()
+	g := computeRetained(top)
+
+	r := MultiReport{Total: top.Size, Shared: top.Size, Truncated: w.budget.err() != nil}
+	for _, n := range top.Children {
+		exclusive := n.Retained
+		r.Shared -= exclusive
+		r.Roots = append(r.Roots, RootSize{Name: n.Path, Size: g.reachable(g.ids[n]), Exclusive: exclusive})
+	}
+	return r
+}
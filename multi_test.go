@@ -0,0 +1,46 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetTotalSizeMulti(t *testing.T) {
+	Debug = false
+
+	interned := &blob{Data: make([]byte, 1000)}
+	byID := map[int]*blob{1: interned, 2: {Data: make([]byte, 100)}}
+	byName := map[string]*blob{"interned": interned}
+
+	r := GetTotalSizeMulti(map[string]interface{}{"byID": byID, "byName": byName})
+	fmt.Printf("Multi: %+v\n", r)
+
+	sum := GetTotalSize(byID) + GetTotalSize(byName)
+	if r.Total >= sum {
+		t.Errorf("Expected the total %d to count the interned blob once, unlike the sum %d", r.Total, sum)
+	}
+	if len(r.Roots) != 2 || r.Roots[0].Name != "byID" || r.Roots[1].Name != "byName" {
+		t.Fatalf("Expected the roots ordered by name, got %+v", r.Roots)
+	}
+
+	for _, root := range r.Roots {
+		if root.Exclusive >= root.Size {
+			t.Errorf("%s: expected the exclusive size %d to leave out the interned blob of %d",
+				root.Name, root.Exclusive, root.Size)
+		}
+	}
+	if size := GetTotalSize(byName); r.Roots[1].Size != size {
+		t.Errorf("Expected byName to have its own size %d, got %d", size, r.Roots[1].Size)
+	}
+	if shared := r.Total - r.Roots[0].Exclusive - r.Roots[1].Exclusive; r.Shared != shared || shared < 1000 {
+		t.Errorf("Expected at least the interned blob to be shared, got %d", r.Shared)
+	}
+
+	t.Run("Disjoint", func(t *testing.T) {
+		a, b := []string{"a"}, []string{"b"}
+		r := GetTotalSizeMulti(map[string]interface{}{"a": a, "b": b})
+		if r.Shared != 0 || r.Total != GetTotalSize(a)+GetTotalSize(b) {
+			t.Errorf("Expected disjoint roots to add up without sharing, got %+v", r)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+// offheap.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// offHeapSizers holds a func(unsafe.Pointer) uint64 per type registered with RegisterOffHeap
+var offHeapSizers sync.Map
+
+// RegisterOffHeap registers a function reporting the memory a value of type T holds outside the
+// Go heap, such as a buffer allocated with C.malloc. Reports track it apart from Go memory in
+// Node.OffHeap and Report.OffHeapBytes. Off-heap memory is counted once per T value, so values
+// copied by value that share a foreign allocation count it once each.
+func RegisterOffHeap[T any](size func(*T) uint64) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	offHeapSizers.Store(t, func(p unsafe.Pointer) uint64 {
+		return size((*T)(p))
+	})
+	// Plans computed before may have folded the type as a constant
+	resetPlans()
+}
+
+// offHeap returns the off-heap size reported for the frame's value, if its type was registered
+func (w *walker) offHeap(f *frame) uint64 {
+	fn, ok := offHeapSizers.Load(f.v.Type())
+	if !ok {
+		return 0
+	}
+	p, ok := pointerTo(f.v)
+	if !ok {
+		w.reject(f, f.v.Type(),
+			"off-heap sizes of unexported fields reached by value cannot be reported")
+		return 0
+	}
+	return fn.(func(unsafe.Pointer) uint64)(p)
+}
@@ -0,0 +1,56 @@
+package memsize
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// cBuffer stands in for a wrapper around memory allocated in C
+type cBuffer struct {
+	handle uintptr
+	size   int
+}
+
+func init() {
+	RegisterOffHeap(func(b *cBuffer) uint64 {
+		return uint64(b.size)
+	})
+}
+
+func TestOffHeap(t *testing.T) {
+	Debug = false
+
+	entries := map[string]*cBuffer{"a": {handle: 1, size: 1000}, "b": {handle: 2, size: 500}}
+	shared := entries["a"]
+	v := struct {
+		Entries map[string]*cBuffer
+		Inline  []cBuffer
+		Shared  *cBuffer
+	}{entries, []cBuffer{{handle: 3, size: 100}}, shared}
+
+	report := GetReport(v)
+	if report.OffHeapBytes != 1600 {
+		t.Errorf("Expected 1600 off-heap bytes, got %d", report.OffHeapBytes)
+	}
+	if total := GetTotalSize(v); report.Total() != total {
+		t.Errorf("Expected off-heap memory to be left out of the total %d, got %d", total, report.Total())
+	}
+
+	var sum uint64
+	report.Walk(func(n *Node) bool {
+		sum += n.OffHeap
+		return true
+	})
+	if sum != report.OffHeapBytes {
+		t.Errorf("Expected nodes to add up to %d off-heap bytes, got %d", report.OffHeapBytes, sum)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.OffHeapBytes != 1600 {
+		t.Errorf("Expected off-heap bytes to survive JSON, got %d: %v", decoded.OffHeapBytes, err)
+	}
+}
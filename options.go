@@ -0,0 +1,274 @@
+// options.go
+package memsize
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"regexp"
+	"time"
+	"unsafe"
+)
+
+// Option configures a single measurement
+type Option func(*config)
+
+// config holds the settings of a measurement
+type config struct {
+	debug    bool
+	debugOut io.Writer
+	// debugRecord receives every finished value when set by WithSlogLogger
+	debugRecord func(path string, v reflect.Value, shallow, size uint64)
+	// handlers take precedence over DefaultHandlers, see WithHandlers
+	handlers *Handlers
+	// tables are the handlers of both sets when the measurement was configured
+	tables handlerTables
+	// events receives the steps of the traversal, see WithEventSink
+	events EventSink
+	// debugFilter and debugMaxLines restrict the values that are logged
+	debugFilter   *regexp.Regexp
+	debugMaxLines int
+	gc            bool
+	// omitRootHeader leaves the pointer or interface header of the root out, see WithRootHeader
+	omitRootHeader bool
+	parallelism    int
+	// yieldEvery calls runtime.Gosched every so many steps, see WithYield
+	yieldEvery int
+	// realTime enables the soft real-time profile and maxSlice caps the time slices of a
+	// ResumableSizer, see SoftRealTime
+	realTime    bool
+	maxSlice    time.Duration
+	maxNodes    uint64
+	maxBytes    uint64
+	sampling    float64
+	seed        int64
+	strict      bool
+	model       SizeModel
+	arch        *archInfo
+	sizeClasses bool
+	gcOverhead  bool
+	// overheads replaces DefaultModel, see WithModel
+	overheads *Model
+	exclude   map[uintptr]bool
+	// identity deduplicates objects by logical identity, see WithIdentityFunc
+	identity IdentityFunc
+	// errorChains follows the causes of wrapped errors, see WithErrorChains
+	errorChains bool
+
+	// sharedPolicy attributes shared objects in reports, see WithSharedPolicy
+	sharedPolicy SharedPolicy
+	// visitedArena allocates the visited set outside the heap, see WithVisitedArena
+	visitedArena bool
+	// approxObjects and approxRate size a Bloom filter as visited set, see WithApproximateDedup
+	approxObjects uint64
+	approxRate    float64
+	// progress is called during the traversal, see WithProgress
+	progress ProgressFunc
+	// order sorts map entries and the children of report nodes, see WithOrder
+	order Order
+
+	pointerPolicy   PointerPolicy
+	maxPointerDepth int
+	maxDepth        int
+	// includeTypes restricts the named types traversed, see WithIncludeTypes
+	includeTypes *typeFilter
+
+	typeDescriptors bool
+
+	safe    bool
+	retries int
+
+	uniqueValues bool
+	weakPointers bool
+	unsafeTypes  map[reflect.Type]reflect.Type
+	// reflectValues follows the values held by reflect.Value, see WithReflectValues
+	reflectValues bool
+	// pools holds the retention of sync.Pools by address, see WithPoolEstimate
+	pools map[uintptr]poolEstimate
+
+	// arenas lists the arenas declared with WithArena, in order
+	arenas []arenaRegion
+
+	mapped     []memRegion
+	detectMmap bool
+	// mappedRegions lists the regions of mapped memory, including detected ones, in order
+	mappedRegions []memRegion
+
+	// ctx is set by GetTotalSizeContext
+	ctx context.Context
+	// err reports an invalid option
+	err error
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{debug: Debug}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.tables = handlerTables{set: cfg.handlers.snapshot(), defaults: DefaultHandlers.snapshot()}
+	if len(cfg.mapped) > 0 || cfg.detectMmap {
+		cfg.mappedRegions = mappedRegions(cfg)
+	}
+	// The profile wins over options given after it
+	if cfg.realTime {
+		cfg.gc = false
+		cfg.parallelism = 0
+	}
+	return cfg
+}
+
+// WithDebug enables or disables debug logging for a single measurement, overriding Debug
+func WithDebug(enabled bool) Option {
+	return func(c *config) {
+		c.debug = enabled
+	}
+}
+
+// WithDebugWriter enables debug logging for a single measurement and writes it to out
+// instead of standard output
+func WithDebugWriter(out io.Writer) Option {
+	return func(c *config) {
+		c.debug = true
+		c.debugOut = out
+	}
+}
+
+// WithDebugPathFilter restricts debug output, including WithSlogLogger records, to the values
+// whose path matches re, e.g. regexp.MustCompile(`root\.cache\.entries\[.*\]\.value`)
+func WithDebugPathFilter(re *regexp.Regexp) Option {
+	return func(c *config) {
+		c.debugFilter = re
+	}
+}
+
+// WithDebugMaxLines stops debug output after n lines about values; the final size is still printed
+func WithDebugMaxLines(n int) Option {
+	return func(c *config) {
+		c.debugMaxLines = n
+	}
+}
+
+// debugPath reports whether values at path are logged
+func (c *config) debugPath(path string) bool {
+	return c.debugFilter == nil || c.debugFilter.MatchString(path)
+}
+
+// tracing reports whether values are logged as they are sized
+func (c *config) tracing() bool {
+	return c.debug || c.debugRecord != nil || c.events != nil
+}
+
+// WithGC runs a garbage collection before measuring, so objects finalized in between
+// are released first. It is off by default since a forced GC pauses the whole program.
+func WithGC() Option {
+	return func(c *config) {
+		c.gc = true
+	}
+}
+
+// WithRootHeader sets whether the header of a pointer or interface root is counted. By default
+// it is, so GetTotalSize(&x) is the size of the pointer plus GetTotalSize(x), and an interface
+// measured as its static type with GetTotalSizeValue or SizeOf includes its own words. Without
+// the header, a pointer root counts only the value it points to, which makes GetTotalSize(&x)
+// and GetTotalSize(x) equal, and an interface root only its dynamic value as GetTotalSize
+// counts it.
+//
+// Other roots have no header: the bytes of a value root, such as the fields of a struct or
+// the header of a slice, are always counted. GetTotalSize never sees the interface{} its
+// argument is passed in, so it never counts an interface header.
+func WithRootHeader(include bool) Option {
+	return func(c *config) {
+		c.omitRootHeader = !include
+	}
+}
+
+// WithParallelism spreads the traversal of large slices and maps across n goroutines.
+// It applies to measurements returning only a total; reports are always built sequentially.
+func WithParallelism(n int) Option {
+	return func(c *config) {
+		c.parallelism = n
+	}
+}
+
+// WithYield calls runtime.Gosched every n steps of the traversal, each visiting or finishing a
+// value, so other goroutines get to run on the processor of a long measurement
+func WithYield(n int) Option {
+	return func(c *config) {
+		c.yieldEvery = n
+	}
+}
+
+// WithMaxNodes stops the traversal after n values were visited
+func WithMaxNodes(n uint64) Option {
+	return func(c *config) {
+		c.maxNodes = n
+	}
+}
+
+// WithMaxBytes stops the traversal once more than b bytes were accounted, which is
+// enough to answer whether a value exceeds b without measuring all of it
+func WithMaxBytes(b uint64) Option {
+	return func(c *config) {
+		c.maxBytes = b
+	}
+}
+
+// WithSampling visits only the given fraction of the elements of large slices and maps and
+// extrapolates their size from the sample. Use GetSizeEstimate to obtain a confidence interval.
+func WithSampling(rate float64) Option {
+	return func(c *config) {
+		c.sampling = rate
+	}
+}
+
+// WithStrict makes GetTotalSizeE return a *SizeError naming the first value that could not
+// be sized accurately instead of silently approximating it. Strict measurements track the
+// path of every value, which costs memory proportional to the depth of the object graph.
+func WithStrict() Option {
+	return func(c *config) {
+		c.strict = true
+	}
+}
+
+// WithSizeModel selects how headers and inline values are sized, see SizeModel
+func WithSizeModel(m SizeModel) Option {
+	return func(c *config) {
+		c.model = m
+	}
+}
+
+// WithSizeClasses rounds every heap allocation up to the runtime's malloc size class, so a
+// 33-byte string occupies 48 bytes and totals track HeapAlloc. Allocations are only known
+// with ExactSizes, which it selects.
+func WithSizeClasses() Option {
+	return func(c *config) {
+		c.model = ExactSizes
+		c.sizeClasses = true
+	}
+}
+
+// WithGCOverhead adds the runtime's per-object bookkeeping to every heap allocation: the heap
+// bitmap of objects with pointers, the span structures, and the space lost at the end of spans.
+// Together with size classes, which it enables, totals approximate the contribution to RSS.
+func WithGCOverhead() Option {
+	return func(c *config) {
+		c.model = ExactSizes
+		c.sizeClasses = true
+		c.gcOverhead = true
+	}
+}
+
+// WithExcludePointers treats the objects at the given addresses as already counted, so
+// singletons shared by everything, such as a global configuration or schema registry, are
+// never attributed to the measured value. References to them still count their own header.
+// Maps and channels are excluded by the pointer their value holds, e.g. reflect.ValueOf(m).UnsafePointer().
+func WithExcludePointers(ptrs ...unsafe.Pointer) Option {
+	return func(c *config) {
+		if c.exclude == nil {
+			c.exclude = make(map[uintptr]bool)
+		}
+		for _, p := range ptrs {
+			c.exclude[uintptr(p)] = true
+		}
+	}
+}
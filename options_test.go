@@ -0,0 +1,166 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestOptions(t *testing.T) {
+	Debug = false
+
+	t.Run("No GC By Default", func(t *testing.T) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		GetTotalSize([]string{"a", "b"})
+		runtime.ReadMemStats(&after)
+		if after.NumForcedGC != before.NumForcedGC {
+			t.Error("Expected no forced garbage collection")
+		}
+	})
+
+	t.Run("WithGC", func(t *testing.T) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		GetTotalSize([]string{"a", "b"}, WithGC())
+		runtime.ReadMemStats(&after)
+		if after.NumForcedGC == before.NumForcedGC {
+			t.Error("Expected a forced garbage collection")
+		}
+	})
+
+	t.Run("WithDebug Overrides Debug", func(t *testing.T) {
+		Debug = true
+		defer func() { Debug = false }()
+		if cfg := newConfig([]Option{WithDebug(false)}); cfg.debug {
+			t.Error("Expected WithDebug(false) to disable debug output")
+		}
+	})
+
+	t.Run("WithExcludePointers", func(t *testing.T) {
+		global := &blob{Data: make([]byte, 1000)}
+		registry := map[string]int{"a": 1}
+		v := &struct {
+			Config   *blob
+			Registry map[string]int
+			Own      []byte
+		}{Config: global, Registry: registry, Own: make([]byte, 10)}
+
+		opts := []Option{WithSizeModel(ExactSizes), WithExcludePointers(unsafe.Pointer(global),
+			reflect.ValueOf(registry).UnsafePointer())}
+		full := GetTotalSize(v, WithSizeModel(ExactSizes))
+		excluded := GetTotalSize(v, opts...)
+		expected := full - GetTotalSize(*global, WithSizeModel(ExactSizes)) -
+			(GetTotalSize(registry, WithSizeModel(ExactSizes)) - 8)
+		if excluded != expected {
+			t.Errorf("Expected %d bytes without the shared objects, got %d", expected, excluded)
+		}
+		if size := GetTotalSize(v, append(opts, WithParallelism(4))...); size != excluded {
+			t.Errorf("Expected a parallel measurement to exclude them too, got %d", size)
+		}
+	})
+
+	t.Run("WithDebugWriter", func(t *testing.T) {
+		var out strings.Builder
+		size := GetTotalSize([]string{"a", "b"}, WithDebugWriter(&out))
+		if !strings.Contains(out.String(), "root[1]: String header(16) + data(1) = 17") {
+			t.Errorf("Expected the debug output to be written, got %q", out.String())
+		}
+		if !strings.HasSuffix(out.String(), fmt.Sprintf("Final size: %d\n", size)) {
+			t.Errorf("Expected the output to end with the final size, got %q", out.String())
+		}
+	})
+
+	t.Run("WithDebugPathFilter", func(t *testing.T) {
+		var out strings.Builder
+		v := map[string][]string{"a": {"x", "y"}}
+		GetTotalSize(v, WithDebugWriter(&out), WithDebugPathFilter(regexp.MustCompile(`\["a"\]\[\d+\]$`)))
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		expected := []string{
+			`root["a"][0]: String header(16) + data(1) = 17`,
+			`root["a"][1]: String header(16) + data(1) = 17`,
+		}
+		if len(lines) != 3 || lines[0] != expected[0] || lines[1] != expected[1] ||
+			!strings.HasPrefix(lines[2], "Final size") {
+			t.Errorf("Expected only the elements and the final size, got %q", lines)
+		}
+	})
+
+	t.Run("WithDebugMaxLines", func(t *testing.T) {
+		var out strings.Builder
+		GetTotalSize(make([]string, 100), WithDebugWriter(&out), WithDebugMaxLines(5))
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 7 || lines[5] != "... debug output truncated after 5 lines" {
+			t.Errorf("Expected 5 lines, a truncation notice and the final size, got %q", lines)
+		}
+	})
+
+	t.Run("WithRootHeader", func(t *testing.T) {
+		type record struct {
+			Name string
+			Tags []string
+		}
+		x := record{Name: "name", Tags: []string{"a", "b"}}
+		var err error = &SizeError{Path: "root", Type: "int"}
+
+		for model, name := range map[SizeModel]string{LegacySizes: "Legacy", ExactSizes: "Exact"} {
+			t.Run(name, func(t *testing.T) {
+				m := WithSizeModel(model)
+				header := headerSize(reflect.TypeOf(&x), newConfig([]Option{m}).layout())
+				value := GetTotalSize(x, m)
+
+				// Pointer roots: the pointer is the header
+				if got := GetTotalSize(&x, m); got != value+header {
+					t.Errorf("Expected a pointer root to count its pointer, %d, got %d", value+header, got)
+				}
+				if got := GetTotalSize(&x, m, WithRootHeader(true)); got != value+header {
+					t.Errorf("Expected WithRootHeader(true) to be the default, %d, got %d", value+header, got)
+				}
+				if got := GetTotalSize(&x, m, WithRootHeader(false)); got != value {
+					t.Errorf("Expected a pointer root without header to count the value, %d, got %d", value, got)
+				}
+
+				// Value roots have no header, their inline bytes always count
+				if got := GetTotalSize(x, m, WithRootHeader(false)); got != value {
+					t.Errorf("Expected a value root to be unaffected, %d, got %d", value, got)
+				}
+
+				// Interface roots: without their header they count their dynamic value as
+				// GetTotalSize does. With exact sizes the data word holding a pointer is the
+				// pointer of the dynamic value, so only the type word is left out.
+				iface := headerSize(reflect.TypeOf(&err).Elem(), newConfig([]Option{m}).layout())
+				if model == ExactSizes {
+					iface -= 8
+				}
+				dynamic := GetTotalSize(err, m)
+				if got := SizeOf(err, m); got != dynamic+iface {
+					t.Errorf("Expected an interface root to count its header, %d, got %d", dynamic+iface, got)
+				}
+				if got := SizeOf(err, m, WithRootHeader(false)); got != dynamic {
+					t.Errorf("Expected an interface root without header to count its value, %d, got %d", dynamic, got)
+				}
+				// GetTotalSize only sees the dynamic pointer
+				if got, want := GetTotalSize(err, m, WithRootHeader(false)), GetTotalSize(err, m)-header; got != want {
+					t.Errorf("Expected the dynamic pointer to lose its header, %d, got %d", want, got)
+				}
+			})
+		}
+
+		t.Run("Nested", func(t *testing.T) {
+			items := []*record{&x, {Name: "other"}}
+			want := GetTotalSize(items)
+			if got := GetTotalSize(items, WithRootHeader(false), WithParallelism(4)); got != want {
+				t.Errorf("Expected pointers below the root to keep their header, %d, got %d", want, got)
+			}
+			report := GetReport(&items, WithRootHeader(false))
+			if report.Root.Size != want || report.Root.Shallow != 0 {
+				t.Errorf("Expected a root node of %d bytes without shallow bytes, got %d and %d",
+					want, report.Root.Size, report.Root.Shallow)
+			}
+		})
+	})
+}
@@ -0,0 +1,135 @@
+// order.go
+package memsize
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Order selects how the children of report nodes are ordered
+type Order int
+
+const (
+	// TraversalOrder keeps children in the order they were visited, in which map entries
+	// follow the randomized iteration order of maps
+	TraversalOrder Order = iota
+	// PathOrder sorts children by path
+	PathOrder
+	// SizeOrder sorts children by decreasing size, then by path
+	SizeOrder
+)
+
+// WithOrder makes reports and debug output deterministic between runs: map entries are visited
+// in the order of their keys, so the same path is credited with objects reachable through
+// several entries, and the children of report nodes are sorted as o tells. Keys are ordered by
+// value for numbers, strings and booleans and by their rendering otherwise; pointers render as
+// addresses, which differ between runs. Parallelism is ignored.
+func WithOrder(o Order) Option {
+	return func(c *config) {
+		c.order = o
+	}
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		return lessKeys(keys[i], keys[j])
+	})
+	return keys
+}
+
+// lessKeys orders map keys by value if they are of the same basic kind and by their rendering
+// otherwise
+func lessKeys(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.String:
+			return a.String() < b.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.Bool:
+			return !a.Bool() && b.Bool()
+		}
+	}
+	return renderKey(a) < renderKey(b)
+}
+
+// renderKey returns a stable text for a map key: Go syntax for strings, numbers and booleans,
+// and the %v formatting prefixed with the type for other keys
+func renderKey(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return renderKey(v.Elem())
+	}
+	if v.CanInterface() {
+		return fmt.Sprintf("%s(%v)", v.Type(), v.Interface())
+	}
+	return fmt.Sprintf("%s(%v)", v.Type(), v)
+}
+
+// pathKey renders a map key for paths: Go syntax for strings, numbers and booleans, and a
+// hash of the rendering of other keys, which would make paths unreadable
+func pathKey(v reflect.Value) string {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return renderKey(v)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(renderKey(v)))
+	return fmt.Sprintf("#%016x", h.Sum64())
+}
+
+// sortChildren orders the children of every node below root
+func sortChildren(root *Node, o Order) {
+	if root == nil || o == TraversalOrder {
+		return
+	}
+	stack := []*Node{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		children := n.Children
+		sort.SliceStable(children, func(i, j int) bool {
+			if o == SizeOrder && children[i].Size != children[j].Size {
+				return children[i].Size > children[j].Size
+			}
+			return children[i].Path < children[j].Path
+		})
+		stack = append(stack, children...)
+	}
+}
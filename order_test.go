@@ -0,0 +1,87 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	Debug = false
+
+	shared := &struct{ Data [100]byte }{}
+	m := make(map[string]interface{})
+	for i := 0; i < 20; i++ {
+		m[fmt.Sprintf("key%02d", i)] = strings.Repeat("v", i)
+	}
+	m["a"] = shared
+	m["z"] = shared
+
+	t.Run("Deterministic", func(t *testing.T) {
+		var first string
+		for i := 0; i < 5; i++ {
+			var buf bytes.Buffer
+			GetTotalSize(m, WithDebugWriter(&buf), WithOrder(PathOrder))
+			if i == 0 {
+				first = buf.String()
+			} else if buf.String() != first {
+				t.Fatal("Expected the same debug output on every run")
+			}
+		}
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		// Entries are visited by key, so the shared pointer is first reached through "a"
+		var key, first string
+		_ = Walk(m, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			switch {
+			case strings.HasPrefix(path, "root.key["):
+				key = val.String()
+			case val.Kind() == reflect.Ptr && first == "":
+				first = key
+			}
+			return WalkContinue
+		}, WithOrder(PathOrder))
+		if first != "a" {
+			t.Errorf("Expected the pointer reached through a first, got %q", first)
+		}
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		r := GetReport(m, WithOrder(SizeOrder))
+		children := r.Root.Children
+		for i := 1; i < len(children); i++ {
+			if children[i].Size > children[i-1].Size {
+				t.Fatalf("Expected decreasing sizes, got %d after %d", children[i].Size, children[i-1].Size)
+			}
+		}
+	})
+
+	t.Run("Path", func(t *testing.T) {
+		v := struct{ B, A string }{"b", "a"}
+		r := GetReport(v, WithOrder(PathOrder))
+		if r.Root.Children[0].Path != "root.A" {
+			t.Errorf("Expected root.A first, got %s", r.Root.Children[0].Path)
+		}
+		if r := GetReport(v); r.Root.Children[0].Path != "root.B" {
+			t.Errorf("Expected fields in declaration order by default, got %s", r.Root.Children[0].Path)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		keys := sortedKeys(reflect.ValueOf(map[int]bool{10: true, 9: true, -1: true}))
+		if keys[0].Int() != -1 || keys[1].Int() != 9 || keys[2].Int() != 10 {
+			t.Errorf("Expected numeric order, got %v", keys)
+		}
+		keys = sortedKeys(reflect.ValueOf(map[interface{}]bool{"b": true, 2: true, "a": true, 1: true}))
+		var rendered []string
+		for _, k := range keys {
+			rendered = append(rendered, renderKey(k))
+		}
+		if got := strings.Join(rendered, " "); got != `"a" "b" 1 2` {
+			t.Errorf("Expected keys by rendering, got %s", got)
+		}
+	})
+}
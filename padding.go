@@ -0,0 +1,152 @@
+// padding.go
+package memsize
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// typePadding describes the alignment padding of a type
+type typePadding struct {
+	// padding is the number of bytes of a struct not occupied by any field
+	padding uint64
+	// savings is how much smaller the struct gets with its fields reordered
+	savings uint64
+	// deepPadding and deepSavings include nested structs and arrays stored inline
+	deepPadding uint64
+	deepSavings uint64
+}
+
+// paddings caches a typePadding per planKey
+var paddings sync.Map
+
+// paddingOf returns the padding of values of type t under the layout l
+func paddingOf(t reflect.Type, l layout) typePadding {
+	key := planKey{t, l}
+	if p, ok := paddings.Load(key); ok {
+		return p.(typePadding)
+	}
+
+	var p typePadding
+	switch t.Kind() {
+	case reflect.Array:
+		elem := paddingOf(t.Elem(), l)
+		p.deepPadding = uint64(t.Len()) * elem.deepPadding
+		p.deepSavings = uint64(t.Len()) * elem.deepSavings
+	case reflect.Struct:
+		p = structPaddingOf(t, l)
+	}
+	paddings.Store(key, p)
+	return p
+}
+
+func structPaddingOf(t reflect.Type, l layout) typePadding {
+	var p typePadding
+	size := l.sizeof(t)
+	fields := make([]typeLayout, t.NumField())
+	var used uint64
+	for i := range fields {
+		fields[i] = l.layoutOf(t.Field(i).Type)
+		used += fields[i].size
+		nested := paddingOf(t.Field(i).Type, l)
+		p.deepPadding += nested.deepPadding
+		p.deepSavings += nested.deepSavings
+	}
+
+	// Zero-size fields go first so none of them trails, then fields by decreasing alignment,
+	// which leaves padding only at the end
+	sort.SliceStable(fields, func(i, j int) bool {
+		if (fields[i].size == 0) != (fields[j].size == 0) {
+			return fields[i].size == 0
+		}
+		return fields[i].align > fields[j].align
+	})
+	p.padding = size - used
+	if optimal := packFields(fields).size; optimal < size {
+		p.savings = size - optimal
+	}
+	p.deepPadding += p.padding
+	p.deepSavings += p.savings
+	return p
+}
+
+// padding returns the padding of a frame's value: a struct's own padding, since fields are
+// visited on their own, and the padding of the values folded into slices, arrays and maps
+func (w *walker) padding(f *frame) (padding, savings uint64) {
+	v := f.v
+	l := w.cfg.layout()
+	folded := func(t reflect.Type, n int) {
+		if planFor(t, l).constant {
+			p := paddingOf(t, l)
+			padding += uint64(n) * p.deepPadding
+			savings += uint64(n) * p.deepSavings
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		p := paddingOf(v.Type(), l)
+		return p.padding, p.savings
+	case reflect.Slice, reflect.Array:
+		folded(v.Type().Elem(), v.Len())
+	case reflect.Map:
+		folded(v.Type().Key(), v.Len())
+		folded(v.Type().Elem(), v.Len())
+	}
+	return padding, savings
+}
+
+// ReorderSaving is the memory a struct type would save if its fields were reordered
+type ReorderSaving struct {
+	// Type is the struct, or the slice, array or map holding structs without indirections
+	Type string
+	// Count is the number of values of the type in the report
+	Count uint64
+	// Padding is the total padding of those values
+	Padding uint64
+	// Savings is the total number of bytes saved by ordering fields by decreasing alignment
+	Savings uint64
+}
+
+// PaddingBytes returns the total number of bytes structs in the report spend on padding
+func (r *Report) PaddingBytes() uint64 {
+	var total uint64
+	r.Walk(func(n *Node) bool {
+		total += n.Padding
+		return true
+	})
+	return total
+}
+
+// ReorderSavings returns the struct types of the report whose values would take less memory
+// with their fields reordered, largest total savings first
+func (r *Report) ReorderSavings() []ReorderSaving {
+	byType := make(map[string]*ReorderSaving)
+	r.Walk(func(n *Node) bool {
+		if n.Reorderable == 0 {
+			return true
+		}
+		s := byType[n.Type]
+		if s == nil {
+			s = &ReorderSaving{Type: n.Type}
+			byType[n.Type] = s
+		}
+		s.Count++
+		s.Padding += n.Padding
+		s.Savings += n.Reorderable
+		return true
+	})
+
+	savings := make([]ReorderSaving, 0, len(byType))
+	for _, s := range byType {
+		savings = append(savings, *s)
+	}
+	sort.Slice(savings, func(i, j int) bool {
+		if savings[i].Savings != savings[j].Savings {
+			return savings[i].Savings > savings[j].Savings
+		}
+		return savings[i].Type < savings[j].Type
+	})
+	return savings
+}
@@ -0,0 +1,80 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+type wasteful struct {
+	A int8
+	B int64
+	C int8
+}
+
+type compact struct {
+	B int64
+	A int8
+	C int8
+}
+
+func TestPadding(t *testing.T) {
+	Debug = false
+
+	type trailingEmpty struct {
+		A int64
+		B struct{}
+	}
+
+	tests := []struct {
+		name             string
+		value            interface{}
+		padding, savings uint64
+	}{
+		// 1 + 7 padding + 8 + 1 + 7 padding, or 8 + 1 + 1 + 6 padding when reordered
+		{"Wasteful", wasteful{}, 14, 8},
+		{"Compact", compact{}, 6, 0},
+		// The trailing empty struct costs a padded byte unless it goes first
+		{"Trailing Empty", trailingEmpty{}, 8, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := GetReport(tt.value, WithArch("amd64"))
+			if report.Root.Padding != tt.padding {
+				t.Errorf("Expected %d bytes of padding, got %d", tt.padding, report.Root.Padding)
+			}
+			if report.Root.Reorderable != tt.savings {
+				t.Errorf("Expected %d reorderable bytes, got %d", tt.savings, report.Root.Reorderable)
+			}
+		})
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		v := struct {
+			Items []wasteful
+			Other compact
+		}{Items: make([]wasteful, 3)}
+
+		report := GetReport(v, WithArch("amd64"))
+		if padding := report.PaddingBytes(); padding != 3*14+6 {
+			t.Errorf("Expected %d bytes of padding, got %d", 3*14+6, padding)
+		}
+
+		savings := report.ReorderSavings()
+		fmt.Printf("Reorder savings: %+v\n", savings)
+		if len(savings) != 1 {
+			t.Fatalf("Expected savings for a single type, got %+v", savings)
+		}
+		// Elements without indirections are folded into their slice
+		if s := savings[0]; s.Type != "[]memsize.wasteful" || s.Savings != 3*8 {
+			t.Errorf("Expected the wasteful slice to save 24 bytes, got %+v", s)
+		}
+	})
+
+	t.Run("Arch", func(t *testing.T) {
+		// int64 is only 4-byte aligned on 386
+		if p := GetReport(wasteful{}, WithArch("386")).Root; p.Padding != 6 || p.Reorderable != 4 {
+			t.Errorf("Expected 6 bytes of padding and 4 reorderable on 386, got %d and %d",
+				p.Padding, p.Reorderable)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+// parallel.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelChunk is the number of slice elements or map entries handed to a worker at once
+const parallelChunk = 1024
+
+// parallel coordinates workers sharing one traversal
+type parallel struct {
+	cfg      *config
+	budget   *budget
+	progress *progress
+	sampler  *sampler
+	strict   *strictLog
+	stats    *traversalStats
+	descs    *descriptorSet
+	ids      *identitySet
+	seen     addrSet
+	tasks    chan parallelTask
+	wg       sync.WaitGroup
+	total    uint64
+}
+
+// parallelTask is a chunk of slice elements or a batch of map keys and values to measure
+type parallelTask struct {
+	slice  reflect.Value
+	lo, hi int
+	values []reflect.Value
+}
+
+// parallelTotalSize measures v with the walker's parallelism, each worker running its own
+// walker sharing the visited set and budget of w
+func parallelTotalSize(w *walker, v reflect.Value) uint64 {
+	cfg := w.cfg
+	var seen addrSet = newStripedSet()
+	if bloom, ok := w.seen.(*bloomSet); ok {
+		seen = bloom
+	}
+	p := &parallel{
+		cfg:      cfg,
+		budget:   w.budget,
+		progress: w.progress,
+		sampler:  w.sampler,
+		strict:   w.strict,
+		stats:    w.stats,
+		descs:    w.descs,
+		ids:      w.ids,
+		seen:     seen,
+		tasks:    make(chan parallelTask, cfg.parallelism),
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < cfg.parallelism; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			w := p.walker()
+			for t := range p.tasks {
+				atomic.AddUint64(&p.total, w.run(t))
+				p.wg.Done()
+			}
+		}()
+	}
+
+	atomic.AddUint64(&p.total, p.walker().getTotalSize(v, "root"))
+	p.wg.Wait()
+	close(p.tasks)
+	workers.Wait()
+	p.stats.visited(p.seen.bytes())
+
+	return atomic.LoadUint64(&p.total)
+}
+
+func (p *parallel) walker() *walker {
+	w := &walker{cfg: p.cfg, seen: p.seen, par: p, budget: p.budget,
+		progress: p.progress, sampler: p.sampler,
+		strict: p.strict, stats: p.stats, descs: p.descs, ids: p.ids}
+	w.rng = p.sampler.newRand()
+	return w
+}
+
+// trySend hands a task to an idle worker and reports whether one accepted it
+func (p *parallel) trySend(t parallelTask) bool {
+	p.wg.Add(1)
+	select {
+	case p.tasks <- t:
+		return true
+	default:
+		p.wg.Done()
+		return false
+	}
+}
+
+// offloadSlice hands the remaining elements of a large slice to workers in chunks,
+// keeping whatever no worker is free to take
+func (p *parallel) offloadSlice(f *frame) {
+	n := f.v.Len()
+	for n-f.next > parallelChunk {
+		t := parallelTask{slice: f.v, lo: n - parallelChunk, hi: n}
+		if !p.trySend(t) {
+			break
+		}
+		n -= parallelChunk
+	}
+	// Elements past n now belong to workers
+	if n < f.v.Len() {
+		f.v = f.v.Slice(0, n)
+	}
+}
+
+// offloadMap hands map entries to workers in batches once the map turns out to be large.
+// Batches no worker is free to take are measured right away by a separate walker.
+func (p *parallel) offloadMap(w *walker, f *frame) {
+	if f.iter == nil || f.pendingVal || f.v.Len() <= parallelChunk {
+		return
+	}
+
+	for f.iter != nil {
+		values := make([]reflect.Value, 0, 2*parallelChunk)
+		for len(values) < cap(values) {
+			if !f.iter.Next() {
+				w.endRange(f)
+				break
+			}
+			if !f.skipKeys {
+				values = append(values, f.iter.Key())
+			}
+			if !f.skipValues {
+				values = append(values, f.iter.Value())
+			}
+		}
+		if len(values) == 0 {
+			return
+		}
+
+		t := parallelTask{values: values}
+		if !p.trySend(t) {
+			f.size += p.walker().run(t)
+		}
+	}
+}
+
+// run measures the values of a task
+func (w *walker) run(t parallelTask) uint64 {
+	var size uint64
+	for i := t.lo; i < t.hi; i++ {
+		size += w.getTotalSize(t.slice.Index(i), "")
+	}
+	for _, v := range t.values {
+		size += w.getTotalSize(v, "")
+	}
+	return size
+}
+
+// stripedSet is an addrSet safe for concurrent use, split into shards with separate locks
+type stripedSet struct {
+	shards [64]struct {
+		mu    sync.Mutex
+		table addrTable
+	}
+}
+
+func newStripedSet() *stripedSet {
+	return &stripedSet{}
+}
+
+func (s *stripedSet) visit(k visitKey) bool {
+	// The top bits of the hash pick the shard, the low bits the slot in its table
+	e := entryOf(k)
+	h := e.hash()
+	shard := &s.shards[h>>58]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return shard.table.insert(e, h/visitedShards, false)
+}
+
+func (s *stripedSet) bytes() uint64 {
+	var size uint64
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		size += shard.table.bytes()
+		shard.mu.Unlock()
+	}
+	return size
+}
@@ -0,0 +1,90 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+type graphNode struct {
+	ID    int
+	Name  string
+	Edges []*graphNode
+	Attrs map[string]string
+}
+
+func buildGraph(n int) []*graphNode {
+	nodes := make([]*graphNode, n)
+	for i := range nodes {
+		nodes[i] = &graphNode{ID: i, Name: fmt.Sprintf("node-%d", i), Attrs: map[string]string{"k": "v"}}
+	}
+	// Edges point across chunk boundaries so workers race to count shared nodes
+	for i, node := range nodes {
+		node.Edges = []*graphNode{nodes[(i+1)%n], nodes[(i*7)%n], nodes[0]}
+	}
+	return nodes
+}
+
+func TestWithParallelism(t *testing.T) {
+	Debug = false
+
+	// Graphs of a few chunks keep the workers racing without slowing down the race detector
+	n := 20000
+	if raceEnabled {
+		n = 5 * parallelChunk
+	}
+
+	t.Run("Slice", func(t *testing.T) {
+		nodes := buildGraph(n)
+		expected := GetTotalSize(nodes)
+		for _, n := range []int{2, 4, 8} {
+			if got := GetTotalSize(nodes, WithParallelism(n)); got != expected {
+				t.Errorf("Parallelism %d: expected %d, got %d", n, expected, got)
+			}
+		}
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		nodes := buildGraph(n / 2)
+		index := make(map[string]*graphNode, len(nodes))
+		for _, node := range nodes {
+			index[node.Name] = node
+		}
+		expected := GetTotalSize(index)
+		if got := GetTotalSize(index, WithParallelism(4)); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+		if got := SizeOf(index, WithParallelism(4)); got != SizeOf(index) {
+			t.Errorf("Expected %d, got %d", SizeOf(index), got)
+		}
+	})
+
+	t.Run("Small Values", func(t *testing.T) {
+		v := []string{"a", "b"}
+		if got, expected := GetTotalSize(v, WithParallelism(4)), GetTotalSize(v); got != expected {
+			t.Errorf("Expected %d, got %d", expected, got)
+		}
+	})
+}
+
+func BenchmarkParallelism(b *testing.B) {
+	Debug = false
+
+	// Independent subtrees, the shape parallel traversal is designed for
+	records := make([]*graphNode, 200000)
+	for i := range records {
+		records[i] = &graphNode{
+			ID:    i,
+			Name:  fmt.Sprintf("record-%d", i),
+			Edges: []*graphNode{{Name: "child"}, {Name: "child"}},
+			Attrs: map[string]string{"k": "v"},
+		}
+	}
+
+	for _, n := range []int{1, 4} {
+		b.Run(fmt.Sprintf("Workers %d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				GetTotalSize(records, WithParallelism(n))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// path.go
+package memsize
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// stepKind tells how a pathStep extends the path of its parent
+type stepKind uint8
+
+const (
+	// stepRoot starts a path with name
+	stepRoot stepKind = iota
+	// stepSuffix appends name, such as ".ptr" or ".key"
+	stepSuffix
+	// stepField appends the name of the parent struct's field at index
+	stepField
+	// stepIndex appends "[index]"
+	stepIndex
+	// stepMapValue appends the key of the parent map's current entry, e.g. `["name"]`, and
+	// stepMapKey the same prefixed with ".key"
+	stepMapValue
+	stepMapKey
+)
+
+// pathStep is the part of a value's path added to the path of its parent. Frames record steps
+// instead of paths, which are only built when a report, debug output, a WalkFunc or a strict
+// error needs them, and then only once per frame.
+type pathStep struct {
+	kind  stepKind
+	name  string
+	index int
+}
+
+func rootStep(name string) pathStep {
+	return pathStep{kind: stepRoot, name: name}
+}
+
+func suffixStep(suffix string) pathStep {
+	return pathStep{kind: stepSuffix, name: suffix}
+}
+
+// suffix returns the text the step appends to the path of the parent frame
+func (s pathStep) suffix(parent *frame) string {
+	switch s.kind {
+	case stepField:
+		// Field names are looked up only now, since reflect.Type.Field allocates
+		return fieldSegment(parent.v.Type().Field(s.index))
+	case stepIndex:
+		return "[" + strconv.Itoa(s.index) + "]"
+	case stepMapValue:
+		// Keys are rendered only now, while the map's iterator is still at the entry
+		return "[" + pathKey(parent.entryKey()) + "]"
+	case stepMapKey:
+		return ".key[" + pathKey(parent.entryKey()) + "]"
+	}
+	return s.name
+}
+
+// fieldSegment returns the path segment of a struct field. Embedded fields are named by their
+// type in parentheses, e.g. ".(Base)", so they stand out from the fields promoted from them.
+func fieldSegment(f reflect.StructField) string {
+	if f.Anonymous {
+		return ".(" + f.Name + ")"
+	}
+	return "." + f.Name
+}
+
+// entryKey returns the key of the entry a map frame is visiting
+func (f *frame) entryKey() reflect.Value {
+	if f.keys != nil {
+		return f.keys[f.next-1]
+	}
+	return f.iter.Key()
+}
+
+// path returns the path of a frame on the stack, or "" unless paths are needed
+func (w *walker) path(f *frame) string {
+	if !w.paths() {
+		return ""
+	}
+	return w.fullPath(f)
+}
+
+// fullPath builds the path of a frame on the stack even if paths are not needed. Its steps
+// are only complete if pointer and interface frames were not collapsed.
+func (w *walker) fullPath(f *frame) string {
+	if f.pathDone {
+		return f.path
+	}
+
+	// Frames are usually on top of the stack, and their ancestors' paths are usually known
+	i := len(w.stack) - 1
+	for i >= 0 && &w.stack[i] != f {
+		i--
+	}
+	if i < 0 {
+		return ""
+	}
+	first := i
+	for first > 0 && !w.stack[first-1].pathDone {
+		first--
+	}
+	for j := first; j <= i; j++ {
+		g := &w.stack[j]
+		if g.step.kind == stepRoot || j == 0 {
+			g.path = g.step.name
+		} else {
+			g.path = w.stack[j-1].path + g.step.suffix(&w.stack[j-1])
+		}
+		g.pathDone = true
+	}
+	return f.path
+}
+
+// reject reports a value of the frame that could not be sized accurately in strict mode
+func (w *walker) reject(f *frame, t reflect.Type, reason string) {
+	if w.strict != nil {
+		w.strict.reject(w.path(f), t, reason)
+	}
+}
+
+// rejectPlan reports the approximated value folded into a value of the plan's type, found at
+// the path of the frame followed by the step, if any
+func (w *walker) rejectPlan(f *frame, s pathStep, p *typePlan) {
+	if w.strict != nil && p.issue != nil {
+		w.strict.rejectPlan(w.path(f)+s.suffix(f), p)
+	}
+}
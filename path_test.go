@@ -0,0 +1,172 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	Debug = false
+
+	type leaf struct {
+		Data []byte
+	}
+	type branch struct {
+		Leaves []*leaf
+		ByName map[string]interface{}
+	}
+	v := &branch{
+		Leaves: []*leaf{{Data: []byte("a")}, {Data: []byte("bc")}},
+		ByName: map[string]interface{}{"x": &leaf{Data: []byte("d")}},
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		var paths []string
+		GetReport(v).Walk(func(n *Node) bool {
+			paths = append(paths, n.Path)
+			return true
+		})
+		fmt.Printf("Paths: %v\n", paths)
+
+		want := []string{
+			"root", "root.ptr", "root.ptr.Leaves",
+			"root.ptr.Leaves[0]", "root.ptr.Leaves[0].ptr", "root.ptr.Leaves[0].ptr.Data",
+			"root.ptr.Leaves[1]", "root.ptr.Leaves[1].ptr", "root.ptr.Leaves[1].ptr.Data",
+			"root.ptr.ByName", `root.ptr.ByName.key["x"]`, `root.ptr.ByName["x"]`,
+			`root.ptr.ByName["x"].elem`, `root.ptr.ByName["x"].elem.ptr`,
+			`root.ptr.ByName["x"].elem.ptr.Data`,
+		}
+		if !reflect.DeepEqual(paths, want) {
+			t.Errorf("Expected paths %v, got %v", want, paths)
+		}
+	})
+
+	t.Run("Walk", func(t *testing.T) {
+		var paths []string
+		Walk(v, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			paths = append(paths, path)
+			return WalkContinue
+		})
+		if len(paths) == 0 || paths[len(paths)-1] != `root.ptr.ByName["x"].elem.ptr.Data` {
+			t.Errorf("Expected paths built from their parents, got %v", paths)
+		}
+	})
+
+	t.Run("Map Keys", func(t *testing.T) {
+		type point struct{ X, Y int }
+		tests := []struct {
+			name  string
+			value interface{}
+			want  *regexp.Regexp
+		}{
+			{"String", map[string][]byte{"a b": {1}}, regexp.MustCompile(`^root\["a b"\]$`)},
+			{"Int", map[int]string{-3: "x"}, regexp.MustCompile(`^root\[-3\]$`)},
+			{"Bool", map[bool]*leaf{true: {}}, regexp.MustCompile(`^root\[true\]$`)},
+			{"Interface", map[interface{}]*leaf{1.5: {}}, regexp.MustCompile(`^root\[1\.5\]$`)},
+			{"Struct", map[point]*leaf{{1, 2}: {}}, regexp.MustCompile(`^root\[#[0-9a-f]{16}\]$`)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				r := GetReport(tt.value)
+				value := r.Root.Children[len(r.Root.Children)-1]
+				if !tt.want.MatchString(value.Path) {
+					t.Errorf("Expected a path matching %s, got %s", tt.want, value.Path)
+				}
+			})
+		}
+
+		r := GetReport(map[string]string{"k": "v"})
+		if key := r.Root.Children[0].Path; key != `root.key["k"]` {
+			t.Errorf("Expected the key's path to name the entry, got %s", key)
+		}
+	})
+
+	t.Run("Strict Allocations", func(t *testing.T) {
+		// Paths are only built for values that are rejected, so strict mode costs nothing
+		// more while every value can be sized
+		items := make([]*leaf, 100)
+		for i := range items {
+			items[i] = &leaf{Data: make([]byte, i)}
+		}
+		plain := testing.AllocsPerRun(100, func() { GetTotalSize(&items) })
+		strict := testing.AllocsPerRun(100, func() {
+			if _, err := GetTotalSizeE(&items, WithStrict()); err != nil {
+				t.Fatal(err)
+			}
+		})
+		fmt.Printf("Allocations: %.1f, strict %.1f\n", plain, strict)
+		if strict > plain+5 && !raceEnabled {
+			t.Errorf("Expected strict mode to allocate about as much as %.1f, got %.1f", plain, strict)
+		}
+	})
+}
+
+type embeddedBase struct {
+	buf []byte
+}
+
+type embeddedByValue struct {
+	embeddedBase
+	Name string
+}
+
+type embeddedByPointer struct {
+	*embeddedBase
+	fmt.Stringer
+}
+
+type embeddedName string
+
+func (n embeddedName) String() string { return string(n) }
+
+func TestEmbeddedFields(t *testing.T) {
+	Debug = false
+
+	paths := func(r *Report) map[string]*Node {
+		nodes := make(map[string]*Node)
+		r.Walk(func(n *Node) bool {
+			nodes[n.Path] = n
+			return true
+		})
+		return nodes
+	}
+
+	t.Run("Value", func(t *testing.T) {
+		v := embeddedByValue{embeddedBase: embeddedBase{buf: make([]byte, 10)}, Name: "n"}
+		nodes := paths(GetReport(v))
+		if nodes["root.(embeddedBase)"] == nil || nodes["root.(embeddedBase).buf"] == nil || nodes["root.Name"] == nil {
+			t.Errorf("Expected the embedded struct apart from the promoted field, got %v", nodes)
+		}
+	})
+
+	t.Run("Pointer And Interface", func(t *testing.T) {
+		v := embeddedByPointer{&embeddedBase{buf: make([]byte, 10)}, embeddedName("name")}
+		nodes := paths(GetReport(v))
+		for _, path := range []string{"root.(embeddedBase).ptr.buf", "root.(Stringer).elem"} {
+			if nodes[path] == nil {
+				t.Errorf("Expected a node at %s, got %v", path, nodes)
+			}
+		}
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		// The promoted fields of a base embedded by pointer in two values are counted once
+		base := &embeddedBase{buf: make([]byte, 1000)}
+		v := []embeddedByPointer{{embeddedBase: base}, {embeddedBase: base}}
+		nodes := paths(GetReport(v))
+		first, second := nodes["root[0].(embeddedBase)"], nodes["root[1].(embeddedBase)"]
+		if first == nil || second == nil || first.Shared || !second.Shared || second.Size >= 1000 {
+			t.Errorf("Expected the second embedded pointer to be shared, got %+v and %+v", first, second)
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		v := &embeddedByPointer{embeddedBase: &embeddedBase{}}
+		addr, ok := resolveRegion(reflect.ValueOf(v), "root.ptr.(embeddedBase).ptr.buf")
+		if !ok || addr != reflect.ValueOf(v.embeddedBase).Pointer() {
+			t.Errorf("Expected the embedded pointer to resolve, got %x", addr)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+// physical.go
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Result holds the two sizes Measure reports for a value
+type Result struct {
+	// Logical is the sum of the sizes of the value's types and of the memory it references,
+	// what the code asked for, as measured with ExactSizes
+	Logical uint64
+	// Physical is what the heap spends on it: every allocation rounded up to its size class
+	// plus the runtime's per-object bookkeeping, as measured with WithGCOverhead
+	Physical uint64
+}
+
+// Overhead returns the bytes the heap spends beyond the logical size
+func (r Result) Overhead() uint64 {
+	if r.Physical < r.Logical {
+		return 0
+	}
+	return r.Physical - r.Logical
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("logical %s, physical %s", Format(r.Logical), Format(r.Physical))
+}
+
+// Measure returns both the logical and the physical size of v, which a single number leaves
+// ambiguous. The options apply to both traversals, except those selecting the size model,
+// size classes and GC overhead, which Measure sets for each. Like GetTotalSizeE it reports why
+// a traversal stopped early, in which case the sizes are lower bounds.
+func Measure(v interface{}, opts ...Option) (Result, error) {
+	rv := reflect.ValueOf(v)
+	logical, err := newWalker(withModel(opts, false)...).measure(rv)
+	if err != nil {
+		return Result{Logical: logical}, err
+	}
+	physical, err := newWalker(withModel(opts, true)...).measure(rv)
+	return Result{Logical: logical, Physical: physical}, err
+}
+
+// withModel appends to opts an option measuring with ExactSizes, with or without the
+// allocation overheads of the heap
+func withModel(opts []Option, physical bool) []Option {
+	return append(opts[:len(opts):len(opts)], func(c *config) {
+		c.model = ExactSizes
+		c.sizeClasses = physical
+		c.gcOverhead = physical
+	})
+}
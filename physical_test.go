@@ -0,0 +1,47 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	Debug = false
+
+	v := &Person{Name: strings.Repeat("x", 33)}
+
+	t.Run("Sizes", func(t *testing.T) {
+		r, err := Measure(v)
+		fmt.Printf("Result: %s\n", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := GetTotalSize(v, WithSizeModel(ExactSizes)); r.Logical != expected {
+			t.Errorf("Expected logical size %d, got %d", expected, r.Logical)
+		}
+		if expected := GetTotalSize(v, WithGCOverhead()); r.Physical != expected {
+			t.Errorf("Expected physical size %d, got %d", expected, r.Physical)
+		}
+		// The 33-byte string alone is rounded up to 48 bytes
+		if r.Overhead() < 15 {
+			t.Errorf("Expected at least 15 bytes of overhead, got %d", r.Overhead())
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		r, _ := Measure(v)
+		other, _ := Measure(v, WithSizeClasses(), WithSizeModel(LegacySizes))
+		if other != r {
+			t.Errorf("Expected size model options to be overridden, %v, got %v", r, other)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		_, err := Measure(v, WithMaxNodes(1))
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded, got %v", err)
+		}
+	})
+}
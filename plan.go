@@ -0,0 +1,194 @@
+// plan.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typePlan is the precomputed traversal strategy for a type
+type typePlan struct {
+	// constant is set when every value of the type has the same total size
+	constant bool
+	// size is the total size of constant types, or the fixed part of a struct's size
+	size uint64
+	// fields lists the struct fields that still need to be traversed
+	fields []int
+	// issue locates a value sized only approximately within the constant part of the type
+	issue *planIssue
+}
+
+// planIssue is a value of an unsupported kind, located by its path relative to the planned type
+type planIssue struct {
+	suffix string
+	typ    reflect.Type
+}
+
+// planKey identifies a plan; sizes differ between layouts
+type planKey struct {
+	t reflect.Type
+	l layout
+}
+
+// plans caches a *typePlan per planKey
+var plans sync.Map
+
+// resetPlans drops all cached plans and item estimates, after registrations changed how types
+// are sized
+func resetPlans() {
+	plans.Range(func(key, _ interface{}) bool {
+		plans.Delete(key)
+		return true
+	})
+	estimates.Range(func(key, _ interface{}) bool {
+		estimates.Delete(key)
+		return true
+	})
+}
+
+// planFor returns the cached plan for t under the layout l, computing it on first use
+func planFor(t reflect.Type, l layout) *typePlan {
+	key := planKey{t, l}
+	if p, ok := plans.Load(key); ok {
+		return p.(*typePlan)
+	}
+	p, _ := plans.LoadOrStore(key, computePlan(t, l))
+	return p.(*typePlan)
+}
+
+func computePlan(t reflect.Type, l layout) *typePlan {
+	m := l.model
+	if size, ok := constantSize(t, l); ok {
+		return &typePlan{constant: true, size: size, issue: findIssue(t, m)}
+	}
+
+	p := &typePlan{}
+	if t.Kind() == reflect.Struct {
+		if m == ExactSizes {
+			// Fields that are traversed account for their own inline bytes
+			p.size = l.sizeof(t)
+		} else {
+			p.size = l.header()
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if size, ok := constantSize(t.Field(i).Type, l); ok {
+				if m == LegacySizes {
+					p.size += size
+				}
+				if p.issue == nil {
+					p.issue = fieldIssue(t.Field(i), m)
+				}
+			} else {
+				if m == ExactSizes {
+					p.size -= l.sizeof(t.Field(i).Type)
+				}
+				p.fields = append(p.fields, i)
+			}
+		}
+	}
+	return p
+}
+
+// constantSize reports the size of t if it doesn't depend on the value, following the same
+// rules as walker.enter
+func constantSize(t reflect.Type, l layout) (uint64, bool) {
+	if _, ok := offHeapSizers.Load(t); ok {
+		return 0, false
+	}
+	if registeredHandler(t, l) != nil {
+		return 0, false
+	}
+	if builtinHandler(t) != nil {
+		// Interned references and the internals of sync primitives are not followed, so
+		// those types have a constant size
+		if internedType(t) || syncPrimitives[t] {
+			return inlineSize(t, l), true
+		}
+		return 0, false
+	}
+	if zeroSized(t) {
+		return 0, true
+	}
+	m := l.model
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1, true
+	case reflect.Int16, reflect.Uint16:
+		return 2, true
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4, true
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		return 8, true
+	case reflect.Int, reflect.Uint:
+		return l.sizeof(t), true
+
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.String, reflect.Map:
+		return 0, false
+
+	case reflect.UnsafePointer, reflect.Uintptr:
+		if _, ok := unsafeTypes.Load(t); ok {
+			return 0, false
+		}
+
+	case reflect.Struct:
+		size := l.header()
+		for i := 0; i < t.NumField(); i++ {
+			fieldSize, ok := constantSize(t.Field(i).Type, l)
+			if !ok {
+				return 0, false
+			}
+			size += fieldSize
+		}
+		if m == ExactSizes {
+			// Includes padding, which field sizes alone miss
+			size = l.sizeof(t)
+		}
+		return size, true
+	}
+
+	if t.Kind() == reflect.Array {
+		elemSize, ok := constantSize(t.Elem(), l)
+		if !ok {
+			return 0, false
+		}
+		if m == LegacySizes {
+			return l.header() + uint64(t.Len())*elemSize, true
+		}
+		return l.sizeof(t), true
+	}
+	if m == LegacySizes {
+		return l.header(), true
+	}
+	if t.Kind() == reflect.Chan {
+		return 0, false
+	}
+	return l.sizeof(t), true
+}
+
+// findIssue returns the first value of an unsupported kind within the constant type t
+func findIssue(t reflect.Type, m SizeModel) *planIssue {
+	if unsupportedKind(t.Kind(), m) {
+		return &planIssue{typ: t}
+	}
+	switch t.Kind() {
+	case reflect.Array:
+		if issue := findIssue(t.Elem(), m); issue != nil && t.Len() > 0 {
+			return &planIssue{suffix: "[0]" + issue.suffix, typ: issue.typ}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if issue := fieldIssue(t.Field(i), m); issue != nil {
+				return issue
+			}
+		}
+	}
+	return nil
+}
+
+func fieldIssue(field reflect.StructField, m SizeModel) *planIssue {
+	issue := findIssue(field.Type, m)
+	if issue != nil {
+		issue = &planIssue{suffix: fieldSegment(field) + issue.suffix, typ: issue.typ}
+	}
+	return issue
+}
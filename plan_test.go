@@ -0,0 +1,119 @@
+package memsize
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+type point struct {
+	X, Y  float64
+	Flags uint8
+}
+
+type labeledPoint struct {
+	point
+	Label string
+	Tags  []string
+}
+
+func TestPlanCache(t *testing.T) {
+	Debug = false
+
+	t.Run("Constant Types", func(t *testing.T) {
+		p := planFor(reflect.TypeOf(point{}), layout{})
+		if !p.constant {
+			t.Fatal("Expected a pointer-free struct to have a constant size")
+		}
+		if p.size != GetReport(point{}).Total() {
+			t.Errorf("Expected constant size %d, got %d", GetReport(point{}).Total(), p.size)
+		}
+	})
+
+	t.Run("Dynamic Fields", func(t *testing.T) {
+		p := planFor(reflect.TypeOf(labeledPoint{}), layout{})
+		if p.constant {
+			t.Fatal("Expected a struct with strings to need traversal")
+		}
+		if len(p.fields) != 2 {
+			t.Errorf("Expected 2 fields to traverse, got %v", p.fields)
+		}
+	})
+
+	t.Run("Fast Path Matches Full Walk", func(t *testing.T) {
+		values := []interface{}{
+			point{1, 2, 3},
+			&labeledPoint{Label: "a", Tags: []string{"x", "yy"}},
+			[]labeledPoint{{Label: "b"}, {Tags: make([]string, 3)}},
+			map[string]point{"a": {}, "b": {}},
+		}
+		for _, v := range values {
+			if fast, full := GetTotalSize(v), GetReport(v).Total(); fast != full {
+				t.Errorf("%T: fast path returned %d, full walk %d", v, fast, full)
+			}
+		}
+	})
+}
+
+func TestPrimitiveContainers(t *testing.T) {
+	Debug = false
+
+	t.Run("Byte Slice", func(t *testing.T) {
+		buf := make([]byte, 10<<20)
+		report := GetReport(buf)
+		if len(report.Root.Children) != 0 {
+			t.Errorf("Expected byte slice elements to be folded, got %d children", len(report.Root.Children))
+		}
+		header := uint64(unsafe.Sizeof(interface{}(nil)))
+		if expected := header + 2*uint64(len(buf)); report.Total() != expected {
+			t.Errorf("Expected %d bytes, got %d", expected, report.Total())
+		}
+	})
+
+	t.Run("Primitive Map", func(t *testing.T) {
+		m := map[int]int64{1: 1, 2: 2, 3: 3}
+		report := GetReport(m)
+		if len(report.Root.Children) != 0 {
+			t.Errorf("Expected map entries to be folded, got %d children", len(report.Root.Children))
+		}
+		if report.Total() != GetTotalSize(m) {
+			t.Errorf("Expected %d bytes, got %d", GetTotalSize(m), report.Total())
+		}
+	})
+
+	t.Run("Mixed Map", func(t *testing.T) {
+		m := map[int]string{1: "one", 2: "two"}
+		report := GetReport(m)
+		if len(report.Root.Children) != 2 {
+			t.Errorf("Expected only the 2 string values to be visited, got %d children", len(report.Root.Children))
+		}
+	})
+}
+
+func BenchmarkGetTotalSize(b *testing.B) {
+	Debug = false
+	points := make([]labeledPoint, 1000)
+	for i := range points {
+		points[i].Label = "point"
+	}
+
+	b.Run("Plans", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			SizeOf(points)
+		}
+	})
+
+	b.Run("Full Walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ReportOf(points)
+		}
+	})
+}
+
+func BenchmarkByteSlice(b *testing.B) {
+	Debug = false
+	buf := make([]byte, 100<<20)
+	for i := 0; i < b.N; i++ {
+		SizeOf(buf)
+	}
+}
@@ -0,0 +1,105 @@
+// policy.go
+package memsize
+
+import "reflect"
+
+// PointerPolicy selects which pointers a measurement follows
+type PointerPolicy int
+
+const (
+	// FollowAll follows every pointer; this is the default
+	FollowAll PointerPolicy = iota
+	// FollowSamePackage follows pointers and interfaces only to types defined in the package of
+	// the measured value's type, or to unnamed and predeclared types such as []byte. Values of
+	// other packages, such as user-provided callbacks or handlers, count only their reference.
+	FollowSamePackage
+)
+
+// WithPointerPolicy selects which pointers are followed, see PointerPolicy
+func WithPointerPolicy(p PointerPolicy) Option {
+	return func(c *config) {
+		c.pointerPolicy = p
+	}
+}
+
+// WithMaxPointerDepth follows at most n pointers and interfaces on the way from the root to
+// any value; references beyond count only themselves. Zero, the default, sets no limit.
+// Measurements with a depth limit are not parallelized.
+func WithMaxPointerDepth(n int) Option {
+	return func(c *config) {
+		c.maxPointerDepth = n
+	}
+}
+
+// WithMaxDepth visits values at most n levels below the root, counting values at the limit
+// only by their own size. Their report nodes are marked Truncated and the report as a whole
+// too, as their sizes are lower bounds. This bounds the work spent on deep graphs and gives a
+// quick coarse measurement of their upper levels. Zero, the default, sets no limit.
+// Measurements with a depth limit are not parallelized.
+//
+// Objects cut off at the limit count as visited, so they aren't counted either where they
+// are reached again higher up.
+func WithMaxDepth(n int) Option {
+	return func(c *config) {
+		c.maxDepth = n
+	}
+}
+
+// depthLimited reports whether the children of the frame on top of the stack are beyond the
+// depth limit, marking its node as truncated if so
+func (w *walker) depthLimited(f *frame) bool {
+	if limit := w.cfg.maxDepth; limit == 0 || len(w.stack) <= limit {
+		return false
+	}
+	if w.cfg.debug {
+		w.debugPrint(f, "Depth limit %d reached, not descending", w.cfg.maxDepth)
+	}
+	w.truncated = true
+	if f.node != nil {
+		f.node.Truncated = true
+	}
+	return true
+}
+
+// pointerDepth returns the number of pointers and interfaces followed to reach the value of
+// a new frame, whose parent is on top of the stack
+func (w *walker) pointerDepth() int {
+	if len(w.stack) == 0 {
+		return 0
+	}
+	parent := &w.stack[len(w.stack)-1]
+	if k := parent.v.Kind(); parent.follow && (k == reflect.Ptr || k == reflect.Interface) {
+		return parent.depth + 1
+	}
+	return parent.depth
+}
+
+// follows reports whether the policy allows following a pointer or interface of the frame
+// to a value of type elem
+func (w *walker) follows(f *frame, elem reflect.Type) bool {
+	if limit := w.cfg.maxPointerDepth; limit > 0 && f.depth >= limit {
+		w.debugPrint(f, "Pointer depth limit %d reached, not following", limit)
+		return false
+	}
+	if w.cfg.pointerPolicy == FollowSamePackage {
+		if pkg := basePackage(elem); pkg != "" && pkg != w.rootPackage {
+			w.debugPrint(f, "Not following %s outside of package %s", elem, w.rootPackage)
+			return false
+		}
+	}
+	return true
+}
+
+// basePackage returns the package of the named type a type is built from, e.g. the package
+// of T for []*T, or "" for unnamed and predeclared types
+func basePackage(t reflect.Type) string {
+	for t.Name() == "" {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+			t = t.Elem()
+		default:
+			return ""
+		}
+	}
+	return t.PkgPath()
+}
@@ -0,0 +1,144 @@
+package memsize
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestPointerPolicy(t *testing.T) {
+	Debug = false
+
+	type item struct {
+		Data []byte
+	}
+	type library struct {
+		Items   []*item
+		Payload *[]byte
+		User    interface{}
+		Ext     *big.Int
+	}
+	payload := make([]byte, 100)
+	external := new(big.Int).Lsh(big.NewInt(1), 4096)
+	v := &library{
+		Items:   []*item{{Data: make([]byte, 50)}},
+		Payload: &payload,
+		User:    external,
+		Ext:     external,
+	}
+
+	all := GetTotalSize(v)
+	same := GetTotalSize(v, WithPointerPolicy(FollowSamePackage))
+	fmt.Printf("All: %d, same package: %d\n", all, same)
+
+	// Without the big.Int both references count their headers only
+	v.User, v.Ext = nil, nil
+	internal := GetTotalSize(v)
+	if same != internal {
+		t.Errorf("Expected %d bytes without the external values, got %d", internal, same)
+	}
+	if same >= all {
+		t.Errorf("Expected following the same package only to count less than %d, got %d", all, same)
+	}
+
+	t.Run("Max Depth", func(t *testing.T) {
+		type link struct {
+			Next *link
+			Data [64]byte
+		}
+		var head *link
+		for i := 0; i < 10; i++ {
+			head = &link{Next: head}
+		}
+
+		// Each followed link adds its struct to the pointer
+		linkSize := GetTotalSize(&link{}) - GetTotalSize((*link)(nil))
+		for _, depth := range []int{1, 3} {
+			got := GetTotalSize(head, WithMaxPointerDepth(depth))
+			expected := GetTotalSize((*link)(nil)) + uint64(depth)*linkSize
+			if got != expected {
+				t.Errorf("Expected %d bytes at depth %d, got %d", expected, depth, got)
+			}
+		}
+		if got := GetTotalSize(head, WithMaxPointerDepth(0)); got != GetTotalSize(head) {
+			t.Errorf("Expected no limit at depth 0, got %d", got)
+		}
+		if got, full := GetTotalSize(head, WithMaxPointerDepth(20), WithParallelism(4)), GetTotalSize(head); got != full {
+			t.Errorf("Expected the whole chain below the limit, got %d instead of %d", got, full)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		v.User = external
+		report := GetReport(v, WithPointerPolicy(FollowSamePackage))
+		report.Walk(func(n *Node) bool {
+			if n.Path == "root.ptr.User" && len(n.Children) != 0 {
+				t.Errorf("Expected the external value not to be visited, got %d children", len(n.Children))
+			}
+			return true
+		})
+	})
+}
+
+func TestMaxDepth(t *testing.T) {
+	Debug = false
+
+	type node struct {
+		Name     string
+		Children []*node
+	}
+	var build func(depth int) *node
+	build = func(depth int) *node {
+		n := &node{Name: fmt.Sprint("level-", depth)}
+		if depth < 6 {
+			n.Children = []*node{build(depth + 1), build(depth + 1)}
+		}
+		return n
+	}
+	tree := build(0)
+	full := GetTotalSize(tree)
+
+	t.Run("Lower Bound", func(t *testing.T) {
+		previous := uint64(0)
+		for _, depth := range []int{1, 4, 8, 16, 32} {
+			size := GetTotalSize(tree, WithMaxDepth(depth))
+			fmt.Printf("Max depth %d: %d of %d bytes\n", depth, size, full)
+			if size < previous || size > full {
+				t.Errorf("Expected a size between %d and %d at depth %d, got %d", previous, full, depth, size)
+			}
+			previous = size
+		}
+		if previous != full {
+			t.Errorf("Expected the whole tree above the limit, got %d instead of %d", previous, full)
+		}
+		if got := GetTotalSize(tree, WithMaxDepth(0)); got != full {
+			t.Errorf("Expected no limit at depth 0, got %d", got)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		// Levels 0 to 2 are the pointer, the node and its slice of children
+		report := GetReport(tree, WithMaxDepth(2))
+		if !report.Truncated || report.Total() != GetTotalSize(tree, WithMaxDepth(2)) {
+			t.Errorf("Expected a truncated report of %d bytes, got %d (truncated %v)",
+				GetTotalSize(tree, WithMaxDepth(2)), report.Total(), report.Truncated)
+		}
+		truncated := 0
+		report.Walk(func(n *Node) bool {
+			if n.Truncated {
+				truncated++
+				if len(n.Children) > 0 || n.Path != "root.ptr.Children" {
+					t.Errorf("Expected only root.ptr.Children to be cut, got %s with %d children", n.Path, len(n.Children))
+				}
+			}
+			return true
+		})
+		if truncated != 1 {
+			t.Errorf("Expected one truncated node, got %d", truncated)
+		}
+
+		if GetReport(tree, WithMaxDepth(64)).Truncated {
+			t.Error("Expected a report above the limit not to be truncated")
+		}
+	})
+}
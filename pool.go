@@ -0,0 +1,66 @@
+// pool.go
+package memsize
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+func init() {
+	typeHandlers[reflect.TypeOf(sync.Pool{})] = poolHandler
+}
+
+// poolEstimate is the retention of a sync.Pool registered with WithPoolEstimate
+type poolEstimate struct {
+	sample func() interface{}
+	count  int
+}
+
+// WithPoolEstimate estimates the objects retained by a sync.Pool as count objects the size of
+// a value returned by sample, e.g. the pool's New function. A count of zero or less assumes
+// one object per P, which the pool keeps in its private slots between collections.
+//
+// Pooled objects are held by per-P storage the walker can't reach, so by default only the
+// pool itself is counted. The estimate is a best effort: pools are drained by the garbage
+// collector over two cycles, and their objects are measured as the sample, not as they are.
+func WithPoolEstimate(pool *sync.Pool, sample func() interface{}, count int) Option {
+	return func(c *config) {
+		if pool == nil || sample == nil {
+			c.err = errors.New("memsize: WithPoolEstimate needs a pool and a sample function")
+			return
+		}
+		if c.pools == nil {
+			c.pools = make(map[uintptr]poolEstimate)
+		}
+		c.pools[uintptr(unsafe.Pointer(pool))] = poolEstimate{sample: sample, count: count}
+	}
+}
+
+// poolHandler sizes a sync.Pool by its fields, never traversing its per-P storage, and adds
+// the retention registered for it with WithPoolEstimate
+func poolHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	size := inlineSize(v.Type(), w.cfg.layout())
+	if len(w.cfg.pools) == 0 || !v.CanAddr() {
+		return size, nil
+	}
+	est, ok := w.cfg.pools[v.UnsafeAddr()]
+	if !ok {
+		return size, nil
+	}
+
+	count := est.count
+	if count <= 0 {
+		count = runtime.GOMAXPROCS(0)
+	}
+	// Samples are distinct objects, measured apart from the values already counted and
+	// without logging their internals
+	cfg := *w.cfg
+	cfg.debug = false
+	inner := w.child(&cfg)
+	inner.seen, inner.ids = newVisitedSet(false), newIdentitySet(&cfg)
+	sample := inner.getTotalSize(reflect.ValueOf(est.sample()), "")
+	return size + uint64(count)*sample, nil
+}
@@ -0,0 +1,61 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestPoolEstimate(t *testing.T) {
+	Debug = false
+	exact := WithSizeModel(ExactSizes)
+
+	type server struct {
+		Name    string
+		Buffers sync.Pool
+	}
+	s := &server{Name: "api"}
+	s.Buffers.New = func() interface{} { return bytes.NewBuffer(make([]byte, 0, 4096)) }
+	for i := 0; i < 10; i++ {
+		s.Buffers.Put(s.Buffers.New())
+	}
+	sample := GetTotalSize(s.Buffers.New(), exact)
+
+	t.Run("Default", func(t *testing.T) {
+		size := GetTotalSize(s, exact)
+		fmt.Printf("Server without pool estimate: %d bytes\n", size)
+		if size >= sample {
+			t.Errorf("Expected pooled buffers not to be counted, got %d bytes", size)
+		}
+	})
+
+	t.Run("Estimated", func(t *testing.T) {
+		without := GetTotalSize(s, exact)
+		size := GetTotalSize(s, exact, WithPoolEstimate(&s.Buffers, s.Buffers.New, 10))
+		fmt.Printf("Server with 10 pooled buffers of %d bytes: %d bytes\n", sample, size)
+		if size != without+10*sample {
+			t.Errorf("Expected %d, got %d", without+10*sample, size)
+		}
+
+		perP := GetTotalSize(s, exact, WithPoolEstimate(&s.Buffers, s.Buffers.New, 0))
+		if expected := without + uint64(runtime.GOMAXPROCS(0))*sample; perP != expected {
+			t.Errorf("Expected one buffer per P, %d bytes, got %d", expected, perP)
+		}
+	})
+
+	t.Run("Other Pool", func(t *testing.T) {
+		var other sync.Pool
+		size := GetTotalSize(s, exact, WithPoolEstimate(&other, s.Buffers.New, 10))
+		if size != GetTotalSize(s, exact) {
+			t.Errorf("Expected an estimate for another pool to be ignored, got %d", size)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := GetTotalSizeE(s, WithPoolEstimate(nil, s.Buffers.New, 1)); err == nil {
+			t.Error("Expected an error for a nil pool")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+// profile.go
+package memsize
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// Field numbers of the messages in github.com/google/pprof/proto/profile.proto
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+	profileTimeNanos   = 9
+	profilePeriodType  = 11
+	profilePeriod      = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+)
+
+// WriteProfile writes the report as a gzip-compressed pprof profile. Every node becomes a
+// sample whose stack is its path from the root, valued at its object count and shallow bytes,
+// so `go tool pprof -http` renders retention as a flame graph.
+func (r *Report) WriteProfile(w io.Writer) error {
+	p := &profileBuilder{strings: map[string]int64{"": 0}, stringTable: []string{""}}
+
+	// An explicit stack keeps arbitrarily deep reports from overflowing the goroutine stack
+	type queued struct {
+		node   *Node
+		parent uint64
+	}
+	var stack []queued
+	if r != nil && r.Root != nil {
+		stack = append(stack, queued{node: r.Root})
+	}
+	for len(stack) > 0 {
+		q := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		loc := p.location(q.node, q.parent)
+		if q.node.Shallow > 0 {
+			p.samples = append(p.samples, profileSampleData{location: loc, shallow: q.node.Shallow})
+		}
+		for i := len(q.node.Children) - 1; i >= 0; i-- {
+			stack = append(stack, queued{node: q.node.Children[i], parent: loc})
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := p.encode(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// profileSampleData is a sample whose stack is its location followed by its ancestors
+type profileSampleData struct {
+	location uint64
+	shallow  uint64
+}
+
+type profileBuilder struct {
+	strings     map[string]int64
+	stringTable []string
+	functions   []*Node
+	// parents holds the location of the parent of every location, 0 for the root
+	parents []uint64
+	samples []profileSampleData
+}
+
+func (p *profileBuilder) str(s string) int64 {
+	if id, ok := p.strings[s]; ok {
+		return id
+	}
+	id := int64(len(p.stringTable))
+	p.strings[s] = id
+	p.stringTable = append(p.stringTable, s)
+	return id
+}
+
+// location allocates a location with a single function for a node; ids start at 1
+func (p *profileBuilder) location(n *Node, parent uint64) uint64 {
+	p.functions = append(p.functions, n)
+	p.parents = append(p.parents, parent)
+	return uint64(len(p.functions))
+}
+
+// stack appends the stack of a sample at location loc to locs, ordered leaf first as in pprof
+func (p *profileBuilder) stack(locs []uint64, loc uint64) []uint64 {
+	for ; loc != 0; loc = p.parents[loc-1] {
+		locs = append(locs, loc)
+	}
+	return locs
+}
+
+// encode writes the profile to w. Samples are flushed as they are encoded, since the stacks of
+// deep reports repeat their ancestors and would otherwise all be held at once.
+func (p *profileBuilder) encode(w io.Writer) error {
+	var b protoBuffer
+
+	valueType := func(typ, unit string) func(*protoBuffer) {
+		t, u := p.str(typ), p.str(unit)
+		return func(m *protoBuffer) {
+			m.int64Field(valueTypeType, t)
+			m.int64Field(valueTypeUnit, u)
+		}
+	}
+	b.message(profileSampleType, valueType("objects", "count"))
+	b.message(profileSampleType, valueType("space", "bytes"))
+
+	var locs []uint64
+	for _, s := range p.samples {
+		locs = p.stack(locs[:0], s.location)
+		shallow := s.shallow
+		b.message(profileSample, func(m *protoBuffer) {
+			m.packed(sampleLocationID, locs)
+			m.packed(sampleValue, []uint64{1, shallow})
+		})
+		if _, err := w.Write(b.buf); err != nil {
+			return err
+		}
+		b.buf = b.buf[:0]
+	}
+
+	for i, n := range p.functions {
+		id := uint64(i + 1)
+		name, typ := p.str(n.Path), p.str(n.Type)
+		b.message(profileLocation, func(m *protoBuffer) {
+			m.uint64Field(locationID, id)
+			m.message(locationLine, func(l *protoBuffer) {
+				l.uint64Field(lineFunctionID, id)
+			})
+		})
+		b.message(profileFunction, func(m *protoBuffer) {
+			m.uint64Field(functionID, id)
+			m.int64Field(functionName, name)
+			m.int64Field(functionSystemName, typ)
+		})
+	}
+
+	b.int64Field(profileTimeNanos, time.Now().UnixNano())
+	b.message(profilePeriodType, valueType("space", "bytes"))
+	b.int64Field(profilePeriod, 1)
+
+	// The string table is written last since the fields above keep adding to it
+	for _, s := range p.stringTable {
+		b.stringField(profileStringTable, s)
+	}
+	_, err := w.Write(b.buf)
+	return err
+}
+
+// protoBuffer is a minimal protocol buffer encoder
+type protoBuffer struct {
+	buf []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protoBuffer) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) uint64Field(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64Field(field int, x int64) {
+	b.uint64Field(field, uint64(x))
+}
+
+func (b *protoBuffer) bytesField(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.buf = append(b.buf, data...)
+}
+
+// stringField is always written, even when empty, since string table positions matter
+func (b *protoBuffer) stringField(field int, s string) {
+	b.bytesField(field, []byte(s))
+}
+
+func (b *protoBuffer) packed(field int, xs []uint64) {
+	var m protoBuffer
+	for _, x := range xs {
+		m.varint(x)
+	}
+	b.bytesField(field, m.buf)
+}
+
+func (b *protoBuffer) message(field int, fn func(*protoBuffer)) {
+	var m protoBuffer
+	fn(&m)
+	b.bytesField(field, m.buf)
+}
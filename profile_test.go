@@ -0,0 +1,148 @@
+package memsize
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+// protoFields decodes the top-level fields of a protocol buffer message;
+// varints are returned as uint64 and length-delimited fields as []byte
+func protoFields(t *testing.T, data []byte) map[int][]interface{} {
+	t.Helper()
+	fields := make(map[int][]interface{})
+	for len(data) > 0 {
+		key, n := protoVarint(data)
+		data = data[n:]
+		field, wireType := int(key>>3), key&7
+		switch wireType {
+		case 0:
+			x, n := protoVarint(data)
+			data = data[n:]
+			fields[field] = append(fields[field], x)
+		case 2:
+			l, n := protoVarint(data)
+			data = data[n:]
+			fields[field] = append(fields[field], data[:l])
+			data = data[l:]
+		default:
+			t.Fatalf("Unexpected wire type %d", wireType)
+		}
+	}
+	return fields
+}
+
+func protoVarint(data []byte) (uint64, int) {
+	var x uint64
+	for i, b := range data {
+		x |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			return x, i + 1
+		}
+	}
+	return 0, len(data)
+}
+
+func TestWriteProfile(t *testing.T) {
+	Debug = false
+
+	person := &Person{
+		Name: "John Doe",
+		Data: map[string]interface{}{"age": 30},
+	}
+	report := GetReport(person)
+
+	var buf bytes.Buffer
+	if err := report.WriteProfile(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Profile is not gzip-compressed: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := protoFields(t, data)
+
+	t.Run("String Table", func(t *testing.T) {
+		strs := make(map[string]bool)
+		for _, s := range profile[profileStringTable] {
+			strs[string(s.([]byte))] = true
+		}
+		if first := string(profile[profileStringTable][0].([]byte)); first != "" {
+			t.Errorf("Expected first string to be empty, got %q", first)
+		}
+		for _, want := range []string{"space", "bytes", "root.ptr.Name", "*memsize.Person"} {
+			if !strs[want] {
+				t.Errorf("Expected string table to contain %q", want)
+			}
+		}
+	})
+
+	t.Run("Sample Values", func(t *testing.T) {
+		var total uint64
+		for _, s := range profile[profileSample] {
+			sample := protoFields(t, s.([]byte))
+			values := sample[sampleValue][0].([]byte)
+			_, n := protoVarint(values)
+			space, _ := protoVarint(values[n:])
+			total += space
+		}
+		if total != report.Total() {
+			t.Errorf("Sample values add up to %d, expected %d", total, report.Total())
+		}
+	})
+
+	t.Run("Locations", func(t *testing.T) {
+		if len(profile[profileLocation]) != len(profile[profileFunction]) {
+			t.Errorf("Expected one function per location, got %d locations and %d functions",
+				len(profile[profileLocation]), len(profile[profileFunction]))
+		}
+	})
+
+	t.Run("Deep", func(t *testing.T) {
+		// Every element of a list is two levels deeper than the previous one
+		const n = 500
+		var head *listNode
+		for i := 0; i < n; i++ {
+			head = &listNode{Value: i, Next: head}
+		}
+
+		var buf bytes.Buffer
+		if err := GetReport(head).WriteProfile(&buf); err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var deepest []uint64
+		for _, s := range protoFields(t, data)[profileSample] {
+			packed := protoFields(t, s.([]byte))[sampleLocationID][0].([]byte)
+			var locs []uint64
+			for len(packed) > 0 {
+				loc, n := protoVarint(packed)
+				locs = append(locs, loc)
+				packed = packed[n:]
+			}
+			if len(locs) > len(deepest) {
+				deepest = locs
+			}
+		}
+		if len(deepest) < 2*n {
+			t.Errorf("Expected a stack of at least %d locations, got %d", 2*n, len(deepest))
+		}
+		if len(deepest) > 0 && deepest[len(deepest)-1] != 1 {
+			t.Errorf("Expected stacks to end at the root, got location %d", deepest[len(deepest)-1])
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// progress.go
+package memsize
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressFunc receives the number of values visited and the bytes accounted so far
+type ProgressFunc func(nodesVisited, bytesSoFar uint64)
+
+// WithProgress calls fn about every 100ms during the traversal and once when it is done, so
+// CLIs can show progress bars and services can log heartbeats during long measurements. Calls
+// are serialized, also with WithParallelism, and run on the traversing goroutine, so fn should
+// return quickly. Bytes are the shallow sizes accounted so far, before sampling extrapolates.
+func WithProgress(fn ProgressFunc) Option {
+	return func(c *config) {
+		c.progress = fn
+	}
+}
+
+// progressCheckInterval is the number of visited values between checks of the clock
+const progressCheckInterval = 1024
+
+// progressPeriod is the least time between two calls of a ProgressFunc
+var progressPeriod = 100 * time.Millisecond
+
+// progress reports the advance of a measurement, shared by all of its walkers. All of its
+// methods are no-ops on nil, which is the case unless WithProgress was given.
+type progress struct {
+	fn ProgressFunc
+
+	nodes uint64 // atomic
+	bytes uint64 // atomic
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newProgress(cfg *config) *progress {
+	if cfg.progress == nil {
+		return nil
+	}
+	return &progress{fn: cfg.progress, last: time.Now()}
+}
+
+// charge accounts a visited value of the given shallow size
+func (p *progress) charge(size uint64) {
+	if p == nil {
+		return
+	}
+	bytes := atomic.AddUint64(&p.bytes, size)
+	nodes := atomic.AddUint64(&p.nodes, 1)
+	if nodes%progressCheckInterval != 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := time.Now(); now.Sub(p.last) >= progressPeriod {
+		p.last = now
+		p.fn(nodes, bytes)
+	}
+}
+
+// done reports the final counts
+func (p *progress) done() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fn(atomic.LoadUint64(&p.nodes), atomic.LoadUint64(&p.bytes))
+}
@@ -0,0 +1,67 @@
+package memsize
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	Debug = false
+
+	// Report on every check of the clock
+	period := progressPeriod
+	progressPeriod = 0
+	defer func() { progressPeriod = period }()
+
+	items := make([]*[4]int, 10000)
+	for i := range items {
+		items[i] = new([4]int)
+	}
+
+	type call struct{ nodes, bytes uint64 }
+	record := func(calls *[]call) ProgressFunc {
+		var mu sync.Mutex
+		return func(nodes, bytes uint64) {
+			mu.Lock()
+			defer mu.Unlock()
+			*calls = append(*calls, call{nodes, bytes})
+		}
+	}
+
+	t.Run("Sequential", func(t *testing.T) {
+		var calls []call
+		size, stats, _ := GetTotalSizeStats(items, WithProgress(record(&calls)))
+		fmt.Printf("Progress: %d calls, last %v\n", len(calls), calls[len(calls)-1])
+		if len(calls) < 10 {
+			t.Fatalf("Expected a call every %d nodes, got %d calls", progressCheckInterval, len(calls))
+		}
+		for i := 1; i < len(calls); i++ {
+			if calls[i].nodes < calls[i-1].nodes || calls[i].bytes < calls[i-1].bytes {
+				t.Errorf("Expected growing counts, got %v after %v", calls[i], calls[i-1])
+			}
+		}
+		if last := calls[len(calls)-1]; last.nodes != stats.Nodes || last.bytes != size {
+			t.Errorf("Expected a final call with %d nodes and %d bytes, got %v", stats.Nodes, size, last)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		var calls []call
+		size := GetTotalSize(items, WithProgress(record(&calls)), WithParallelism(4))
+		if len(calls) == 0 || calls[len(calls)-1].bytes != size {
+			t.Errorf("Expected a final call with %d bytes, got %v", size, calls)
+		}
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		// A period no traversal reaches, even a slow one under the race detector
+		progressPeriod = time.Hour
+		var calls []call
+		GetTotalSize(items, WithProgress(record(&calls)))
+		if len(calls) != 1 {
+			t.Errorf("Expected only the final call within the period, got %d", len(calls))
+		}
+	})
+}
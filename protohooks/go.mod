@@ -0,0 +1,10 @@
+module github.com/afshin-deriv/go-memsize/protohooks
+
+go 1.20
+
+require (
+	github.com/afshin-deriv/go-memsize v0.0.0
+	google.golang.org/protobuf v1.34.2
+)
+
+replace github.com/afshin-deriv/go-memsize => ../
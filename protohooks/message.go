@@ -0,0 +1,21 @@
+// message.go
+package protohooks
+
+import (
+	"reflect"
+
+	"github.com/afshin-deriv/go-memsize"
+	"google.golang.org/protobuf/runtime/protoimpl"
+)
+
+func init() {
+	memsize.RegisterHandler(reflect.TypeOf(protoimpl.MessageState{}), messageStateHandler)
+}
+
+// messageStateHandler sizes the state embedded in generated messages without following it:
+// once a message was used through reflection, marshaled or compared, the state points to the
+// MessageInfo of its type. That cache of descriptors and coders is shared by every message of
+// the type and would otherwise be counted in full against the first message reached.
+func messageStateHandler(c *memsize.HandlerContext, v reflect.Value) (uint64, []memsize.Child) {
+	return c.InlineSize(v.Type()), nil
+}
@@ -0,0 +1,32 @@
+package protohooks
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/afshin-deriv/go-memsize"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMessageState(t *testing.T) {
+	memsize.Debug = false
+
+	opts := []memsize.Option{memsize.WithSizeModel(memsize.ExactSizes)}
+	msg := wrapperspb.String(strings.Repeat("x", 100))
+	before := memsize.GetTotalSize(msg, opts...)
+
+	// Marshaling stores the MessageInfo of the type in the message's state
+	if _, err := proto.Marshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	after := memsize.GetTotalSize(msg, opts...)
+	fmt.Printf("StringValue: %d bytes before marshaling, %d after\n", before, after)
+	if after != before {
+		t.Errorf("Expected the size not to change with the type's descriptors, %d, got %d", before, after)
+	}
+	if expected := uint64(8 + 56 + 100); after != expected {
+		t.Errorf("Expected %d, got %d", expected, after)
+	}
+}
@@ -0,0 +1,52 @@
+// provider.go
+package memsize
+
+import (
+	"reflect"
+	"strconv"
+)
+
+func init() {
+	typeHandlers[reflect.TypeOf(providedValues(nil))] = providedHandler
+}
+
+// RootProvider returns the values a subsystem holds at the time it is called, such as the items
+// queued for its workers, which no long-lived struct reaches while they are in flight
+type RootProvider func() []interface{}
+
+// providedValues are the values returned by a RootProvider, measured as one root
+type providedValues []interface{}
+
+// providedHandler counts the values of a provider without the slice holding them, which only
+// exists while they are measured
+func providedHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	children := make([]handledChild, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if elem := v.Index(i); !elem.IsNil() {
+			children = append(children, handledChild{v: elem.Elem(), suffix: "[" + strconv.Itoa(i) + "]"})
+		}
+	}
+	return 0, children
+}
+
+// RegisterProvider adds or replaces a provider of values measured under name in
+// DefaultRegistry, see Registry.RegisterProvider
+func RegisterProvider(name string, fn RootProvider) {
+	DefaultRegistry.RegisterProvider(name, fn)
+}
+
+// RegisterProvider adds or replaces a provider of values measured under name. Whenever the
+// registry's roots are measured, by MeasureAll, the exporters or the threshold watcher, fn is
+// called and the values it returns are measured together as the root, with paths such as
+// "name[0]". Unregister removes the provider like a root.
+func (r *Registry) RegisterProvider(name string, fn RootProvider) {
+	r.Register(name, fn)
+}
+
+// resolveRoot returns the value measured for a registered root, calling it if it is a provider
+func resolveRoot(root interface{}) interface{} {
+	if fn, ok := root.(RootProvider); ok {
+		return providedValues(fn())
+	}
+	return root
+}
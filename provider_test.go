@@ -0,0 +1,66 @@
+package memsize
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegisterProvider(t *testing.T) {
+	Debug = false
+
+	type job struct{ Payload [1000]byte }
+	var mu sync.Mutex
+	queue := []*job{{}, {}}
+	calls := 0
+	provider := func() []interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		items := make([]interface{}, len(queue))
+		for i, j := range queue {
+			items[i] = j
+		}
+		return items
+	}
+
+	r := NewRegistry()
+	r.RegisterProvider("queue", provider)
+
+	t.Run("MeasureAll", func(t *testing.T) {
+		m := r.MeasureAll()
+		expected := GetTotalSize(queue[0]) + GetTotalSize(queue[1])
+		fmt.Printf("Provided: %+v\n", m.Roots)
+		if len(m.Roots) != 1 || m.Roots[0].Size != expected {
+			t.Errorf("Expected the queued jobs to take %d bytes, got %+v", expected, m.Roots)
+		}
+	})
+
+	t.Run("Current", func(t *testing.T) {
+		mu.Lock()
+		queue = append(queue, &job{})
+		mu.Unlock()
+		before := calls
+		report := GetReport(r.Roots()[0].Value)
+		if calls != before+1 || len(report.Root.Children) != 3 || report.Root.Children[2].Path != "root[2]" {
+			t.Errorf("Expected the provider called for the 3 jobs now queued, got %d children", len(report.Root.Children))
+		}
+	})
+
+	t.Run("Threshold", func(t *testing.T) {
+		var reports []*Report
+		cancel := r.OnThreshold("queue", 2000, func(report *Report) { reports = append(reports, report) })
+		defer cancel()
+		r.CheckThresholds()
+		if len(reports) != 1 || reports[0].Total() < 3000 {
+			t.Errorf("Expected one report of the queued jobs, got %d", len(reports))
+		}
+	})
+
+	t.Run("Unregister", func(t *testing.T) {
+		r.Unregister("queue")
+		if roots := r.Roots(); len(roots) != 0 {
+			t.Errorf("Expected no roots, got %v", roots)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// prune.go
+package memsize
+
+import "fmt"
+
+// OtherSegment is the last path segment of the nodes Report.Prune collapses insignificant
+// children into
+const OtherSegment = "(other)"
+
+// Prune returns a copy of the report reduced to its significant nodes, leaving the report
+// itself unchanged. Children smaller than minBytes or than minPercent of the total are
+// collapsed into a single node per parent with the path segment "(other)", whose type tells
+// how many values it stands for and whose size is theirs combined. Nodes deeper than maxDepth,
+// counted from 0 at the root, are dropped; their ancestors' sizes still include them. Zero
+// values disable the respective limit.
+func (r *Report) Prune(minBytes uint64, minPercent float64, maxDepth int) *Report {
+	if r == nil {
+		return nil
+	}
+	pruned := *r
+	if r.Root == nil {
+		return &pruned
+	}
+	total := r.Total()
+	significant := func(n *Node) bool {
+		if n.Size < minBytes {
+			return false
+		}
+		return minPercent <= 0 || total > 0 && 100*float64(n.Size)/float64(total) >= minPercent
+	}
+
+	type entry struct {
+		from, to *Node
+		depth    int
+	}
+	pruned.Root = new(Node)
+	stack := []entry{{from: r.Root, to: pruned.Root}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		*e.to = *e.from
+		e.to.Children = nil
+		if maxDepth > 0 && e.depth >= maxDepth {
+			continue
+		}
+
+		var other *Node
+		count := 0
+		for _, child := range e.from.Children {
+			if significant(child) {
+				to := new(Node)
+				e.to.Children = append(e.to.Children, to)
+				stack = append(stack, entry{from: child, to: to, depth: e.depth + 1})
+				continue
+			}
+			if other == nil {
+				other = &Node{Path: e.from.Path + "." + OtherSegment}
+			}
+			count++
+			other.Size += child.Size
+			other.Shallow += child.Size
+			other.Retained += child.Retained
+		}
+		if other != nil {
+			other.Type = "1 value"
+			if count > 1 {
+				other.Type = fmt.Sprintf("%d values", count)
+			}
+			e.to.Children = append(e.to.Children, other)
+		}
+	}
+	return &pruned
+}
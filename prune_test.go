@@ -0,0 +1,60 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	Debug = false
+
+	v := &struct {
+		Big   string
+		Small []string
+		Tiny  int
+	}{Big: strings.Repeat("b", 1000), Small: []string{"a", "b", "c"}}
+	r := GetReport(v)
+	before := r.String()
+
+	t.Run("Threshold", func(t *testing.T) {
+		p := r.Prune(200, 0, 0)
+		fmt.Print(p.String())
+		s := r.Root.Children[0]
+		ps := p.Root.Children[0]
+		if len(ps.Children) != 2 || ps.Children[0].Path != "root.ptr.Big" {
+			t.Fatalf("Expected Big and an other node, got %d children", len(ps.Children))
+		}
+		other := ps.Children[1]
+		if other.Path != "root.ptr.(other)" || other.Type != "2 values" ||
+			other.Size != s.Children[1].Size+s.Children[2].Size {
+			t.Errorf("Expected Small and Tiny collapsed, got %+v", other)
+		}
+		if p.Total() != r.Total() {
+			t.Errorf("Expected total %d, got %d", r.Total(), p.Total())
+		}
+	})
+
+	t.Run("Percent", func(t *testing.T) {
+		p := r.Prune(0, 50, 0)
+		if children := p.Root.Children[0].Children; len(children) != 2 || children[1].Type != "2 values" {
+			t.Errorf("Expected Big and an other node, got %d children", len(children))
+		}
+	})
+
+	t.Run("Depth", func(t *testing.T) {
+		p := r.Prune(0, 0, 1)
+		if len(p.Root.Children) != 1 || len(p.Root.Children[0].Children) != 0 {
+			t.Errorf("Expected only the root and its pointer target, got %s", p.String())
+		}
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		if r.String() != before {
+			t.Error("Expected the original report to be unchanged")
+		}
+		if (*Report)(nil).Prune(1, 1, 1) != nil {
+			t.Error("Expected nil for a nil report")
+		}
+	})
+}
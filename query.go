@@ -0,0 +1,208 @@
+// query.go
+package memsize
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryResult is the set of nodes matched by a path query
+type QueryResult struct {
+	// Nodes lists the matched nodes in depth-first order. Nodes below a matched node are not
+	// matched again, so their sizes are counted once.
+	Nodes []*Node
+	// Size is the combined size of the matched nodes
+	Size uint64
+}
+
+// Query returns the nodes whose path matches pattern and their combined size. Patterns are
+// paths as in Node.Path in which a segment may be a wildcard: ".*" matches any field, "[*]"
+// any slice index or map key, and "**" any number of segments, including none. Pointer and
+// interface steps (".ptr", ".elem") may be left out, so "root.shards[*].postings" matches
+// "root.shards[3].ptr.postings" as well, and "**.Data[\"hobbies\"]" matches that entry of
+// every Data map.
+func (r *Report) Query(pattern string) (QueryResult, error) {
+	var result QueryResult
+	q, err := parseQuery(pattern)
+	if err != nil || r == nil || r.Root == nil {
+		return result, err
+	}
+
+	type entry struct {
+		n      *Node
+		states []int
+	}
+	stack := []entry{{n: r.Root, states: q.advance(q.start(), splitPath(r.Root.Path))}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(e.states) == 0 {
+			continue
+		}
+		if q.accepts(e.states) {
+			result.Nodes = append(result.Nodes, e.n)
+			result.Size += e.n.Size
+			continue
+		}
+		for i := len(e.n.Children) - 1; i >= 0; i-- {
+			child := e.n.Children[i]
+			segments := splitPath(strings.TrimPrefix(child.Path, e.n.Path))
+			stack = append(stack, entry{n: child, states: q.advance(e.states, segments)})
+		}
+	}
+	return result, nil
+}
+
+// Query returns the nodes whose path matches pattern, see Report.Query
+func (s *Snapshot) Query(pattern string) (QueryResult, error) {
+	return s.report.Query(pattern)
+}
+
+// query is a parsed pattern, matched by tracking the set of pattern segments a path can be at
+type query struct {
+	segments []string
+}
+
+func parseQuery(pattern string) (*query, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("memsize: empty query")
+	}
+	// A leading "**" isn't preceded by a dot
+	var segments []string
+	rest := pattern
+	if strings.HasPrefix(rest, "**") {
+		segments, rest = append(segments, "**"), rest[2:]
+	}
+	for _, s := range splitPath(rest) {
+		if s == ".**" {
+			s = "**"
+		}
+		segments = append(segments, s)
+	}
+	for _, s := range segments {
+		if strings.HasPrefix(s, "[") && !strings.HasSuffix(s, "]") ||
+			strings.HasPrefix(s, ".(") && !strings.HasSuffix(s, ")") || s == "." {
+			return nil, fmt.Errorf("memsize: malformed segment %q in query %q", s, pattern)
+		}
+	}
+	return &query{segments: segments}, nil
+}
+
+// start returns the states before the first segment of a path
+func (q *query) start() []int {
+	return q.closure([]int{0})
+}
+
+// closure adds the states following "**" segments, which may match nothing
+func (q *query) closure(states []int) []int {
+	for i := 0; i < len(states); i++ {
+		if s := states[i]; s < len(q.segments) && q.segments[s] == "**" && !containsInt(states, s+1) {
+			states = append(states, s+1)
+		}
+	}
+	return states
+}
+
+// advance returns the states reached from states by consuming the segments of a path
+func (q *query) advance(states []int, segments []string) []int {
+	for _, seg := range segments {
+		var next []int
+		add := func(s int) {
+			if !containsInt(next, s) {
+				next = append(next, s)
+			}
+		}
+		for _, s := range states {
+			if s == len(q.segments) {
+				continue
+			}
+			switch p := q.segments[s]; {
+			case p == "**":
+				add(s)
+			case matchSegment(p, seg):
+				add(s + 1)
+			case seg == ".ptr" || seg == ".elem":
+				// Pointer and interface steps are optional in patterns
+				add(s)
+			}
+		}
+		states = q.closure(next)
+		if len(states) == 0 {
+			break
+		}
+	}
+	return states
+}
+
+// accepts reports whether the whole pattern matched
+func (q *query) accepts(states []int) bool {
+	return containsInt(states, len(q.segments))
+}
+
+func matchSegment(pattern, segment string) bool {
+	switch pattern {
+	case ".*":
+		return strings.HasPrefix(segment, ".")
+	case "[*]":
+		return strings.HasPrefix(segment, "[")
+	}
+	return pattern == segment
+}
+
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits a path into its segments, each starting with a dot or a bracket except
+// the name of the root. Brackets hold quoted map keys, which may contain dots and brackets.
+func splitPath(path string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.', '[':
+			if i > start {
+				segments = append(segments, path[start:i])
+			}
+			start = i
+			if path[i] == '[' {
+				i = closing(path, i, '[', ']')
+			} else if i+1 < len(path) && path[i+1] == '(' {
+				i = closing(path, i+1, '(', ')')
+			}
+		}
+	}
+	if start < len(path) {
+		segments = append(segments, path[start:])
+	}
+	return segments
+}
+
+// closing returns the index of the bracket closing the one at path[i], skipping quoted
+// strings, or the last index if it isn't closed
+func closing(path string, i int, open, close byte) int {
+	depth := 0
+	for ; i < len(path); i++ {
+		switch path[i] {
+		case '"':
+			for i++; i < len(path) && path[i] != '"'; i++ {
+				if path[i] == '\\' {
+					i++
+				}
+			}
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(path) - 1
+}
@@ -0,0 +1,87 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	Debug = false
+
+	type shard struct {
+		Postings []uint32
+		Meta     map[string]string
+	}
+	type index struct {
+		Shards []*shard
+		Data   map[string]interface{}
+	}
+	v := index{
+		Shards: []*shard{
+			{Postings: make([]uint32, 100), Meta: map[string]string{"a.b[c]": "x"}},
+			{Postings: make([]uint32, 50)},
+		},
+		Data: map[string]interface{}{"hobbies": []string{"chess"}, "age": 30},
+	}
+	r := GetReport(v)
+	node := func(path string) *Node {
+		var found *Node
+		r.Walk(func(n *Node) bool {
+			if n.Path == path {
+				found = n
+			}
+			return found == nil
+		})
+		if found == nil {
+			t.Fatalf("Expected a node at %s", path)
+		}
+		return found
+	}
+
+	for _, tc := range []struct {
+		pattern string
+		paths   []string
+	}{
+		{"root.Shards[*].Postings", []string{"root.Shards[0].ptr.Postings", "root.Shards[1].ptr.Postings"}},
+		{"root.Shards[1].ptr.Postings", []string{"root.Shards[1].ptr.Postings"}},
+		{`**.Data["hobbies"]`, []string{`root.Data["hobbies"]`}},
+		{`root.**.Meta["a.b[c]"]`, []string{`root.Shards[0].ptr.Meta["a.b[c]"]`}},
+		{"root.*", []string{"root.Shards", "root.Data"}},
+		{"**", []string{"root"}},
+		{"root.Missing", nil},
+	} {
+		t.Run(tc.pattern, func(t *testing.T) {
+			result, err := r.Query(tc.pattern)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var size uint64
+			var paths []string
+			for _, n := range result.Nodes {
+				paths = append(paths, n.Path)
+			}
+			for _, path := range tc.paths {
+				size += node(path).Size
+			}
+			fmt.Printf("Query %s: %v, %d bytes\n", tc.pattern, paths, result.Size)
+			if fmt.Sprint(paths) != fmt.Sprint(tc.paths) || result.Size != size {
+				t.Errorf("Expected %v with %d bytes, got %v with %d", tc.paths, size, paths, result.Size)
+			}
+		})
+	}
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, pattern := range []string{"", "root.Data[\"x\"", "root..Data"} {
+			if _, err := r.Query(pattern); err == nil {
+				t.Errorf("Expected an error for %q", pattern)
+			}
+		}
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		result, err := TakeSnapshot(v).Query("root.Shards[*].Postings")
+		if err != nil || len(result.Nodes) != 2 {
+			t.Errorf("Expected 2 nodes, got %d (%v)", len(result.Nodes), err)
+		}
+	})
+}
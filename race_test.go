@@ -0,0 +1,6 @@
+//go:build race
+
+package memsize
+
+// raceEnabled is set when tests run with the race detector, which makes sync.Pool drop items
+const raceEnabled = true
@@ -0,0 +1,57 @@
+// realtime.go
+package memsize
+
+import (
+	"context"
+	"time"
+)
+
+// Settings of the SoftRealTime profile
+const (
+	// RealTimeYield is the number of steps between calls to runtime.Gosched
+	RealTimeYield = 256
+	// RealTimeSlice is the longest a ResumableSizer runs per call to Run
+	RealTimeSlice = time.Millisecond
+)
+
+// SoftRealTime is a profile for services with tight latency objectives, combining the options
+// that bound how much a measurement delays the rest of the program:
+//   - runtime.GC is never called, even with WithGC
+//   - runtime.Gosched is called every RealTimeYield steps, unless WithYield sets another interval
+//   - parallelism is disabled, so a measurement occupies a single processor
+//   - ResumableSizer.Run returns after RealTimeSlice at most, whatever slice it is given
+//
+// The profile takes precedence over WithGC and WithParallelism even when they come after it.
+// Measurements spread over several calls use a ResumableSizer, or MeasureSoftRealTime which
+// drives one; add WithSafeMode if the value may change in between.
+func SoftRealTime() Option {
+	return func(c *config) {
+		c.realTime = true
+		if c.yieldEvery == 0 {
+			c.yieldEvery = RealTimeYield
+		}
+		c.maxSlice = RealTimeSlice
+	}
+}
+
+// MeasureSoftRealTime measures v in the SoftRealTime profile, running a ResumableSizer for a
+// slice at a time with pause in between, until the measurement is complete or ctx is done. The
+// error is that of ResumableSizer.Run; once ctx is done the size is only a lower bound.
+func MeasureSoftRealTime(ctx context.Context, v interface{}, pause time.Duration, opts ...Option) (uint64, error) {
+	s := NewResumableSizer(v, append(opts[:len(opts):len(opts)], SoftRealTime())...)
+	defer s.Close()
+
+	for {
+		done, err := s.Run(ctx, RealTimeSlice)
+		if done || err != nil {
+			return s.Size(), err
+		}
+		timer := time.NewTimer(pause)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return s.Size(), ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package memsize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSoftRealTime(t *testing.T) {
+	Debug = false
+
+	type item struct {
+		name  string
+		value *[4]int
+	}
+	data := make(map[int]*item)
+	// Large enough that a measurement takes several slices, which the race detector slows
+	// down enough with fewer entries
+	n := 20000
+	if raceEnabled {
+		n = 2000
+	}
+	for i := 0; i < n; i++ {
+		data[i] = &item{name: fmt.Sprint("item ", i), value: new([4]int)}
+	}
+	want := GetTotalSize(data)
+
+	t.Run("Profile", func(t *testing.T) {
+		cfg := newConfig([]Option{SoftRealTime(), WithGC(), WithParallelism(4)})
+		if cfg.gc || cfg.parallelism != 0 {
+			t.Errorf("Expected no GC and no parallelism, got %v and %d", cfg.gc, cfg.parallelism)
+		}
+		if cfg.yieldEvery != RealTimeYield || cfg.maxSlice != RealTimeSlice {
+			t.Errorf("Expected yields every %d steps and slices of %v, got %d and %v",
+				RealTimeYield, RealTimeSlice, cfg.yieldEvery, cfg.maxSlice)
+		}
+		if cfg := newConfig([]Option{WithYield(10), SoftRealTime()}); cfg.yieldEvery != 10 {
+			t.Errorf("Expected the yield interval of WithYield to be kept, got %d", cfg.yieldEvery)
+		}
+	})
+
+	t.Run("Yield", func(t *testing.T) {
+		if got := GetTotalSize(data, WithYield(1)); got != want {
+			t.Errorf("Expected %d, got %d", want, got)
+		}
+		if got := GetTotalSize(data, SoftRealTime()); got != want {
+			t.Errorf("Expected %d, got %d", want, got)
+		}
+	})
+
+	t.Run("Capped", func(t *testing.T) {
+		s := NewResumableSizer(data, SoftRealTime())
+		defer s.Close()
+		calls := 0
+		for {
+			calls++
+			done, err := s.Run(context.Background(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done {
+				break
+			}
+		}
+		fmt.Printf("Soft real-time: %d bytes in %d calls\n", s.Size(), calls)
+		if calls < 2 || s.Size() != want {
+			t.Errorf("Expected %d bytes in several calls, got %d in %d", want, s.Size(), calls)
+		}
+	})
+
+	t.Run("Measure", func(t *testing.T) {
+		got, err := MeasureSoftRealTime(context.Background(), data, time.Microsecond)
+		if err != nil || got != want {
+			t.Errorf("Expected %d, got %d and %v", want, got, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := MeasureSoftRealTime(ctx, data, time.Microsecond); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
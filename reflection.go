@@ -0,0 +1,116 @@
+// reflection.go
+package memsize
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// WithReflectValues follows the values held by reflect.Value fields, as if they were
+// pointers to them. By default only the reflect.Value itself is counted.
+//
+// Values obtained from addressable memory or from a field of another value refer to memory
+// owned elsewhere, which is then counted at the reflect.Value if it is reached there first.
+func WithReflectValues() Option {
+	return func(c *config) {
+		c.reflectValues = true
+	}
+}
+
+// flagIndir and flagMethod mirror the flags of reflect.Value: the value is held through its
+// data pointer, or is a method value without memory of its own. The low flagKindBits hold the
+// kind of the value.
+const (
+	flagKindBits = 5
+	flagIndir    = 1 << 7
+	flagMethod   = 1 << 9
+)
+
+var (
+	reflectTypeType  = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+	reflectValueType = reflect.TypeOf(reflect.Value{})
+)
+
+// reflectLayout is set when reflect.Value matches reflectValue and the flags above, which are
+// private to reflect and could change with any release. Without it reflect.Types and
+// reflect.Values are only counted by their headers.
+var reflectLayout = checkReflectLayout()
+
+// checkReflectLayout looks into reflect.Values whose data pointer and flags are known
+func checkReflectLayout() bool {
+	raw := func(v reflect.Value) reflectValue {
+		return *(*reflectValue)(unsafe.Pointer(&v))
+	}
+	flagged := func(v reflectValue, kind reflect.Kind, indir, method bool) bool {
+		return v.flag&(1<<flagKindBits-1) == uintptr(kind) &&
+			(v.flag&flagIndir != 0) == indir && (v.flag&flagMethod != 0) == method
+	}
+
+	// A pointer is held in the data word and the array it points to through the data pointer
+	arr := [2]int{1, 2}
+	addr := unsafe.Pointer(&arr)
+	direct := raw(reflect.ValueOf(&arr))
+	indirect := raw(reflect.ValueOf(&arr).Elem())
+	method := raw(reflect.ValueOf(reflectValueType).Method(0))
+	return direct.ptr == addr && flagged(direct, reflect.Ptr, false, false) &&
+		indirect.ptr == addr && flagged(indirect, reflect.Array, true, false) &&
+		flagged(method, reflect.Func, false, true)
+}
+
+func init() {
+	typeHandlers[reflectTypeType] = reflectTypeHandler
+	typeHandlers[reflectValueType] = reflectValueHandler
+}
+
+// memoryOf returns the address of the memory holding v, whose type is held indirectly
+func memoryOf(v reflect.Value) unsafe.Pointer {
+	if v.CanAddr() {
+		return unsafe.Pointer(v.UnsafeAddr())
+	}
+	return (*reflectValue)(unsafe.Pointer(&v)).ptr
+}
+
+// reflectTypeHandler sizes a reflect.Type as an interface header. The runtime type descriptor
+// it refers to is only counted with WithTypeDescriptors, never traversed.
+func reflectTypeHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	l := w.cfg.layout()
+	size := headerSize(v.Type(), l)
+	if v.IsNil() || !reflectLayout {
+		return size, nil
+	}
+	t := *(*reflect.Type)(memoryOf(v))
+	return size + w.descs.size(interfaceType, t, l), nil
+}
+
+// reflectValueHandler sizes a reflect.Value by its fields, following the value it holds with
+// WithReflectValues. The runtime type descriptor it refers to is only counted with
+// WithTypeDescriptors, never traversed.
+func reflectValueHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	l := w.cfg.layout()
+	size := inlineSize(v.Type(), l)
+	if !reflectLayout {
+		return size, nil
+	}
+	mem := memoryOf(v)
+	held := *(*reflect.Value)(mem)
+	if !held.IsValid() {
+		return size, nil
+	}
+	size += w.descs.size(interfaceType, held.Type(), l)
+
+	raw := (*reflectValue)(mem)
+	if !w.cfg.reflectValues || raw.flag&flagMethod != 0 {
+		return size, nil
+	}
+	// Values held indirectly are followed as a pointer to their memory, counted once however
+	// many reflect.Values refer to it, others are pointer-shaped and held in the data word.
+	// Either way the child accounts for the data word.
+	child := held
+	if raw.flag&flagIndir != 0 {
+		child = reflect.NewAt(held.Type(), raw.ptr)
+	}
+	if l.model == ExactSizes {
+		size -= l.word()
+	}
+	return size, []handledChild{{v: child, suffix: ".value"}}
+}
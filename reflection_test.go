@@ -0,0 +1,71 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestReflection(t *testing.T) {
+	Debug = false
+	exact := WithSizeModel(ExactSizes)
+
+	type field struct {
+		Type    reflect.Type
+		Default reflect.Value
+		Copy    reflect.Value
+		Nil     reflect.Value
+	}
+	data := make([]byte, 1000)
+	f := &field{Type: reflect.TypeOf(data), Default: reflect.ValueOf(data)}
+	f.Copy = f.Default
+
+	t.Run("Default", func(t *testing.T) {
+		report := GetReport(f, exact)
+		report.Walk(func(n *Node) bool {
+			if n.Path != "root" && n.Path != "root.ptr" && len(n.Children) > 0 {
+				t.Errorf("Expected %s not to be traversed, got %d children", n.Path, len(n.Children))
+			}
+			return true
+		})
+		// The pointer, two words of the interface and three reflect.Values
+		if size := GetTotalSize(f, exact); size != 8+16+3*24 {
+			t.Errorf("Expected only the fields to be counted, got %d", size)
+		}
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		size := GetTotalSize(f, exact, WithReflectValues())
+		fmt.Printf("reflect.Value holding 1000 bytes: %d bytes\n", size)
+		// The boxed slice header and its data, counted once for both copies
+		if expected := uint64(8 + 16 + 3*24 + 24 + 1000); size != expected {
+			t.Errorf("Expected %d, got %d", expected, size)
+		}
+
+		ptr := GetTotalSize(reflect.ValueOf(&data), exact, WithReflectValues())
+		if ptr != 24+24+1000 {
+			t.Errorf("Expected the pointer held by the value to be followed, got %d", ptr)
+		}
+	})
+
+	t.Run("Type Descriptors", func(t *testing.T) {
+		without := GetTotalSize(f, exact)
+		with := GetTotalSize(f, exact, WithTypeDescriptors())
+		fmt.Printf("Type descriptors: %d bytes\n", with-without)
+		// The descriptor of []byte is counted once
+		if with-without != typeDescriptorSize(reflect.TypeOf(data), layout{model: ExactSizes}) {
+			t.Errorf("Expected a single descriptor, got %d bytes", with-without)
+		}
+	})
+
+	t.Run("Layout", func(t *testing.T) {
+		if !reflectLayout {
+			t.Fatal("Expected reflect.Value to match the mirrored layout")
+		}
+		reflectLayout = false
+		defer func() { reflectLayout = true }()
+		if size := GetTotalSize(f, exact, WithReflectValues()); size != 8+16+3*24 {
+			t.Errorf("Expected only the fields to be counted without the layout, got %d", size)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// registry.go
+package memsize
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry is a set of named roots shared by the exporters: HTTP handlers, expvar variables and
+// Prometheus collectors built on a registry measure whatever is registered when they are read
+type Registry struct {
+	mu    sync.RWMutex
+	roots map[string]*registeredRoot
+
+	// thresholds are checked by a watcher goroutine running while there are any, see OnThreshold
+	thresholds []*threshold
+	stopWatch  chan struct{}
+}
+
+// registeredRoot is a root of a Registry, replaced when a root is registered again under its name
+type registeredRoot struct {
+	value interface{}
+	// generation is bumped by every invalidation; a cached size is only fresh if measured since
+	generation uint64
+}
+
+func (e *registeredRoot) invalidate() {
+	atomic.AddUint64(&e.generation, 1)
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{roots: make(map[string]*registeredRoot)}
+}
+
+// DefaultRegistry is the registry used by Register, Unregister, MeasureAll and Handler
+// without roots
+var DefaultRegistry = NewRegistry()
+
+// Register adds or replaces the root measured under name in DefaultRegistry
+func Register(name string, root interface{}) {
+	DefaultRegistry.Register(name, root)
+}
+
+// Unregister removes a root from DefaultRegistry
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// root returns the root registered under name, or nil
+func (r *Registry) root(name string) *registeredRoot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.roots[name]
+}
+
+// names returns the names of the registered roots in order
+func (r *Registry) names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.roots))
+	for name := range r.roots {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// MeasureAll measures the roots of DefaultRegistry, see Registry.MeasureAll
+func MeasureAll(opts ...Option) MultiReport {
+	return DefaultRegistry.MeasureAll(opts...)
+}
+
+// Register adds or replaces the root measured under name
+func (r *Registry) Register(name string, root interface{}) {
+	r.register(name, root)
+}
+
+func (r *Registry) register(name string, root interface{}) *registeredRoot {
+	e := &registeredRoot{value: root, generation: 1}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roots[name] = e
+	return e
+}
+
+// Unregister removes a previously registered root
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.roots, name)
+}
+
+// Roots returns the registered roots ordered by name, with the values of providers as they
+// currently are
+func (r *Registry) Roots() []Root {
+	r.mu.RLock()
+	roots := make([]Root, 0, len(r.roots))
+	for name, e := range r.roots {
+		roots = append(roots, Root{Name: name, Value: e.value})
+	}
+	r.mu.RUnlock()
+	// Providers are called without the lock, so they may use the registry themselves
+	for i := range roots {
+		roots[i].Value = resolveRoot(roots[i].Value)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].Name < roots[j].Name
+	})
+	return roots
+}
+
+// MeasureAll measures the registered roots together, so objects shared between them are
+// counted once in the total, as GetTotalSizeMulti does
+func (r *Registry) MeasureAll(opts ...Option) MultiReport {
+	roots := make(map[string]interface{})
+	for _, root := range r.Roots() {
+		roots[root.Name] = root.Value
+	}
+	return GetTotalSizeMulti(roots, opts...)
+}
+
+// Handler returns an http.Handler rendering the registered roots like Handler
+func (r *Registry) Handler() http.Handler {
+	return rootsHandler(r.Roots)
+}
+
+// PublishExpvar registers an expvar variable with the given name reporting the size of every
+// registered root by name. Like expvar.Publish, it panics if the name is already in use.
+func (r *Registry) PublishExpvar(name string) *RegistryVar {
+	v := &RegistryVar{sizer: NewRegistrySizer(r, DefaultExpvarTTL)}
+	expvar.Publish(name, v)
+	return v
+}
+
+// RegistryVar is an expvar.Var reporting the sizes of the roots of a Registry as a JSON
+// object, recomputed lazily when read
+type RegistryVar struct {
+	sizer *CachedSizer
+}
+
+// SetTTL changes how long computed sizes are reused; zero recomputes on every read
+func (v *RegistryVar) SetTTL(ttl time.Duration) {
+	v.sizer.setTTL(expvarTTL(ttl))
+}
+
+// Value returns the size of every root by name, recomputing those whose cached size expired
+func (v *RegistryVar) Value() map[string]uint64 {
+	return v.sizer.Sizes()
+}
+
+// String implements expvar.Var
+func (v *RegistryVar) String() string {
+	b, _ := json.Marshal(v.Value())
+	return string(b)
+}
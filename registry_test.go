@@ -0,0 +1,83 @@
+package memsize
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	Debug = false
+
+	shared := &[1000]byte{}
+	sessions := map[string]*[1000]byte{"alice": shared}
+	history := []*[1000]byte{shared, {}}
+
+	r := NewRegistry()
+	r.Register("sessions", &sessions)
+	r.Register("history", &history)
+
+	t.Run("Roots", func(t *testing.T) {
+		roots := r.Roots()
+		if len(roots) != 2 || roots[0].Name != "history" || roots[1].Name != "sessions" {
+			t.Errorf("Expected history and sessions in order, got %v", roots)
+		}
+	})
+
+	t.Run("Measure All", func(t *testing.T) {
+		all := r.MeasureAll(WithSizeModel(ExactSizes))
+		if len(all.Roots) != 2 || all.Roots[1].Size != GetTotalSize(&sessions, WithSizeModel(ExactSizes)) {
+			t.Errorf("Expected the size of every root, got %+v", all.Roots)
+		}
+		if all.Shared < 1000 {
+			t.Errorf("Expected the shared buffer to be counted once, got %d shared bytes", all.Shared)
+		}
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/memsize?format=json", nil))
+		var results []rootReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected both roots, got %d", len(results))
+		}
+	})
+
+	t.Run("Expvar", func(t *testing.T) {
+		v := r.PublishExpvar("memsize_test_registry")
+		var sizes map[string]uint64
+		if err := json.Unmarshal([]byte(expvar.Get("memsize_test_registry").String()), &sizes); err != nil {
+			t.Fatalf("Published value is not a JSON object: %v", err)
+		}
+		if sizes["history"] != GetTotalSize(&history) {
+			t.Errorf("Expected %d, got %v", GetTotalSize(&history), sizes)
+		}
+
+		// Roots registered later are reported once the cached sizes expire
+		r.Register("late", &shared)
+		v.SetTTL(0)
+		if _, ok := v.Value()["late"]; !ok {
+			t.Errorf("Expected the late root, got %v", v.Value())
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		Register("memsize_test_default", &history)
+		defer Unregister("memsize_test_default")
+
+		if all := MeasureAll(); len(all.Roots) != 1 || all.Roots[0].Name != "memsize_test_default" {
+			t.Errorf("Expected the registered root, got %+v", all.Roots)
+		}
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/memsize", nil))
+		if !strings.Contains(rec.Body.String(), "memsize_test_default") {
+			t.Error("Expected Handler without roots to render the default registry")
+		}
+	})
+}
@@ -0,0 +1,94 @@
+// render.go
+package memsize
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data Report.Render executes templates with
+type TemplateData struct {
+	Report *Report
+	// Total is the size of the report's root
+	Total uint64
+	// Nodes lists every node in depth-first order
+	Nodes []TemplateNode
+}
+
+// TemplateNode is a node of the report together with its place in the tree
+type TemplateNode struct {
+	*Node
+	// Name is the node's path relative to its parent, the full path for the root
+	Name string
+	// Depth is 0 for the root and increases by one per level
+	Depth int
+	// Share is the node's size as a percentage of the total, ParentShare as a percentage of
+	// its parent's, 100 for the root
+	Share       float64
+	ParentShare float64
+}
+
+// TemplateFuncs returns the functions available to DefaultTemplate, for custom templates to
+// be parsed with:
+//
+//	format   renders bytes with binary units, see Format
+//	indent   returns two spaces per level of depth
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"format": Format,
+		"indent": func(depth int) string { return strings.Repeat("  ", depth) },
+	}
+}
+
+// DefaultTemplate renders the same indented tree as WriteText without colors
+var DefaultTemplate = template.Must(template.New("memsize").Funcs(TemplateFuncs()).Parse(
+	`{{range .Nodes}}{{indent .Depth}}{{.Name}} ({{.Type}}) {{format .Size}} {{printf "%.1f" .Share}}%` +
+		`{{if .Depth}} ({{printf "%.1f" .ParentShare}}% of parent){{end}}
+{{end}}`))
+
+// Render executes tmpl, or DefaultTemplate if it is nil, with the report's TemplateData, so
+// the text output can be tailored, e.g. cut off at a depth or limited to nodes above a share:
+//
+//	{{range .Nodes}}{{if and (le .Depth 2) (ge .Share 5.0)}}{{.Path}} {{format .Size}}
+//	{{end}}{{end}}
+func (r *Report) Render(w io.Writer, tmpl *template.Template) error {
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+	bw := bufio.NewWriter(w)
+	if err := tmpl.Execute(bw, r.templateData()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (r *Report) templateData() TemplateData {
+	data := TemplateData{Report: r, Total: r.Total()}
+	if r == nil || r.Root == nil {
+		return data
+	}
+
+	stack := []TemplateNode{{Node: r.Root, Name: r.Root.Path, ParentShare: 100}}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n.Share = 100
+		if data.Total > 0 {
+			n.Share = 100 * float64(n.Size) / float64(data.Total)
+		}
+		data.Nodes = append(data.Nodes, n)
+
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			child := n.Children[i]
+			parentShare := 100.0
+			if n.Size > 0 {
+				parentShare = 100 * float64(child.Size) / float64(n.Size)
+			}
+			stack = append(stack, TemplateNode{Node: child, Name: pathSegment(n.Path, child.Path), Depth: n.Depth + 1,
+				ParentShare: parentShare})
+		}
+	}
+	return data
+}
@@ -0,0 +1,53 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestRender(t *testing.T) {
+	Debug = false
+
+	v := &struct {
+		Name  string
+		Items []string
+	}{strings.Repeat("n", 200), []string{"a", "b"}}
+	r := GetReport(v)
+
+	t.Run("Default", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := r.Render(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Print(buf.String())
+		if buf.String() != r.String() {
+			t.Errorf("Expected the text output\n%s\ngot\n%s", r.String(), buf.String())
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		tmpl := template.Must(template.New("runbook").Funcs(TemplateFuncs()).Parse(
+			`total={{format .Total}}
+{{range .Nodes}}{{if and (le .Depth 2) (ge .Share 50.0)}}{{.Path}} {{.Size}}
+{{end}}{{end}}`))
+		var buf bytes.Buffer
+		if err := r.Render(&buf, tmpl); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Print(buf.String())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 || lines[0] != "total="+Format(r.Total()) || !strings.HasPrefix(lines[3], "root.ptr.Name ") {
+			t.Errorf("Expected the total and the three largest nodes, got %q", lines)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		tmpl := template.Must(template.New("bad").Parse(`{{.Missing}}`))
+		if err := r.Render(&bytes.Buffer{}, tmpl); err == nil {
+			t.Error("Expected an error executing a template with an unknown field")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// report.go
+package memsize
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Report is a hierarchical breakdown of the memory size of a value
+type Report struct {
+	Root *Node
+	// OffHeapBytes is the memory outside the Go heap reported by types registered with
+	// RegisterOffHeap; it is not part of the sizes of nodes
+	OffHeapBytes uint64
+	// MappedBytes is the capacity of slices backed by mapped memory, see WithMappedMemory;
+	// it is not part of the sizes of nodes either
+	MappedBytes uint64
+	// StringBytes is the total length of the strings in the report, and DuplicateStringBytes
+	// the part of it repeating the contents of another string with separate data, which
+	// interning the strings would save
+	StringBytes          uint64
+	DuplicateStringBytes uint64
+	// SharedBytes is the size of the objects reachable through several references, which
+	// WithSharedPolicy(SeparateShared) and ListShared attribute to no node; it is part of Total
+	SharedBytes uint64
+	// SharedObjects lists the shared objects, largest first, with WithSharedPolicy(ListShared)
+	SharedObjects []SharedObject
+	// Groups holds the bytes per group of struct tags, see TagName; bytes outside any group
+	// are not listed
+	Groups map[string]uint64
+	// Truncated is set when a limit stopped the traversal early or WithMaxDepth cut it
+	// short; sizes are then lower bounds
+	Truncated bool
+	// Unstable lists the paths of the values given up in safe mode, see WithSafeMode
+	Unstable []string
+	// Errors holds the panics of the values given up in safe mode, in the order of Unstable
+	Errors []PathError
+	// Model is a copy of the overhead model the report was measured with, see WithModel
+	Model *Model
+}
+
+// Node is a single value reached during traversal
+type Node struct {
+	// Path is the location of the value relative to the root, e.g. "root.ptr.Friends[0]"
+	Path string `json:"path"`
+	// Type is the static type of the value at this location
+	Type string `json:"type"`
+	// Size is the total size of the value including all of its children
+	Size uint64 `json:"size"`
+	// Shallow is the part of Size not attributed to any child
+	Shallow uint64 `json:"shallow"`
+	// Alloc is the size of the heap allocation the value refers to and accounts for, such as
+	// the target of a pointer or the backing array of a slice, see Report.SizeHistogram
+	Alloc uint64 `json:"alloc,omitempty"`
+	// OffHeap is the memory outside the Go heap held by the value itself, see RegisterOffHeap
+	OffHeap uint64 `json:"offHeap,omitempty"`
+	// Mapped is the capacity of a slice backed by mapped memory instead of the heap
+	Mapped uint64 `json:"mapped,omitempty"`
+	// Arena names the arena a pointer or slice refers into, see WithArena
+	Arena string `json:"arena,omitempty"`
+	// Global names the singleton a reference refers to, see RegisterGlobal
+	Global string `json:"global,omitempty"`
+	// Retained is the size freed if the value were cleared, set by Report.ComputeRetained
+	Retained uint64 `json:"retained,omitempty"`
+	// FanOut is the number of indirections, such as pointers, stored in the value or in the
+	// allocation it refers to, set by Report.ComputeIndirections
+	FanOut uint64 `json:"fanOut,omitempty"`
+	// Depth is the longest chain of indirections below the value, set by Report.ComputeIndirections
+	Depth int `json:"depth,omitempty"`
+	// Padding is the number of bytes a struct spends on alignment between and after its
+	// fields. It is part of Shallow only with ExactSizes, which sizes structs by their layout.
+	Padding uint64 `json:"padding,omitempty"`
+	// Reorderable is how many of the padding bytes reordering the struct's fields would save
+	Reorderable uint64 `json:"reorderable,omitempty"`
+	// Header is the part of Shallow taken by the header of a string or slice; the rest is
+	// its data
+	Header uint64 `json:"header,omitempty"`
+	// Unused is the capacity of a slice beyond its length in bytes, (cap-len)*elemSize
+	Unused uint64 `json:"unused,omitempty"`
+	// Spare is the part of Shallow a map spends on buckets it doesn't need for its current
+	// entries, left over from entries since deleted; only set with ExactSizes
+	Spare uint64 `json:"spare,omitempty"`
+	// Addr is the target address of a non-nil pointer
+	Addr uintptr `json:"addr,omitempty"`
+	// Shared is set on pointers whose target was already counted through another path
+	Shared bool `json:"shared,omitempty"`
+	// Unstable is set on values given up in safe mode, whose size is then a lower bound
+	Unstable bool `json:"unstable,omitempty"`
+	// Truncated is set on values whose children were beyond WithMaxDepth, whose size is then
+	// a lower bound
+	Truncated bool `json:"truncated,omitempty"`
+	// Children are the values directly referenced by this one
+	Children []*Node `json:"children,omitempty"`
+}
+
+// GetReport traverses v and returns a per-path breakdown of its size
+func GetReport(v interface{}, opts ...Option) *Report {
+	w := newWalker(opts...)
+	w.tree = true
+	return w.report(reflect.ValueOf(v))
+}
+
+func (w *walker) report(v reflect.Value) *Report {
+	w.strings = newStringStats()
+	w.groups = make(map[string]uint64)
+	_, err := w.measure(v)
+	return w.buildReport(err)
+}
+
+// buildReport turns the tree of the last traversal into a Report
+func (w *walker) buildReport(err error) *Report {
+	shared, objects := attributeShared(w.root, w.cfg.sharedPolicy)
+	sortChildren(w.root, w.cfg.order)
+	r := &Report{
+		SharedBytes:   shared,
+		SharedObjects: objects,
+		Root:          w.root,
+		OffHeapBytes:  w.offHeapBytes,
+		MappedBytes:   w.mappedBytes,
+		Truncated:     err != nil && !errors.Is(err, ErrUnstable) || w.truncated,
+		Unstable:      w.unstable,
+		Errors:        w.panics,
+		Model:         w.cfg.layout().overhead().clone(),
+	}
+	if w.strings != nil {
+		r.StringBytes, r.DuplicateStringBytes = w.strings.bytes, w.strings.duplicate
+	}
+	if len(w.groups) > 0 {
+		r.Groups = w.groups
+	}
+	return r
+}
+
+// Total returns the total size of the measured value, including SharedBytes
+func (r *Report) Total() uint64 {
+	if r == nil || r.Root == nil {
+		return 0
+	}
+	return r.Root.Size + r.SharedBytes
+}
+
+// Walk calls fn for every node of the report in depth-first order.
+// Children of a node are skipped when fn returns false.
+func (r *Report) Walk(fn func(n *Node) bool) {
+	if r == nil || r.Root == nil {
+		return
+	}
+
+	// An explicit stack keeps arbitrarily deep reports from overflowing the goroutine stack
+	stack := []*Node{r.Root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fn(n) {
+			continue
+		}
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			stack = append(stack, n.Children[i])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetReport(t *testing.T) {
+	Debug = false
+
+	person := &Person{
+		Name:    "John Doe",
+		Friends: make([]*Person, 0),
+		Data: map[string]interface{}{
+			"age": 30,
+		},
+	}
+
+	report := GetReport(person)
+
+	t.Run("Total Matches", func(t *testing.T) {
+		if total, expected := report.Total(), GetTotalSize(person); total != expected {
+			t.Errorf("Report total %d, expected %d", total, expected)
+		}
+	})
+
+	t.Run("Sizes Add Up", func(t *testing.T) {
+		report.Walk(func(n *Node) bool {
+			sum := n.Shallow
+			for _, child := range n.Children {
+				sum += child.Size
+			}
+			if sum != n.Size {
+				t.Errorf("%s: shallow plus children is %d, expected %d", n.Path, sum, n.Size)
+			}
+			return true
+		})
+	})
+
+	t.Run("Paths", func(t *testing.T) {
+		paths := make(map[string]string)
+		report.Walk(func(n *Node) bool {
+			fmt.Printf("%s (%s): %d bytes\n", n.Path, n.Type, n.Size)
+			paths[n.Path] = n.Type
+			return true
+		})
+
+		if paths["root"] != "*memsize.Person" {
+			t.Errorf("Expected root of type *memsize.Person, got %q", paths["root"])
+		}
+		if paths["root.ptr.Name"] != "string" {
+			t.Errorf("Expected root.ptr.Name of type string, got %q", paths["root.ptr.Name"])
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		if total := GetReport(nil).Total(); total != 0 {
+			t.Errorf("Expected zero total for nil, got %d", total)
+		}
+	})
+}
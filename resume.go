@@ -0,0 +1,126 @@
+// resume.go
+package memsize
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// resumeCheckInterval is the number of steps between checks of the clock and context
+const resumeCheckInterval = 64
+
+// ResumableSizer measures a value in time slices, keeping the state of the traversal between
+// calls to Run, so a huge measurement is spread over many short pauses instead of one long one.
+// The value must not be mutated until the measurement is complete, except in safe mode, which
+// gives up values that change. Parallelism is ignored.
+type ResumableSizer struct {
+	mu      sync.Mutex
+	w       *walker
+	v       reflect.Value
+	fast    bool
+	started bool
+	done    bool
+	size    uint64
+	err     error
+}
+
+// NewResumableSizer prepares the measurement of v with the given options; nothing is
+// traversed until the first call to Run
+func NewResumableSizer(v interface{}, opts ...Option) *ResumableSizer {
+	w := newWalker(opts...)
+	return &ResumableSizer{w: w, v: reflect.ValueOf(v), fast: w.fast()}
+}
+
+// Run advances the traversal for about the given duration and reports whether it is complete,
+// along with the error GetTotalSizeE would return. At least one step is taken per call. If ctx
+// is done, Run returns its error without completing; a later call resumes where it stopped.
+func (s *ResumableSizer) Run(ctx context.Context, slice time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return true, s.err
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	w := s.w
+	if !s.started {
+		s.started = true
+		if w.cfg.err != nil {
+			s.complete(0, w.cfg.err)
+			return true, s.err
+		}
+		if w.cfg.gc {
+			runtime.GC()
+		}
+		w.stack = w.stack[:0]
+		w.total = 0
+		if w.cfg.safe {
+			w.safeStep(func() { w.push(s.v, rootStep("root")) })
+		} else {
+			w.push(s.v, rootStep("root"))
+		}
+	}
+
+	if w.cfg.maxSlice > 0 && slice > w.cfg.maxSlice {
+		slice = w.cfg.maxSlice
+	}
+	deadline := time.Now().Add(slice)
+	for n := 1; len(w.stack) > 0 || w.retry != nil; n++ {
+		if w.cfg.safe {
+			w.safeAdvance(s.fast)
+		} else {
+			w.step(s.fast)
+		}
+		if n%resumeCheckInterval == 0 && len(w.stack) > 0 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			if time.Now().After(deadline) {
+				return false, nil
+			}
+		}
+	}
+	s.complete(w.result(w.total))
+	return true, s.err
+}
+
+// Size returns the bytes accounted so far, a lower bound of the size until Run reported the
+// measurement complete
+func (s *ResumableSizer) Size() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return s.size
+	}
+	return s.accounted()
+}
+
+// Close abandons an incomplete measurement, releasing the memory of its traversal. Size keeps
+// returning the bytes accounted until then, and Run reports completion with context.Canceled.
+func (s *ResumableSizer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.complete(s.accounted(), context.Canceled)
+	}
+}
+
+// accounted sums the sizes of the finished values and of those still being traversed
+func (s *ResumableSizer) accounted() uint64 {
+	size := s.w.total
+	for i := range s.w.stack {
+		size += s.w.stack[i].size
+	}
+	return size
+}
+
+func (s *ResumableSizer) complete(size uint64, err error) {
+	s.size, s.err, s.done = size, err, true
+	s.w.release()
+	s.v = reflect.Value{}
+}
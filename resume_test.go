@@ -0,0 +1,92 @@
+package memsize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestResumableSizer(t *testing.T) {
+	Debug = false
+
+	type item struct {
+		name  string
+		value *[4]int
+	}
+	data := make(map[int]*item)
+	for i := 0; i < 20000; i++ {
+		data[i] = &item{name: fmt.Sprint("item ", i), value: new([4]int)}
+	}
+	want := GetTotalSize(data)
+
+	t.Run("Slices", func(t *testing.T) {
+		s := NewResumableSizer(data)
+		calls := 0
+		var last uint64
+		for {
+			calls++
+			done, err := s.Run(context.Background(), time.Microsecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Size() < last {
+				t.Fatalf("Expected a growing size, got %d after %d", s.Size(), last)
+			}
+			last = s.Size()
+			if done {
+				break
+			}
+		}
+		fmt.Printf("Resumable: %d bytes in %d slices\n", s.Size(), calls)
+		if calls < 2 || s.Size() != want {
+			t.Errorf("Expected %d bytes in several slices, got %d in %d", want, s.Size(), calls)
+		}
+		if done, _ := s.Run(context.Background(), time.Microsecond); !done {
+			t.Error("Expected a completed sizer to stay done")
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		s := NewResumableSizer(data)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if done, err := s.Run(ctx, time.Second); done || !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected an incomplete run with context.Canceled, got %v and %v", done, err)
+		}
+		if done, err := s.Run(context.Background(), time.Minute); !done || err != nil || s.Size() != want {
+			t.Errorf("Expected %d bytes after resuming, got %d, %v and %v", want, s.Size(), done, err)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		s := NewResumableSizer(data)
+		s.Run(context.Background(), time.Microsecond)
+		s.Close()
+		if done, err := s.Run(context.Background(), time.Minute); !done || !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected a closed sizer to report context.Canceled, got %v and %v", done, err)
+		}
+		if s.Size() > want {
+			t.Errorf("Expected at most %d bytes, got %d", want, s.Size())
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		s := NewResumableSizer(data, WithSafeMode(1))
+		for done := false; !done; {
+			done, _ = s.Run(context.Background(), time.Microsecond)
+		}
+		if s.Size() != want {
+			t.Errorf("Expected %d bytes in safe mode, got %d", want, s.Size())
+		}
+		s = NewResumableSizer(data, WithMaxNodes(100))
+		if done, err := s.Run(context.Background(), time.Minute); !done || !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded, got %v and %v", done, err)
+		}
+		s = NewResumableSizer(data, WithArch("nope"))
+		if done, err := s.Run(context.Background(), time.Minute); !done || err == nil {
+			t.Errorf("Expected the option error, got %v and %v", done, err)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+// retained.go
+package memsize
+
+import "strings"
+
+// ComputeRetained sets the Retained size of every node of the report: the bytes that would
+// become unreachable if the value at its path were cleared. Unlike Size, which counts
+// objects reachable through several paths at the first of them only, it leaves out objects
+// still referenced from elsewhere, so it answers what dropping a field actually frees.
+//
+// Retained sizes are computed from the dominator tree of the report's object graph, in which
+// pointers already counted through another path refer to the target counted elsewhere.
+func (r *Report) ComputeRetained() {
+	if r == nil || r.Root == nil {
+		return
+	}
+	computeRetained(r.Root)
+}
+
+// computeRetained sets the Retained size of every node below root and returns its graph
+func computeRetained(root *Node) *domGraph {
+	g, retained := retainedSizes(root)
+	for v, n := range g.nodes {
+		n.Retained = retained[v]
+	}
+	return g
+}
+
+// retainedSizes returns the graph of the nodes below root and their retained sizes, indexed
+// like its nodes, leaving the nodes unchanged
+func retainedSizes(root *Node) (*domGraph, []uint64) {
+	g := newDomGraph(root)
+	g.dominators()
+
+	retained := make([]uint64, len(g.nodes))
+	for i := len(g.order) - 1; i >= 0; i-- {
+		v := g.order[i]
+		retained[v] += g.nodes[v].Shallow
+		if v != 0 {
+			retained[g.idom[v]] += retained[v]
+		}
+	}
+	return g, retained
+}
+
+// reachable returns the total shallow size of the nodes reachable from node v
+func (g *domGraph) reachable(v int) uint64 {
+	visited := map[int]bool{v: true}
+	stack := []int{v}
+	var size uint64
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		size += g.nodes[v].Shallow
+		for _, s := range g.succs[v] {
+			if !visited[s] {
+				visited[s] = true
+				stack = append(stack, s)
+			}
+		}
+	}
+	return size
+}
+
+// domGraph is the object graph of a report with nodes numbered in depth-first preorder
+type domGraph struct {
+	nodes []*Node
+	ids   map[*Node]int
+	succs [][]int
+	preds [][]int
+
+	// order lists the nodes in reverse postorder and rpo is the position of each node in it
+	order []int
+	rpo   []int
+	idom  []int
+}
+
+func newDomGraph(root *Node) *domGraph {
+	ids := make(map[*Node]int)
+	g := &domGraph{ids: ids}
+	owners := make(map[uintptr]*Node)
+
+	// An explicit stack keeps arbitrarily deep reports from overflowing the goroutine stack
+	stack := []*Node{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		ids[n] = len(g.nodes)
+		g.nodes = append(g.nodes, n)
+		if n.Addr != 0 && !n.Shared {
+			if _, ok := owners[n.Addr]; !ok {
+				owners[n.Addr] = n
+			}
+		}
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			stack = append(stack, n.Children[i])
+		}
+	}
+
+	g.succs = make([][]int, len(g.nodes))
+	g.preds = make([][]int, len(g.nodes))
+	for id, n := range g.nodes {
+		for _, child := range n.Children {
+			g.edge(id, ids[child])
+		}
+		owner, ok := owners[n.Addr]
+		if !ok || !n.Shared {
+			continue
+		}
+		// The header of a pointer or interface belongs to the value holding it, only its target
+		// is shared
+		if derefStep(owner) != "" {
+			for _, child := range owner.Children {
+				g.edge(id, ids[child])
+			}
+		} else {
+			g.edge(id, ids[owner])
+		}
+	}
+	return g
+}
+
+// derefStep returns the path step, ".ptr" or ".elem", through which a pointer or interface
+// node refers to the object at its Addr, whose contents are then its single child. It returns
+// "" for nodes such as maps and channels that hold the referenced memory themselves.
+func derefStep(n *Node) string {
+	if n.Addr == 0 || len(n.Children) != 1 {
+		return ""
+	}
+	switch step := strings.TrimPrefix(n.Children[0].Path, n.Path); step {
+	case ".ptr", ".elem":
+		return step
+	}
+	return ""
+}
+
+func (g *domGraph) edge(from, to int) {
+	g.succs[from] = append(g.succs[from], to)
+	g.preds[to] = append(g.preds[to], from)
+}
+
+// postorder numbers the nodes reachable from the root in reverse postorder
+func (g *domGraph) postorder() {
+	type visit struct{ v, next int }
+	visited := make([]bool, len(g.nodes))
+	post := make([]int, 0, len(g.nodes))
+	stack := []visit{{v: 0}}
+	visited[0] = true
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(g.succs[top.v]) {
+			s := g.succs[top.v][top.next]
+			top.next++
+			if !visited[s] {
+				visited[s] = true
+				stack = append(stack, visit{v: s})
+			}
+			continue
+		}
+		post = append(post, top.v)
+		stack = stack[:len(stack)-1]
+	}
+
+	g.order = make([]int, len(post))
+	g.rpo = make([]int, len(g.nodes))
+	for i, v := range post {
+		g.order[len(post)-1-i] = v
+		g.rpo[v] = len(post) - 1 - i
+	}
+}
+
+// dominators computes the immediate dominator of every node with the iterative algorithm
+// of Cooper, Harvey and Kennedy, which converges in a few passes on graphs that are mostly trees
+func (g *domGraph) dominators() {
+	g.postorder()
+	g.idom = make([]int, len(g.nodes))
+	for i := range g.idom {
+		g.idom[i] = -1
+	}
+	g.idom[0] = 0
+
+	for changed := true; changed; {
+		changed = false
+		for _, v := range g.order[1:] {
+			idom := -1
+			for _, p := range g.preds[v] {
+				if g.idom[p] == -1 {
+					continue
+				}
+				if idom == -1 {
+					idom = p
+				} else {
+					idom = g.intersect(p, idom)
+				}
+			}
+			if idom != g.idom[v] {
+				g.idom[v] = idom
+				changed = true
+			}
+		}
+	}
+}
+
+func (g *domGraph) intersect(a, b int) int {
+	for a != b {
+		for g.rpo[a] > g.rpo[b] {
+			a = g.idom[a]
+		}
+		for g.rpo[b] > g.rpo[a] {
+			b = g.idom[b]
+		}
+	}
+	return a
+}
@@ -0,0 +1,87 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+type blob struct {
+	Data []byte
+}
+
+type holders struct {
+	A, B *blob
+	Own  *blob
+}
+
+func TestComputeRetained(t *testing.T) {
+	Debug = false
+
+	shared := &blob{Data: make([]byte, 1000)}
+	v := &holders{A: shared, B: shared, Own: &blob{Data: make([]byte, 500)}}
+
+	report := GetReport(v, WithSizeModel(ExactSizes))
+	report.ComputeRetained()
+
+	nodes := make(map[string]*Node)
+	report.Walk(func(n *Node) bool {
+		fmt.Printf("%s: size %d, retained %d\n", n.Path, n.Size, n.Retained)
+		nodes[n.Path] = n
+		return true
+	})
+
+	if root := report.Root; root.Retained != root.Size {
+		t.Errorf("Expected the root to retain its total %d, got %d", root.Size, root.Retained)
+	}
+
+	// A counted the shared blob, but clearing it leaves the blob reachable through B
+	if a := nodes["root.ptr.A"]; a.Size <= 1000 || a.Retained != 8 {
+		t.Errorf("Expected A to account the blob but retain only its pointer, got size %d, retained %d",
+			a.Size, a.Retained)
+	}
+	if b := nodes["root.ptr.B"]; b.Retained != 8 {
+		t.Errorf("Expected B to retain only its pointer, got %d", b.Retained)
+	}
+	if own := nodes["root.ptr.Own"]; own.Retained != own.Size {
+		t.Errorf("Expected Own to retain its size %d, got %d", own.Size, own.Retained)
+	}
+
+	t.Run("Cycle", func(t *testing.T) {
+		type ring struct {
+			Next *ring
+			Data [64]byte
+		}
+		a, b := &ring{}, &ring{}
+		a.Next, b.Next = b, a
+
+		report := GetReport(a, WithSizeModel(ExactSizes))
+		report.ComputeRetained()
+		if report.Root.Retained != report.Total() {
+			t.Errorf("Expected the root to retain the whole cycle of %d bytes, got %d",
+				report.Total(), report.Root.Retained)
+		}
+	})
+
+	t.Run("Interfaces", func(t *testing.T) {
+		// Copies of an interface share the box of its value like pointers share their target
+		var boxed interface{} = blob{Data: make([]byte, 1000)}
+		v := &struct{ A, B interface{} }{A: boxed, B: boxed}
+
+		report := GetReport(v, WithSizeModel(ExactSizes))
+		report.ComputeRetained()
+		nodes := make(map[string]*Node)
+		report.Walk(func(n *Node) bool {
+			nodes[n.Path] = n
+			return true
+		})
+		if a := nodes["root.ptr.A"]; a.Size <= 1000 || a.Retained >= 1000 {
+			t.Errorf("Expected A to account the boxed blob but not retain it, got size %d, retained %d",
+				a.Size, a.Retained)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var r *Report
+		r.ComputeRetained()
+	})
+}
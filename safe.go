@@ -0,0 +1,211 @@
+// safe.go
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+)
+
+// ErrUnstable is wrapped by the UnstableError returned in safe mode
+var ErrUnstable = errors.New("memsize: value changed while being sized")
+
+// UnstableError lists the values a measurement in safe mode gave up on because sizing them
+// kept panicking, typically since they were mutated concurrently
+type UnstableError struct {
+	// Paths are the locations of the values relative to the root, as in Node.Path
+	Paths []string
+	// Errors holds the last panic of every value given up, in the order of Paths
+	Errors []PathError
+}
+
+func (e *UnstableError) Error() string {
+	return fmt.Sprintf("memsize: couldn't size %s because they changed while being sized",
+		strings.Join(e.Paths, ", "))
+}
+
+func (e *UnstableError) Unwrap() error {
+	return ErrUnstable
+}
+
+// PathError is a panic raised while sizing the value at Path, recovered in safe mode
+type PathError struct {
+	// Path is the location of the value relative to the root, as in Node.Path
+	Path string
+	Type string
+	// Err is the panic, wrapped in an error if it isn't one
+	Err error
+}
+
+func (e PathError) Error() string {
+	return fmt.Sprintf("memsize: sizing %s (%s) panicked: %v", e.Path, e.Type, e.Err)
+}
+
+func (e PathError) Unwrap() error {
+	return e.Err
+}
+
+// pathErrorRecord is a PathError as encoded in reports, whose panic is kept as its message
+type pathErrorRecord struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+func encodePathErrors(errs []PathError) []pathErrorRecord {
+	if errs == nil {
+		return nil
+	}
+	records := make([]pathErrorRecord, len(errs))
+	for i, e := range errs {
+		records[i] = pathErrorRecord{Path: e.Path, Type: e.Type, Error: e.Err.Error()}
+	}
+	return records
+}
+
+func decodePathErrors(records []pathErrorRecord) []PathError {
+	if records == nil {
+		return nil
+	}
+	errs := make([]PathError, len(records))
+	for i, r := range records {
+		errs[i] = PathError{Path: r.Path, Type: r.Type, Err: errors.New(r.Error)}
+	}
+	return errs
+}
+
+// WithSafeMode recovers from panics raised while sizing values that are mutated during the
+// traversal, such as a slice shrinking between reading its length and its elements. The value
+// whose traversal panicked is sized again up to retries times, then given up: it keeps the size
+// accounted so far, is marked Unstable in reports, and GetTotalSizeE returns an *UnstableError.
+//
+// The panics of the values given up are collected as PathErrors in Report.Errors and
+// UnstableError.Errors, so one value that can't be sized doesn't fail the whole measurement.
+// Faults reading memory that is no longer mapped, e.g. behind handles freed by a finalizer or
+// pointers into memory managed by C, are recovered as panics too.
+//
+// Safe mode is best effort: writing a map while it is being iterated is a fatal error of the
+// runtime that cannot be recovered from, so maps must not be written during the measurement.
+// Measurements in safe mode are sequential.
+func WithSafeMode(retries int) Option {
+	return func(c *config) {
+		c.safe = true
+		c.retries = retries
+	}
+}
+
+// retryFrame is a value to size again after its traversal panicked
+type retryFrame struct {
+	v        reflect.Value
+	step     pathStep
+	attempts int
+}
+
+// safeTotalSize is getTotalSize in safe mode, which recovers from panics of every step
+func (w *walker) safeTotalSize(v reflect.Value, path string, fast bool) {
+	w.safeStep(func() { w.push(v, rootStep(path)) })
+	for len(w.stack) > 0 || w.retry != nil {
+		w.safeAdvance(fast)
+	}
+}
+
+// safeAdvance sizes again the value given up by the last panic, or takes the next step
+func (w *walker) safeAdvance(fast bool) {
+	if r := w.retry; r != nil {
+		w.retry = nil
+		w.safeStep(func() {
+			w.attempts = r.attempts
+			w.push(r.v, r.step)
+		})
+		return
+	}
+	w.safeStep(func() { w.step(fast) })
+}
+
+// safeStep runs a step of the traversal, retrying or giving up the value on top of the stack
+// if it panics
+func (w *walker) safeStep(step func()) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			w.recoverFrame(r)
+		}
+	}()
+	step()
+}
+
+// recoverFrame handles a panic raised while sizing the value on top of the stack
+func (w *walker) recoverFrame(r interface{}) {
+	if len(w.stack) == 0 {
+		return
+	}
+	i := len(w.stack) - 1
+	f := &w.stack[i]
+	if w.cfg.debug {
+		w.debugPrint(f, "Recovered from panic: %v", r)
+	}
+
+	// A value that panics again after it was given up, e.g. while finishing, is dropped with
+	// the size accounted so far
+	if f.unstable {
+		size := f.size
+		w.stack = w.stack[:i]
+		w.add(size)
+		return
+	}
+
+	if f.attempts < w.cfg.retries {
+		// Objects first visited within the value are forgotten, so the retry counts them again
+		if seen, ok := w.seen.(*visitedSet); ok {
+			for _, k := range w.visitLog[f.logMark:] {
+				seen.remove(k)
+			}
+		}
+		w.visitLog = w.visitLog[:f.logMark]
+		if w.scratch != nil {
+			w.endRange(f)
+			w.endEntries(f)
+		}
+
+		w.retry = &retryFrame{v: f.v, step: f.step, attempts: f.attempts + 1}
+		if f.node != nil {
+			if i == 0 {
+				w.root = nil
+			} else if parent := w.stack[i-1].node; parent != nil {
+				parent.Children = parent.Children[:len(parent.Children)-1]
+			}
+		}
+		w.stack = w.stack[:i]
+		return
+	}
+
+	f.unstable = true
+	f.pruned = true
+	if f.node != nil {
+		f.node.Unstable = true
+	}
+	e := PathError{Path: w.fullPath(f), Err: panicError(r)}
+	if f.v.IsValid() {
+		e.Type = f.v.Type().String()
+	}
+	w.unstable = append(w.unstable, e.Path)
+	w.panics = append(w.panics, e)
+}
+
+// panicError returns a recovered panic as an error
+func panicError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// unstableErr returns an *UnstableError listing the values given up in safe mode, if any
+func (w *walker) unstableErr() error {
+	if len(w.unstable) == 0 {
+		return nil
+	}
+	return &UnstableError{Paths: w.unstable, Errors: w.panics}
+}
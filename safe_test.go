@@ -0,0 +1,136 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+type flaky struct {
+	Name string
+}
+
+func TestSafeMode(t *testing.T) {
+	Debug = false
+
+	type inventory struct {
+		Items []string
+		Owner *string
+	}
+
+	t.Run("Retry", func(t *testing.T) {
+		owner := "owner"
+		v := &inventory{Items: []string{"a", "b", "c"}, Owner: &owner}
+		visits := make(map[string]int)
+		err := Walk(v, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			visits[path]++
+			// A value that changed while it was read, once
+			if path == "root.ptr.Items[1]" && visits[path] == 1 {
+				panic("index out of range")
+			}
+			return WalkContinue
+		}, WithSafeMode(1))
+		fmt.Printf("Visits: %v\n", visits)
+		if err != nil {
+			t.Fatalf("Expected the retry to succeed, got %v", err)
+		}
+		if visits["root.ptr.Items[1]"] != 2 {
+			t.Errorf("Expected the element to be sized again, got %v", visits)
+		}
+		for _, path := range []string{"root.ptr.Items", "root.ptr.Items[2]", "root.ptr.Owner.ptr"} {
+			if visits[path] != 1 {
+				t.Errorf("Expected %s to be visited once, got %v", path, visits)
+			}
+		}
+	})
+
+	t.Run("Given Up", func(t *testing.T) {
+		v := &inventory{Items: []string{"a", "b"}}
+		calls := 0
+		err := Walk(v, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			if path == "root.ptr.Items[0]" {
+				calls++
+				panic("concurrent modification")
+			}
+			return WalkContinue
+		}, WithSafeMode(2))
+
+		var unstable *UnstableError
+		if !errors.Is(err, ErrUnstable) || !errors.As(err, &unstable) {
+			t.Fatalf("Expected an UnstableError, got %v", err)
+		}
+		if len(unstable.Paths) != 1 || unstable.Paths[0] != "root.ptr.Items[0]" {
+			t.Errorf("Expected root.ptr.Items[0] to be unstable, got %v", unstable.Paths)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 2 retries, got %d calls", calls)
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		RegisterOffHeap(func(*flaky) uint64 { panic("unstable") })
+		defer offHeapSizers.Delete(reflect.TypeOf(flaky{}))
+
+		v := struct {
+			Stable   []int
+			Unstable flaky
+		}{Stable: []int{1, 2, 3}, Unstable: flaky{Name: "x"}}
+		report := GetReport(v, WithSafeMode(0))
+		fmt.Printf("Unstable: %v\n", report.Unstable)
+		if len(report.Unstable) != 1 || report.Unstable[0] != "root.Unstable" || report.Truncated {
+			t.Errorf("Expected only root.Unstable to be unstable, got %v", report.Unstable)
+		}
+		for _, n := range report.Root.Children {
+			if n.Unstable != (n.Path == "root.Unstable") {
+				t.Errorf("Expected only root.Unstable to be marked, got %s: %v", n.Path, n.Unstable)
+			}
+		}
+		if stable := report.Root.Children[0]; stable.Size != GetTotalSize(v.Stable) {
+			t.Errorf("Expected the stable field to be sized, got %d", stable.Size)
+		}
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to propagate without safe mode")
+			}
+		}()
+		Walk([]int{1}, func(string, reflect.Value, uint64) WalkAction { panic("boom") })
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		RegisterOffHeap(func(*flaky) uint64 { panic(errors.New("handle closed")) })
+		defer offHeapSizers.Delete(reflect.TypeOf(flaky{}))
+
+		v := []flaky{{Name: "a"}, {Name: "b"}}
+		report := GetReport(v, WithSafeMode(0))
+		fmt.Printf("Errors: %v\n", report.Errors)
+		if len(report.Errors) != 2 || report.Errors[1].Path != "root[1]" || report.Errors[1].Type != "memsize.flaky" {
+			t.Fatalf("Expected an error per element, got %v", report.Errors)
+		}
+		if report.Errors[0].Err.Error() != "handle closed" {
+			t.Errorf("Expected the panic as the error, got %v", report.Errors[0].Err)
+		}
+	})
+
+	t.Run("Fault", func(t *testing.T) {
+		// A string whose header lies in memory that isn't mapped, like a handle freed behind
+		// the walker's back
+		v := struct {
+			Name string
+			Gone *string
+		}{Name: "valid", Gone: (*string)(unsafe.Add(nil, 0x1008))}
+		size, err := GetTotalSizeE(&v, WithSafeMode(0))
+		var unstable *UnstableError
+		if !errors.As(err, &unstable) || len(unstable.Errors) != 1 || unstable.Errors[0].Path != "root.ptr.Gone.ptr" {
+			t.Fatalf("Expected the fault to be recovered, got %v", err)
+		}
+		fmt.Printf("Fault: %v\n", unstable.Errors[0])
+		if size < uint64(len(v.Name)) {
+			t.Errorf("Expected the valid fields to be counted, got %d", size)
+		}
+	})
+}
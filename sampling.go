@@ -0,0 +1,186 @@
+// sampling.go
+package memsize
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// samplingMinLen is the smallest collection that is sampled instead of fully traversed
+	samplingMinLen = 1000
+	// samplingMinSamples is the expected number of samples taken from each collection
+	samplingMinSamples = 100
+	// samplingZ is the normal quantile of a two-sided 95% confidence interval
+	samplingZ = 1.96
+)
+
+// Estimate is a size that may have been extrapolated from samples
+type Estimate struct {
+	Size uint64
+	// Low and High bound the 95% confidence interval of Size; they equal Size when
+	// nothing was sampled
+	Low  uint64
+	High uint64
+}
+
+// GetSizeEstimate measures v like GetTotalSize and reports the confidence interval
+// resulting from WithSampling. Samples of different collections are assumed independent,
+// and objects shared between sampled and skipped elements make the estimate less reliable.
+func GetSizeEstimate(v interface{}, opts ...Option) Estimate {
+	w := newWalker(opts...)
+	size, _ := w.measure(reflect.ValueOf(v))
+	return w.sampler.estimate(size)
+}
+
+// sampler decides which elements of large collections are visited and accumulates the
+// variance of the resulting estimate; it is shared by all walkers of a measurement
+type sampler struct {
+	rate float64
+
+	mu       sync.Mutex
+	seeds    *rand.Rand
+	variance float64
+}
+
+// sampleState tracks the sample taken from a single slice or map
+type sampleState struct {
+	n int
+	p float64
+
+	// k sampled elements with sizes adding up to sum, and squares to sumSq
+	k     int
+	sum   float64
+	sumSq float64
+
+	// mark is the size of the frame when the element in progress started
+	mark uint64
+	open bool
+}
+
+// newSampler returns nil when sampling is disabled, which makes all sampler methods no-ops
+func newSampler(cfg *config) *sampler {
+	if cfg.sampling <= 0 || cfg.sampling >= 1 {
+		return nil
+	}
+	seed := cfg.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &sampler{rate: cfg.sampling, seeds: rand.New(rand.NewSource(seed))}
+}
+
+// newRand returns a random source for one walker, since rand.Rand is not safe for concurrent use
+func (s *sampler) newRand() *rand.Rand {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return rand.New(rand.NewSource(s.seeds.Int63()))
+}
+
+// start returns the sample state for a collection of n elements, or nil if it is fully traversed
+func (s *sampler) start(n int) *sampleState {
+	if s == nil || n < samplingMinLen {
+		return nil
+	}
+	p := math.Max(s.rate, float64(samplingMinSamples)/float64(n))
+	if p >= 1 {
+		return nil
+	}
+	return &sampleState{n: n, p: p}
+}
+
+// skip returns the number of elements to pass over before the next sampled one. The gaps of
+// independent per-element coin flips are geometrically distributed, so skipped elements cost nothing.
+func (w *walker) skip(st *sampleState) int {
+	u := 1 - w.rng.Float64()
+	gap := math.Floor(math.Log(u) / math.Log(1-st.p))
+	if gap > float64(st.n) {
+		return st.n
+	}
+	return int(gap)
+}
+
+// nextEntry advances a sampled map iterator past the skipped entries to the next sampled one
+func (w *walker) nextEntry(f *frame) bool {
+	if f.keys != nil {
+		if f.sample != nil {
+			f.next += w.skip(f.sample)
+		}
+		f.next++
+		return f.next <= len(f.keys)
+	}
+	if f.sample != nil {
+		for skip := w.skip(f.sample); skip > 0; skip-- {
+			if !f.iter.Next() {
+				return false
+			}
+		}
+	}
+	return f.iter.Next()
+}
+
+// begin marks the start of a sampled element at the current size of its collection
+func (st *sampleState) begin(size uint64) {
+	if st == nil {
+		return
+	}
+	st.mark = size
+	st.open = true
+}
+
+// observe completes the sampled element in progress, if any
+func (st *sampleState) observe(size uint64) {
+	if st == nil || !st.open {
+		return
+	}
+	x := float64(size - st.mark)
+	st.k++
+	st.sum += x
+	st.sumSq += x * x
+	st.open = false
+}
+
+// extrapolate completes the sample of a finished collection and returns the estimated size
+// of the elements that were skipped
+func (s *sampler) extrapolate(st *sampleState, size uint64) uint64 {
+	st.observe(size)
+	if st.k == 0 {
+		return 0
+	}
+
+	k, n := float64(st.k), float64(st.n)
+	mean := st.sum / k
+	if st.k > 1 {
+		// Variance of the estimated total of a simple random sample without replacement
+		sampleVar := math.Max(0, (st.sumSq-k*mean*mean)/(k-1))
+		s.mu.Lock()
+		s.variance += n * n * sampleVar / k * (1 - k/n)
+		s.mu.Unlock()
+	}
+	return uint64(math.Round(mean*n - st.sum))
+}
+
+func (s *sampler) estimate(size uint64) Estimate {
+	e := Estimate{Size: size, Low: size, High: size}
+	if s == nil {
+		return e
+	}
+
+	s.mu.Lock()
+	margin := samplingZ * math.Sqrt(s.variance)
+	s.mu.Unlock()
+
+	e.High = size + uint64(math.Ceil(margin))
+	if low := float64(size) - margin; low > 0 {
+		e.Low = uint64(low)
+	} else {
+		e.Low = 0
+	}
+	return e
+}
@@ -0,0 +1,83 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func withSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+func TestSampling(t *testing.T) {
+	Debug = false
+
+	t.Run("Homogeneous Slice", func(t *testing.T) {
+		values := make([]string, 10000)
+		for i := range values {
+			values[i] = "0123456789"
+		}
+		full := GetTotalSize(values)
+		estimate := GetSizeEstimate(values, WithSampling(0.05), withSeed(1))
+		fmt.Printf("Homogeneous slice: full %d, estimate %+v\n", full, estimate)
+		if estimate.Size != full || estimate.Low != full || estimate.High != full {
+			t.Errorf("Expected an exact estimate of %d, got %+v", full, estimate)
+		}
+	})
+
+	t.Run("Heterogeneous Slice", func(t *testing.T) {
+		values := make([]string, 20000)
+		for i := range values {
+			values[i] = strings.Repeat("x", i%97)
+		}
+		full := GetTotalSize(values)
+		estimate := GetSizeEstimate(values, WithSampling(0.1), withSeed(2))
+		fmt.Printf("Heterogeneous slice: full %d, estimate %+v\n", full, estimate)
+		if estimate.Low > full || estimate.High < full {
+			t.Errorf("Expected %d within [%d, %d]", full, estimate.Low, estimate.High)
+		}
+		if estimate.Low == estimate.High {
+			t.Error("Expected a non-empty confidence interval")
+		}
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		values := make(map[int][]byte, 5000)
+		for i := 0; i < 5000; i++ {
+			values[i] = make([]byte, i%50)
+		}
+		full := GetTotalSize(values)
+		estimate := GetSizeEstimate(values, WithSampling(0.1), withSeed(3))
+		fmt.Printf("Map: full %d, estimate %+v\n", full, estimate)
+		// Map iteration order is random, so the sample differs between runs despite the seed
+		if estimate.Size < full*9/10 || estimate.Size > full*11/10 {
+			t.Errorf("Expected an estimate within 10%% of %d, got %d", full, estimate.Size)
+		}
+		if estimate.Low == estimate.High {
+			t.Error("Expected a non-empty confidence interval")
+		}
+	})
+
+	t.Run("Small Collections", func(t *testing.T) {
+		values := []string{"a", "bb", "ccc"}
+		full := GetTotalSize(values)
+		if size := GetTotalSize(values, WithSampling(0.01)); size != full {
+			t.Errorf("Expected small collections to be fully traversed, got %d instead of %d", size, full)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		values := make([][]byte, 20000)
+		for i := range values {
+			values[i] = make([]byte, i%10)
+		}
+		full := GetTotalSize(values)
+		estimate := GetSizeEstimate(values, WithSampling(0.1), WithParallelism(4), withSeed(4))
+		if estimate.Low > full || estimate.High < full {
+			t.Errorf("Expected %d within [%d, %d]", full, estimate.Low, estimate.High)
+		}
+	})
+}
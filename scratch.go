@@ -0,0 +1,140 @@
+// scratch.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+)
+
+// maxPooledVisited is the size above which visited sets are dropped instead of pooled, so a
+// single large measurement doesn't pin its memory
+const maxPooledVisited = 1 << 16
+
+// scratch holds the reusable memory of a sequential measurement
+type scratch struct {
+	seen  *visitedSet
+	stack []frame
+	iters []*reflect.MapIter
+	// entries holds addressable values map entries are copied into, by type
+	entries map[reflect.Type][]reflect.Value
+}
+
+// scratchPool recycles scratch memory between measurements, so measuring the same kind of
+// value repeatedly, e.g. in a request path, doesn't allocate once the pool is warm
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return &scratch{seen: newVisitedSet(false), entries: make(map[reflect.Type][]reflect.Value)}
+	},
+}
+
+// release clears the walker's scratch memory and returns it to the pool
+func (w *walker) release() {
+	if w.seen != nil {
+		w.stats.visited(w.seen.bytes())
+	}
+	if arena, ok := w.seen.(*visitedSet); ok && arena.arena {
+		arena.free()
+	}
+	s := w.scratch
+	if s == nil {
+		return
+	}
+	stack := w.stack[:cap(w.stack)]
+	w.scratch, w.seen, w.stack = nil, nil, nil
+	if s.seen.len() > maxPooledVisited {
+		return
+	}
+	s.seen.reset()
+	// Frames hold values that must not be kept alive by the pool
+	for i := range stack {
+		stack[i] = frame{}
+	}
+	s.stack = stack[:0]
+	for _, values := range s.entries {
+		for _, v := range values {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+	scratchPool.Put(s)
+}
+
+// mapRange returns an iterator over the entries of a map, reusing a released one if possible
+func (w *walker) mapRange(v reflect.Value) *reflect.MapIter {
+	if w.scratch == nil || len(w.scratch.iters) == 0 {
+		return v.MapRange()
+	}
+	iters := w.scratch.iters
+	iter := iters[len(iters)-1]
+	w.scratch.iters = iters[:len(iters)-1]
+	iter.Reset(v)
+	return iter
+}
+
+// endRange releases the iterator of a map frame once its entries were visited
+func (w *walker) endRange(f *frame) {
+	if f.iter == nil {
+		return
+	}
+	if w.scratch != nil {
+		f.iter.Reset(reflect.Value{})
+		w.scratch.iters = append(w.scratch.iters, f.iter)
+	}
+	f.iter = nil
+}
+
+// mapEntry returns the key or value the iterator of a map frame is at. Entries are copied into
+// addressable values the frame reuses for every entry, which avoids allocating a copy each, as
+// long as no entry escapes the traversal: values are handed to WalkFunc callbacks and to
+// parallel workers as separate copies. Entries of unexported maps can't be copied that way.
+func (w *walker) mapEntry(f *frame, key bool) reflect.Value {
+	if f.keys != nil {
+		if key {
+			return f.keys[f.next-1]
+		}
+		return f.v.MapIndex(f.keys[f.next-1])
+	}
+	if w.scratch == nil || !w.fast() || !f.v.CanInterface() {
+		if key {
+			return f.iter.Key()
+		}
+		return f.iter.Value()
+	}
+
+	dst, t := &f.val, f.v.Type().Elem()
+	if key {
+		dst, t = &f.key, f.v.Type().Key()
+	}
+	if !dst.IsValid() {
+		*dst = w.takeEntry(t)
+	}
+	if key {
+		dst.SetIterKey(f.iter)
+	} else {
+		dst.SetIterValue(f.iter)
+	}
+	return *dst
+}
+
+// takeEntry returns an addressable value of type t to copy map entries into
+func (w *walker) takeEntry(t reflect.Type) reflect.Value {
+	values := w.scratch.entries[t]
+	if len(values) == 0 {
+		return reflect.New(t).Elem()
+	}
+	v := values[len(values)-1]
+	w.scratch.entries[t] = values[:len(values)-1]
+	return v
+}
+
+// endEntries releases the values a map frame copied its entries into
+func (w *walker) endEntries(f *frame) {
+	w.putEntry(&f.key)
+	w.putEntry(&f.val)
+}
+
+func (w *walker) putEntry(v *reflect.Value) {
+	if v.IsValid() {
+		w.scratch.entries[v.Type()] = append(w.scratch.entries[v.Type()], *v)
+		*v = reflect.Value{}
+	}
+}
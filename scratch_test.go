@@ -0,0 +1,57 @@
+package memsize
+
+import "testing"
+
+func TestScratchAllocations(t *testing.T) {
+	Debug = false
+
+	type item struct {
+		Name  string
+		Tags  []string
+		Count *int
+		Attrs map[string]int
+		Any   interface{}
+	}
+	n := 1
+	items := make([]*item, 100)
+	for i := range items {
+		items[i] = &item{Name: "item", Tags: []string{"a"}, Count: &n, Attrs: map[string]int{"a": 1},
+			Any: [2]int{i, i}}
+	}
+
+	// Only the configuration and the walker are allocated once scratch memory is pooled. The
+	// slice is passed by pointer since boxing its header in an interface would allocate too.
+	size := GetTotalSize(&items)
+	allocs := testing.AllocsPerRun(100, func() {
+		if GetTotalSize(&items) != size {
+			t.Fatal("Expected the same size on every run")
+		}
+	})
+	if allocs > 2 && !raceEnabled {
+		t.Errorf("Expected at most 2 allocations per measurement, got %.1f", allocs)
+	}
+
+	t.Run("Nested Maps", func(t *testing.T) {
+		// Entries of the inner maps are copied into separate values while the outer entry is in use
+		nested := map[string]map[string][]byte{
+			"a": {"x": make([]byte, 10), "y": make([]byte, 20)},
+			"b": {"z": make([]byte, 30)},
+		}
+		if size, full := GetTotalSize(nested), GetReport(nested).Total(); size != full {
+			t.Errorf("Expected %d bytes like a full walk, got %d", full, size)
+		}
+	})
+
+	t.Run("Large Visited Set", func(t *testing.T) {
+		many := make([]*int, maxPooledVisited+1)
+		for i := range many {
+			many[i] = new(int)
+		}
+		if size, full := GetTotalSize(many), GetReport(many).Total(); size != full {
+			t.Errorf("Expected %d bytes like a full walk, got %d", full, size)
+		}
+		if size := GetTotalSize(items); size != GetReport(items).Total() {
+			t.Errorf("Expected a fresh visited set afterwards, got %d", size)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+// self.go
+package memsize
+
+import "reflect"
+
+func init() {
+	for _, t := range selfTypes {
+		typeHandlers[t] = selfHandler
+	}
+}
+
+// selfTypes lists the types holding the state of measurements: the walker with the report it
+// builds and its stack, the visited sets and the regions of an IncrementalSizer. A measured
+// value can reach them, e.g. through a ResumableSizer it holds; the memory is the library's own
+// and traversing the state of a measurement as it grows might never end.
+var selfTypes = []reflect.Type{
+	reflect.TypeOf(walker{}),
+	reflect.TypeOf(scratch{}),
+	reflect.TypeOf(visitedSet{}),
+	reflect.TypeOf(bloomSet{}),
+	reflect.TypeOf(stripedSet{}),
+	reflect.TypeOf(parallel{}),
+	reflect.TypeOf(regionSet{}),
+	reflect.TypeOf(identitySet{}),
+}
+
+// selfHandler excludes the state of measurements from results
+func selfHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	return 0, nil
+}
@@ -0,0 +1,58 @@
+package memsize
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSelfMeasurement(t *testing.T) {
+	Debug = false
+
+	t.Run("Report", func(t *testing.T) {
+		type holder struct {
+			name   string
+			report *Report
+		}
+		h := &holder{name: "holder"}
+		h.report = GetReport(h)
+		h.report = GetReport(h)
+
+		size := GetTotalSize(h)
+		fmt.Printf("Holder with its report: %d bytes\n", size)
+		if without := GetTotalSize(&holder{name: "holder"}); size <= without {
+			t.Errorf("Expected the report to be counted on top of %d, got %d", without, size)
+		}
+	})
+
+	t.Run("Resumable", func(t *testing.T) {
+		type holder struct {
+			data  []int
+			sizer *ResumableSizer
+		}
+		h := &holder{data: make([]int, 100)}
+		h.sizer = NewResumableSizer(h, WithSizeModel(ExactSizes))
+		defer h.sizer.Close()
+		for {
+			done, err := h.sizer.Run(context.Background(), time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done {
+				break
+			}
+		}
+
+		report := GetReport(h, WithSizeModel(ExactSizes))
+		report.Walk(func(n *Node) bool {
+			if n.Type == "memsize.walker" && (n.Size != 0 || len(n.Children) > 0) {
+				t.Errorf("Expected the walker to be excluded, got %d bytes at %s", n.Size, n.Path)
+			}
+			return true
+		})
+		if size := h.sizer.Size(); size < 800 || size > report.Total() {
+			t.Errorf("Expected the sizer to count the data but not its own state, got %d", size)
+		}
+	})
+}
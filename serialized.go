@@ -0,0 +1,91 @@
+// serialized.go
+package memsize
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Encoder writes an encoding of v to w, e.g. with protobuf or msgpack
+type Encoder func(w io.Writer, v interface{}) error
+
+// JSONEncoder and GobEncoder encode values with encoding/json and encoding/gob
+var (
+	JSONEncoder Encoder = func(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) }
+	GobEncoder  Encoder = func(w io.Writer, v interface{}) error { return gob.NewEncoder(w).Encode(v) }
+)
+
+// SerializedReport is the in-memory size of a value next to the sizes of its encodings
+type SerializedReport struct {
+	Memory    uint64
+	Encodings []EncodedSize
+}
+
+// EncodedSize is the size of one encoding of a value
+type EncodedSize struct {
+	Name  string
+	Bytes uint64
+	// Ratio is the memory size over the encoded size: how much bigger the value is in RAM
+	// than on the wire
+	Ratio float64
+	// Err is set when the value couldn't be encoded; Bytes then counts what was written
+	Err error
+}
+
+// String summarizes the report, e.g. "memory 12.00 KiB, json 3.10 KiB (3.9x), gob 2.00 KiB (6.0x)"
+func (r SerializedReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "memory %s", Format(r.Memory))
+	for _, e := range r.Encodings {
+		if e.Err != nil {
+			fmt.Fprintf(&b, ", %s failed: %v", e.Name, e.Err)
+		} else {
+			fmt.Fprintf(&b, ", %s %s (%.1fx)", e.Name, Format(e.Bytes), e.Ratio)
+		}
+	}
+	return b.String()
+}
+
+// CompareSerialized measures v with the given options and encodes it with encoding/json,
+// encoding/gob and the given encoders by name, for capacity planning between storage or
+// network and memory. Encodings are counted without being kept, and listed as json, gob and
+// the others by name. Note that encodings only cover what the encoder sees, such as exported
+// fields.
+func CompareSerialized(v interface{}, encoders map[string]Encoder, opts ...Option) SerializedReport {
+	r := SerializedReport{Memory: GetTotalSize(v, opts...)}
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	encode := func(name string, enc Encoder) {
+		var c countingWriter
+		e := EncodedSize{Name: name, Err: enc(&c, v)}
+		e.Bytes = c.n
+		if e.Bytes > 0 {
+			e.Ratio = float64(r.Memory) / float64(e.Bytes)
+		}
+		r.Encodings = append(r.Encodings, e)
+	}
+	encode("json", JSONEncoder)
+	encode("gob", GobEncoder)
+	for _, name := range names {
+		encode(name, encoders[name])
+	}
+	return r
+}
+
+// countingWriter counts the bytes written to it and discards them
+type countingWriter struct {
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += uint64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,70 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompareSerialized(t *testing.T) {
+	Debug = false
+
+	type record struct {
+		ID    int
+		Name  string
+		Tags  []string
+		Attrs map[string]string
+	}
+	records := make([]*record, 100)
+	for i := range records {
+		records[i] = &record{ID: i, Name: fmt.Sprint("record-", i), Tags: []string{"a", "b"},
+			Attrs: map[string]string{"k": "v"}}
+	}
+
+	t.Run("Builtin", func(t *testing.T) {
+		r := CompareSerialized(records, nil)
+		fmt.Printf("CompareSerialized: %s\n", r)
+		if r.Memory != GetTotalSize(records) {
+			t.Errorf("Expected memory size %d, got %d", GetTotalSize(records), r.Memory)
+		}
+		if len(r.Encodings) != 2 || r.Encodings[0].Name != "json" || r.Encodings[1].Name != "gob" {
+			t.Fatalf("Expected json and gob, got %v", r.Encodings)
+		}
+		for _, e := range r.Encodings {
+			if e.Err != nil || e.Bytes == 0 || e.Ratio <= 1 {
+				t.Errorf("Expected %s to be smaller than in memory, got %d bytes (%.1fx, %v)",
+					e.Name, e.Bytes, e.Ratio, e.Err)
+			}
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		failing := errors.New("unsupported")
+		r := CompareSerialized(records, map[string]Encoder{
+			"text": func(w io.Writer, v interface{}) error {
+				_, err := fmt.Fprint(w, v)
+				return err
+			},
+			"broken": func(w io.Writer, v interface{}) error { return failing },
+		})
+		fmt.Printf("CompareSerialized: %s\n", r)
+		if len(r.Encodings) != 4 || r.Encodings[2].Name != "broken" || r.Encodings[3].Name != "text" {
+			t.Fatalf("Expected json, gob, broken and text, got %v", r.Encodings)
+		}
+		if r.Encodings[2].Err != failing || r.Encodings[3].Bytes == 0 {
+			t.Errorf("Expected a failed and a counted encoding, got %v", r.Encodings[2:])
+		}
+		if !strings.Contains(r.String(), "broken failed: unsupported") {
+			t.Errorf("Expected the failure in the summary, got %s", r)
+		}
+	})
+
+	t.Run("Unencodable", func(t *testing.T) {
+		r := CompareSerialized(make(chan int), nil)
+		if r.Encodings[0].Err == nil || r.Encodings[1].Err == nil {
+			t.Errorf("Expected channels to fail to encode, got %v", r.Encodings)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+// shared.go
+package memsize
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SharedPolicy selects which nodes of a report the objects reachable through several
+// references are attributed to
+type SharedPolicy int
+
+const (
+	// FirstOwner attributes a shared object to the first reference reaching it, the others
+	// only count their own header and are marked Shared
+	FirstOwner SharedPolicy = iota
+	// SplitShared divides the bytes of a shared object evenly among its references
+	SplitShared
+	// SeparateShared attributes shared objects to no reference and adds their bytes to
+	// Report.SharedBytes instead
+	SeparateShared
+	// ListShared sets shared objects aside like SeparateShared and lists each of them once in
+	// Report.SharedObjects, with its references counted by the type holding them
+	ListShared
+)
+
+// SharedObject is an object reachable through several references, see ListShared
+type SharedObject struct {
+	// Type is the type of the references, e.g. "*main.Schema"
+	Type string `json:"type"`
+	// Path locates the reference that reached the object first
+	Path string `json:"path"`
+	// Size is the size of the object without the references
+	Size uint64 `json:"size"`
+	// References is the number of references to the object, the first included
+	References int `json:"references"`
+	// Referrers counts the references by the type of the value holding them
+	Referrers map[string]int `json:"referrers,omitempty"`
+}
+
+func (o SharedObject) String() string {
+	return fmt.Sprintf("%s shared by %d references, %s", o.Type, o.References, Format(o.Size))
+}
+
+// WithSharedPolicy selects how reports attribute objects reachable through several pointers,
+// interfaces, maps or channels. Any policy but FirstOwner gives per-field sizes that don't
+// depend on which field happens to be visited first. Sizes of nodes then no longer add up to
+// the sizes of their children; totals are unaffected.
+func WithSharedPolicy(p SharedPolicy) Option {
+	return func(c *config) {
+		c.sharedPolicy = p
+	}
+}
+
+// attributeShared moves the bytes of shared objects below root according to the policy and
+// returns the bytes set aside by SeparateShared and ListShared, along with the objects listed
+func attributeShared(root *Node, p SharedPolicy) (uint64, []SharedObject) {
+	if root == nil || p == FirstOwner {
+		return 0, nil
+	}
+
+	parents := make(map[*Node]*Node)
+	owners := make(map[uintptr]*Node)
+	referrers := make(map[*Node][]*Node)
+	var order []*Node
+	stack := []*Node{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		order = append(order, n)
+		if n.Addr != 0 && !n.Shared {
+			if _, ok := owners[n.Addr]; !ok {
+				owners[n.Addr] = n
+			}
+		}
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			parents[n.Children[i]] = n
+			stack = append(stack, n.Children[i])
+		}
+	}
+	for _, n := range order {
+		if owner, ok := owners[n.Addr]; ok && n.Shared {
+			referrers[owner] = append(referrers[owner], n)
+		}
+	}
+
+	add := func(n *Node, delta int64) {
+		for ; n != nil; n = parents[n] {
+			n.Size = uint64(int64(n.Size) + delta)
+		}
+	}
+
+	// Owners below others go first, so the bytes of an object shared within a shared object
+	// are moved once
+	var separated uint64
+	var objects []SharedObject
+	for i := len(order) - 1; i >= 0; i-- {
+		owner := order[i]
+		refs := referrers[owner]
+		if len(refs) == 0 || owner.Size <= refs[0].Size {
+			continue
+		}
+		// The owner's header is as large as that of the references counting nothing else
+		object := owner.Size - refs[0].Size
+		switch p {
+		case SplitShared:
+			share := object / uint64(len(refs)+1)
+			add(owner, -int64(share)*int64(len(refs)))
+			for _, ref := range refs {
+				add(ref, int64(share))
+			}
+		case SeparateShared, ListShared:
+			add(owner, -int64(object))
+			separated += object
+		}
+		if p == ListShared {
+			o := SharedObject{Type: owner.Type, Path: owner.Path, Size: object,
+				References: len(refs) + 1, Referrers: make(map[string]int)}
+			if parent := parents[owner]; parent != nil {
+				o.Referrers[parent.Type]++
+			}
+			for _, ref := range refs {
+				if parent := parents[ref]; parent != nil {
+					o.Referrers[parent.Type]++
+				}
+			}
+			objects = append(objects, o)
+		}
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].Size > objects[j].Size
+	})
+	return separated, objects
+}
@@ -0,0 +1,111 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSharedPolicy(t *testing.T) {
+	Debug = false
+
+	type blob struct{ Data [1000]byte }
+	type holder struct{ Blob *blob }
+	shared := &blob{}
+	v := struct{ A, B, C holder }{holder{shared}, holder{shared}, holder{&blob{}}}
+	total := GetTotalSize(v)
+
+	t.Run("FirstOwner", func(t *testing.T) {
+		r := GetReport(v)
+		a, b := r.Root.Children[0], r.Root.Children[1]
+		fmt.Printf("First owner: A %d, B %d\n", a.Size, b.Size)
+		if a.Size < 1000 || b.Size >= 1000 || r.SharedBytes != 0 {
+			t.Errorf("Expected A to hold the blob, got A %d, B %d", a.Size, b.Size)
+		}
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		r := GetReport(v, WithSharedPolicy(SplitShared))
+		a, b, c := r.Root.Children[0], r.Root.Children[1], r.Root.Children[2]
+		fmt.Printf("Split: A %d, B %d, C %d\n", a.Size, b.Size, c.Size)
+		if a.Size != b.Size || a.Size < 500 || a.Size >= 1000 {
+			t.Errorf("Expected A and B to hold half of the blob each, got A %d, B %d", a.Size, b.Size)
+		}
+		if c.Size < 1000 {
+			t.Errorf("Expected C to keep its own blob, got %d", c.Size)
+		}
+		if r.Total() != total {
+			t.Errorf("Expected total %d, got %d", total, r.Total())
+		}
+	})
+
+	t.Run("Separate", func(t *testing.T) {
+		r := GetReport(v, WithSharedPolicy(SeparateShared))
+		a, b := r.Root.Children[0], r.Root.Children[1]
+		fmt.Printf("Separate: A %d, B %d, shared %d\n", a.Size, b.Size, r.SharedBytes)
+		if a.Size != b.Size || a.Size >= 1000 || r.SharedBytes < 1000 {
+			t.Errorf("Expected the blob set aside, got A %d, B %d, shared %d", a.Size, b.Size, r.SharedBytes)
+		}
+		if r.Total() != total {
+			t.Errorf("Expected total %d, got %d", total, r.Total())
+		}
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		// The inner blob is shared within the shared outer object and set aside once
+		type outer struct{ X, Y *blob }
+		inner := &blob{}
+		o := &outer{inner, inner}
+		v := []*outer{o, o}
+		total := GetTotalSize(v)
+		r := GetReport(v, WithSharedPolicy(SeparateShared))
+		if r.Total() != total || r.SharedBytes >= total {
+			t.Errorf("Expected total %d with part of it shared, got %d and %d shared", total, r.Total(), r.SharedBytes)
+		}
+		for _, policy := range []SharedPolicy{SplitShared, SeparateShared} {
+			if r := GetReport(v, WithSharedPolicy(policy)); r.Total() != total {
+				t.Errorf("Expected total %d, got %d", total, r.Total())
+			}
+		}
+	})
+}
+
+func TestListShared(t *testing.T) {
+	Debug = false
+
+	type schema struct{ Fields [500]byte }
+	type entry struct{ Schema *schema }
+	type index struct{ Schema *schema }
+	s := &schema{}
+	v := struct {
+		Entries []entry
+		Index   index
+	}{[]entry{{s}, {s}, {s}}, index{s}}
+
+	r := GetReport(v, WithSharedPolicy(ListShared))
+	fmt.Printf("Shared objects: %v\n", r.SharedObjects)
+	if len(r.SharedObjects) != 1 {
+		t.Fatalf("Expected one shared object, got %d", len(r.SharedObjects))
+	}
+	o := r.SharedObjects[0]
+	if o.References != 4 || o.Size < 500 || r.SharedBytes != o.Size {
+		t.Errorf("Expected 4 references to the schema, got %d to %d bytes (%d shared)", o.References, o.Size, r.SharedBytes)
+	}
+	var entries, indexes int
+	for typ, n := range o.Referrers {
+		switch typ {
+		case fmt.Sprintf("%T", entry{}):
+			entries = n
+		case fmt.Sprintf("%T", index{}):
+			indexes = n
+		}
+	}
+	if entries != 3 || indexes != 1 {
+		t.Errorf("Expected 3 entry and 1 index referrers, got %v", o.Referrers)
+	}
+	if r.Total() != GetTotalSize(v) {
+		t.Errorf("Expected total %d, got %d", GetTotalSize(v), r.Total())
+	}
+	if GetReport(v, WithSharedPolicy(SeparateShared)).SharedObjects != nil {
+		t.Error("Expected no list with SeparateShared")
+	}
+}
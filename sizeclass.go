@@ -0,0 +1,80 @@
+// sizeclass.go
+package memsize
+
+import "sort"
+
+// sizeClasses are the object sizes of the runtime's small-object allocator, as generated by
+// its mksizeclasses.go, for DefaultModel
+var sizeClasses = [...]uint64{
+	8, 16, 24, 32, 48, 64, 80, 96, 112, 128, 144, 160, 176, 192, 208, 224, 240, 256,
+	288, 320, 352, 384, 416, 448, 480, 512, 576, 640, 704, 768, 896, 1024, 1152, 1280,
+	1408, 1536, 1792, 2048, 2304, 2688, 3072, 3200, 3456, 4096, 4864, 5376, 6144, 6528,
+	6784, 6912, 8192, 9472, 9728, 10240, 10880, 12288, 13568, 14336, 16384, 18432, 19072,
+	20480, 21760, 24576, 27264, 28672, 32768,
+}
+
+// sizeClassPages is the number of pages of a span for each of sizeClasses
+var sizeClassPages = [len(sizeClasses)]uint64{
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	2, 1, 2, 1, 2, 1, 3, 2, 3, 1, 3, 2, 3, 4,
+	5, 6, 1, 7, 6, 5, 4, 3, 5, 7, 2, 9, 7,
+	5, 8, 3, 10, 7, 4,
+}
+
+// roundAlloc returns the number of bytes the runtime reserves for an allocation of size bytes
+func (m *Model) roundAlloc(size uint64) uint64 {
+	if size == 0 {
+		return 0
+	}
+	if size > m.SizeClasses[len(m.SizeClasses)-1] {
+		return (size + m.PageSize - 1) / m.PageSize * m.PageSize
+	}
+	return m.SizeClasses[m.sizeClass(size)]
+}
+
+// sizeClass returns the index of the smallest size class holding size bytes
+func (m *Model) sizeClass(size uint64) int {
+	return sort.Search(len(m.SizeClasses), func(i int) bool { return m.SizeClasses[i] >= size })
+}
+
+// gcOverhead estimates the bookkeeping an allocation of size bytes costs the runtime besides
+// its size class: its share of the unusable tail and the mspan of its span, and the heap
+// bitmap when the object contains pointers. Shares are rounded up, so tiny objects cost at
+// least a byte.
+func (m *Model) gcOverhead(size uint64, scan bool, word uint64) uint64 {
+	rounded := m.roundAlloc(size)
+	if rounded == 0 {
+		return 0
+	}
+
+	// The heap bitmap has a bit per word
+	var bitmap uint64
+	if scan {
+		bitmap = (rounded + 8*word - 1) / (8 * word)
+	}
+	if size > m.SizeClasses[len(m.SizeClasses)-1] {
+		// Large objects get a span of their own
+		return m.SpanOverhead + bitmap
+	}
+
+	spanBytes := m.SizeClassPages[m.sizeClass(size)] * m.PageSize
+	objects := spanBytes / rounded
+	tail := spanBytes - objects*rounded
+	return (tail+m.SpanOverhead+objects-1)/objects + bitmap
+}
+
+// slack returns the bytes an allocation of size bytes costs beyond its size: its size-class
+// rounding with WithSizeClasses, plus the runtime's bookkeeping with WithGCOverhead. Scan is
+// set for allocations containing pointers.
+func (c *config) slack(size uint64, scan bool) uint64 {
+	var slack uint64
+	l := c.layout()
+	if c.sizeClasses {
+		slack = l.overhead().roundAlloc(size) - size
+	}
+	if c.gcOverhead {
+		slack += l.overhead().gcOverhead(size, scan, l.word())
+	}
+	return slack
+}
@@ -0,0 +1,114 @@
+package memsize
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSizeClasses(t *testing.T) {
+	Debug = false
+
+	t.Run("Rounding", func(t *testing.T) {
+		for size, expected := range map[uint64]uint64{0: 0, 1: 8, 8: 8, 33: 48, 32768: 32768, 32769: 40960} {
+			if rounded := defaultModel.roundAlloc(size); rounded != expected {
+				t.Errorf("Expected %d bytes to round to %d, got %d", size, expected, rounded)
+			}
+		}
+	})
+
+	buf := [33]byte{}
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected uint64
+	}{
+		{"String", strings.Repeat("x", 33), 16 + 48},
+		{"Slice", make([]byte, 33), 24 + 48},
+		{"Pointer", &buf, 8 + 48},
+		// A bucket of 8 int32 keys and int8 values takes 56 bytes, rounded to 64
+		{"Map", map[int32]int8{1: 1}, 8 + 48 + 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if size := GetTotalSize(tt.value, WithSizeClasses()); size != tt.expected {
+				t.Errorf("Expected %d bytes, got %d", tt.expected, size)
+			}
+			if report := GetReport(tt.value, WithSizeClasses()); report.Total() != tt.expected {
+				t.Errorf("Expected the report total %d, got %d", tt.expected, report.Total())
+			}
+		})
+	}
+
+	t.Run("Matches HeapAlloc", func(t *testing.T) {
+		type record struct {
+			Name string
+			Tags []string
+			Next *record
+		}
+
+		var before, after runtime.MemStats
+		// Pooled scratch memory of earlier measurements survives one collection
+		runtime.GC()
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		var head *record
+		for i := 0; i < 10000; i++ {
+			head = &record{Name: fmt.Sprintf("record-%06d-with-a-longer-name", i), Tags: make([]string, 3), Next: head}
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+
+		// The root pointer lives on the stack, everything else was allocated above
+		size := GetTotalSize(head, WithSizeClasses()) - 8
+		heap := after.HeapAlloc - before.HeapAlloc
+		fmt.Printf("Size classes: estimated %d bytes, HeapAlloc grew by %d\n", size, heap)
+		if size < heap*95/100 || size > heap*105/100 {
+			t.Errorf("Expected %d bytes to be within 5%% of the HeapAlloc delta %d", size, heap)
+		}
+		runtime.KeepAlive(head)
+	})
+}
+
+func TestGCOverhead(t *testing.T) {
+	Debug = false
+
+	t.Run("Per Object", func(t *testing.T) {
+		tests := []struct {
+			size     uint64
+			scan     bool
+			expected uint64
+		}{
+			{0, true, 0},
+			// 1024 objects share a span with no tail
+			{8, false, 1},
+			// 170 objects share a span with a 32 byte tail, plus a bitmap byte
+			{48, true, 2 + 1},
+			{40000, false, defaultModel.SpanOverhead},
+			{40000, true, defaultModel.SpanOverhead + 40960/64},
+		}
+		for _, tt := range tests {
+			if overhead := defaultModel.gcOverhead(tt.size, tt.scan, 8); overhead != tt.expected {
+				t.Errorf("Expected %d bytes of overhead for %d bytes (scan %v), got %d",
+					tt.expected, tt.size, tt.scan, overhead)
+			}
+		}
+	})
+
+	t.Run("Total", func(t *testing.T) {
+		people := make([]*Person, 1000)
+		for i := range people {
+			people[i] = &Person{Name: fmt.Sprintf("person-%d", i), Data: map[string]interface{}{"id": i}}
+		}
+		classes := GetTotalSize(people, WithSizeClasses())
+		overhead := GetTotalSize(people, WithGCOverhead())
+		fmt.Printf("GC overhead: %d bytes with size classes, %d with overhead\n", classes, overhead)
+		if overhead <= classes || overhead > classes*11/10 {
+			t.Errorf("Expected the overhead to add up to 10%% to %d, got %d", classes, overhead)
+		}
+		if report := GetReport(people, WithGCOverhead()); report.Total() != overhead {
+			t.Errorf("Expected the report total %d, got %d", overhead, report.Total())
+		}
+	})
+}
@@ -0,0 +1,138 @@
+// sizes.go
+package memsize
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// SizeModel selects how headers and values stored inline are sized
+type SizeModel int
+
+const (
+	// LegacySizes is the default and matches earlier releases: pointer, slice, string and
+	// interface headers as well as channels and funcs are all counted as 16 bytes, structs
+	// and arrays as 16 bytes plus their fields or elements, and slice elements are counted
+	// both as part of the backing array and on their own
+	LegacySizes SizeModel = iota
+
+	// ExactSizes counts every byte once using the memory layout of the types: headers take
+	// their actual size (8 for pointers and maps, 16 for strings and interfaces, 24 for
+	// slices), structs include padding, arrays and maps and channels are traversed or
+	// estimated from the runtime layout, and maps and channels are counted once per address.
+	// Interface values are boxed unless they are pointer-shaped, and boxes shared by copies of
+	// an interface are counted once. Values without indirections, such as structs and arrays of
+	// numbers, are sized exactly as unsafe.Sizeof, including padding and zero-sized fields.
+	ExactSizes
+)
+
+// headerSize is the shallow size of a value of type t that doesn't own any memory
+func headerSize(t reflect.Type, l layout) uint64 {
+	if l.model == ExactSizes {
+		return l.sizeof(t)
+	}
+	return l.header()
+}
+
+// exactMapSizes estimates the header, bucket and folded entry sizes of a non-nil map with the
+// bucket layout of the runtime and the number of buckets it allocated. Bucket slots of keys and values that are visited are accounted
+// to those values, so buckets only holds the overhead.
+func exactMapSizes(v reflect.Value, cfg *config) (header, buckets, inline uint64) {
+	l := cfg.layout()
+	t := v.Type()
+	n := uint64(v.Len())
+	header = l.sizeof(t)
+
+	keySlot, keyInline := mapSlot(t.Key(), l)
+	valSlot, valInline := mapSlot(t.Elem(), l)
+
+	numBuckets, _ := mapBuckets(v, l)
+	bucketSize := mapBucketSize(keySlot, valSlot, l)
+	buckets = l.hmapSize() + numBuckets*bucketSize
+	scan := hasPointers(t.Key()) || hasPointers(t.Elem())
+	buckets += cfg.slack(l.hmapSize(), true) + cfg.slack(numBuckets*bucketSize, scan)
+	if keyInline == 0 {
+		buckets += n * cfg.slack(l.sizeof(t.Key()), hasPointers(t.Key()))
+	}
+	if valInline == 0 {
+		buckets += n * cfg.slack(l.sizeof(t.Elem()), hasPointers(t.Elem()))
+	}
+
+	if p := planFor(t.Key(), l); p.constant {
+		inline += n * p.size
+	}
+	if p := planFor(t.Elem(), l); p.constant {
+		inline += n * p.size
+	}
+	buckets -= n * (keyInline + valInline)
+	return header, buckets, inline
+}
+
+// mapSlot returns the size of a bucket slot for values of type t and how much of a value's
+// own size is stored in it; large values are allocated separately and referenced by pointer
+func mapSlot(t reflect.Type, l layout) (slot, inline uint64) {
+	if size := l.sizeof(t); size <= l.overhead().MapMaxInline {
+		return size, size
+	}
+	return l.word(), 0
+}
+
+// directIface reports whether values of type t are stored in the data word of an interface
+// instead of being boxed in a separate allocation
+func directIface(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Struct:
+		return t.NumField() == 1 && directIface(t.Field(0).Type)
+	case reflect.Array:
+		return t.Len() == 1 && directIface(t.Elem())
+	}
+	return false
+}
+
+// boxAddr returns the address of the allocation boxing the value of a non-nil interface whose
+// dynamic type is not pointer-shaped
+func boxAddr(v reflect.Value) uintptr {
+	var iface unsafe.Pointer
+	if v.CanAddr() {
+		iface = unsafe.Pointer(v.UnsafeAddr())
+	} else {
+		// Map entries and fields of values held by value are copies that can't be addressed,
+		// and can't be copied again when reached through unexported fields. reflect.Value
+		// holds interfaces indirectly, so its data pointer refers to the interface words.
+		iface = (*reflectValue)(unsafe.Pointer(&v)).ptr
+	}
+	return uintptr((*[2]unsafe.Pointer)(iface)[1])
+}
+
+// reflectValue mirrors the layout of reflect.Value
+type reflectValue struct {
+	typ  unsafe.Pointer
+	ptr  unsafe.Pointer
+	flag uintptr
+}
+
+// hasPointers reports whether values of type t contain pointers the garbage collector has to scan
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.Interface, reflect.Slice,
+		reflect.String, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// zeroSized reports whether values of t take no memory, like struct{} and [0]T, on any
+// architecture. All allocations of zero size share the runtime's zerobase.
+func zeroSized(t reflect.Type) bool {
+	return t.Size() == 0
+}
@@ -0,0 +1,294 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestExactSizes(t *testing.T) {
+	Debug = false
+
+	type padded struct {
+		A int8
+		B int64
+	}
+	type named struct {
+		ID   int8
+		Name string
+	}
+	type shared struct {
+		A, B map[string]int
+	}
+
+	x := int64(1)
+	counts := map[string]int{"a": 1}
+	// Copies of an interface share its box, converting a value twice boxes it twice
+	box := interface{}([4]int64{x, 2, 3, 4})
+	first, second := interface{}([4]int64{x, 2, 3, 4}), interface{}([4]int64{x, 2, 3, 4})
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected uint64
+	}{
+		{"Int", 42, 8},
+		{"String", "hello", 16 + 5},
+		{"Slice", []int64{1, 2, 3}, 24 + 3*8},
+		{"Spare Capacity", make([]int64, 3, 5), 24 + 5*8},
+		{"String Slice", []string{"a", "bb"}, 24 + 2*16 + 3},
+		{"Nil Slice", []int(nil), 24},
+		{"Nil Map", map[string]int(nil), 8},
+		{"Pointer", &x, 8 + 8},
+		{"Padding", padded{}, 16},
+		{"Struct Fields", named{Name: "x"}, 24 + 1},
+		{"Array", [4]string{"a", "b", "c", "d"}, 4*16 + 4},
+		{"Boxed Interface", []interface{}{int64(1)}, 24 + 16 + 8},
+		{"Direct Interface", []interface{}{&x}, 24 + 16 + 8},
+		{"Shared Box", []interface{}{box, box}, 24 + 2*16 + 32},
+		{"Separate Boxes", []interface{}{first, second}, 24 + 2*16 + 2*32},
+		{"Shared Box In Struct", struct{ A, B interface{} }{box, box}, 2*16 + 32},
+		{"Map", map[int64]int64{1: 1, 2: 2, 3: 3}, 8 + 48 + 8*(1+8+8) + 8},
+		{"Shared Map", shared{A: counts, B: counts}, 2*8 + 48 + 8*(1+16+8) + 8 + 1},
+		{"Channel", make(chan int64, 10), 8 + 96 + 10*8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := GetTotalSize(tt.value, WithSizeModel(ExactSizes))
+			if size != tt.expected {
+				t.Errorf("Expected %d bytes, got %d", tt.expected, size)
+			}
+			if report := GetReport(tt.value, WithSizeModel(ExactSizes)); report.Total() != size {
+				t.Errorf("Expected the report total %d to match %d", report.Total(), size)
+			}
+		})
+	}
+
+	t.Run("Fast Path Matches Full Walk", func(t *testing.T) {
+		people := make([]*Person, 3000)
+		for i := range people {
+			people[i] = &Person{Name: "person", Data: map[string]interface{}{"age": i, "pad": padded{}}}
+			if i > 0 {
+				people[i].Friends = []*Person{people[i-1]}
+			}
+		}
+		values := []interface{}{people, &labeledPoint{Label: "a", Tags: []string{"x"}}, [2][]int{{1}, {2, 3}}}
+		for _, v := range values {
+			fast := GetTotalSize(v, WithSizeModel(ExactSizes))
+			if full := GetReport(v, WithSizeModel(ExactSizes)).Total(); fast != full {
+				t.Errorf("%T: fast path returned %d, full walk %d", v, fast, full)
+			}
+			if parallel := GetTotalSize(v, WithSizeModel(ExactSizes), WithParallelism(4)); parallel != fast {
+				t.Errorf("%T: parallel walk returned %d instead of %d", v, parallel, fast)
+			}
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		if _, err := GetTotalSizeE([4]complex128{}, WithSizeModel(ExactSizes), WithStrict()); err != nil {
+			t.Errorf("Expected arrays and complex numbers to be exact, got %v", err)
+		}
+		_, err := GetTotalSizeE(make(chan *int, 1), WithSizeModel(ExactSizes), WithStrict())
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected buffered pointers to be reported, got %v", err)
+		}
+	})
+}
+
+func TestExactPureValues(t *testing.T) {
+	Debug = false
+
+	type empty struct{}
+	type trailing struct {
+		A int64
+		B bool
+		C empty
+	}
+	type nested struct {
+		A int16
+		B struct {
+			C int8
+			D [2]int32
+		}
+		E bool
+	}
+	type mixed struct {
+		A [3]trailing
+		B complex64
+		C uintptr
+		D float32
+		E [0]int64
+		F empty
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected uintptr
+	}{
+		{"Int8", int8(1), unsafe.Sizeof(int8(1))},
+		{"Complex128", complex128(1), unsafe.Sizeof(complex128(1))},
+		{"Empty Struct", empty{}, unsafe.Sizeof(empty{})},
+		{"Empty Array", [0]byte{}, unsafe.Sizeof([0]byte{})},
+		{"Trailing Zero-Sized Field", trailing{}, unsafe.Sizeof(trailing{})},
+		{"Nested", nested{}, unsafe.Sizeof(nested{})},
+		{"Mixed", mixed{}, unsafe.Sizeof(mixed{})},
+		{"Array of Structs", [5]nested{}, unsafe.Sizeof([5]nested{})},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			size := GetTotalSize(tc.value, WithSizeModel(ExactSizes))
+			report := GetReport(tc.value, WithSizeModel(ExactSizes)).Total()
+			if size != uint64(tc.expected) || report != uint64(tc.expected) {
+				t.Errorf("Expected %d, got %d and %d in a report", tc.expected, size, report)
+			}
+		})
+	}
+
+	// Structs of random layouts built from the pure-value kinds
+	t.Run("Random", func(t *testing.T) {
+		kinds := []reflect.Type{
+			reflect.TypeOf(false), reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)),
+			reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)), reflect.TypeOf(0),
+			reflect.TypeOf(uintptr(0)), reflect.TypeOf(float32(0)), reflect.TypeOf(complex128(0)),
+			reflect.TypeOf(empty{}), reflect.TypeOf([0]int64{}), reflect.TypeOf([3]int8{}),
+		}
+		rng := rand.New(rand.NewSource(1))
+		var random func(depth int) reflect.Type
+		random = func(depth int) reflect.Type {
+			fields := make([]reflect.StructField, rng.Intn(6))
+			for i := range fields {
+				typ := kinds[rng.Intn(len(kinds))]
+				if depth < 2 && rng.Intn(4) == 0 {
+					typ = random(depth + 1)
+				}
+				if rng.Intn(5) == 0 {
+					typ = reflect.ArrayOf(rng.Intn(4), typ)
+				}
+				fields[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: typ}
+			}
+			return reflect.StructOf(fields)
+		}
+		for i := 0; i < 200; i++ {
+			typ := random(0)
+			v := reflect.New(typ).Elem()
+			if size := GetTotalSizeValue(v, WithSizeModel(ExactSizes)); size != uint64(typ.Size()) {
+				t.Fatalf("Expected %d for %s, got %d", typ.Size(), typ, size)
+			}
+		}
+	})
+}
+
+func TestZeroSized(t *testing.T) {
+	Debug = false
+
+	type empty struct{}
+	for name, m := range map[string]SizeModel{"Legacy": LegacySizes, "Exact": ExactSizes} {
+		t.Run(name, func(t *testing.T) {
+			size := func(v interface{}) uint64 { return GetTotalSize(v, WithSizeModel(m)) }
+			for _, v := range []interface{}{empty{}, [0]byte{}, [4]empty{}, struct{ A, B empty }{}} {
+				if got := size(v); got != 0 {
+					t.Errorf("Expected %T to take no memory, got %d", v, got)
+				}
+			}
+
+			// Elements and pointees of zero size add nothing to the headers
+			if got, expected := size(make([]empty, 1000)), size([]empty(nil)); got != expected {
+				t.Errorf("Expected a slice of empty structs to take %d, got %d", expected, got)
+			}
+			if got, expected := size(new(empty)), size((*empty)(nil)); got != expected {
+				t.Errorf("Expected a pointer to an empty struct to take %d, got %d", expected, got)
+			}
+			if got, expected := size(struct {
+				A int64
+				B empty
+			}{}), size(struct{ A int64 }{}); m == LegacySizes && got != expected {
+				t.Errorf("Expected an empty field to add nothing to %d, got %d", expected, got)
+			}
+
+			// Sets keep no values, so they are smaller than maps of booleans
+			set := map[string]struct{}{"a": {}, "b": {}, "c": {}}
+			flags := map[string]bool{"a": true, "b": true, "c": true}
+			fmt.Printf("%s: set %d, flags %d\n", name, size(set), size(flags))
+			if size(set) >= size(flags) {
+				t.Errorf("Expected the set to be smaller than %d, got %d", size(flags), size(set))
+			}
+		})
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		// Pointers to zero-sized values share the runtime's zerobase but nothing is shared
+		r := GetReport([]*empty{new(empty), new(empty)}, WithSharedPolicy(ListShared))
+		for _, n := range r.Root.Children {
+			if n.Shared || len(n.Children) != 0 {
+				t.Errorf("Expected %s not to be followed, got shared %v and %d children", n.Path, n.Shared, len(n.Children))
+			}
+		}
+		if len(r.SharedObjects) != 0 {
+			t.Errorf("Expected no shared objects, got %v", r.SharedObjects)
+		}
+		r = GetReport(map[string]struct{}{"a": {}})
+		r.Walk(func(n *Node) bool {
+			if n.Type == "struct {}" && n.Size != 0 {
+				t.Errorf("Expected %s to take no memory, got %d", n.Path, n.Size)
+			}
+			return true
+		})
+	})
+}
+
+func TestHeaders(t *testing.T) {
+	Debug = false
+
+	type holder struct {
+		Items []int64
+		Index map[string]int64
+	}
+	populated := holder{Items: []int64{1, 2}, Index: map[string]int64{"a": 1}}
+
+	// Headers count the same whether nil, empty or populated; only what they refer to varies
+	tests := []struct {
+		name           string
+		model          SizeModel
+		slice, mapSize uint64
+	}{
+		{"Legacy", LegacySizes, 16, 16},
+		{"Exact", ExactSizes, 24, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithSizeModel(tt.model)}
+			values := map[string]holder{
+				"Nil":       {},
+				"Empty":     {Items: []int64{}, Index: map[string]int64{}},
+				"Populated": populated,
+			}
+			for name, v := range values {
+				report := GetReport(v, opts...)
+				items, index := report.Root.Children[0], report.Root.Children[1]
+				fmt.Printf("%s %s: items %d, index %d\n", tt.name, name, items.Shallow, index.Shallow)
+				if items.Shallow < tt.slice || index.Shallow < tt.mapSize {
+					t.Errorf("%s: expected the headers of %d and %d bytes in the fields, got %d and %d",
+						name, tt.slice, tt.mapSize, items.Shallow, index.Shallow)
+				}
+				if report.Root.Shallow != GetReport(holder{}, opts...).Root.Shallow {
+					t.Errorf("%s: expected the struct's own size not to depend on its fields, got %d",
+						name, report.Root.Shallow)
+				}
+			}
+
+			nilSize := GetTotalSize(holder{}, opts...)
+			if nilSize != GetReport(holder{}, opts...).Root.Shallow+tt.slice+tt.mapSize {
+				t.Errorf("Expected nil fields to count their headers, got %d", nilSize)
+			}
+			if size := GetTotalSize([]int64(nil), opts...); size != tt.slice {
+				t.Errorf("Expected a nil slice to count its header of %d bytes, got %d", tt.slice, size)
+			}
+			if size := GetTotalSize(map[string]int64(nil), opts...); size != tt.mapSize {
+				t.Errorf("Expected a nil map to count its header of %d bytes, got %d", tt.mapSize, size)
+			}
+		})
+	}
+}
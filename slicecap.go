@@ -0,0 +1,51 @@
+// slicecap.go
+package memsize
+
+import (
+	"reflect"
+	"sort"
+)
+
+// unused returns the bytes of a slice's backing array beyond its length, unless the array is
+// mapped memory
+func (w *walker) unused(f *frame) uint64 {
+	v := f.v
+	if v.Kind() != reflect.Slice || v.IsNil() || f.mapped != 0 {
+		return 0
+	}
+	return uint64(v.Cap()-v.Len()) * w.cfg.layout().sizeof(v.Type().Elem())
+}
+
+// UnusedSliceBytes returns the total capacity beyond their length of the slices in the report,
+// the memory over-provisioned slices hold without using it, see Node.Unused
+func (r *Report) UnusedSliceBytes() uint64 {
+	var total uint64
+	r.Walk(func(n *Node) bool {
+		total += n.Unused
+		return true
+	})
+	return total
+}
+
+// OverprovisionedSlices returns up to n slices of the report with unused capacity, largest
+// first; these are the candidates for clipping or allocating with the right capacity
+func (r *Report) OverprovisionedSlices(n int) []*Node {
+	if n <= 0 {
+		return nil
+	}
+
+	var slices []*Node
+	r.Walk(func(node *Node) bool {
+		if node.Unused > 0 {
+			slices = append(slices, node)
+		}
+		return true
+	})
+	sort.SliceStable(slices, func(i, j int) bool {
+		return slices[i].Unused > slices[j].Unused
+	})
+	if len(slices) > n {
+		slices = slices[:n]
+	}
+	return slices
+}
@@ -0,0 +1,46 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSliceCapacity(t *testing.T) {
+	Debug = false
+
+	type buffers struct {
+		Small  []int64
+		Large  []int64
+		Full   []int64
+		Nested [][]byte
+	}
+	v := &buffers{
+		Small:  make([]int64, 1, 4),
+		Large:  make([]int64, 10, 1000),
+		Full:   make([]int64, 8),
+		Nested: [][]byte{make([]byte, 0, 100)},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		model SizeModel
+	}{{"Legacy", LegacySizes}, {"Exact", ExactSizes}} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := GetReport(v, WithSizeModel(tc.model))
+			total := r.UnusedSliceBytes()
+			fmt.Printf("Unused slice bytes: %d\n", total)
+			if expected := uint64(3*8 + 990*8 + 100); total != expected {
+				t.Errorf("Expected %d, got %d", expected, total)
+			}
+
+			top := r.OverprovisionedSlices(2)
+			if len(top) != 2 || top[0].Path != "root.ptr.Large" || top[0].Unused != 990*8 ||
+				top[1].Path != "root.ptr.Nested[0]" {
+				t.Errorf("Expected Large and Nested[0] first, got %v", top)
+			}
+			if all := r.OverprovisionedSlices(10); len(all) != 3 {
+				t.Errorf("Expected 3 over-provisioned slices, got %d", len(all))
+			}
+		})
+	}
+}
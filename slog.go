@@ -0,0 +1,37 @@
+//go:build go1.21
+
+// slog.go
+package memsize
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+)
+
+// WithSlogLogger logs every value of a single measurement to logger as a debug record with its
+// path, kind, type, shallow size and total size. Records are emitted once a value's children
+// were sized, so children are logged before their parents.
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.debugRecord = func(path string, v reflect.Value, shallow, size uint64) {
+			ctx := c.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if !logger.Enabled(ctx, slog.LevelDebug) {
+				return
+			}
+			typ := "<nil>"
+			if v.IsValid() {
+				typ = v.Type().String()
+			}
+			logger.LogAttrs(ctx, slog.LevelDebug, "memsize: sized value",
+				slog.String("path", path),
+				slog.String("kind", v.Kind().String()),
+				slog.String("type", typ),
+				slog.Uint64("shallow", shallow),
+				slog.Uint64("size", size))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+//go:build go1.21
+
+package memsize
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestWithSlogLogger(t *testing.T) {
+	Debug = false
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	size := GetTotalSize(&Person{Name: "John"}, WithSlogLogger(logger))
+
+	type record struct {
+		Path    string `json:"path"`
+		Kind    string `json:"kind"`
+		Type    string `json:"type"`
+		Shallow uint64 `json:"shallow"`
+		Size    uint64 `json:"size"`
+	}
+	var records []record
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+
+	// Children are logged first, so the root comes last
+	if len(records) == 0 {
+		t.Fatal("Expected records to be logged")
+	}
+	root := records[len(records)-1]
+	if root.Path != "root" || root.Kind != "ptr" || root.Type != "*memsize.Person" || root.Size != size {
+		t.Errorf("Expected the root pointer of %d bytes last, got %+v", size, root)
+	}
+	found := false
+	for _, r := range records {
+		found = found || r.Path == "root.ptr.Name" && r.Kind == "string" && r.Size == 16+4
+	}
+	if !found {
+		t.Errorf("Expected a record for root.ptr.Name, got %+v", records)
+	}
+
+	t.Run("Disabled Level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, nil))
+		GetTotalSize(&Person{Name: "John"}, WithSlogLogger(logger))
+		if buf.Len() != 0 {
+			t.Errorf("Expected no records above the debug level, got %q", buf.String())
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// snapshot.go
+package memsize
+
+import "sort"
+
+// Snapshot is a frozen measurement of a value, indexed once so that repeated questions about
+// it, such as the size of a path, the top contributors or the sizes per type, are answered
+// without traversing the value again. A Snapshot is safe for concurrent use.
+type Snapshot struct {
+	report *Report
+	// paths sums the sizes of the nodes sharing a path, as Report.Diff compares them
+	paths map[string]pathSize
+	// top lists all nodes but the root, largest first
+	top    []Contributor
+	byType map[string]TypeStats
+}
+
+// TakeSnapshot measures v once and returns a Snapshot of the result
+func TakeSnapshot(v interface{}, opts ...Option) *Snapshot {
+	return NewSnapshot(GetReport(v, opts...))
+}
+
+// NewSnapshot indexes a report, e.g. one read back by ReadBinaryReport. The report must not be
+// modified afterwards.
+func NewSnapshot(r *Report) *Snapshot {
+	s := &Snapshot{report: r, paths: r.pathSizes(), byType: make(map[string]TypeStats)}
+	r.Walk(func(n *Node) bool {
+		if n != r.Root {
+			s.top = append(s.top, Contributor{Path: n.Path, Type: n.Type, Size: n.Size})
+		}
+		stats := s.byType[n.Type]
+		stats.Count++
+		stats.Bytes += n.Shallow
+		s.byType[n.Type] = stats
+		return true
+	})
+	sort.Slice(s.top, func(i, j int) bool {
+		if s.top[i].Size != s.top[j].Size {
+			return s.top[i].Size > s.top[j].Size
+		}
+		return s.top[i].Path < s.top[j].Path
+	})
+	return s
+}
+
+// Report returns the report the snapshot was taken from, which must not be modified
+func (s *Snapshot) Report() *Report {
+	return s.report
+}
+
+// Total returns the total size of the measured value
+func (s *Snapshot) Total() uint64 {
+	return s.report.Total()
+}
+
+// SizeOfPath returns the size of the value at path, as in Node.Path, including its children.
+// Sizes of nodes sharing the path, such as map entries, are summed. It reports false if no
+// node has the path.
+func (s *Snapshot) SizeOfPath(path string) (uint64, bool) {
+	ps, ok := s.paths[path]
+	return ps.size, ok
+}
+
+// TopN returns the n nodes with the largest total size like Report.TopContributors
+func (s *Snapshot) TopN(n int) []Contributor {
+	if n <= 0 {
+		return nil
+	}
+	if n > len(s.top) {
+		n = len(s.top)
+	}
+	return append([]Contributor(nil), s.top[:n]...)
+}
+
+// ByType returns the shallow bytes and number of nodes per type, like GetSizeByType
+func (s *Snapshot) ByType() map[string]TypeStats {
+	byType := make(map[string]TypeStats, len(s.byType))
+	for name, stats := range s.byType {
+		byType[name] = stats
+	}
+	return byType
+}
+
+// Diff compares s, the earlier snapshot, with other, a later one of the same value, like
+// Report.Diff
+func (s *Snapshot) Diff(other *Snapshot) *DiffReport {
+	return diffPaths(s.Total(), other.Total(), s.paths, other.paths)
+}
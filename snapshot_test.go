@@ -0,0 +1,63 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	Debug = false
+
+	type server struct {
+		Cache map[string][]byte
+		Names []string
+	}
+	v := &server{
+		Cache: map[string][]byte{"a": make([]byte, 100), "b": make([]byte, 200)},
+		Names: []string{"x", "y"},
+	}
+	s := TakeSnapshot(v)
+	r := GetReport(v)
+
+	t.Run("SizeOfPath", func(t *testing.T) {
+		size, ok := s.SizeOfPath("root.ptr.Cache")
+		fmt.Printf("Cache: %d\n", size)
+		if !ok || size != r.Root.Children[0].Children[0].Size {
+			t.Errorf("Expected %d, got %d", r.Root.Children[0].Children[0].Size, size)
+		}
+		if _, ok := s.SizeOfPath("root.ptr.Missing"); ok {
+			t.Error("Expected no size for a missing path")
+		}
+		if s.Total() != r.Total() {
+			t.Errorf("Expected total %d, got %d", r.Total(), s.Total())
+		}
+	})
+
+	t.Run("TopN", func(t *testing.T) {
+		top, expected := s.TopN(3), r.TopContributors(3)
+		if fmt.Sprint(top) != fmt.Sprint(expected) {
+			t.Errorf("Expected %v, got %v", expected, top)
+		}
+		if n := len(s.TopN(1000)); n != len(r.TopContributors(1000)) {
+			t.Errorf("Expected all %d nodes, got %d", len(r.TopContributors(1000)), n)
+		}
+	})
+
+	t.Run("ByType", func(t *testing.T) {
+		byType, expected := s.ByType(), GetSizeByType(v)
+		for name, stats := range expected {
+			if byType[name] != stats {
+				t.Errorf("Expected %s %+v, got %+v", name, stats, byType[name])
+			}
+		}
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		v.Names = append(v.Names, "z")
+		after := TakeSnapshot(v)
+		d, expected := s.Diff(after), r.Diff(GetReport(v))
+		if fmt.Sprint(d) != fmt.Sprint(expected) || d.Delta() <= 0 {
+			t.Errorf("Expected %v, got %v", expected, d)
+		}
+	})
+}
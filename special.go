@@ -0,0 +1,90 @@
+// special.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// typeHandler sizes values of a type whose memory is hidden from reflection, e.g. behind
+// unsafe.Pointer fields. It returns the shallow size of v at path and the values it references.
+type typeHandler func(w *walker, v reflect.Value, path string) (uint64, []handledChild)
+
+// handledChild is a value referenced by a handled value, located by its path suffix
+type handledChild struct {
+	v      reflect.Value
+	suffix string
+}
+
+// interfaceType is the type of interface{} values
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// typeHandlers holds the handler of every specially sized type
+var typeHandlers = map[reflect.Type]typeHandler{}
+
+// typeMatchers return the handler of types of a family, such as the instantiations of a
+// generic type, or nil for other types
+var typeMatchers []func(t reflect.Type) typeHandler
+
+// matchedHandlers caches the typeHandler found by typeMatchers per reflect.Type, nil included
+var matchedHandlers sync.Map
+
+// handlerFor returns the handler of t under the layout l, or nil if it is sized by its kind
+func handlerFor(t reflect.Type, l layout) typeHandler {
+	if h := registeredHandler(t, l); h != nil {
+		return h
+	}
+	return builtinHandler(t)
+}
+
+// registeredHandler returns the handler of t from the sets of handlers applying to the layout l
+func registeredHandler(t reflect.Type, l layout) typeHandler {
+	if h := l.handlers.set.lookup(t); h != nil {
+		return h
+	}
+	return l.handlers.defaults.lookup(t)
+}
+
+// builtinHandler returns the handler of t from typeHandlers and typeMatchers
+func builtinHandler(t reflect.Type) typeHandler {
+	if h := typeHandlers[t]; h != nil {
+		return h
+	}
+	if h, ok := matchedHandlers.Load(t); ok {
+		return h.(typeHandler)
+	}
+	var h typeHandler
+	for _, match := range typeMatchers {
+		if h = match(t); h != nil {
+			break
+		}
+	}
+	matchedHandlers.Store(t, h)
+	return h
+}
+
+// enterHandled sizes a value with its type's handler
+func (w *walker) enterHandled(f *frame, h typeHandler) uint64 {
+	size, children := h(w, f.v, w.path(f))
+	f.handled = children
+	f.custom = true
+	if w.cfg.debug {
+		w.debugPrint(f, "%s size %d with %d children", f.v.Type(), size, len(children))
+	}
+	return size
+}
+
+// pointerTo returns a pointer to the memory of v, copying it if v isn't addressable. Copies
+// need v to be exported, so values of unexported fields reached by value are reported as not ok.
+func pointerTo(v reflect.Value) (unsafe.Pointer, bool) {
+	if v.CanAddr() {
+		return unsafe.Pointer(v.UnsafeAddr()), true
+	}
+	if !v.CanInterface() {
+		return nil, false
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.UnsafePointer(), true
+}
@@ -0,0 +1,114 @@
+// stats.go
+package memsize
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes the traversal behind a measurement, e.g. to tune limits
+type Stats struct {
+	// Nodes is the number of values visited, not counting elements folded into their
+	// slice, array or map
+	Nodes uint64
+	// Pointers is the number of pointers whose target was visited
+	Pointers uint64
+	// Cycles is the number of pointers to a value whose traversal was still in progress
+	Cycles uint64
+	// MaxDepth is the largest number of nested values traversed at once. With
+	// WithParallelism, values offloaded to workers count from the offloaded element.
+	MaxDepth int
+	// VisitedBytes is the memory taken by the set of visited objects, which is not part of
+	// the measured size, see WithVisitedArena
+	VisitedBytes uint64
+	// Duration is the wall time of the measurement
+	Duration time.Duration
+	// Truncated is set when a limit, the context or a WalkFunc stopped the traversal early
+	Truncated bool
+}
+
+// GetTotalSizeStats is like GetTotalSizeE and also returns statistics about the traversal.
+// See SelfBenchmark for the allocations of a measurement.
+func GetTotalSizeStats(v interface{}, opts ...Option) (uint64, Stats, error) {
+	start := time.Now()
+	w := newWalker(opts...)
+	w.stats = &traversalStats{}
+	size, err := w.measure(reflect.ValueOf(v))
+
+	stats := w.stats.snapshot()
+	stats.Duration = time.Since(start)
+	stats.Truncated = w.budget.exceeded() || w.stopped
+	return size, stats, err
+}
+
+// traversalStats collects Stats, shared by all walkers of a measurement. All of its methods
+// are no-ops on nil, which is the case unless statistics were requested.
+type traversalStats struct {
+	nodes        uint64 // atomic
+	pointers     uint64 // atomic
+	cycles       uint64 // atomic
+	maxDepth     int64  // atomic
+	visitedBytes uint64 // atomic
+}
+
+func (s *traversalStats) node(depth int) {
+	if s == nil {
+		return
+	}
+	atomic.AddUint64(&s.nodes, 1)
+	for {
+		max := atomic.LoadInt64(&s.maxDepth)
+		if int64(depth) <= max || atomic.CompareAndSwapInt64(&s.maxDepth, max, int64(depth)) {
+			return
+		}
+	}
+}
+
+// visited records the size of a visited set; the largest of a measurement's sets is reported
+func (s *traversalStats) visited(bytes uint64) {
+	if s == nil {
+		return
+	}
+	for {
+		max := atomic.LoadUint64(&s.visitedBytes)
+		if bytes <= max || atomic.CompareAndSwapUint64(&s.visitedBytes, max, bytes) {
+			return
+		}
+	}
+}
+
+func (s *traversalStats) pointer() {
+	if s != nil {
+		atomic.AddUint64(&s.pointers, 1)
+	}
+}
+
+// seenPointer counts a pointer to an already visited address if it closes a cycle, which is
+// the case when the address is the target of a pointer still being traversed
+func (s *traversalStats) seenPointer(w *walker, addr uintptr) {
+	if s == nil {
+		return
+	}
+	for i := range w.stack[:len(w.stack)-1] {
+		f := &w.stack[i]
+		if f.follow && f.v.Kind() == reflect.Ptr && uintptr(f.v.UnsafePointer()) == addr {
+			atomic.AddUint64(&s.cycles, 1)
+			return
+		}
+	}
+}
+
+func (s *traversalStats) snapshot() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	return Stats{
+		Nodes:    atomic.LoadUint64(&s.nodes),
+		Pointers: atomic.LoadUint64(&s.pointers),
+		Cycles:   atomic.LoadUint64(&s.cycles),
+		MaxDepth: int(atomic.LoadInt64(&s.maxDepth)),
+
+		VisitedBytes: atomic.LoadUint64(&s.visitedBytes),
+	}
+}
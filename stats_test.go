@@ -0,0 +1,79 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetTotalSizeStats(t *testing.T) {
+	Debug = false
+
+	type node struct {
+		Next *node
+		Data []byte
+	}
+
+	t.Run("Chain", func(t *testing.T) {
+		var head *node
+		for i := 0; i < 10; i++ {
+			head = &node{Next: head, Data: make([]byte, 8)}
+		}
+
+		size, stats, err := GetTotalSizeStats(head)
+		fmt.Printf("Chain stats: %+v\n", stats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != GetTotalSize(head) {
+			t.Errorf("Expected size %d, got %d", GetTotalSize(head), size)
+		}
+		if stats.Pointers != 10 {
+			t.Errorf("Expected 10 pointers followed, got %d", stats.Pointers)
+		}
+		if stats.Cycles != 0 || stats.Truncated {
+			t.Errorf("Expected no cycles and no truncation, got %+v", stats)
+		}
+		// Each link is a pointer and its struct, ending with the nil Next of the last one
+		if stats.MaxDepth != 21 {
+			t.Errorf("Expected a depth of 21, got %d", stats.MaxDepth)
+		}
+		if stats.Nodes < 30 {
+			t.Errorf("Expected at least 30 nodes, got %d", stats.Nodes)
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		a, b := &node{}, &node{}
+		a.Next, b.Next = b, a
+		shared := &node{}
+		v := []*node{a, shared, shared}
+
+		_, stats, _ := GetTotalSizeStats(v)
+		if stats.Cycles != 1 {
+			t.Errorf("Expected 1 cycle, got %d", stats.Cycles)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		v := make([]*node, 100)
+		for i := range v {
+			v[i] = &node{}
+		}
+		_, stats, err := GetTotalSizeStats(v, WithMaxNodes(10))
+		if !errors.Is(err, ErrLimitExceeded) || !stats.Truncated {
+			t.Errorf("Expected a truncated walk, got %+v and %v", stats, err)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		v := make([]*node, 5000)
+		for i := range v {
+			v[i] = &node{}
+		}
+		_, stats, _ := GetTotalSizeStats(v, WithParallelism(4))
+		if stats.Pointers != 5000 {
+			t.Errorf("Expected 5000 pointers followed, got %d", stats.Pointers)
+		}
+	})
+}
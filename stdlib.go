@@ -0,0 +1,152 @@
+// stdlib.go
+package memsize
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+)
+
+func init() {
+	registerBuffer(reflect.TypeOf(bytes.Buffer{}), "buf")
+	registerBuffer(reflect.TypeOf(strings.Builder{}), "buf")
+	typeMatchers = append(typeMatchers, matchWrapper, matchInterned)
+}
+
+// interned lists standard library value types whose references point to memory shared by the
+// whole process, by package path and name: the *Location of a time.Time is one of a few
+// cached zones, and the zone of a netip.Addr is interned with package unique.
+var interned = map[string]bool{
+	"time.Time":      true,
+	"net/netip.Addr": true,
+}
+
+// internedType reports whether t is listed in interned
+func internedType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && interned[t.PkgPath()+"."+t.Name()]
+}
+
+func matchInterned(t reflect.Type) typeHandler {
+	if !internedType(t) {
+		return nil
+	}
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		return inlineSize(t, w.cfg.layout()), nil
+	}
+}
+
+// inlineSize is the size of a value of type t without the memory it references
+func inlineSize(t reflect.Type, l layout) uint64 {
+	if l.model == ExactSizes || zeroSized(t) {
+		return l.sizeof(t)
+	}
+	if t.Kind() != reflect.Struct {
+		if size, ok := constantSize(t, l); ok {
+			return size
+		}
+		return l.header()
+	}
+	size := l.header()
+	for i := 0; i < t.NumField(); i++ {
+		size += inlineSize(t.Field(i).Type, l)
+	}
+	return size
+}
+
+// wrappers lists standard library types owning private buffers and state, by package path
+// and name, with the fields referencing the reader or writer they wrap. Types are matched by
+// name, so sizing them doesn't link their packages into every program.
+var wrappers = map[string][]string{
+	"bufio.Reader":                {"rd"},
+	"bufio.Writer":                {"wr"},
+	"compress/flate.Writer":       nil,
+	"compress/flate.decompressor": {"r"},
+	"compress/gzip.Reader":        {"r"},
+	"compress/gzip.Writer":        {"w"},
+	"encoding/json.Decoder":       {"r"},
+	"encoding/json.Encoder":       {"w"},
+}
+
+func matchWrapper(t reflect.Type) typeHandler {
+	external, ok := wrappers[t.PkgPath()+"."+t.Name()]
+	if !ok || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return wrapperHandler(t, external)
+}
+
+var (
+	ioReaderType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	ioWriterType = reflect.TypeOf((*io.Writer)(nil)).Elem()
+)
+
+// wrappedIO reports whether t is an interface of readers or writers, which the private state of
+// a wrapper refers to the wrapped reader or writer by, such as the writer of a flate.Writer's
+// bit writer
+func wrappedIO(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && (t.Implements(ioReaderType) || t.Implements(ioWriterType))
+}
+
+// wrapperHandler sizes a wrapper and its private state with ExactSizes whatever the model of
+// the measurement, since their windows and tables are large arrays whose layout is known
+// exactly. The wrapped reader or writer belongs to the caller and is visited as a child, both
+// when the wrapper holds it in a field listed in wrappers and when its private state does.
+func wrapperHandler(t reflect.Type, external []string) typeHandler {
+	isExternal := make(map[int]bool)
+	for _, name := range external {
+		if f, ok := t.FieldByName(name); ok && len(f.Index) == 1 {
+			isExternal[f.Index[0]] = true
+		}
+	}
+
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		cfg := *w.cfg
+		cfg.model = ExactSizes
+		inner := w.child(&cfg)
+		inner.detach = wrappedIO
+		l := cfg.layout()
+
+		// Internal fields are measured with their inline bytes, external ones account for
+		// their own, leaving the padding
+		var fields, internal uint64
+		var children []handledChild
+		for i := 0; i < t.NumField(); i++ {
+			fields += l.sizeof(t.Field(i).Type)
+			if isExternal[i] {
+				children = append(children, handledChild{v: v.Field(i), suffix: fieldSegment(t.Field(i))})
+			} else {
+				detached := len(inner.detached)
+				internal += inner.getTotalSize(v.Field(i), "")
+				for j := detached; j < len(inner.detached); j++ {
+					inner.detached[j].suffix = fieldSegment(t.Field(i)) + inner.detached[j].suffix
+				}
+			}
+		}
+		return l.sizeof(t) - fields + internal, append(children, inner.detached...)
+	}
+}
+
+// registerBuffer registers a handler for the type t owning the byte slice in the named field,
+// unless the field doesn't exist in this version of Go
+func registerBuffer(t reflect.Type, field string) {
+	f, ok := t.FieldByName(field)
+	if !ok || f.Type != reflect.TypeOf([]byte(nil)) {
+		return
+	}
+	typeHandlers[t] = bufferHandler(f.Index)
+}
+
+// bufferHandler sizes a type owning a byte slice as the type itself plus the capacity of the
+// slice. Unlike a slice traversed on its own, bytes up to the length are not counted twice
+// with LegacySizes, and data written to and drained from the buffer is included.
+func bufferHandler(index []int) typeHandler {
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		size := headerSize(v.Type(), w.cfg.layout())
+		if buf := v.FieldByIndex(index); !buf.IsNil() {
+			c := uint64(buf.Cap())
+			size += c + w.cfg.slack(c, false)
+		}
+		return size, nil
+	}
+}
@@ -0,0 +1,175 @@
+package memsize
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStdlib(t *testing.T) {
+	Debug = false
+
+	t.Run("Buffers", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Grow(1 << 16)
+		buf.WriteString("hello")
+		var sb strings.Builder
+		sb.Grow(1 << 16)
+		sb.WriteString("hello")
+
+		tests := []struct {
+			name     string
+			value    interface{}
+			expected uint64
+		}{
+			{"bytes.Buffer", &buf, 16 + 16 + 1<<16},
+			{"strings.Builder", &sb, 16 + 16 + 1<<16},
+			{"Empty", &bytes.Buffer{}, 16 + 16},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if size := GetTotalSize(tt.value); size != tt.expected {
+					t.Errorf("Expected %d bytes, got %d", tt.expected, size)
+				}
+			})
+		}
+
+		exact := uint64(8) + 40 + 1<<16
+		if size := GetTotalSize(&buf, WithSizeModel(ExactSizes)); size != exact {
+			t.Errorf("Expected %d bytes with exact sizes, got %d", exact, size)
+		}
+	})
+
+	t.Run("Wrappers", func(t *testing.T) {
+		gz := gzip.NewWriter(io.Discard)
+		gz.Write([]byte("hello"))
+
+		tests := []struct {
+			name  string
+			value interface{}
+			min   uint64
+		}{
+			{"bufio.Reader", bufio.NewReaderSize(strings.NewReader("abc"), 1<<16), 1 << 16},
+			{"bufio.Writer", bufio.NewWriterSize(io.Discard, 1<<16), 1 << 16},
+			// The compressor's hash tables and window alone take hundreds of kilobytes
+			{"gzip.Writer", gz, 256 << 10},
+			{"json.Decoder", json.NewDecoder(strings.NewReader(`{"a": 1}`)), 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				legacy, exact := GetTotalSize(tt.value), GetTotalSize(tt.value, WithSizeModel(ExactSizes))
+				fmt.Printf("%s: %d bytes, %d with exact sizes\n", tt.name, legacy, exact)
+				if legacy < tt.min {
+					t.Errorf("Expected at least %d bytes, got %d", tt.min, legacy)
+				}
+				// Only the headers of the wrapper and its reader or writer depend on the model
+				if legacy > exact+64 {
+					t.Errorf("Expected legacy sizes to count the buffers once like the exact %d, got %d",
+						exact, legacy)
+				}
+			})
+		}
+	})
+
+	t.Run("Wrapped Reader", func(t *testing.T) {
+		r := strings.NewReader(strings.Repeat("x", 1000))
+		report := GetReport(bufio.NewReader(r))
+		found := false
+		report.Walk(func(n *Node) bool {
+			found = found || n.Path == "root.ptr.rd.elem.ptr.s" && n.Size >= 1000
+			return true
+		})
+		if !found {
+			t.Error("Expected the wrapped reader to be visited as a child")
+		}
+	})
+
+	t.Run("Wrapped Writer", func(t *testing.T) {
+		buf := bytes.NewBuffer(make([]byte, 0, 1<<20))
+		// Writing creates the compressor, whose bit writer refers to the buffer too
+		gz := gzip.NewWriter(buf)
+		gz.Write([]byte("hello"))
+		for _, tt := range []struct {
+			name  string
+			value interface{}
+		}{
+			{"gzip.Writer", gz},
+			// The writer is only referenced by the compressor's private state
+			{"flate.Writer", mustFlateWriter(buf)},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				report := GetReport(tt.value, WithSizeModel(ExactSizes))
+				wrapper := report.Root.Children[0]
+				own := wrapper.Size
+				for _, c := range wrapper.Children {
+					own -= c.Size
+				}
+				var holder *Node
+				report.Walk(func(n *Node) bool {
+					if n.Type == "*bytes.Buffer" && (holder == nil || n.Size > holder.Size) {
+						holder = n
+					}
+					return true
+				})
+				fmt.Printf("%s: %d own bytes, buffer at %v\n", tt.name, own, holder)
+				if own >= 1<<20 {
+					t.Errorf("Expected the buffer not to be counted as the wrapper's own, got %d bytes", own)
+				}
+				if holder == nil || holder.Size < 1<<20 {
+					t.Errorf("Expected the buffer on a child node, got %+v", holder)
+				}
+			})
+		}
+	})
+
+	t.Run("Value Types", func(t *testing.T) {
+		now := time.Now()
+		zoned := netip.MustParseAddr("fe80::1%eth0")
+		n := new(big.Int).Lsh(big.NewInt(1), 10000)
+		words := uint64(len(n.Bits()) + cap(n.Bits()))
+
+		tests := []struct {
+			name          string
+			value         interface{}
+			legacy, exact uint64
+		}{
+			// The shared *Location is not followed
+			{"time.Time", now, 16 + 8 + 8 + 16, 24},
+			{"Times", []time.Time{now, now}, 16 + 2*24 + 2*48, 24 + 2*24},
+			// Neither is the interned zone
+			{"netip.Addr", zoned, 16 + (16 + 8 + 8) + (16 + 16), 24},
+			// Words of the magnitude are counted with the backing array and as elements
+			{"big.Int", n, 16 + (16 + 1 + (16 + words*8)), 8 + 32 + uint64(cap(n.Bits()))*8},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if size := GetTotalSize(tt.value); size != tt.legacy {
+					t.Errorf("Expected %d bytes, got %d", tt.legacy, size)
+				}
+				if size := GetTotalSize(tt.value, WithSizeModel(ExactSizes)); size != tt.exact {
+					t.Errorf("Expected %d bytes with exact sizes, got %d", tt.exact, size)
+				}
+				if _, err := GetTotalSizeE(tt.value, WithStrict()); err != nil {
+					t.Errorf("Expected an accurate size, got %v", err)
+				}
+			})
+		}
+	})
+}
+
+func mustFlateWriter(w io.Writer) *flate.Writer {
+	fw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		panic(err)
+	}
+	return fw
+}
@@ -0,0 +1,21 @@
+// regexp.go
+package stdlibhooks
+
+import (
+	"reflect"
+	"regexp"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+func init() {
+	memsize.RegisterHandler(reflect.TypeOf(regexp.Regexp{}), regexpHandler)
+}
+
+// regexpHandler sizes a Regexp and its compiled programs with ExactSizes whatever the model of
+// the measurement: a program is a slice of small instructions of a known layout, which
+// LegacySizes counts at about twice their size. The machines running matches are cached in
+// pools shared by every regexp and are not counted.
+func regexpHandler(c *memsize.HandlerContext, v reflect.Value) (uint64, []memsize.Child) {
+	return c.ExactSize(v), nil
+}
@@ -0,0 +1,28 @@
+package stdlibhooks
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+func TestRegexp(t *testing.T) {
+	memsize.Debug = false
+
+	re := regexp.MustCompile(`^([a-z]+)@(\w+)\.(com|org|net)[0-9]{2,4}$`)
+	exact := memsize.GetTotalSize(re, memsize.WithSizeModel(memsize.ExactSizes))
+	legacy := memsize.GetTotalSize(re)
+	fmt.Printf("Regexp: exact %d, legacy %d\n", exact, legacy)
+
+	// Only the pointer to the regexp is sized by the model of the measurement
+	if legacy < exact || legacy > exact+64 {
+		t.Errorf("Expected the legacy size to be within 64 bytes of %d, got %d", exact, legacy)
+	}
+
+	report := memsize.GetReport(re)
+	if got := len(report.Root.Children[0].Children); got != 0 {
+		t.Errorf("Expected the program to be part of the regexp, got %d children", got)
+	}
+}
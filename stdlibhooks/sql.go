@@ -0,0 +1,36 @@
+// sql.go
+package stdlibhooks
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+func init() {
+	memsize.RegisterHandler(reflect.TypeOf(sql.Rows{}), rowsHandler)
+}
+
+// rowsFields lists the fields of sql.Rows holding the result set: the driver's rows, the
+// values of the current row and the buffer scanned RawBytes are copied to. The others lead to
+// the connection and statement, which belong to the pool of the DB.
+var rowsFields = []string{"rowsi", "lasterr", "lastcols", "raw"}
+
+// rowsHandler sizes sql.Rows with the buffers of its result set, without following the
+// connection it holds
+func rowsHandler(c *memsize.HandlerContext, v reflect.Value) (uint64, []memsize.Child) {
+	size := c.InlineSize(v.Type())
+	var children []memsize.Child
+	for _, name := range rowsFields {
+		// Fields missing from this version of Go are skipped
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			continue
+		}
+		// Children account for their own headers
+		size -= c.InlineSize(f.Type())
+		children = append(children, memsize.Child{Value: f, Path: "." + name})
+	}
+	return size, children
+}
@@ -0,0 +1,88 @@
+package stdlibhooks
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/afshin-deriv/go-memsize"
+)
+
+func init() {
+	sql.Register("memsize-rows", rowsDriver{})
+}
+
+// rowsDriver serves the same rows for every query
+type rowsDriver struct{}
+
+func (rowsDriver) Open(string) (driver.Conn, error) { return rowsConn{}, nil }
+
+type rowsConn struct{}
+
+func (rowsConn) Prepare(string) (driver.Stmt, error) { return rowsStmt{}, nil }
+func (rowsConn) Close() error                        { return nil }
+func (rowsConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type rowsStmt struct{}
+
+func (rowsStmt) Close() error                               { return nil }
+func (rowsStmt) NumInput() int                              { return -1 }
+func (rowsStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (rowsStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &driverRows{values: [][]driver.Value{{int64(1), strings.Repeat("x", 1000)}}}, nil
+}
+
+type driverRows struct {
+	values [][]driver.Value
+}
+
+func (r *driverRows) Columns() []string { return []string{"id", "data"} }
+func (r *driverRows) Close() error      { return nil }
+func (r *driverRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestRows(t *testing.T) {
+	memsize.Debug = false
+
+	db, err := sql.Open("memsize-rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT id, data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("Expected a row")
+	}
+
+	report := memsize.GetReport(rows, memsize.WithSizeModel(memsize.ExactSizes))
+	report.Walk(func(n *memsize.Node) bool {
+		if strings.Contains(n.Type, "driverConn") || strings.Contains(n.Type, "sql.DB") {
+			t.Errorf("Expected the connection not to be followed, got %s at %s", n.Type, n.Path)
+		}
+		return true
+	})
+
+	// The current row holds the string, which the driver's rows no longer reference
+	if size := report.Total(); size < 1000 || size > 2000 {
+		t.Errorf("Expected the size of the current row, got %d", size)
+	}
+	var found bool
+	for _, child := range report.Root.Children[0].Children {
+		found = found || child.Path == "root.ptr.lastcols"
+	}
+	if !found {
+		t.Error("Expected the current row to be reported at root.ptr.lastcols")
+	}
+}
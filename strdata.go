@@ -0,0 +1,52 @@
+// strdata.go
+package memsize
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// stringStats totals the data of the strings of a report and the part of it that repeats the
+// contents of another string stored separately
+type stringStats struct {
+	// contents holds every distinct content seen, and data every distinct data pointer and length
+	contents map[string]bool
+	data     map[stringData]bool
+
+	bytes     uint64
+	duplicate uint64
+}
+
+type stringData struct {
+	ptr uintptr
+	len int
+}
+
+func newStringStats() *stringStats {
+	return &stringStats{contents: make(map[string]bool), data: make(map[stringData]bool)}
+}
+
+// add records the data of a string value. Strings sharing their data with one already seen
+// are counted again, as they are in sizes, but aren't duplicates: interning wouldn't save them.
+func (s *stringStats) add(v reflect.Value) {
+	if s == nil || v.Kind() != reflect.String || v.Len() == 0 {
+		return
+	}
+	str := v.String()
+	d := stringData{ptr: *(*uintptr)(unsafe.Pointer(&str)), len: len(str)}
+	s.bytes += uint64(len(str))
+	if s.contents[str] && !s.data[d] {
+		s.duplicate += uint64(len(str))
+	}
+	s.contents[str] = true
+	s.data[d] = true
+}
+
+// header returns the part of the shallow size of a string or slice taken by its header
+func (w *walker) header(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice:
+		return headerSize(v.Type(), w.cfg.layout())
+	}
+	return 0
+}
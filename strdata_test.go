@@ -0,0 +1,76 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStringData(t *testing.T) {
+	Debug = false
+
+	shared := strings.Repeat("x", 100)
+	v := struct {
+		A, B, C string
+		Tags    []string
+		Empty   string
+	}{
+		A:    shared,
+		B:    shared,
+		C:    strings.Repeat("x", 100),
+		Tags: []string{strings.Repeat("y", 10), strings.Repeat("y", 10), "z"},
+	}
+
+	t.Run("Totals", func(t *testing.T) {
+		r := GetReport(v)
+		fmt.Printf("String data: %d bytes, duplicate: %d bytes\n", r.StringBytes, r.DuplicateStringBytes)
+		if r.StringBytes != 3*100+2*10+1 {
+			t.Errorf("Expected %d string bytes, got %d", 3*100+2*10+1, r.StringBytes)
+		}
+		// B shares the data of A, so only C and the second tag are duplicates
+		if r.DuplicateStringBytes != 100+10 {
+			t.Errorf("Expected %d duplicate bytes, got %d", 100+10, r.DuplicateStringBytes)
+		}
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			model  SizeModel
+			string uint64
+			slice  uint64
+		}{{"Legacy", LegacySizes, 16, 16}, {"Exact", ExactSizes, 16, 24}} {
+			t.Run(tc.name, func(t *testing.T) {
+				r := GetReport(v, WithSizeModel(tc.model))
+				for _, n := range r.Root.Children {
+					expected := tc.string
+					if n.Path == "root.Tags" {
+						expected = tc.slice
+					}
+					if n.Header != expected {
+						t.Errorf("Expected a %d byte header for %s, got %d", expected, n.Path, n.Header)
+					}
+					if n.Path == "root.A" && n.Shallow-n.Header != 100 {
+						t.Errorf("Expected 100 data bytes for root.A, got %d", n.Shallow-n.Header)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		r := GetReport(v)
+		data, err := r.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Report
+		if err := decoded.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.StringBytes != r.StringBytes || decoded.DuplicateStringBytes != r.DuplicateStringBytes {
+			t.Errorf("Expected %d/%d, got %d/%d", r.StringBytes, r.DuplicateStringBytes,
+				decoded.StringBytes, decoded.DuplicateStringBytes)
+		}
+	})
+}
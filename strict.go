@@ -0,0 +1,114 @@
+// strict.go
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnsupported is wrapped by the SizeError returned in strict mode
+var ErrUnsupported = errors.New("memsize: value cannot be sized accurately")
+
+// SizeError reports a value whose size was approximated during a strict measurement
+type SizeError struct {
+	// Path is the location of the value relative to the root, as in Node.Path
+	Path string
+	// Type is empty when the value has no type, i.e. for a nil root
+	Type   string
+	Reason string
+}
+
+func (e *SizeError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("memsize: couldn't size %s because %s", e.Path, e.Reason)
+	}
+	return fmt.Sprintf("memsize: couldn't size %s (%s) because %s", e.Path, e.Type, e.Reason)
+}
+
+func (e *SizeError) Unwrap() error {
+	return ErrUnsupported
+}
+
+// strictLog collects the values a strict measurement could not size, shared by all of its walkers
+type strictLog struct {
+	mu    sync.Mutex
+	first *SizeError
+	// each is called with every value that could not be sized, see Check
+	each func(path string, t reflect.Type, reason string)
+}
+
+// newStrictLog returns nil unless WithStrict is set, which makes all strictLog methods no-ops
+func newStrictLog(cfg *config) *strictLog {
+	if !cfg.strict {
+		return nil
+	}
+	return &strictLog{}
+}
+
+func (s *strictLog) reject(path string, t reflect.Type, reason string) {
+	if s == nil {
+		return
+	}
+	e := &SizeError{Path: path, Reason: reason}
+	if t != nil {
+		e.Type = t.String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first == nil {
+		s.first = e
+	}
+	if s.each != nil {
+		s.each(path, t, reason)
+	}
+}
+
+// rejectPlan reports the approximated value folded into a value of the plan's type at path, if any
+func (s *strictLog) rejectPlan(path string, p *typePlan) {
+	if s == nil || p.issue == nil {
+		return
+	}
+	s.reject(path+p.issue.suffix, p.issue.typ, unsupportedReason(p.issue.typ.Kind()))
+}
+
+func (s *strictLog) err() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first == nil {
+		return nil
+	}
+	return s.first
+}
+
+// unsupportedKind reports whether values of kind k are sized only approximately under the
+// size model m. With ExactSizes channels are checked by the walker, since only buffers of
+// elements with indirections are approximated.
+func unsupportedKind(k reflect.Kind, m SizeModel) bool {
+	switch k {
+	case reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Chan, reflect.Complex64, reflect.Complex128, reflect.Uintptr:
+		return m == LegacySizes
+	}
+	return false
+}
+
+// unsupportedReason explains why values of kind k are sized only approximately
+func unsupportedReason(k reflect.Kind) string {
+	switch k {
+	case reflect.Chan:
+		return "buffered channel elements are not traversed"
+	case reflect.Func:
+		return "variables captured by closures are not traversed"
+	case reflect.UnsafePointer:
+		return "unsafe.Pointer targets are not followed"
+	default:
+		return fmt.Sprintf("%s values are counted as a fixed header size", k)
+	}
+}
@@ -0,0 +1,69 @@
+package memsize
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	Debug = false
+
+	type Job struct {
+		Name string
+		Run  func()
+	}
+
+	t.Run("Supported Values", func(t *testing.T) {
+		values := map[string][]int{"a": {1, 2, 3}}
+		size, err := GetTotalSizeE(values, WithStrict())
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if full := GetTotalSize(values); size != full {
+			t.Errorf("Expected %d, got %d", full, size)
+		}
+	})
+
+	t.Run("Nil Root", func(t *testing.T) {
+		_, err := GetTotalSizeE(nil, WithStrict())
+		fmt.Printf("Nil root: %v\n", err)
+		var sizeErr *SizeError
+		if !errors.As(err, &sizeErr) || sizeErr.Path != "root" {
+			t.Fatalf("Expected a SizeError at root, got %v", err)
+		}
+		if _, err := GetTotalSizeE(nil); err != nil {
+			t.Errorf("Expected no error without WithStrict, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		value interface{}
+		path  string
+		typ   string
+	}{
+		{"Struct Field", &Job{Name: "cleanup", Run: func() {}}, "root.ptr.Run", "func()"},
+		{"Slice Element", []Job{{Name: "a"}}, "root[0].Run", "func()"},
+		{"Map Value", map[string]chan int{"jobs": make(chan int, 10)}, "root.value", "chan int"},
+		{"Array Element", [2]func(){}, "root[0]", "func()"},
+		{"Interface", []interface{}{"a", complex(1, 2)}, "root[1].elem", "complex128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			full := GetTotalSize(tt.value)
+			size, err := GetTotalSizeE(tt.value, WithStrict())
+			fmt.Printf("%s: %v\n", tt.name, err)
+			if !errors.Is(err, ErrUnsupported) {
+				t.Fatalf("Expected ErrUnsupported, got %v", err)
+			}
+			var sizeErr *SizeError
+			if !errors.As(err, &sizeErr) || sizeErr.Path != tt.path || sizeErr.Type != tt.typ {
+				t.Errorf("Expected %s (%s), got %v", tt.path, tt.typ, err)
+			}
+			if size != full {
+				t.Errorf("Expected the approximated size %d, got %d", full, size)
+			}
+		})
+	}
+}
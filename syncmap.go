@@ -0,0 +1,49 @@
+// syncmap.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+)
+
+func init() {
+	typeHandlers[reflect.TypeOf((*sync.Map)(nil)).Elem()] = syncMapHandler
+}
+
+// syncMapHandler sizes a sync.Map by ranging over its entries, which are visited as interface
+// keys and values. Its internals are estimated: legacy sizes count them like a map's buckets,
+// exact sizes like the implementation of the toolchain, see syncMapInternals.
+func syncMapHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	l := w.cfg.layout()
+	size := headerSize(v.Type(), l)
+
+	p, ok := pointerTo(v)
+	if !ok {
+		w.strict.reject(path, v.Type(),
+			"sync.Map values of unexported fields reached by value cannot be ranged over")
+		return size, nil
+	}
+
+	var children []handledChild
+	(*sync.Map)(p).Range(func(key, value interface{}) bool {
+		k := reflect.ValueOf(&key).Elem()
+		keySuffix, valueSuffix := ".key", ".value"
+		if path != "" {
+			// Keys are only rendered when paths are needed, as for maps
+			segment := "[" + pathKey(k) + "]"
+			keySuffix, valueSuffix = ".key"+segment, segment
+		}
+		children = append(children,
+			handledChild{v: k, suffix: keySuffix},
+			handledChild{v: reflect.ValueOf(&value).Elem(), suffix: valueSuffix})
+		return true
+	})
+
+	n := uint64(len(children) / 2)
+	if l.model == LegacySizes {
+		m := l.overhead()
+		return size + (n/m.MapBucketEntries+1)*m.MapBucket, children
+	}
+
+	return size + syncMapInternals(w, v, n), children
+}
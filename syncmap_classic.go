@@ -0,0 +1,32 @@
+//go:build !go1.26 && !goexperiment.synchashtriemap
+
+// syncmap_classic.go
+package memsize
+
+import "reflect"
+
+// syncMapInternals estimates the exact size of the read and dirty maps backing a sync.Map
+// with n entries, beyond its header and the keys and values, which account for themselves.
+// The read map is assumed to hold every entry, as it does once loads have promoted the dirty
+// map, which is counted only while it exists.
+func syncMapInternals(w *walker, v reflect.Value, n uint64) uint64 {
+	l := w.cfg.layout()
+	// Keys are stored in both maps, but visited only once; the read map's copies account
+	// for themselves
+	size := syncMapTable(w, n) - n*l.sizeof(interfaceType)
+	if dirty := v.FieldByName("dirty"); dirty.IsValid() && !dirty.IsNil() {
+		size += syncMapTable(w, uint64(dirty.Len()))
+	}
+	// Entries are a pointer to an interface holding the value, allocated separately
+	word := l.word()
+	size += n * (word + w.cfg.slack(word, true) + w.cfg.slack(l.sizeof(interfaceType), true))
+	return size
+}
+
+// syncMapTable returns the size of a map[any]*entry of n entries of a sync.Map
+func syncMapTable(w *walker, n uint64) uint64 {
+	l := w.cfg.layout()
+	keySlot, _ := mapSlot(interfaceType, l)
+	buckets := neededBuckets(n, l) * mapBucketSize(keySlot, l.word(), l)
+	return l.hmapSize() + w.cfg.slack(l.hmapSize(), true) + buckets + w.cfg.slack(buckets, true)
+}
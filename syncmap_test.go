@@ -0,0 +1,61 @@
+package memsize
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncMap(t *testing.T) {
+	Debug = false
+
+	cache := &struct {
+		Name    string
+		Entries sync.Map
+	}{Name: "cache"}
+	for i := 0; i < 100; i++ {
+		cache.Entries.Store(i, strings.Repeat("x", 100))
+	}
+
+	for _, model := range []SizeModel{LegacySizes, ExactSizes} {
+		t.Run(fmt.Sprint("Model ", model), func(t *testing.T) {
+			size := GetTotalSize(cache, WithSizeModel(model))
+			fmt.Printf("sync.Map with 100 entries: %d bytes\n", size)
+			if size < 100*100 {
+				t.Errorf("Expected the values of 10000 bytes to be counted, got %d", size)
+			}
+
+			report := GetReport(cache, WithSizeModel(model))
+			if report.Total() != size {
+				t.Errorf("Expected the report total %d to match %d", report.Total(), size)
+			}
+			entry := regexp.MustCompile(`^root\.ptr\.Entries\[\d+\]$`)
+			values := 0
+			report.Walk(func(n *Node) bool {
+				if entry.MatchString(n.Path) {
+					values++
+				}
+				return true
+			})
+			if values != 100 {
+				t.Errorf("Expected 100 values in the report, got %d", values)
+			}
+		})
+	}
+
+	t.Run("Grows With Entries", func(t *testing.T) {
+		var small, large sync.Map
+		small.Store(1, 1)
+		for i := 0; i < 1000; i++ {
+			large.Store(i, i)
+		}
+		s := GetTotalSize(&small, WithSizeModel(ExactSizes))
+		l := GetTotalSize(&large, WithSizeModel(ExactSizes))
+		// Every entry holds two interfaces and two words
+		if l < s+1000*48 {
+			t.Errorf("Expected 1000 entries to cost at least %d bytes more than %d, got %d", 1000*48, s, l)
+		}
+	})
+}
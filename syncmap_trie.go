@@ -0,0 +1,29 @@
+//go:build go1.26 || goexperiment.synchashtriemap
+
+// syncmap_trie.go
+package memsize
+
+import "reflect"
+
+const (
+	// syncMapChildren is the fan-out of the hash trie backing sync.Map
+	syncMapChildren = 16
+	// syncMapEntriesPerNode approximates the number of entries per trie node; hashes spread
+	// entries evenly, leaving nodes about half full
+	syncMapEntriesPerNode = syncMapChildren / 2
+)
+
+// syncMapInternals estimates the exact size of the hash trie backing a sync.Map with n
+// entries, beyond its header and the keys and values, which account for themselves
+func syncMapInternals(w *walker, v reflect.Value, n uint64) uint64 {
+	l := w.cfg.layout()
+	// Entries hold a node header and an overflow pointer besides their key and value, and
+	// indirect nodes a header, a mutex, a parent pointer and their children
+	word := l.word()
+	entrySize := 2*word + 2*l.sizeof(interfaceType)
+	nodeSize := (syncMapChildren+3)*word + 8
+	nodes := 1 + n/syncMapEntriesPerNode
+	size := nodes * (nodeSize + w.cfg.slack(nodeSize, true))
+	size += n * (2*word + w.cfg.slack(entrySize, true))
+	return size
+}
@@ -0,0 +1,51 @@
+// syncprim.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+)
+
+// syncPrimitives lists the synchronization primitives sized by their fixed layout. Their
+// fields hold lock states, semaphore addresses and noCopy sentinels rather than data, and a
+// Cond's Locker is the caller's lock, usually counted where it is declared.
+var syncPrimitives = map[reflect.Type]bool{
+	reflect.TypeOf(sync.Mutex{}):     true,
+	reflect.TypeOf(sync.RWMutex{}):   true,
+	reflect.TypeOf(sync.Once{}):      true,
+	reflect.TypeOf(sync.WaitGroup{}): true,
+	reflect.TypeOf(sync.Cond{}):      true,
+}
+
+func init() {
+	for t := range syncPrimitives {
+		typeHandlers[t] = syncHandler
+	}
+}
+
+// syncHandler sizes a synchronization primitive without traversing its internals, so reports
+// show it as a single node
+func syncHandler(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+	return inlineSize(v.Type(), w.cfg.layout()), nil
+}
+
+// SyncBytes returns the total size of the sync.Mutex, RWMutex, Once, WaitGroup and Cond values
+// in the report, the overhead of synchronization in the measured value
+func (r *Report) SyncBytes() uint64 {
+	if r == nil || r.Root == nil {
+		return 0
+	}
+	names := make(map[string]bool, len(syncPrimitives))
+	for t := range syncPrimitives {
+		names[t.String()] = true
+	}
+	var total uint64
+	r.Walk(func(n *Node) bool {
+		if names[n.Type] {
+			total += n.Size
+			return false
+		}
+		return true
+	})
+	return total
+}
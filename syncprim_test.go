@@ -0,0 +1,60 @@
+package memsize
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncPrimitives(t *testing.T) {
+	Debug = false
+
+	type guarded struct {
+		mu    sync.Mutex
+		rw    sync.RWMutex
+		once  sync.Once
+		wg    sync.WaitGroup
+		cond  *sync.Cond
+		items []string
+	}
+	g := &guarded{items: []string{"a", "b"}}
+	g.cond = sync.NewCond(&g.mu)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, model := range []SizeModel{LegacySizes, ExactSizes} {
+		t.Run(fmt.Sprint("Model ", model), func(t *testing.T) {
+			report := GetReport(g, WithSizeModel(model))
+			if total := GetTotalSize(g, WithSizeModel(model)); report.Total() != total {
+				t.Errorf("Expected the report total %d to match %d", report.Total(), total)
+			}
+			var primitives uint64
+			report.Walk(func(n *Node) bool {
+				if n.Path == "root.ptr.mu" || n.Path == "root.ptr.wg" || n.Path == "root.ptr.cond.ptr" {
+					if len(n.Children) > 0 {
+						t.Errorf("Expected %s not to be traversed, got %d children", n.Path, len(n.Children))
+					}
+				}
+				if strings.HasPrefix(n.Type, "sync.") {
+					primitives += n.Size
+				}
+				return true
+			})
+			fmt.Printf("Sync overhead: %d of %d bytes\n", report.SyncBytes(), report.Total())
+			if report.SyncBytes() != primitives || primitives == 0 {
+				t.Errorf("Expected sync overhead %d, got %d", primitives, report.SyncBytes())
+			}
+		})
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		text := GetReport(g).String()
+		if !strings.Contains(text, "sync overhead: ") {
+			t.Errorf("Expected the sync overhead in the text report, got\n%s", text)
+		}
+		if strings.Contains(GetReport([]int{1}).String(), "sync overhead") {
+			t.Error("Expected no sync overhead without sync primitives")
+		}
+	})
+}
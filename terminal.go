@@ -0,0 +1,97 @@
+// terminal.go
+package memsize
+
+import (
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+// TerminalTextOptions returns the TextOptions for writing to f: colors if f is a terminal,
+// unless the NO_COLOR environment variable is set or TERM is "dumb", and lines truncated to
+// the width of the terminal, or to the COLUMNS environment variable if its size is unknown.
+func TerminalTextOptions(f *os.File) TextOptions {
+	var opts TextOptions
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return opts
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	opts.Color = !noColor && os.Getenv("TERM") != "dumb"
+	opts.Width = terminalWidth(f)
+	if opts.Width == 0 {
+		opts.Width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	return opts
+}
+
+// WriteTerminal writes the report to f as a text tree with TerminalTextOptions
+func (r *Report) WriteTerminal(f *os.File) error {
+	return r.WriteText(f, TerminalTextOptions(f))
+}
+
+// magnitude styles a formatted size by its unit, so mebibytes and gibibytes stand out
+func magnitude(bytes uint64, formatted string) string {
+	switch {
+	case bytes >= 1<<30:
+		return ansiBold + ansiRed + formatted + ansiReset
+	case bytes >= 1<<20:
+		return ansiBold + formatted + ansiReset
+	case bytes < 1<<10:
+		return ansiDim + formatted + ansiReset
+	}
+	return formatted
+}
+
+// visibleLen returns the number of characters of s shown by a terminal, without escape codes
+func visibleLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if end := escapeEnd(s, i); end > i {
+			i = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// truncate cuts s to width visible characters, ending it with an ellipsis if it was longer.
+// Escape codes are kept and colors reset after a cut.
+func truncate(s string, width int) string {
+	if width <= 0 || visibleLen(s) <= width {
+		return s
+	}
+	n, escaped := 0, false
+	for i := 0; i < len(s); {
+		if end := escapeEnd(s, i); end > i {
+			i, escaped = end, true
+			continue
+		}
+		if n == width-1 {
+			if escaped {
+				return s[:i] + "…" + ansiReset
+			}
+			return s[:i] + "…"
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return s
+}
+
+// escapeEnd returns the end of the ANSI escape code starting at s[i], or i if there is none
+func escapeEnd(s string, i int) int {
+	if i+1 >= len(s) || s[i] != '\x1b' || s[i+1] != '[' {
+		return i
+	}
+	for j := i + 2; j < len(s); j++ {
+		if c := s[j]; c >= '@' && c <= '~' {
+			return j + 1
+		}
+	}
+	return i
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+// terminal_other.go
+package memsize
+
+import "os"
+
+// terminalWidth is only supported on Linux, elsewhere COLUMNS is used
+func terminalWidth(f *os.File) int {
+	return 0
+}
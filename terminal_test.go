@@ -0,0 +1,87 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTerminal(t *testing.T) {
+	Debug = false
+
+	t.Run("Truncate", func(t *testing.T) {
+		tests := []struct {
+			s        string
+			width    int
+			expected string
+		}{
+			{"short", 10, "short"},
+			{"exactly10!", 10, "exactly10!"},
+			{"much too long", 10, "much too …"},
+			{ansiRed + "colored text" + ansiReset, 5, ansiRed + "colo…" + ansiReset},
+			{"größer als", 6, "größe…"},
+			{"anything", 0, "anything"},
+		}
+		for _, tt := range tests {
+			if got := truncate(tt.s, tt.width); got != tt.expected {
+				t.Errorf("Expected %q cut to %d to be %q, got %q", tt.s, tt.width, tt.expected, got)
+			}
+		}
+		if n := visibleLen(ansiBold + "1.00 MiB" + ansiReset); n != 8 {
+			t.Errorf("Expected 8 visible characters, got %d", n)
+		}
+	})
+
+	v := struct {
+		Handlers map[string]func()
+		Payload  []byte
+	}{Payload: make([]byte, 2<<20)}
+	report := GetReport(v)
+
+	t.Run("Width", func(t *testing.T) {
+		for _, color := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := report.WriteText(&buf, TextOptions{Width: 50, Color: color}); err != nil {
+				t.Fatal(err)
+			}
+			fmt.Print(buf.String())
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if visibleLen(line) > 50 {
+					t.Errorf("Expected at most 50 characters, got %d in %q", visibleLen(line), line)
+				}
+			}
+			// Types are shortened before the sizes
+			if !strings.Contains(buf.String(), "…") || !strings.Contains(buf.String(), "MiB") {
+				t.Errorf("Expected shortened types and sizes, got\n%s", buf.String())
+			}
+		}
+	})
+
+	t.Run("Magnitude", func(t *testing.T) {
+		var buf bytes.Buffer
+		report.WriteText(&buf, TextOptions{Color: true})
+		if !strings.Contains(buf.String(), ansiBold+Format(report.Total())) {
+			t.Errorf("Expected mebibytes in bold, got %q", buf.String())
+		}
+	})
+
+	t.Run("Not A Terminal", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "report")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if opts := TerminalTextOptions(f); opts.Color || opts.Width != 0 {
+			t.Errorf("Expected plain output to a file, got %+v", opts)
+		}
+		if err := report.WriteTerminal(f); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(f.Name())
+		if string(data) != report.String() {
+			t.Errorf("Expected the plain text report, got %q", data)
+		}
+	})
+}
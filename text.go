@@ -0,0 +1,201 @@
+// text.go
+package memsize
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format renders a number of bytes with binary units, e.g. "512 B" or "1.43 MiB"
+func Format(bytes uint64) string {
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value := float64(bytes) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f %ciB", value, units[unit])
+}
+
+// TextOptions configures Report.WriteText
+type TextOptions struct {
+	// Color highlights large nodes, styles sizes by magnitude and dims types with ANSI
+	// escape codes
+	Color bool
+	// Threshold is the share of the total, in percent, from which nodes are large: they are
+	// highlighted with Color, in red from 50%. Zero uses DefaultTextThreshold.
+	Threshold float64
+	// ListLarge lists the large nodes none of whose children is large after the tree, the
+	// values holding the memory rather than the ones on the way to them
+	ListLarge bool
+	// Width truncates lines to as many characters, shortening types first; zero doesn't
+	// truncate. See TerminalTextOptions.
+	Width int
+}
+
+// DefaultTextThreshold is the share of the total, in percent, from which WriteText considers
+// nodes large unless TextOptions.Threshold is set
+const DefaultTextThreshold = 10.0
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiDim    = "\x1b[2m"
+)
+
+// String renders the report as an indented tree without colors, see WriteText
+func (r *Report) String() string {
+	var b strings.Builder
+	r.WriteText(&b, TextOptions{})
+	return b.String()
+}
+
+// WriteText renders the report as an indented tree with a line per node giving its path
+// relative to its parent, its type, its size and its share of the total and of its parent,
+// followed by the large nodes if requested, and the escape points and the total size of sync
+// primitives if there are any
+func (r *Report) WriteText(w io.Writer, opts TextOptions) error {
+	if r == nil || r.Root == nil {
+		return nil
+	}
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DefaultTextThreshold
+	}
+
+	type entry struct {
+		n      *Node
+		name   string
+		depth  int
+		parent uint64
+	}
+	total := r.Total()
+	shareOf := func(size, of uint64) float64 {
+		if of == 0 {
+			return 100
+		}
+		return 100 * float64(size) / float64(of)
+	}
+	// sizeText renders a size and its share of the total, coloring sizes by magnitude and
+	// shares from the threshold
+	sizeText := func(size uint64, share float64, highlight bool) string {
+		formatted, percent := Format(size), fmt.Sprintf("%.1f%%", share)
+		if !opts.Color {
+			return formatted + " " + percent
+		}
+		switch {
+		case !highlight:
+		case share >= 50 && share >= threshold:
+			percent = ansiRed + percent + ansiReset
+		case share >= threshold:
+			percent = ansiYellow + percent + ansiReset
+		}
+		return magnitude(size, formatted) + " " + percent
+	}
+	// line renders a node, shortening its type to fit the width
+	line := func(indent, name, typ, size string) string {
+		text := func(typ string) string {
+			typ = "(" + typ + ")"
+			if opts.Color {
+				typ = ansiDim + typ + ansiReset
+			}
+			return indent + name + " " + typ + " " + size
+		}
+		l := text(typ)
+		if excess := visibleLen(l) - opts.Width; opts.Width > 0 && excess > 0 {
+			if keep := utf8.RuneCountInString(typ) - excess - 1; keep > 0 {
+				l = text(string([]rune(typ)[:keep]) + "…")
+			}
+		}
+		return truncate(l, opts.Width)
+	}
+
+	bw := bufio.NewWriter(w)
+	var large []*Node
+	// An explicit stack keeps arbitrarily deep reports from overflowing the goroutine stack
+	stack := []entry{{n: r.Root, name: r.Root.Path}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		share := shareOf(e.n.Size, total)
+		// The root always holds everything, only its descendants are highlighted
+		size := sizeText(e.n.Size, share, e.depth > 0)
+		if e.depth > 0 {
+			size += fmt.Sprintf(" (%.1f%% of parent)", shareOf(e.n.Size, e.parent))
+			if share >= threshold && !largeChild(e.n, total, threshold) {
+				large = append(large, e.n)
+			}
+		}
+		if _, err := fmt.Fprintln(bw, line(strings.Repeat("  ", e.depth), e.name, e.n.Type, size)); err != nil {
+			return err
+		}
+
+		for i := len(e.n.Children) - 1; i >= 0; i-- {
+			child := e.n.Children[i]
+			stack = append(stack, entry{n: child, name: pathSegment(e.n.Path, child.Path), depth: e.depth + 1,
+				parent: e.n.Size})
+		}
+	}
+
+	if opts.ListLarge && len(large) > 0 {
+		if _, err := fmt.Fprintf(bw, "over %.1f%% of the total:\n", threshold); err != nil {
+			return err
+		}
+		for _, n := range large {
+			size := sizeText(n.Size, shareOf(n.Size, total), true)
+			if _, err := fmt.Fprintln(bw, line("  ", n.Path, n.Type, size)); err != nil {
+				return err
+			}
+		}
+	}
+	if points := r.EscapePoints(0); len(points) > 0 {
+		if _, err := fmt.Fprintln(bw, "escape points:"); err != nil {
+			return err
+		}
+		for _, p := range points {
+			size := sizeText(p.Node.Size, shareOf(p.Node.Size, total), true)
+			if p.Global != "" {
+				size += " (global " + p.Global + ")"
+			}
+			if _, err := fmt.Fprintln(bw, line("  ", p.Node.Path, p.Node.Type, size)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(r.SharedObjects) > 0 {
+		if _, err := fmt.Fprintln(bw, "shared objects:"); err != nil {
+			return err
+		}
+		for _, o := range r.SharedObjects {
+			if _, err := fmt.Fprintf(bw, "  %s\n", o); err != nil {
+				return err
+			}
+		}
+	}
+	if overhead := r.SyncBytes(); overhead > 0 {
+		if _, err := fmt.Fprintf(bw, "sync overhead: %s\n", Format(overhead)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// largeChild reports whether a child of n holds at least threshold percent of total
+func largeChild(n *Node, total uint64, threshold float64) bool {
+	for _, child := range n.Children {
+		if total == 0 || 100*float64(child.Size)/float64(total) >= threshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	Debug = false
+
+	tests := []struct {
+		bytes    uint64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.00 KiB"},
+		{1536, "1.50 KiB"},
+		{1500000, "1.43 MiB"},
+		{5 << 30, "5.00 GiB"},
+		{1 << 62, "4.00 EiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := Format(tt.bytes); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReportString(t *testing.T) {
+	Debug = false
+
+	type inner struct {
+		Data []byte
+	}
+	v := struct {
+		Name  string
+		Inner inner
+	}{Name: "abc", Inner: inner{Data: make([]byte, 1000)}}
+
+	report := GetReport(v)
+	text := report.String()
+	fmt.Print(text)
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if !strings.HasPrefix(lines[0], "root (") || !strings.HasSuffix(lines[0], " 100.0%") {
+		t.Errorf("Expected the root line to hold 100%%, got %q", lines[0])
+	}
+	var data string
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "Data ") {
+			data = line
+		}
+	}
+	if !strings.HasPrefix(data, "    Data ([]uint8)") {
+		t.Errorf("Expected Data indented below Inner, got %q", data)
+	}
+	if !strings.HasSuffix(data, "% of parent)") || strings.HasSuffix(lines[0], "of parent)") {
+		t.Errorf("Expected the share of the parent below the root, got %q", data)
+	}
+	if strings.Contains(text, "\x1b[") {
+		t.Errorf("Expected no escape codes without colors")
+	}
+
+	t.Run("Color", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteText(&buf, TextOptions{Color: true}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), ansiRed) {
+			t.Errorf("Expected the dominant Data slice to be highlighted, got %q", buf.String())
+		}
+	})
+
+	t.Run("Large", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteText(&buf, TextOptions{ListLarge: true}); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Print(buf.String())
+		// Inner holds Data, which is listed instead
+		list := buf.String()[strings.Index(buf.String(), "over 10.0% of the total:\n"):]
+		lines := strings.Split(strings.TrimSpace(list), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[1], "  root.Inner.Data ([]uint8) ") {
+			t.Errorf("Expected only root.Inner.Data to be listed, got %q", list)
+		}
+
+		buf.Reset()
+		report.WriteText(&buf, TextOptions{ListLarge: true, Threshold: 100})
+		if strings.Contains(buf.String(), "of the total:") {
+			t.Errorf("Expected no node over 100%%, got %q", buf.String())
+		}
+		buf.Reset()
+		report.WriteText(&buf, TextOptions{Color: true, Threshold: 0.1})
+		if !strings.Contains(buf.String(), ansiYellow) {
+			t.Errorf("Expected the small Name to be highlighted, got %q", buf.String())
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var r *Report
+		if s := r.String(); s != "" {
+			t.Errorf("Expected an empty string, got %q", s)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+// top.go
+package memsize
+
+import "sort"
+
+// Contributor is a path together with the total size retained below it
+type Contributor struct {
+	Path string
+	Type string
+	Size uint64
+}
+
+// TopContributors returns the n paths of v with the largest total size, largest first.
+// The root itself is not included since it always accounts for the whole value.
+func TopContributors(v interface{}, n int) []Contributor {
+	return GetReport(v).TopContributors(n)
+}
+
+// TopContributors returns the n nodes of the report with the largest total size
+func (r *Report) TopContributors(n int) []Contributor {
+	if n <= 0 {
+		return nil
+	}
+
+	var all []Contributor
+	r.Walk(func(node *Node) bool {
+		if node != r.Root {
+			all = append(all, Contributor{Path: node.Path, Type: node.Type, Size: node.Size})
+		}
+		return true
+	})
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Size != all[j].Size {
+			return all[i].Size > all[j].Size
+		}
+		return all[i].Path < all[j].Path
+	})
+
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
@@ -0,0 +1,62 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+type index struct {
+	Name   string
+	Shards []shard
+}
+
+type shard struct {
+	ID       int
+	Postings []uint64
+}
+
+func TestTopContributors(t *testing.T) {
+	Debug = false
+
+	idx := &index{
+		Name: "test",
+		Shards: []shard{
+			{ID: 0, Postings: make([]uint64, 10)},
+			{ID: 1, Postings: make([]uint64, 1000)},
+			{ID: 2, Postings: make([]uint64, 100)},
+		},
+	}
+
+	top := TopContributors(idx, 5)
+	for _, c := range top {
+		fmt.Printf("%s (%s): %d bytes\n", c.Path, c.Type, c.Size)
+	}
+
+	if len(top) != 5 {
+		t.Fatalf("Expected 5 contributors, got %d", len(top))
+	}
+	for i := 1; i < len(top); i++ {
+		if top[i].Size > top[i-1].Size {
+			t.Errorf("Contributors not sorted: %d before %d", top[i-1].Size, top[i].Size)
+		}
+	}
+	for _, c := range top {
+		if c.Path == "root" {
+			t.Error("Root should not be listed as a contributor")
+		}
+	}
+
+	found := false
+	for _, c := range top {
+		if c.Path == "root.ptr.Shards[1].Postings" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected root.ptr.Shards[1].Postings among the top contributors")
+	}
+
+	if got := TopContributors(idx, 0); got != nil {
+		t.Errorf("Expected nil for n=0, got %v", got)
+	}
+}
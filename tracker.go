@@ -0,0 +1,118 @@
+// tracker.go
+package memsize
+
+import (
+	"sync"
+	"time"
+)
+
+// Tracker keeps the latest reports of a root to follow how its size evolves over time,
+// e.g. as a lightweight in-process leak detector
+type Tracker struct {
+	root interface{}
+	opts []Option
+
+	mu sync.Mutex
+	// samples is a ring buffer of which next is the oldest entry once it is full
+	samples []Sample
+	next    int
+	full    bool
+}
+
+// Sample is a single measurement taken by a Tracker
+type Sample struct {
+	Time   time.Time
+	Report *Report
+}
+
+// Size returns the total size of the sample
+func (s Sample) Size() uint64 {
+	return s.Report.Total()
+}
+
+// NewTracker creates a tracker measuring root with opts and keeping the last capacity samples.
+// It panics if capacity is less than 2, which is the minimum to compute a growth.
+func NewTracker(root interface{}, capacity int, opts ...Option) *Tracker {
+	if capacity < 2 {
+		panic("memsize: tracker capacity must be at least 2")
+	}
+	return &Tracker{root: root, opts: opts, samples: make([]Sample, 0, capacity)}
+}
+
+// Sample measures the root and records the result, dropping the oldest sample if the
+// tracker is full
+func (t *Tracker) Sample() Sample {
+	s := Sample{Time: time.Now(), Report: GetReport(t.root, t.opts...)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		t.samples = append(t.samples, s)
+		t.full = len(t.samples) == cap(t.samples)
+		return s
+	}
+	t.samples[t.next] = s
+	t.next = (t.next + 1) % len(t.samples)
+	return s
+}
+
+// Samples returns the recorded samples, oldest first
+func (t *Tracker) Samples() []Sample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	samples := make([]Sample, 0, len(t.samples))
+	samples = append(samples, t.samples[t.next:]...)
+	return append(samples, t.samples[:t.next]...)
+}
+
+// GrowthRate returns the growth of the root in bytes per second, the slope of a least-squares
+// fit over the recorded samples. It is zero until two samples were taken.
+func (t *Tracker) GrowthRate() float64 {
+	samples := t.Samples()
+	if len(samples) < 2 {
+		return 0
+	}
+
+	// Times are relative to the first sample so that the sums stay precise
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.Time.Sub(samples[0].Time).Seconds()
+		y := float64(s.Size())
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// TopGrowth returns the k paths that grew the most over the last n samples, largest growth
+// first. As in Diff, the size of a path includes its children; the root is left out since
+// it accounts for the whole growth. Fewer samples are compared if fewer were recorded.
+func (t *Tracker) TopGrowth(n, k int) []DiffEntry {
+	samples := t.Samples()
+	if n > len(samples) {
+		n = len(samples)
+	}
+	if n < 2 || k <= 0 {
+		return nil
+	}
+
+	first, last := samples[len(samples)-n].Report, samples[len(samples)-1].Report
+	var grown []DiffEntry
+	for _, e := range first.Diff(last).Entries {
+		if e.Delta > 0 && e.Path != last.Root.Path {
+			grown = append(grown, e)
+		}
+	}
+	// Diff sorts by absolute change, so the remaining entries are already in order
+	if len(grown) > k {
+		grown = grown[:k]
+	}
+	return grown
+}
@@ -0,0 +1,70 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	Debug = false
+
+	type cache struct {
+		Entries map[int]string
+		Static  []byte
+	}
+	c := &cache{Entries: make(map[int]string), Static: make([]byte, 100)}
+	tracker := NewTracker(c, 3)
+
+	if rate := tracker.GrowthRate(); rate != 0 {
+		t.Errorf("Expected no growth without samples, got %f", rate)
+	}
+
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 100; j++ {
+			c.Entries[len(c.Entries)] = "leaked value"
+		}
+		tracker.Sample()
+		time.Sleep(time.Millisecond)
+	}
+
+	samples := tracker.Samples()
+	if len(samples) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(samples))
+	}
+	for i := 1; i < len(samples); i++ {
+		if !samples[i].Time.After(samples[i-1].Time) || samples[i].Size() <= samples[i-1].Size() {
+			t.Errorf("Expected samples oldest first with growing sizes, got %d after %d",
+				samples[i].Size(), samples[i-1].Size())
+		}
+	}
+
+	rate := tracker.GrowthRate()
+	fmt.Printf("Growth rate: %.0f bytes/s\n", rate)
+	if rate <= 0 {
+		t.Errorf("Expected a positive growth rate, got %f", rate)
+	}
+
+	top := tracker.TopGrowth(3, 1)
+	fmt.Printf("Top growth: %+v\n", top)
+	if len(top) != 1 || top[0].Path != "root.ptr" {
+		t.Fatalf("Expected the pointed-to cache to grow the most, got %+v", top)
+	}
+	top = tracker.TopGrowth(10, 3)
+	if len(top) < 2 || top[1].Path != "root.ptr.Entries" {
+		t.Errorf("Expected the entries to grow next, got %+v", top)
+	}
+	for _, e := range top {
+		if e.Path == "root.ptr.Static" {
+			t.Errorf("Expected the static slice not to grow")
+		}
+	}
+
+	t.Run("Single Sample", func(t *testing.T) {
+		tracker := NewTracker(c, 2)
+		tracker.Sample()
+		if top := tracker.TopGrowth(2, 5); top != nil {
+			t.Errorf("Expected no growth from a single sample, got %+v", top)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// typedesc.go
+package memsize
+
+import (
+	"reflect"
+	"sync"
+)
+
+// WithTypeDescriptors also accounts for the runtime type descriptors and itabs reached through
+// interfaces, once per type and per interface and type pair. They are shared by all values of
+// a type and mostly live in the binary rather than on the heap, but matter for programs holding
+// many values of many different types in interfaces. Their sizes are estimated from the
+// runtime's layout, without the names and kind-specific data such as struct fields.
+func WithTypeDescriptors() Option {
+	return func(c *config) {
+		c.typeDescriptors = true
+	}
+}
+
+// descriptorSet tracks the type descriptors and itabs already accounted, shared by all walkers
+// of a measurement. All of its methods are no-ops on nil, which is the case unless
+// WithTypeDescriptors is set.
+type descriptorSet struct {
+	mu    sync.Mutex
+	types map[reflect.Type]bool
+	itabs map[[2]reflect.Type]bool
+}
+
+func newDescriptorSet(cfg *config) *descriptorSet {
+	if !cfg.typeDescriptors {
+		return nil
+	}
+	return &descriptorSet{types: make(map[reflect.Type]bool), itabs: make(map[[2]reflect.Type]bool)}
+}
+
+// size returns the size of the descriptors an interface of type iface holding a value of type
+// elem refers to that were not accounted yet
+func (s *descriptorSet) size(iface, elem reflect.Type, l layout) uint64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var size uint64
+	if !s.types[elem] {
+		s.types[elem] = true
+		size += typeDescriptorSize(elem, l)
+	}
+	// Empty interfaces refer to the type descriptor directly
+	if n := iface.NumMethod(); n > 0 && !s.itabs[[2]reflect.Type{iface, elem}] {
+		s.itabs[[2]reflect.Type{iface, elem}] = true
+		// The interface and type pointers, the hash with padding and the method table
+		size += 2*l.word() + 8 + uint64(n)*l.word()
+	}
+	return size
+}
+
+// typeDescriptorSize estimates the size of the runtime's descriptor of t: the common part
+// shared by all kinds and, for named types, the method table of the uncommon part
+func typeDescriptorSize(t reflect.Type, l layout) uint64 {
+	// Size, pointer bytes, equality function and GC data, then hash, flags, alignments,
+	// kind, name and pointer-to-this offsets
+	size := 4*l.word() + 16
+	if t.Name() != "" {
+		size += 16 + 16*uint64(t.NumMethod())
+	}
+	return size
+}
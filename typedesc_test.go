@@ -0,0 +1,55 @@
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type shape interface {
+	Area() float64
+}
+
+type square struct{ Side float64 }
+
+func (s square) Area() float64 { return s.Side * s.Side }
+
+type circle struct{ Radius float64 }
+
+func (c circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+func TestWithTypeDescriptors(t *testing.T) {
+	Debug = false
+
+	shapes := []shape{square{1}, square{2}, circle{1}, circle{2}, square{3}}
+	plain := GetTotalSize(shapes)
+	withDescs := GetTotalSize(shapes, WithTypeDescriptors())
+	fmt.Printf("Shapes: %d, with type descriptors: %d\n", plain, withDescs)
+
+	// Two named types with one method each, and an itab with one method per type
+	perType := typeDescriptorSize(reflect.TypeOf(square{}), layout{}) + 2*8 + 8 + 8
+	if extra := withDescs - plain; extra != 2*perType {
+		t.Errorf("Expected %d bytes of descriptors for 2 types, got %d", 2*perType, extra)
+	}
+
+	t.Run("Empty Interface", func(t *testing.T) {
+		values := []interface{}{square{1}, square{2}, []byte("x")}
+		extra := GetTotalSize(values, WithTypeDescriptors()) - GetTotalSize(values)
+		expected := typeDescriptorSize(reflect.TypeOf(square{}), layout{}) +
+			typeDescriptorSize(reflect.TypeOf([]byte(nil)), layout{})
+		if extra != expected {
+			t.Errorf("Expected %d bytes of descriptors without itabs, got %d", expected, extra)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		many := make([]shape, 5000)
+		for i := range many {
+			many[i] = square{float64(i)}
+		}
+		extra := GetTotalSize(many, WithTypeDescriptors(), WithParallelism(4)) - GetTotalSize(many)
+		if extra != perType {
+			t.Errorf("Expected descriptors counted once across workers, got %d instead of %d", extra, perType)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+// typefilter.go
+package memsize
+
+import (
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+// WithIncludeTypes traverses only values of named types whose package path and name, e.g.
+// "github.com/me/app/cache.Entry", match re, such as regexp.MustCompile(`^github\.com/me/app/`).
+// Values of other named types, including those of the standard library and third-party
+// containers, count only their own inline size, not the memory they reference. Predeclared
+// types such as string and unnamed types such as []byte or map[string]*Entry are always
+// traversed, so the strings, slices and maps held by included types are counted.
+func WithIncludeTypes(re *regexp.Regexp) Option {
+	return func(c *config) {
+		c.includeTypes = &typeFilter{re: re}
+	}
+}
+
+// typeFilter caches which named types match the pattern of WithIncludeTypes
+type typeFilter struct {
+	re *regexp.Regexp
+	// matched holds a bool per reflect.Type
+	matched sync.Map
+}
+
+// excludes reports whether values of type t are not traversed; all methods of typeFilter are
+// no-ops on nil, which is the case unless WithIncludeTypes is set
+func (f *typeFilter) excludes(t reflect.Type) bool {
+	if f == nil || t.Name() == "" || t.PkgPath() == "" {
+		return false
+	}
+	if m, ok := f.matched.Load(t); ok {
+		return !m.(bool)
+	}
+	m := f.re.MatchString(t.PkgPath() + "." + t.Name())
+	f.matched.Store(t, m)
+	return !m
+}
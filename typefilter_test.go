@@ -0,0 +1,62 @@
+package memsize
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"regexp"
+	"testing"
+)
+
+func TestIncludeTypes(t *testing.T) {
+	Debug = false
+	exact := WithSizeModel(ExactSizes)
+	mine := WithIncludeTypes(regexp.MustCompile(`^github\.com/afshin-deriv/go-memsize\.`))
+
+	type session struct {
+		ID   string
+		Data []byte
+	}
+	type server struct {
+		Sessions map[string]*session
+		Log      bytes.Buffer
+		Big      *big.Int
+		Any      interface{}
+	}
+	s := &server{
+		Sessions: map[string]*session{"a": {ID: "a", Data: make([]byte, 1000)}},
+		Big:      new(big.Int).Lsh(big.NewInt(1), 1<<16),
+		Any:      bytes.NewBuffer(make([]byte, 4096)),
+	}
+	s.Log.Write(make([]byte, 8192))
+
+	t.Run("Own Types", func(t *testing.T) {
+		all := GetTotalSize(s, exact)
+		included := GetTotalSize(s, exact, mine)
+		fmt.Printf("All types: %d bytes, own types: %d bytes\n", all, included)
+		if all < included+8192+4096+8192 {
+			t.Errorf("Expected the buffers and the big.Int not to be counted, got %d of %d", included, all)
+		}
+		// Sessions are counted in full
+		if included < 1000 {
+			t.Errorf("Expected the session data to be counted, got %d", included)
+		}
+		if report := GetReport(s, exact, mine); report.Total() != included {
+			t.Errorf("Expected the report total %d to match %d", report.Total(), included)
+		}
+	})
+
+	t.Run("Shallow", func(t *testing.T) {
+		// Excluded values count their own size: the pointer and the big.Int it refers to
+		if size := GetTotalSize(s.Big, exact, mine); size != 8+GetTotalSize(big.Int{}, exact) {
+			t.Errorf("Expected the pointer and the big.Int header, got %d", size)
+		}
+	})
+
+	t.Run("Everything", func(t *testing.T) {
+		all := WithIncludeTypes(regexp.MustCompile(``))
+		if got := GetTotalSize(s, exact, all); got != GetTotalSize(s, exact) {
+			t.Errorf("Expected a pattern matching all types to count %d, got %d", GetTotalSize(s, exact), got)
+		}
+	})
+}
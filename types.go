@@ -0,0 +1,26 @@
+// types.go
+package memsize
+
+import "reflect"
+
+// TypeStats holds the aggregated memory usage of a single concrete type
+type TypeStats struct {
+	// Count is the number of values of this type encountered during traversal
+	Count uint64
+	// Bytes is the shallow size of those values, excluding memory attributed to their children
+	Bytes uint64
+}
+
+// GetSizeByType returns the memory usage of v aggregated per concrete type.
+// The Bytes of all entries add up to the total size of v.
+func GetSizeByType(v interface{}, opts ...Option) map[string]TypeStats {
+	w := newWalker(opts...)
+	w.byType = make(map[string]*TypeStats)
+	w.measure(reflect.ValueOf(v))
+
+	result := make(map[string]TypeStats, len(w.byType))
+	for name, stats := range w.byType {
+		result[name] = *stats
+	}
+	return result
+}
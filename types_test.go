@@ -0,0 +1,50 @@
+package memsize
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetSizeByType(t *testing.T) {
+	Debug = false
+
+	t.Run("Totals Match", func(t *testing.T) {
+		person := &Person{
+			Name:    "John Doe",
+			Friends: make([]*Person, 0, 2),
+			Data: map[string]interface{}{
+				"age":     30,
+				"hobbies": []string{"reading", "coding"},
+			},
+		}
+		person.Friends = append(person.Friends, &Person{Name: "Jane Doe", Friends: []*Person{person}})
+
+		stats := GetSizeByType(person)
+		var sum uint64
+		for name, s := range stats {
+			fmt.Printf("%s: count=%d bytes=%d\n", name, s.Count, s.Bytes)
+			sum += s.Bytes
+		}
+
+		if total := GetTotalSize(person); sum != total {
+			t.Errorf("Per-type bytes add up to %d, expected total %d", sum, total)
+		}
+	})
+
+	t.Run("Counts", func(t *testing.T) {
+		stats := GetSizeByType([]string{"a", "bb", "ccc"})
+
+		if got := stats["string"].Count; got != 3 {
+			t.Errorf("Expected 3 strings, got %d", got)
+		}
+		if got := stats["[]string"].Count; got != 1 {
+			t.Errorf("Expected 1 slice, got %d", got)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		if stats := GetSizeByType(nil); len(stats) != 0 {
+			t.Errorf("Expected no types for nil, got %v", stats)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// unique.go
+package memsize
+
+import (
+	"reflect"
+	"strings"
+)
+
+func init() {
+	typeMatchers = append(typeMatchers, matchUniqueHandle, matchWeakPointer)
+}
+
+// WithUniqueValues attributes the values interned by unique.Handle to every handle referencing
+// them. By default only the handle itself is counted, since interned values are shared by the
+// whole process.
+func WithUniqueValues() Option {
+	return func(c *config) {
+		c.uniqueValues = true
+	}
+}
+
+// WithWeakPointers follows weak.Pointer values as if they were strong. By default only the weak
+// pointer itself is counted, since it doesn't keep its target alive.
+func WithWeakPointers() Option {
+	return func(c *config) {
+		c.weakPointers = true
+	}
+}
+
+// genericName reports whether t is an instantiation of the named generic type of package pkg
+func genericName(t reflect.Type, pkg, name string) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == pkg && strings.HasPrefix(t.Name(), name+"[")
+}
+
+// matchUniqueHandle handles unique.Handle[T], which holds a *T to the interned value
+func matchUniqueHandle(t reflect.Type) typeHandler {
+	if !genericName(t, "unique", "Handle") || t.NumField() != 1 || t.Field(0).Type.Kind() != reflect.Ptr {
+		return nil
+	}
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		if !w.cfg.uniqueValues {
+			return inlineSize(t, w.cfg.layout()), nil
+		}
+		// The pointer accounts for the handle holding it
+		return 0, []handledChild{{v: v.Field(0), suffix: ".value"}}
+	}
+}
+
+// matchWeakPointer handles weak.Pointer[T], whose target can only be obtained from its Value method
+func matchWeakPointer(t reflect.Type) typeHandler {
+	if !genericName(t, "weak", "Pointer") {
+		return nil
+	}
+	value, ok := t.MethodByName("Value")
+	if !ok || value.Type.NumOut() != 1 {
+		return nil
+	}
+
+	return func(w *walker, v reflect.Value, path string) (uint64, []handledChild) {
+		if !w.cfg.weakPointers {
+			return inlineSize(t, w.cfg.layout()), nil
+		}
+		p, ok := pointerTo(v)
+		if !ok {
+			w.strict.reject(path, t, "weak pointers of unexported fields reached by value cannot be loaded")
+			return inlineSize(t, w.cfg.layout()), nil
+		}
+		// Values created from the pointer may call methods even if v was reached through an
+		// unexported field. The strong pointer accounts for the weak one.
+		target := reflect.NewAt(t, p).Elem().Method(value.Index).Call(nil)[0]
+		return 0, []handledChild{{v: target, suffix: ".value"}}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build go1.24
+
+package memsize
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"unique"
+	"weak"
+)
+
+func TestUnique(t *testing.T) {
+	Debug = false
+
+	t.Run("Handle", func(t *testing.T) {
+		h := unique.Make(strings.Repeat("x", 1000))
+		v := &struct{ A, B unique.Handle[string] }{h, h}
+
+		if size := GetTotalSize(v, WithSizeModel(ExactSizes)); size != 8+2*8 {
+			t.Errorf("Expected only the handles to be counted, got %d", size)
+		}
+		// The interned string is counted once
+		if size := GetTotalSize(v, WithSizeModel(ExactSizes), WithUniqueValues()); size != 8+2*8+16+1000 {
+			t.Errorf("Expected the interned string to be counted once, got %d", size)
+		}
+	})
+
+	t.Run("Weak Pointer", func(t *testing.T) {
+		b := &blob{Data: make([]byte, 1000)}
+		v := &struct{ Cache weak.Pointer[blob] }{weak.Make(b)}
+
+		if size := GetTotalSize(v, WithSizeModel(ExactSizes)); size != 8+8 {
+			t.Errorf("Expected only the weak pointer to be counted, got %d", size)
+		}
+		if size := GetTotalSize(v, WithSizeModel(ExactSizes), WithWeakPointers()); size != 8+8+24+1000 {
+			t.Errorf("Expected the target to be followed, got %d", size)
+		}
+		runtime.KeepAlive(b)
+	})
+}
@@ -0,0 +1,52 @@
+// unsafeptr.go
+package memsize
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// unsafeTypes holds every type registered with WithUnsafePointerType by any measurement.
+// Plans treat them as variable-sized, since whether they are followed depends on the options.
+var unsafeTypes sync.Map
+
+// WithUnsafePointerType follows values of ptrType, an unsafe.Pointer or uintptr type such as
+// `type cBuffer unsafe.Pointer`, as pointers to pointeeType. Non-zero values must point to
+// memory holding a valid pointeeType, e.g. a struct allocated in C; targets are counted once
+// like those of ordinary pointers. Other unsafe.Pointer and uintptr values are not followed.
+func WithUnsafePointerType(ptrType, pointeeType reflect.Type) Option {
+	return func(c *config) {
+		if k := ptrType.Kind(); k != reflect.UnsafePointer && k != reflect.Uintptr {
+			c.err = fmt.Errorf("memsize: %s is not an unsafe.Pointer or uintptr type", ptrType)
+			return
+		}
+		if c.unsafeTypes == nil {
+			c.unsafeTypes = make(map[reflect.Type]reflect.Type)
+		}
+		c.unsafeTypes[ptrType] = pointeeType
+
+		if _, loaded := unsafeTypes.LoadOrStore(ptrType, true); !loaded {
+			// Plans computed before may have folded the type as a constant
+			resetPlans()
+		}
+	}
+}
+
+// unsafeTarget returns the pointer to the registered pointee of an unsafe.Pointer or uintptr
+// value, if its type was registered with WithUnsafePointerType
+func (w *walker) unsafeTarget(v reflect.Value) (reflect.Value, bool) {
+	pointee, ok := w.cfg.unsafeTypes[v.Type()]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	var p unsafe.Pointer
+	if v.Kind() == reflect.UnsafePointer {
+		p = v.UnsafePointer()
+	} else {
+		addr := uintptr(v.Uint())
+		p = *(*unsafe.Pointer)(unsafe.Pointer(&addr))
+	}
+	return reflect.NewAt(pointee, p), true
+}
@@ -0,0 +1,50 @@
+package memsize
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+type rawBlob unsafe.Pointer
+
+type blobAddr uintptr
+
+func TestUnsafePointerType(t *testing.T) {
+	Debug = false
+
+	b := &blob{Data: make([]byte, 1000)}
+	v := &struct {
+		Raw  rawBlob
+		Addr blobAddr
+		Nil  rawBlob
+	}{Raw: rawBlob(b), Addr: blobAddr(uintptr(unsafe.Pointer(b)))}
+
+	opts := []Option{WithSizeModel(ExactSizes),
+		WithUnsafePointerType(reflect.TypeOf(rawBlob(nil)), reflect.TypeOf(blob{})),
+		WithUnsafePointerType(reflect.TypeOf(blobAddr(0)), reflect.TypeOf(blob{}))}
+
+	if size := GetTotalSize(v, WithSizeModel(ExactSizes)); size != 8+3*8 {
+		t.Errorf("Expected raw pointers not to be followed by default, got %d", size)
+	}
+	// The blob is counted once although both fields point to it
+	if size := GetTotalSize(v, opts...); size != 8+3*8+24+1000 {
+		t.Errorf("Expected the blob to be counted once, got %d", size)
+	}
+	if size := GetTotalSize(v, WithSizeModel(ExactSizes)); size != 8+3*8 {
+		t.Errorf("Expected registrations not to leak into other measurements, got %d", size)
+	}
+
+	report := GetReport(v, opts...)
+	if n := report.Root.Children[0].Children[0]; n.Type != "memsize.rawBlob" || n.Size != 8+24+1000 {
+		t.Errorf("Expected the raw pointer to retain the blob, got %s of %d bytes", n.Type, n.Size)
+	}
+	runtime.KeepAlive(b)
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := GetTotalSizeE(v, WithUnsafePointerType(reflect.TypeOf(0), reflect.TypeOf(blob{}))); err == nil {
+			t.Error("Expected an error for an int type")
+		}
+	})
+}
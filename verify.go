@@ -0,0 +1,173 @@
+// verify.go
+package memsize
+
+import (
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// Verification compares the computed size of a value with the growth of the Go heap when a deep
+// copy of it is allocated
+type Verification struct {
+	// Computed is the size of the copy as measured with the options given to Verify
+	Computed uint64
+	// HeapAlloc is how much runtime.MemStats.HeapAlloc grew by allocating the copy
+	HeapAlloc uint64
+	// Difference is Computed minus HeapAlloc: positive when the value is overestimated
+	Difference int64
+	// RelativeError is Difference as a fraction of HeapAlloc
+	RelativeError float64
+}
+
+// Verify allocates a deep copy of v with the garbage collector settled, measures how much the
+// heap grew, and compares it to the computed size of the copy. Large discrepancies point at
+// types the size model gets wrong. Compare with WithSizeClasses, since the heap grows by whole
+// size classes.
+//
+// The copy keeps pointers and maps shared within v shared. Channels are copied empty, and funcs
+// and unsafe pointers are copied as they are. Allocations of other goroutines while the copy is
+// made distort the result, and the two forced collections pause the whole program.
+func Verify(v interface{}, opts ...Option) Verification {
+	var before, after runtime.MemStats
+	// Two collections also release memory that was waiting for finalizers or a sweep
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	root := deepCopy(reflect.ValueOf(v))
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	computed := GetTotalSizeValue(root.Elem(), opts...)
+	// Were v no longer used, it could be collected while the copy is allocated
+	runtime.KeepAlive(v)
+	runtime.KeepAlive(root)
+
+	heap := after.HeapAlloc - before.HeapAlloc
+	if after.HeapAlloc < before.HeapAlloc {
+		heap = 0
+	}
+	r := Verification{Computed: computed, HeapAlloc: heap, Difference: int64(computed) - int64(heap)}
+	if heap > 0 {
+		r.RelativeError = float64(r.Difference) / float64(heap)
+	}
+	return r
+}
+
+// deepCopy returns a pointer to a deep copy of v, which is itself allocated on the heap like
+// the objects it references
+func deepCopy(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return reflect.New(reflect.TypeOf((*interface{})(nil)).Elem())
+	}
+	c := &copier{ptrs: make(map[visitKey]reflect.Value), maps: make(map[uintptr]reflect.Value)}
+	root := reflect.New(v.Type())
+	c.copy(root.Elem(), v)
+	return root
+}
+
+// copier deep-copies values, including unexported fields, preserving shared pointers and maps
+type copier struct {
+	ptrs map[visitKey]reflect.Value
+	maps map[uintptr]reflect.Value
+}
+
+// copy copies src into dst, which is addressable and of the same type
+func (c *copier) copy(dst, src reflect.Value) {
+	// Unexported fields are written through their address
+	if !dst.CanSet() {
+		dst = reflect.NewAt(dst.Type(), unsafe.Pointer(dst.UnsafeAddr())).Elem()
+	}
+
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		k := visitKey{addr: src.Pointer(), typ: src.Type().Elem()}
+		p, ok := c.ptrs[k]
+		if !ok {
+			p = reflect.New(src.Type().Elem())
+			c.ptrs[k] = p
+			c.copy(p.Elem(), src.Elem())
+		}
+		dst.Set(p)
+
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		elem := reflect.New(src.Elem().Type()).Elem()
+		c.copy(elem, src.Elem())
+		dst.Set(elem)
+
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			c.copy(dst.Field(i), src.Field(i))
+		}
+
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.copy(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		for i := 0; i < src.Len(); i++ {
+			c.copy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m, ok := c.maps[src.Pointer()]
+		if !ok {
+			m = reflect.MakeMapWithSize(src.Type(), src.Len())
+			c.maps[src.Pointer()] = m
+			iter := src.MapRange()
+			for iter.Next() {
+				key := reflect.New(src.Type().Key()).Elem()
+				c.copy(key, iter.Key())
+				val := reflect.New(src.Type().Elem()).Elem()
+				c.copy(val, iter.Value())
+				m.SetMapIndex(key, val)
+			}
+		}
+		dst.Set(m)
+
+	case reflect.Chan:
+		if !src.IsNil() {
+			dst.Set(reflect.MakeChan(src.Type(), src.Cap()))
+		}
+
+	case reflect.String:
+		if src.Len() > 0 {
+			dst.SetString(string(append([]byte(nil), src.String()...)))
+		}
+
+	case reflect.Bool:
+		dst.SetBool(src.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		dst.SetUint(src.Uint())
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(src.Float())
+	case reflect.Complex64, reflect.Complex128:
+		dst.SetComplex(src.Complex())
+	case reflect.UnsafePointer:
+		dst.SetPointer(src.UnsafePointer())
+
+	case reflect.Func:
+		if src.CanInterface() {
+			dst.Set(src)
+		} else if src.CanAddr() {
+			dst.Set(reflect.NewAt(src.Type(), unsafe.Pointer(src.UnsafeAddr())).Elem())
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package memsize
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	Debug = false
+
+	type record struct {
+		Name  string
+		Tags  []string
+		Attrs map[string]int
+		Next  *record
+		Any   interface{}
+		note  string
+	}
+	var head *record
+	for i := 0; i < 2000; i++ {
+		head = &record{
+			Name:  fmt.Sprintf("record-%06d", i),
+			Tags:  []string{"a", strings.Repeat("b", i%50)},
+			Attrs: map[string]int{"x": i},
+			Next:  head,
+			Any:   [3]int{i},
+			note:  "unexported",
+		}
+	}
+
+	r := Verify(head, WithSizeClasses())
+	fmt.Printf("Verify: %+v\n", r)
+	if math.Abs(r.RelativeError) > 0.1 {
+		t.Errorf("Expected the computed size to be within 10%% of the heap growth, got %+v", r)
+	}
+	if r.Difference != int64(r.Computed)-int64(r.HeapAlloc) {
+		t.Errorf("Expected the difference of %d and %d, got %d", r.Computed, r.HeapAlloc, r.Difference)
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		shared := &record{Name: "shared"}
+		v := []*record{shared, shared, {note: "private", Attrs: map[string]int{"a": 1}}}
+		c := deepCopy(reflect.ValueOf(v)).Elem().Interface().([]*record)
+		if c[0] == shared || c[0] != c[1] {
+			t.Error("Expected shared pointers to be copied once")
+		}
+		if c[2].note != "private" || c[2].Attrs["a"] != 1 {
+			t.Errorf("Expected unexported fields and maps to be copied, got %+v", c[2])
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		Verify(nil)
+	})
+}
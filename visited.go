@@ -0,0 +1,199 @@
+// visited.go
+package memsize
+
+import "unsafe"
+
+const (
+	// visitedShardBits are the top bits of a hash that choose the table of a visitedSet
+	visitedShardBits = 4
+	// visitedShards is the number of tables of a visitedSet, which grow independently so
+	// no single table has to be rehashed all at once
+	visitedShards = 1 << visitedShardBits
+	// visitedMinEntries is the initial size of a table
+	visitedMinEntries = 16
+)
+
+// visitEntry is a visitKey without pointers, so the garbage collector never scans visited
+// sets however large they grow. Types are identified by their runtime descriptor, which is
+// never freed.
+type visitEntry struct {
+	addr uintptr
+	typ  uintptr
+}
+
+func entryOf(k visitKey) visitEntry {
+	e := visitEntry{addr: k.addr}
+	if k.typ != nil {
+		e.typ = uintptr((*[2]unsafe.Pointer)(unsafe.Pointer(&k.typ))[1])
+	}
+	return e
+}
+
+// hash mixes the address and type of an entry with Fibonacci hashing, which spreads aligned
+// addresses evenly
+func (e visitEntry) hash() uint64 {
+	return (uint64(e.addr) ^ uint64(e.typ)*31) * 0x9E3779B97F4A7C15
+}
+
+// addrTable is an open addressing hash table of entries with linear probing. Empty slots
+// have a zero address, which no visited object has.
+type addrTable struct {
+	entries []visitEntry
+	n       int
+	// mapped is set when entries live outside the Go heap
+	mapped bool
+}
+
+// visitedSet is the addrSet of sequential traversals: a fixed number of addrTables chosen by
+// the top bits of the hash, since the low bits of aligned addresses stay zero when multiplied.
+// Tables are allocated on the Go heap or, for an arena set, in memory mapped outside of it,
+// which has to be freed.
+type visitedSet struct {
+	shards [visitedShards]addrTable
+	arena  bool
+}
+
+func newVisitedSet(arena bool) *visitedSet {
+	return &visitedSet{arena: arena}
+}
+
+func (s *visitedSet) visit(k visitKey) bool {
+	e := entryOf(k)
+	h := e.hash()
+	return s.shards[h>>(64-visitedShardBits)].insert(e, h/visitedShards, s.arena)
+}
+
+// remove forgets a visited key
+func (s *visitedSet) remove(k visitKey) {
+	e := entryOf(k)
+	h := e.hash()
+	s.shards[h>>(64-visitedShardBits)].remove(e, h/visitedShards)
+}
+
+// len returns the number of visited keys
+func (s *visitedSet) len() int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].n
+	}
+	return n
+}
+
+// bytes returns the memory taken by the tables
+func (s *visitedSet) bytes() uint64 {
+	var size uint64
+	for i := range s.shards {
+		size += s.shards[i].bytes()
+	}
+	return size
+}
+
+// reset forgets all keys, keeping the tables for reuse
+func (s *visitedSet) reset() {
+	for i := range s.shards {
+		t := &s.shards[i]
+		for j := range t.entries {
+			t.entries[j] = visitEntry{}
+		}
+		t.n = 0
+	}
+}
+
+// free releases the tables
+func (s *visitedSet) free() {
+	for i := range s.shards {
+		t := &s.shards[i]
+		freeEntries(t.entries, t.mapped)
+		*t = addrTable{}
+	}
+}
+
+func (t *addrTable) bytes() uint64 {
+	return uint64(cap(t.entries)) * uint64(unsafe.Sizeof(visitEntry{}))
+}
+
+// insert adds e with hash h, reporting whether it was present already
+func (t *addrTable) insert(e visitEntry, h uint64, arena bool) bool {
+	if 4*(t.n+1) > 3*len(t.entries) {
+		t.grow(arena)
+	}
+	mask := uint64(len(t.entries) - 1)
+	for i := h & mask; ; i = (i + 1) & mask {
+		switch t.entries[i] {
+		case e:
+			return true
+		case visitEntry{}:
+			t.entries[i] = e
+			t.n++
+			return false
+		}
+	}
+}
+
+// remove deletes e with hash h, shifting back the entries probed past it
+func (t *addrTable) remove(e visitEntry, h uint64) {
+	if t.n == 0 {
+		return
+	}
+	mask := uint64(len(t.entries) - 1)
+	i := h & mask
+	for t.entries[i] != e {
+		if t.entries[i] == (visitEntry{}) {
+			return
+		}
+		i = (i + 1) & mask
+	}
+	t.n--
+	for j := (i + 1) & mask; t.entries[j] != (visitEntry{}); j = (j + 1) & mask {
+		// An entry moves into the hole unless its home slot lies cyclically after the hole
+		home := t.entries[j].hash() / visitedShards & mask
+		if (j-home)&mask >= (j-i)&mask {
+			t.entries[i] = t.entries[j]
+			i = j
+		}
+	}
+	t.entries[i] = visitEntry{}
+}
+
+func (t *addrTable) grow(arena bool) {
+	size := 2 * len(t.entries)
+	if size < visitedMinEntries {
+		size = visitedMinEntries
+	}
+	old, mapped := t.entries, t.mapped
+	t.entries, t.mapped = allocEntries(size, arena)
+	t.n = 0
+	for _, e := range old {
+		if e != (visitEntry{}) {
+			t.insert(e, e.hash()/visitedShards, arena)
+		}
+	}
+	freeEntries(old, mapped)
+}
+
+// allocEntries allocates a zeroed table, outside the Go heap for arenas if the platform
+// supports it, and reports whether it did
+func allocEntries(n int, arena bool) ([]visitEntry, bool) {
+	if arena {
+		if entries := mapEntries(n); entries != nil {
+			return entries, true
+		}
+	}
+	return make([]visitEntry, n), false
+}
+
+func freeEntries(entries []visitEntry, mapped bool) {
+	if mapped && cap(entries) > 0 {
+		unmapEntries(entries)
+	}
+}
+
+// WithVisitedArena allocates the set of visited objects outside the Go heap, on Linux in
+// anonymous mapped memory released once the measurement is done, so sizing graphs with
+// hundreds of millions of pointers neither grows the heap nor triggers collections that
+// distort the result. Other platforms use the heap. Parallel traversals ignore it.
+func WithVisitedArena() Option {
+	return func(c *config) {
+		c.visitedArena = true
+	}
+}
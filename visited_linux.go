@@ -0,0 +1,24 @@
+// visited_linux.go
+package memsize
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// mapEntries allocates a table in anonymous mapped memory, or returns nil if mapping fails
+func mapEntries(n int) []visitEntry {
+	size := n * int(unsafe.Sizeof(visitEntry{}))
+	b, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil
+	}
+	return unsafe.Slice((*visitEntry)(unsafe.Pointer(&b[0])), n)
+}
+
+// unmapEntries releases a table allocated by mapEntries
+func unmapEntries(entries []visitEntry) {
+	size := cap(entries) * int(unsafe.Sizeof(visitEntry{}))
+	_ = syscall.Munmap(unsafe.Slice((*byte)(unsafe.Pointer(&entries[0])), size))
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+// visited_other.go
+package memsize
+
+// mapEntries is only supported on Linux; arenas fall back to the heap elsewhere
+func mapEntries(n int) []visitEntry {
+	return nil
+}
+
+func unmapEntries(entries []visitEntry) {}
@@ -0,0 +1,81 @@
+package memsize
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func TestVisitedSet(t *testing.T) {
+	Debug = false
+
+	t.Run("Against Map", func(t *testing.T) {
+		types := []reflect.Type{reflect.TypeOf(0), reflect.TypeOf(""), nil}
+		rng := rand.New(rand.NewSource(1))
+		s := newVisitedSet(false)
+		want := make(map[visitKey]bool)
+		for i := 0; i < 100000; i++ {
+			k := visitKey{addr: uintptr(rng.Intn(20000)+1) * 8, typ: types[rng.Intn(len(types))]}
+			if rng.Intn(4) == 0 {
+				s.remove(k)
+				delete(want, k)
+				continue
+			}
+			if seen := s.visit(k); seen != want[k] {
+				t.Fatalf("Expected visit of %v to report %v, got %v", k, want[k], seen)
+			}
+			want[k] = true
+		}
+		if s.len() != len(want) {
+			t.Errorf("Expected %d keys, got %d", len(want), s.len())
+		}
+		s.reset()
+		if s.len() != 0 || s.visit(visitKey{addr: 8}) {
+			t.Error("Expected an empty set after reset")
+		}
+	})
+
+	t.Run("Shards", func(t *testing.T) {
+		s := newVisitedSet(false)
+		typ := reflect.TypeOf([4]int{})
+		objects := make([]*[4]int, 1000)
+		for i := range objects {
+			objects[i] = new([4]int)
+			s.visit(visitKey{addr: uintptr(unsafe.Pointer(objects[i])), typ: typ})
+		}
+		for i := range s.shards {
+			if n := s.shards[i].n; n < len(objects)/visitedShards/4 {
+				t.Errorf("Expected entries spread across shards, got %d in shard %d", n, i)
+			}
+		}
+	})
+
+	items := make([]*[4]int, 100000)
+	for i := range items {
+		items[i] = new([4]int)
+	}
+	items[1] = items[0]
+
+	t.Run("Arena", func(t *testing.T) {
+		size, stats, err := GetTotalSizeStats(items, WithVisitedArena())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("Visited set: %d bytes for %d pointers\n", stats.VisitedBytes, stats.Pointers)
+		if size != GetTotalSize(items) {
+			t.Errorf("Expected %d bytes, got %d", GetTotalSize(items), size)
+		}
+		if stats.VisitedBytes < uint64(len(items))*16 {
+			t.Errorf("Expected the visited set to hold %d entries, got %d bytes", len(items), stats.VisitedBytes)
+		}
+	})
+
+	t.Run("Parallel", func(t *testing.T) {
+		size, stats, _ := GetTotalSizeStats(items, WithParallelism(4))
+		if size != GetTotalSize(items) || stats.VisitedBytes == 0 {
+			t.Errorf("Expected %d bytes and a visited set, got %d and %d bytes", GetTotalSize(items), size, stats.VisitedBytes)
+		}
+	})
+}
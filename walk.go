@@ -0,0 +1,47 @@
+// walk.go
+package memsize
+
+import "reflect"
+
+// WalkAction tells Walk how to continue after visiting a value
+type WalkAction int
+
+const (
+	// WalkContinue visits the children of the value
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren continues with the value's siblings without visiting its children.
+	// Objects it references are not visited through other paths either.
+	WalkSkipChildren
+	// WalkStop ends the traversal
+	WalkStop
+)
+
+// WalkFunc is called by Walk for every value with its path, as in Node.Path, and its
+// shallow size, the part of its size not attributed to any child
+type WalkFunc func(path string, val reflect.Value, shallow uint64) WalkAction
+
+// Walk traverses v like GetTotalSize and calls fn for every value reached, so custom
+// aggregations can be built on the traversal without reimplementing cycle detection.
+// Objects reachable through several paths are visited once. Elements of slices and maps
+// without indirections are folded into their container instead of visited. The error
+// reports why the traversal stopped early, as in GetTotalSizeE; WalkStop is not an error.
+func Walk(v interface{}, fn WalkFunc, opts ...Option) error {
+	w := newWalker(opts...)
+	w.visitor = fn
+	_, err := w.measure(reflect.ValueOf(v))
+	return err
+}
+
+// visit calls the visitor for a value that was just entered
+func (w *walker) visit(f *frame) {
+	if w.visitor == nil {
+		return
+	}
+	switch w.visitor(w.path(f), f.v, f.shallow) {
+	case WalkSkipChildren:
+		f.pruned = true
+	case WalkStop:
+		f.pruned = true
+		w.stopped = true
+	}
+}
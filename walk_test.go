@@ -0,0 +1,73 @@
+package memsize
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	Debug = false
+
+	friend := &Person{Name: strings.Repeat("x", 2000)}
+	person := &Person{
+		Name:    "John",
+		Friends: []*Person{friend, friend},
+		Data:    map[string]interface{}{"bio": strings.Repeat("y", 1500)},
+	}
+
+	t.Run("Large Strings", func(t *testing.T) {
+		var large []string
+		err := Walk(person, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			if val.Kind() == reflect.String && val.Len() > 1024 {
+				large = append(large, path)
+			}
+			return WalkContinue
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The shared friend is visited once
+		expected := []string{"root.ptr.Friends[0].ptr.Name", `root.ptr.Data["bio"].elem`}
+		if !reflect.DeepEqual(large, expected) {
+			t.Errorf("Expected %v, got %v", expected, large)
+		}
+	})
+
+	t.Run("Shallow Sizes Add Up", func(t *testing.T) {
+		var total uint64
+		Walk(person, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			total += shallow
+			return WalkContinue
+		})
+		if size := GetTotalSize(person); total != size {
+			t.Errorf("Expected shallow sizes to add up to %d, got %d", size, total)
+		}
+	})
+
+	t.Run("Skip Children", func(t *testing.T) {
+		var paths []string
+		Walk(person, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			paths = append(paths, path)
+			if path == "root.ptr.Friends" || path == "root.ptr.Data" {
+				return WalkSkipChildren
+			}
+			return WalkContinue
+		})
+		expected := []string{"root", "root.ptr", "root.ptr.Name", "root.ptr.Friends", "root.ptr.Data"}
+		if !reflect.DeepEqual(paths, expected) {
+			t.Errorf("Expected %v, got %v", expected, paths)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		visited := 0
+		err := Walk(person, func(path string, val reflect.Value, shallow uint64) WalkAction {
+			visited++
+			return WalkStop
+		})
+		if err != nil || visited != 1 {
+			t.Errorf("Expected to stop after the root without an error, visited %d: %v", visited, err)
+		}
+	})
+}